@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -14,8 +15,19 @@ import (
 var BuildIndexCmd = &cobra.Command{
 	Use:   "build-index",
 	Short: "Build file index for smart context selection",
-	Long:  `Build or update the file index for intelligent context selection during code analysis.`,
-	RunE:  buildIndex,
+	Long: `Build or update the file index for intelligent context selection during code analysis.
+
+With --map, also generate the repository map (package tree, package docs, key
+types, entry points and test layout) that is sent with every planning prompt,
+cache it for the current commit and print it.`,
+	RunE: buildIndex,
+}
+
+// buildRepoMap is set by --map
+var buildRepoMap bool
+
+func init() {
+	BuildIndexCmd.Flags().BoolVar(&buildRepoMap, "map", false, "Also generate and print the repository map")
 }
 
 func buildIndex(cmd *cobra.Command, args []string) error {
@@ -51,7 +63,7 @@ func buildIndex(cmd *cobra.Command, args []string) error {
 		logger.Info("Index is already up to date")
 		indexPath := filepath.Join(repoRoot, indexer.IndexDirName, indexer.IndexFileName)
 		logger.Info("Using existing index", "path", indexPath)
-		return nil
+		return writeRepoMap(idx, fileIndex, repoRoot)
 	}
 
 	logger.Info("Index built successfully", "files", len(fileIndex.Files), "modules", len(fileIndex.Modules))
@@ -76,5 +88,23 @@ func buildIndex(cmd *cobra.Command, args []string) error {
 		logger.Info("  - "+category, "count", count)
 	}
 
+	return writeRepoMap(idx, fileIndex, repoRoot)
+}
+
+// writeRepoMap generates (or reuses) the repository map for the index's
+// commit and prints it, if --map was given
+func writeRepoMap(idx *indexer.Indexer, fileIndex *indexer.FileIndex, repoRoot string) error {
+	if !buildRepoMap {
+		return nil
+	}
+
+	repoMap, err := idx.GetOrBuildRepoMap(fileIndex, 0)
+	if err != nil {
+		logger.Error("Failed to save repository map", "error", err)
+		return err
+	}
+
+	logger.Info("Repository map saved", "path", filepath.Join(repoRoot, indexer.IndexDirName, indexer.ProjectIndexName), "bytes", len(repoMap))
+	fmt.Print(repoMap)
 	return nil
 }
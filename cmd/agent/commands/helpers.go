@@ -6,6 +6,7 @@ import (
 
 	"intern/internal/config"
 	"intern/internal/errors"
+	"intern/internal/indexer"
 	"intern/internal/orchestrator"
 	"intern/internal/provider"
 	"intern/internal/repository"
@@ -23,11 +24,11 @@ type Dependencies struct {
 	JiraClient   interface{}            // JIRA ticketing client
 	SlackClient  *slackticketing.Client // set only when TicketingMode == "slack"
 	TicketingSvc *ticketing.Service
-	GitHubClient repository.RepositoryClient
-	RepoSvc      *repository.RepositoryService
+	RepoSvc      *repository.RepositoryService // the first repository's
 	State        *orchestrator.State
-	Agent        interface{} // AI agent (provider-agnostic)
-	Coordinator  *orchestrator.Coordinator
+	Agent        interface{}               // AI agent (provider-agnostic)
+	Coordinator  *orchestrator.Coordinator // the first repository's
+	Dispatcher   *orchestrator.Dispatcher  // routes tickets across all repositories
 	RepoPaths    *repository.RepositoryPath
 }
 
@@ -76,22 +77,12 @@ func InitDependencies(ctx context.Context) (*Dependencies, error) {
 		ticketingSvc = ticketing.NewService(client)
 	}
 
-	// Create repository path manager
+	// Base directory for repository clones
 	workingDir := cfg.WorkingDir
 	if workingDir == "" {
 		workingDir = "./workspace"
 	}
 
-	repoPaths, err := repository.NewRepositoryPath(workingDir, cfg.GitHubRepo)
-	if err != nil {
-		logger.Error("Failed to create repository path manager", "error", err)
-		return nil, err
-	}
-
-	// Initialize GitHub client and repository service
-	githubClient := github.NewClient(cfg.GitHubToken, cfg.GitHubOwner, cfg.GitHubRepo, repoPaths)
-	repoSvc := repository.NewRepositoryService(githubClient)
-
 	// Load state
 	stateFile := "agent_state.jsonc"
 	state := orchestrator.NewState(stateFile)
@@ -114,20 +105,68 @@ func InitDependencies(ctx context.Context) (*Dependencies, error) {
 	}
 	logger.Info("Initialized AI provider", "provider", cfg.AIProvider)
 
-	// Create coordinator
-	coordinator := orchestrator.NewCoordinator(ticketingSvc, repoSvc, agent, cfg, state, repoPaths)
+	// Optional model escalation ladder for planning and self-healing
+	escalation, err := provider.NewEscalationLadder(cfg)
+	if err != nil {
+		logger.Error("Failed to initialize AI escalation ladder: %v", err)
+		return nil, err
+	}
+	if len(escalation) > 0 {
+		logger.Info("Initialized AI escalation ladder", "tiers", cfg.AIEscalationLadder)
+	}
+
+	// Optional semantic retrieval, shared by every repository's retriever
+	var embedder indexer.Embedder
+	if cfg.EmbeddingsProvider != "" {
+		embedder, err = indexer.NewEmbedder(cfg.EmbeddingsProvider, cfg.EmbeddingsBaseURL, cfg.EmbeddingsModel, cfg.EmbeddingsAPIKey)
+		if err != nil {
+			logger.Error("Failed to initialize embeddings: %v", err)
+			return nil, err
+		}
+		logger.Info("Initialized semantic retrieval", "provider", cfg.EmbeddingsProvider, "model", cfg.EmbeddingsModel)
+	}
+
+	// One coordinator per repository, each with its own clone, client and
+	// journal; the dispatcher routes tickets between them
+	var targets []orchestrator.RepoTarget
+	for _, repo := range cfg.RepoConfigs() {
+		repoCfg := cfg.ForRepo(repo)
+		repoPaths, err := repository.NewRepositoryPath(workingDir, repo.Name)
+		if err != nil {
+			logger.Error("Failed to create repository path manager", "repo", repo.Name, "error", err)
+			return nil, err
+		}
+		githubClient, err := github.NewFromConfig(repoCfg, repoPaths)
+		if err != nil {
+			logger.Error("Failed to initialize GitHub client", "repo", repo.Name, "error", err)
+			return nil, err
+		}
+		coordinator := orchestrator.NewCoordinator(ticketingSvc, repository.NewRepositoryService(githubClient), agent, repoCfg, state, repoPaths)
+		coordinator.Escalation = escalation
+		if embedder != nil {
+			coordinator.Semantic = indexer.NewSemanticRetriever(repoPaths.Root(), embedder)
+		}
+		targets = append(targets, orchestrator.RepoTarget{Config: repo, Coordinator: coordinator})
+	}
+	dispatcher := orchestrator.NewDispatcher(ticketingSvc, cfg, state, targets...)
+	if len(targets) > 1 {
+		logger.Info("Routing tickets across repositories", "count", len(targets))
+	}
 
+	// The first repository is the fallback route, and the one single-repo
+	// commands operate on
+	coordinator := targets[0].Coordinator
 	return &Dependencies{
 		Config:       cfg,
 		JiraClient:   jiraClient,
 		SlackClient:  slackClient,
 		TicketingSvc: ticketingSvc,
-		GitHubClient: githubClient,
-		RepoSvc:      repoSvc,
+		RepoSvc:      coordinator.Repository,
 		State:        state,
 		Agent:        agent,
 		Coordinator:  coordinator,
-		RepoPaths:    repoPaths,
+		Dispatcher:   dispatcher,
+		RepoPaths:    coordinator.RepoPaths,
 	}, nil
 }
 
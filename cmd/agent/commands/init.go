@@ -39,6 +39,20 @@ JIRA_TRANSITION_DONE="31"
 GITHUB_TOKEN="your-github-token"
 GITHUB_OWNER="company"
 GITHUB_REPO="main-repo"
+# Several repositories: YAML list with per-repo base branch, allowed dirs, gates
+# and routing (JIRA components/labels); see docs/COORDINATOR.md
+# REPOS_FILE="./repos.yaml"
+# GitHub App authentication (replaces GITHUB_TOKEN when GITHUB_APP_ID is set)
+# GITHUB_APP_ID=123456
+# GITHUB_APP_INSTALLATION_ID=7890123
+# GITHUB_APP_PRIVATE_KEY="/secrets/app.pem"  # Path to the app's private key, or the PEM itself
+# GitHub Enterprise Server / custom endpoints
+# GITHUB_API_URL="https://ghe.company.com/api/v3/"  # Default: https://api.github.com/
+# GITHUB_GIT_URL=""                # Clone URL; derived from GITHUB_API_URL's host by default
+# GITHUB_CA_BUNDLE="/etc/ssl/corp-ca.pem"  # Extra CA certificates for the API and HTTPS git
+# GITHUB_SSH_KEY="/secrets/deploy_key"      # Clone and push over SSH with a deploy key
+# GITHUB_SSH_KEY_PASSPHRASE=""
+# GITHUB_SSH_KNOWN_HOSTS=""        # Default: ~/.ssh/known_hosts
 
 # AI Provider Configuration
 # Options: "anthropic" (cloud API) or "ollama" (local LLM)
@@ -51,6 +65,9 @@ ANTHROPIC_API_KEY="your-anthropic-api-key"
 # Make sure Ollama is running locally: https://ollama.ai
 OLLAMA_BASE_URL="http://localhost:11434"
 OLLAMA_MODEL="qwen2.5-coder:7b"  # Options: qwen2.5-coder:7b, deepseek-coder:6.7b, codellama:13b
+# Optional escalation ladder, cheapest first: planning moves up a tier on unparseable
+# output, self-healing uses one tier per attempt (e.g. "ollama:qwen2.5-coder:7b,anthropic")
+AI_ESCALATION_LADDER=""
 
 AGENT_USERNAME="ai-intern"
 POLLING_INTERVAL="30s"
@@ -60,10 +77,23 @@ WORKING_DIR="./workspace"  # Will be ./workspace/{GITHUB_REPO} automatically
 BASE_BRANCH="master"
 BRANCH_PREFIX="feature/"
 
+# Clone trimming for large repositories (cold starts)
+CLONE_DEPTH=0               # Commits of history to clone; 0 = full history
+CLONE_SINGLE_BRANCH=false   # Clone only BASE_BRANCH
+CLONE_SPARSE_PATHS=""       # Only check out these path prefixes (e.g. "services/api,go.mod,go.sum")
+
 CONTEXT_MAX_FILES=40
-CONTEXT_MAX_BYTES=32
+CONTEXT_MAX_BYTES=32768      # Larger files are shown signatures-only
+CONTEXT_MAX_TOKENS=0         # Token budget for repository context; 0 derives it from the AI model
 CONTEXT_CACHE_ENABLED=true  # Enable context caching for better performance
 CONTEXT_CACHE_TTL=1h         # Cache time-to-live (e.g., "1h", "30m")
+REPO_MAP_ENABLED=true        # Open planning prompts with a repository map (see build-index --map)
+
+# Semantic retrieval (optional): embed code chunks to match ticket prose
+EMBEDDINGS_PROVIDER=""       # ollama or openai (any OpenAI-compatible API); empty disables
+EMBEDDINGS_MODEL=""          # e.g. nomic-embed-text, text-embedding-3-small
+EMBEDDINGS_BASE_URL=""       # Default: OLLAMA_BASE_URL for ollama, https://api.openai.com/v1 for openai
+EMBEDDINGS_API_KEY=""
 
 PLAN_MAX_FILES=10
 ALLOWED_WRITE_DIRS="internal,cmd,pkg,docs,config,."
@@ -74,10 +104,40 @@ SELF_HEAL_MAX_ATTEMPTS=3     # Maximum healing attempts (default: 3)
 SELF_HEAL_ON_TESTS=true      # Retry on test failures
 SELF_HEAL_ON_VET=true        # Retry on vet failures
 SELF_HEAL_ON_BUILD=false     # Retry on build failures (usually not needed for Go)
+REQUIRE_TESTS=false          # Require _test.go changes per touched package and no coverage drop
+
+# Quality Gate Sandbox (go build/vet/test run AI-written code)
+SANDBOX_ENABLED=true         # Private filesystem and /proc, rlimits, nobody (non-root agents need uidmap + /etc/subuid)
+SANDBOX_ALLOW_NETWORK=false  # Network is off inside the sandbox by default
+SANDBOX_ALLOW_DEGRADED=false # Gates fail if the host can't isolate them; true runs them without namespaces
+SANDBOX_TIMEOUT=10m          # Wall-clock limit per command
+SANDBOX_CPU_SECONDS=600      # CPU time limit per command
+SANDBOX_MEMORY_MB=4096       # Address-space limit per command
+SANDBOX_ENV_ALLOWLIST=""     # Extra env vars passed to gates (secrets are always scrubbed)
 
 # Operational Mode
 DRY_RUN=false  # If true, process tickets but don't create PRs (preview mode)
 
+# Pull Request Settings
+PR_DRAFT=false                      # Open PRs as drafts
+PR_LABELS=""                        # Labels added to every PR (e.g. "ai-generated,needs-review")
+PR_REVIEWERS_FROM_CODEOWNERS=false  # Request reviews from CODEOWNERS of the changed files
+PR_REPORTER_GITHUB_MAP=""           # Ticket reporter to GitHub login (e.g. "Jane Doe=janed,bob@acme.com=bob")
+PR_ASSIGNEES=""                     # Logins to assign; "reporter" assigns the mapped reporter
+
+# Branch Maintenance (keeps open agent PRs current with BASE_BRANCH)
+BRANCH_MAINTENANCE_ENABLED=false  # Rebase/merge, re-gate and force-push (with lease) stale PR branches each cycle
+BRANCH_REFRESH_STRATEGY=rebase    # "rebase" (falls back to merge on conflict) or "merge"
+
+# Commit Identity and Signing
+COMMIT_AUTHOR_NAME="AI Intern"
+COMMIT_AUTHOR_EMAIL="ai-intern@example.com"
+COMMIT_SIGNING_FORMAT=""     # "gpg" (armored private key) or "ssh" (OpenSSH private key); empty = unsigned
+COMMIT_SIGNING_KEY=""        # Path to the private key; for gpg, also import it into gpg for branch maintenance
+COMMIT_SIGNING_PASSPHRASE="" # Only for encrypted keys
+COMMIT_CO_AUTHORS=""         # Co-authored-by on every commit (e.g. "Ops Bot <ops@acme.com>")
+COMMIT_REPORTER_EMAIL_MAP="" # Ticket reporter to email, credited as co-author (e.g. "Jane Doe=jane@acme.com")
+
 # Metrics Configuration
 METRICS_ENABLED=false  # Enable HTTP metrics server with Prometheus format
 METRICS_PORT=9090      # Port for metrics server (default: 9090)
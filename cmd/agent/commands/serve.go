@@ -46,7 +46,7 @@ func serve(cmd *cobra.Command, args []string) error {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
 	})
-	mux.Handle("/slack/events", slackticketing.NewHandler(deps.SlackClient, deps.Config.SlackSigningSecret, deps.Coordinator))
+	mux.Handle("/slack/events", slackticketing.NewHandler(deps.SlackClient, deps.Config.SlackSigningSecret, deps.Dispatcher))
 
 	addr := ":" + deps.Config.Port
 	server := &http.Server{
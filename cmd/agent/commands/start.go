@@ -42,7 +42,7 @@ func startAgent(cmd *cobra.Command, args []string) error {
 	}()
 
 	logger.Info("Starting AI Intern Agent MVP...")
-	deps.Coordinator.Run(ctx)
+	deps.Dispatcher.Run(ctx)
 
 	return nil
 }
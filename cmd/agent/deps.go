@@ -64,7 +64,11 @@ func InitDependencies(ctx context.Context) (*Dependencies, error) {
 	}
 
 	// Initialize GitHub client and repository service
-	githubClient := github.NewClient(cfg.GitHubToken, cfg.GitHubOwner, cfg.GitHubRepo, repoPaths)
+	githubClient, err := github.NewFromConfig(cfg, repoPaths)
+	if err != nil {
+		logger.Error("Failed to initialize GitHub client: %v", err)
+		return nil, err
+	}
 	repoSvc := repository.NewRepositoryService(githubClient)
 
 	// Load state
@@ -89,8 +93,19 @@ func InitDependencies(ctx context.Context) (*Dependencies, error) {
 	}
 	logger.Info("Initialized AI provider", "provider", cfg.AIProvider)
 
+	// Optional model escalation ladder for planning and self-healing
+	escalation, err := provider.NewEscalationLadder(cfg)
+	if err != nil {
+		logger.Error("Failed to initialize AI escalation ladder: %v", err)
+		return nil, err
+	}
+	if len(escalation) > 0 {
+		logger.Info("Initialized AI escalation ladder", "tiers", cfg.AIEscalationLadder)
+	}
+
 	// Create coordinator
 	coordinator := orchestrator.NewCoordinator(ticketingSvc, repoSvc, agent, cfg, state, repoPaths)
+	coordinator.Escalation = escalation
 
 	return &Dependencies{
 		Config:       cfg,
@@ -1,6 +1,16 @@
 package agent
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidResponse is wrapped by PlanChanges/FixErrors errors when the
+// model answered but its output couldn't be parsed into changes. Unlike
+// transport or API errors, this is a sign the model itself isn't up to the
+// task, so callers may escalate to a stronger model (see Tier). Metrics are
+// still returned alongside it, since the tokens were spent.
+var ErrInvalidResponse = errors.New("invalid JSON from model")
 
 // Agent is the interface that AI providers must implement.
 // It abstracts the code generation capability across different providers (Anthropic, OpenAI, etc.)
@@ -17,9 +27,11 @@ type Agent interface {
 	// FixErrors generates fixes for errors in previously generated code.
 	// Used by the self-healing system to iteratively improve code that fails quality gates.
 	// fileContents maps repo-relative paths to their current on-disk content, giving the
-	// model the ground truth it needs to emit accurate edit hunks.
+	// model the ground truth it needs to emit accurate edit hunks. history lists the
+	// earlier healing attempts for this ticket (oldest first) so the model doesn't
+	// repeat a fix that already failed.
 	// Returns the fixed changes, usage metrics, and any error.
-	FixErrors(ctx context.Context, ticketKey, ticketSummary, errorType, errorOutput string, previousChanges []CodeChange, fileContents map[string]string) ([]CodeChange, *UsageMetrics, error)
+	FixErrors(ctx context.Context, ticketKey, ticketSummary, errorType, errorOutput string, previousChanges []CodeChange, fileContents map[string]string, history []HealAttempt) ([]CodeChange, *UsageMetrics, error)
 }
 
 // UsageMetrics contains token usage and cost information for an AI operation.
@@ -29,6 +41,7 @@ type UsageMetrics struct {
 	OutputTokens  int     // Number of tokens in the generated response
 	TotalTokens   int     // InputTokens + OutputTokens
 	EstimatedCost float64 // Estimated cost in USD based on provider pricing
+	Model         string  // Model that produced the response (e.g. "qwen2.5-coder:7b")
 	ContextStats  ContextStats
 }
 
@@ -39,4 +52,14 @@ type ContextStats struct {
 	FilesIncluded int    // Number of files included in the context
 	ContextBytes  int    // Total size of context in bytes
 	Keywords      int    // Number of keywords extracted (only for smart context)
+	PackedTokens  int    // Estimated tokens of the packed context (only for smart context)
+	TokenBudget   int    // Token budget the context was packed into
+}
+
+// Tier is one rung of a model escalation ladder. Callers try the cheapest
+// tier first and move up only when it fails, so local models can handle easy
+// work and paid ones are reserved for what they can't.
+type Tier struct {
+	Name  string // "provider:model", for logs and heal history
+	Agent Agent
 }
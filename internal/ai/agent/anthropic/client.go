@@ -129,7 +129,7 @@ func (c *Client) PlanChanges(ctx context.Context, ticketKey, ticketSummary, tick
 				"response_length", len(raw),
 				"response_preview", raw[:util.Min(1000, len(raw))],
 				"stop_reason", cg.StopReason)
-			return nil, nil, nil, fmt.Errorf("invalid JSON from model: %w", err)
+			return nil, nil, metrics, fmt.Errorf("%w: %w", agent.ErrInvalidResponse, err)
 		}
 	}
 
@@ -138,8 +138,8 @@ func (c *Client) PlanChanges(ctx context.Context, ticketKey, ticketSummary, tick
 
 // FixErrors generates fixes for errors in previously generated code.
 // This is used by the self-healing system to iteratively improve code that fails quality gates.
-func (c *Client) FixErrors(ctx context.Context, ticketKey, ticketSummary, errorType, errorOutput string, previousChanges []agent.CodeChange, fileContents map[string]string) ([]agent.CodeChange, *agent.UsageMetrics, error) {
-	prompt := agent.BuildFixErrorsPrompt(ticketKey, ticketSummary, errorType, errorOutput, previousChanges, fileContents, agent.PlanPromptOptions{AllowBase64: true})
+func (c *Client) FixErrors(ctx context.Context, ticketKey, ticketSummary, errorType, errorOutput string, previousChanges []agent.CodeChange, fileContents map[string]string, history []agent.HealAttempt) ([]agent.CodeChange, *agent.UsageMetrics, error) {
+	prompt := agent.BuildFixErrorsPrompt(ticketKey, ticketSummary, errorType, errorOutput, previousChanges, fileContents, history, agent.PlanPromptOptions{AllowBase64: true})
 	logger.Debug("fix errors prompt in anthropic", "prompt", prompt)
 
 	reqBody := codeGenRequest{
@@ -211,7 +211,7 @@ func (c *Client) FixErrors(ctx context.Context, ticketKey, ticketSummary, errorT
 				"response_length", len(raw),
 				"response_preview", raw[:util.Min(1000, len(raw))],
 				"stop_reason", cg.StopReason)
-			return nil, nil, fmt.Errorf("invalid JSON from model: %w", err)
+			return nil, c.buildUsageMetrics(&cg.Usage, len(errorOutput)), fmt.Errorf("%w: %w", agent.ErrInvalidResponse, err)
 		}
 	}
 
@@ -412,6 +412,7 @@ func (c *Client) buildUsageMetrics(usage *Usage, contextBytes int) *agent.UsageM
 		OutputTokens:  usage.OutputTokens,
 		TotalTokens:   usage.InputTokens + usage.OutputTokens,
 		EstimatedCost: cost,
+		Model:         c.Model,
 		ContextStats: agent.ContextStats{
 			ContextBytes: contextBytes,
 			// Strategy, FilesIncluded, and Keywords will be set by the orchestrator
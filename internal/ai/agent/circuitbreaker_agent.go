@@ -72,13 +72,13 @@ func (a *CircuitBreakerAgent) PlanChanges(ctx context.Context, ticketKey, ticket
 }
 
 // FixErrors wraps the underlying agent's FixErrors with circuit breaker protection
-func (a *CircuitBreakerAgent) FixErrors(ctx context.Context, ticketKey, ticketSummary, errorType, errorOutput string, previousChanges []CodeChange, fileContents map[string]string) ([]CodeChange, *UsageMetrics, error) {
+func (a *CircuitBreakerAgent) FixErrors(ctx context.Context, ticketKey, ticketSummary, errorType, errorOutput string, previousChanges []CodeChange, fileContents map[string]string, history []HealAttempt) ([]CodeChange, *UsageMetrics, error) {
 	var changes []CodeChange
 	var metrics *UsageMetrics
 	var err error
 
 	cbErr := a.circuitBreaker.Execute(ctx, func(ctx context.Context) error {
-		changes, metrics, err = a.agent.FixErrors(ctx, ticketKey, ticketSummary, errorType, errorOutput, previousChanges, fileContents)
+		changes, metrics, err = a.agent.FixErrors(ctx, ticketKey, ticketSummary, errorType, errorOutput, previousChanges, fileContents, history)
 		return err
 	})
 
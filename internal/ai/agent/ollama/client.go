@@ -95,7 +95,7 @@ func (c *Client) PlanChanges(ctx context.Context, ticketKey, ticketSummary, tick
 	}
 
 	if genResp.Response == "" {
-		return nil, nil, nil, fmt.Errorf("empty response from ollama")
+		return nil, nil, nil, fmt.Errorf("%w: empty response from ollama", agent.ErrInvalidResponse)
 	}
 
 	raw := agent.SanitizeResponse(genResp.Response)
@@ -125,7 +125,7 @@ func (c *Client) PlanChanges(ctx context.Context, ticketKey, ticketSummary, tick
 				"response_length", len(raw),
 				"response_preview", raw[:util.Min(1000, len(raw))],
 				"model", c.Model)
-			return nil, nil, nil, fmt.Errorf("invalid JSON from model: %w", err)
+			return nil, nil, metrics, fmt.Errorf("%w: %w", agent.ErrInvalidResponse, err)
 		}
 	}
 
@@ -134,8 +134,8 @@ func (c *Client) PlanChanges(ctx context.Context, ticketKey, ticketSummary, tick
 
 // FixErrors generates fixes for errors in previously generated code.
 // This is used by the self-healing system to iteratively improve code that fails quality gates.
-func (c *Client) FixErrors(ctx context.Context, ticketKey, ticketSummary, errorType, errorOutput string, previousChanges []agent.CodeChange, fileContents map[string]string) ([]agent.CodeChange, *agent.UsageMetrics, error) {
-	prompt := agent.BuildFixErrorsPrompt(ticketKey, ticketSummary, errorType, errorOutput, previousChanges, fileContents, agent.PlanPromptOptions{AllowBase64: true})
+func (c *Client) FixErrors(ctx context.Context, ticketKey, ticketSummary, errorType, errorOutput string, previousChanges []agent.CodeChange, fileContents map[string]string, history []agent.HealAttempt) ([]agent.CodeChange, *agent.UsageMetrics, error) {
+	prompt := agent.BuildFixErrorsPrompt(ticketKey, ticketSummary, errorType, errorOutput, previousChanges, fileContents, history, agent.PlanPromptOptions{AllowBase64: true})
 	logger.Debug("fix errors prompt in ollama", "prompt_length", len(prompt))
 
 	reqBody := GenerateRequest{
@@ -188,7 +188,7 @@ func (c *Client) FixErrors(ctx context.Context, ticketKey, ticketSummary, errorT
 	}
 
 	if genResp.Response == "" {
-		return nil, nil, fmt.Errorf("empty response from ollama")
+		return nil, nil, fmt.Errorf("%w: empty response from ollama", agent.ErrInvalidResponse)
 	}
 
 	raw := agent.SanitizeResponse(genResp.Response)
@@ -208,7 +208,7 @@ func (c *Client) FixErrors(ctx context.Context, ticketKey, ticketSummary, errorT
 				"response_length", len(raw),
 				"response_preview", raw[:util.Min(1000, len(raw))],
 				"model", c.Model)
-			return nil, nil, fmt.Errorf("invalid JSON from model: %w", err)
+			return nil, c.buildUsageMetrics(&genResp, len(errorOutput)), fmt.Errorf("%w: %w", agent.ErrInvalidResponse, err)
 		}
 	}
 
@@ -244,6 +244,7 @@ func (c *Client) buildUsageMetrics(resp *GenerateResponse, contextBytes int) *ag
 		OutputTokens:  outputTokens,
 		TotalTokens:   totalTokens,
 		EstimatedCost: 0.0, // Local execution is free
+		Model:         c.Model,
 		ContextStats: agent.ContextStats{
 			ContextBytes: contextBytes,
 			// Strategy, FilesIncluded, and Keywords will be set by the orchestrator
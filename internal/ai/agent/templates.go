@@ -11,6 +11,10 @@ import (
 // previous changes touched large files.
 const maxFixContextFileBytes = 20 * 1024
 
+// maxHistoryHunkBytes caps each old/new block quoted in the previous
+// attempts section; the model only needs enough to recognise its own fix.
+const maxHistoryHunkBytes = 300
+
 // PlanPromptOptions configures the prompt generation
 type PlanPromptOptions struct {
 	AllowBase64 bool
@@ -31,7 +35,8 @@ func BuildPlanChangesPrompt(ticketKey, ticketSummary, ticketDescription, repoCon
 		"Fulfil every acceptance criterion in the ticket; add nothing beyond it.",
 		"Use POSIX-style relative paths under the repo root.",
 		`Files marked "(full content)" are shown verbatim and may be edited. Files marked "(signatures only)" show declarations without bodies and CANNOT be safely edited.`,
-		`Only use operation=edit on a file that is shown ABOVE in this exact prompt with its full content. Never guess, reconstruct, or assume the content of a file - including a file you know exists from context like a README, one shown signatures-only, or one implied by repo conventions but not shown at all (e.g. a variables file that must declare a new toggle). If you need to edit any file whose full content is not shown above, respond with EXACTLY this JSON object and nothing else: {"need_files":["relative/path.ext", "relative/other.ext"]} - you will be given the full content and asked again. Do not respond with a bare array like ["relative/path.ext"] - it must be the object shown above, with the "need_files" key.`,
+		`Files marked "(excerpt: lines ...)" show only those lines verbatim, each prefixed with its line number and "| ", followed by the signatures of the rest of the file. Edit such a file only within the lines shown, and never include the line number prefixes in an old or new block.`,
+		`Only use operation=edit on a file that is shown ABOVE in this exact prompt with its full content, or in an excerpt that includes every line you change. Never guess, reconstruct, or assume the content of a file - including a file you know exists from context like a README, one shown signatures-only, or one implied by repo conventions but not shown at all (e.g. a variables file that must declare a new toggle). If you need to edit any file whose full content is not shown above, respond with EXACTLY this JSON object and nothing else: {"need_files":["relative/path.ext", "relative/other.ext"]} - you will be given the full content and asked again. Do not respond with a bare array like ["relative/path.ext"] - it must be the object shown above, with the "need_files" key.`,
 		`If a name the ticket asked for (a resource, variable, identifier, etc.) already exists for something unrelated, don't fail the ticket - pick a clear, non-colliding alternative yourself and continue. Record what you changed and why in that change's "note" field, e.g. {"path":"...","operation":"edit","edits":[...],"note":"renamed X to Y: X already exists for an unrelated resource"}. Use "note" sparingly, only for judgment calls a human should be able to review or override - not for routine changes.`,
 	}
 	return fmt.Sprintf(
@@ -48,8 +53,9 @@ func BuildPlanChangesPrompt(ticketKey, ticketSummary, ticketDescription, repoCon
 // This is used by the self-healing system to iteratively improve code that fails quality gates.
 // fileContents maps repo-relative paths to their current on-disk content; it covers every
 // file from previousChanges plus every file referenced in errorOutput, so the model can see
-// what's actually on disk instead of guessing from a path list.
-func BuildFixErrorsPrompt(ticketKey, ticketSummary, errorType, errorOutput string, previousChanges []CodeChange, fileContents map[string]string, opts PlanPromptOptions) string {
+// what's actually on disk instead of guessing from a path list. history lists earlier
+// attempts for this ticket so the model can try a different approach.
+func BuildFixErrorsPrompt(ticketKey, ticketSummary, errorType, errorOutput string, previousChanges []CodeChange, fileContents map[string]string, history []HealAttempt, opts PlanPromptOptions) string {
 	rules := []string{
 		"Output ONLY a compact JSON array. No markdown, no backticks, no commentary.",
 		`For files shown below: {"path":"relative/path.go","operation":"edit","edits":[{"old":"<exact lines copied verbatim from the file content below, including indentation>","new":"<replacement lines>"}]}`,
@@ -64,6 +70,19 @@ func BuildFixErrorsPrompt(ticketKey, ticketSummary, errorType, errorOutput strin
 		"If you see interface/type errors with third-party libraries, REMOVE the library usage entirely and use simpler alternatives.",
 		"Use POSIX-style relative paths under repo root.",
 	}
+	if errorType == "coverage" {
+		rules = append(rules,
+			"This is a test-coverage failure, not a compile error: write tests. Add or extend _test.go files in the SAME directory as the code under test, using the same package name.",
+			"Cover the new or changed behaviour shown above, including error paths. Do NOT change non-test code, and do NOT delete, skip or weaken existing tests.",
+		)
+	}
+
+	if errorType == "merge_conflict" {
+		rules = append(rules,
+			"This is a merge conflict, not a compile error: for each file shown, replace every block from <<<<<<< through >>>>>>> with code that keeps the intent of BOTH sides.",
+			"Each edit's old block must include the complete conflict block, markers included. Leave no conflict markers behind and change nothing outside the conflicts.",
+		)
+	}
 
 	// Track which paths were touched by the previous attempt vs. merely
 	// referenced in the error output, for labeling below.
@@ -95,10 +114,15 @@ func BuildFixErrorsPrompt(ticketKey, ticketSummary, errorType, errorOutput strin
 		filesSection.WriteString("(no file contents available)\n")
 	}
 
+	if len(history) > 0 {
+		rules = append(rules, "The previous attempts listed above did NOT fix the problem. Do NOT repeat them - take a different approach.")
+	}
+
 	return fmt.Sprintf(
 		"You are a senior Go engineer fixing errors in code you previously generated.\n\n"+
 			"Original ticket: %s - %s\n\n"+
 			"%s\n"+
+			"%s"+
 			"Error type: %s\n"+
 			"Error output:\n```\n%s\n```\n\n"+
 			"Your task: Fix the errors above using the SIMPLEST possible solution.\n"+
@@ -107,8 +131,57 @@ func BuildFixErrorsPrompt(ticketKey, ticketSummary, errorType, errorOutput strin
 		strings.TrimSpace(ticketKey),
 		strings.TrimSpace(ticketSummary),
 		filesSection.String(),
+		formatHealHistory(history),
 		errorType,
 		strings.TrimSpace(errorOutput),
 		strings.Join(rules, "\n- "),
 	)
 }
+
+// formatHealHistory renders earlier healing attempts compactly: what error
+// each one targeted, which hunks it applied, and whether the error changed.
+func formatHealHistory(history []HealAttempt) string {
+	if len(history) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Previous fix attempts (already applied to the files above):\n")
+	for _, h := range history {
+		outcome := "the SAME error persisted"
+		if h.ErrorChanged {
+			outcome = "the error changed"
+		}
+		b.WriteString(fmt.Sprintf("\nAttempt %d (%s errors) - %s.\n", h.Attempt, h.ErrorType, outcome))
+		if sig := strings.TrimSpace(h.ErrorSignature); sig != "" {
+			b.WriteString("Targeted:\n")
+			for _, line := range strings.Split(sig, "\n") {
+				b.WriteString("  " + line + "\n")
+			}
+		}
+		if len(h.Changes) == 0 {
+			b.WriteString("Applied: (no changes)\n")
+			continue
+		}
+		b.WriteString("Applied:\n")
+		for _, ch := range h.Changes {
+			switch {
+			case ch.Operation == OperationEdit && len(ch.Edits) > 0:
+				b.WriteString(fmt.Sprintf("  - edit %s\n", ch.Path))
+				for _, e := range ch.Edits {
+					b.WriteString(fmt.Sprintf("      old: %q\n      new: %q\n", truncateHunk(e.Old), truncateHunk(e.New)))
+				}
+			default:
+				b.WriteString(fmt.Sprintf("  - %s %s\n", ch.Operation, ch.Path))
+			}
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func truncateHunk(s string) string {
+	if len(s) <= maxHistoryHunkBytes {
+		return s
+	}
+	return s[:maxHistoryHunkBytes] + "..."
+}
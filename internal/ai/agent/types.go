@@ -29,6 +29,16 @@ type CodeChange struct {
 	Note string `json:"note,omitempty"`
 }
 
+// HealAttempt is a compact record of an earlier self-healing attempt, fed
+// back into BuildFixErrorsPrompt so the model can see what it already tried.
+type HealAttempt struct {
+	Attempt        int          // 1-based attempt number
+	ErrorType      string       // "build", "vet", "test" or "coverage"
+	ErrorSignature string       // normalized error lines the attempt targeted
+	Changes        []CodeChange // fixes that were applied
+	ErrorChanged   bool         // whether the gates reported a different error afterwards
+}
+
 // ParseNeedFiles checks whether raw is a retrieval request rather than a
 // changes array: either the instructed {"need_files":["path", ...]} form,
 // or a bare ["path", ...] array of strings, which models sometimes emit
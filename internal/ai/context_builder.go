@@ -15,11 +15,13 @@ import (
 )
 
 // BuildRepoContext reads a subset of files (small text/code files) to provide
-// a lightweight context string for the LLM. It skips binaries, vendor, node_modules, and large files.
+// a lightweight context string for the LLM. It skips what the index excludes:
+// binaries, vendor, node_modules, large files and what the repo ignores.
 func BuildRepoContext(repoRoot string, maxFiles int, maxBytesPerFile int) string {
 	var b strings.Builder
 	count := 0
 	stop := errors.New("stop-walk")
+	idx := indexer.New(repoRoot)
 	_ = filepath.WalkDir(repoRoot, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return nil
@@ -28,14 +30,9 @@ func BuildRepoContext(repoRoot string, maxFiles int, maxBytesPerFile int) string
 		if rErr != nil {
 			return nil
 		}
-		lower := strings.ToLower(rel)
-		// Skip common large/noise directories early
+		// Skip noise directories early
 		if d.IsDir() {
-			if lower == ".git" || strings.HasPrefix(lower, ".git/") ||
-				lower == "vendor" || strings.HasPrefix(lower, "vendor/") ||
-				lower == "node_modules" || strings.HasPrefix(lower, "node_modules/") ||
-				lower == ".idea" || lower == ".vscode" ||
-				lower == "build" || lower == "dist" || lower == "out" {
+			if idx.Excluded(rel, true) {
 				return fs.SkipDir
 			}
 			return nil
@@ -43,8 +40,7 @@ func BuildRepoContext(repoRoot string, maxFiles int, maxBytesPerFile int) string
 		if count >= maxFiles {
 			return stop
 		}
-		// Skip obvious binaries
-		if hasAnySuffix(lower, ".png", ".jpg", ".jpeg", ".gif", ".pdf", ".zip", ".exe", ".bin", ".mp4", ".mov", ".dll") {
+		if idx.Excluded(rel, false) {
 			return nil
 		}
 		// Read up to maxBytesPerFile
@@ -75,35 +71,100 @@ func hasAnySuffix(s string, suff ...string) bool {
 }
 
 // fullContentTierSize is the number of highest-scored files rendered with
-// full content; the rest are rendered as signatures-only via
-// ExtractMinimalContext. The model can only produce valid edit "old" blocks
-// for files it has seen verbatim, so this tiering bounds which files are
-// eligible for operation=edit on the first planning call.
+// full content when there's no token budget; the rest are rendered as
+// signatures-only via ExtractMinimalContext. The model can only produce
+// valid edit "old" blocks for files it has seen verbatim, so this tiering
+// bounds which files are eligible for operation=edit on the first planning
+// call.
 const fullContentTierSize = 4
 
+// neighborBudgetBytes bounds the signatures of files added because a
+// full-content file depends on them or they depend on it (see
+// indexer.Neighbors); neighbors beyond it are left out
+const neighborBudgetBytes = 16 * 1024
+
+// repoMapShare is the share of a token budget the repository map (see
+// indexer.BuildRepoMap) may take; the cached base context takes a fifth
+// (see ContextCacheManager.BuildBaseContext)
+const repoMapShare = 0.1
+
+// RepoMapBytes returns how large the repository map may be within a token
+// budget, 0 (the map's own cap) without one
+func RepoMapBytes(tokens int) int {
+	if tokens <= 0 {
+		return 0
+	}
+	return int(float64(tokens)*repoMapShare) * charsPerToken
+}
+
+// ContextBudget bounds what PackSmartRepoContext includes
+type ContextBudget struct {
+	MaxFiles        int // Files selected by score, before related files are added
+	MaxBytesPerFile int // Larger files are shown as excerpts or signatures, never in full; 0 = no limit
+	Tokens          int // Estimated tokens for the whole context; 0 = no limit (fullContentTierSize applies)
+}
+
+// PackedContext is repository context packed by PackSmartRepoContext
+type PackedContext struct {
+	Text     string
+	Files    int // Files shown in any representation
+	Keywords int // Keywords extracted from the ticket
+	Tokens   int // Estimated tokens of Text
+
+	// Excerpts lists the lines shown of each file shown as an excerpt; edits
+	// to those files must stay within them
+	Excerpts map[string][]indexer.LineRange
+}
+
 // BuildSmartRepoContext builds repository context using intelligent file selection
 // based on keywords extracted from ticket description.
 // Falls back to BuildRepoContext if index is not available or keywords are empty.
 // Uses context caching to avoid rebuilding common files repeatedly.
 // forceFullContent names additional files (beyond the top-scored tier) that
 // must be rendered with full content - used for the retrieval pass when the
-// AI responds with {"need_files":[...]}.
-func BuildSmartRepoContext(repoRoot, ticketDescription string, maxFiles int, forceFullContent []string) (string, error) {
-	return BuildSmartRepoContextWithCache(repoRoot, ticketDescription, maxFiles, DefaultCacheConfig(), forceFullContent)
+// AI responds with {"need_files":[...]}. scoreOpts are passed to
+// indexer.ScoreFiles, e.g. indexer.WithSemantic.
+func BuildSmartRepoContext(repoRoot, ticketDescription string, maxFiles int, forceFullContent []string, scoreOpts ...indexer.ScoreOption) (string, error) {
+	return BuildSmartRepoContextWithCache(repoRoot, ticketDescription, maxFiles, DefaultCacheConfig(), forceFullContent, scoreOpts...)
 }
 
 // BuildSmartRepoContextWithCache builds repository context with caching support.
 // It combines a cached base context (core files) with ticket-specific context (relevant files).
-func BuildSmartRepoContextWithCache(repoRoot, ticketDescription string, maxFiles int, cacheConfig CacheConfig, forceFullContent []string) (string, error) {
+func BuildSmartRepoContextWithCache(repoRoot, ticketDescription string, maxFiles int, cacheConfig CacheConfig, forceFullContent []string, scoreOpts ...indexer.ScoreOption) (string, error) {
+	packed, err := PackSmartRepoContext(repoRoot, ticketDescription, ContextBudget{MaxFiles: maxFiles}, cacheConfig, forceFullContent, scoreOpts...)
+	if err != nil {
+		return "", err
+	}
+	return packed.Text, nil
+}
+
+// PackSmartRepoContext builds smart repository context (see
+// BuildSmartRepoContext) within budget. With a token budget, the cached base
+// context is sized from it, and each selected file is shown in full, as an
+// excerpt (see indexer.ExtractExcerpt), signatures-only or not at all,
+// chosen by PackContext to maximize relevance within the tokens left after
+// the base context; related files then fill what remains. Files over MaxBytesPerFile that would be
+// shown in full, including requested ones, are excerpted instead when they
+// have an excerpt.
+func PackSmartRepoContext(repoRoot, ticketDescription string, budget ContextBudget, cacheConfig CacheConfig, forceFullContent []string, scoreOpts ...indexer.ScoreOption) (*PackedContext, error) {
 	var baseContext string
+	var baseFiles []string
+	maxFiles := budget.MaxFiles
 	maxBytesPerFile := 32 * 1024
 
-	// Try to get cached base context if caching is enabled
+	// Try to get cached base context if caching is enabled; it takes a
+	// fifth of the token budget, if there is one, else of what maxFiles
+	// files could take
 	if cacheConfig.Enabled {
 		cacheMgr := NewContextCacheManager(cacheConfig)
-		cache, err := cacheMgr.GetOrBuildBaseContext(repoRoot, maxFiles*maxBytesPerFile)
+		contextBytes := maxFiles * maxBytesPerFile
+		if budget.Tokens > 0 {
+			contextBytes = budget.Tokens * charsPerToken
+		}
+		cache, err := cacheMgr.GetOrBuildBaseContext(repoRoot, contextBytes)
 		if err == nil && cache != nil {
 			baseContext = cache.BaseContext
+			baseFiles = cache.FilesIncluded
 			// Reduce maxFiles for ticket-specific context since we already have base context
 			maxFiles = maxFiles - len(cache.FilesIncluded)
 			if maxFiles < 5 {
@@ -118,14 +179,14 @@ func BuildSmartRepoContextWithCache(repoRoot, ticketDescription string, maxFiles
 	// Check if index exists
 	if !idx.IndexExists() {
 		// No index available, return error to signal fallback needed
-		return "", fmt.Errorf("file index not found, smart context unavailable")
+		return nil, fmt.Errorf("file index not found, smart context unavailable")
 	}
 
 	// Load index
 	fileIndex, err := idx.LoadIndex()
 	if err != nil {
 		// Failed to load index, return error
-		return "", fmt.Errorf("failed to load index: %w", err)
+		return nil, fmt.Errorf("failed to load index: %w", err)
 	}
 
 	// Extract keywords from ticket description
@@ -133,21 +194,15 @@ func BuildSmartRepoContextWithCache(repoRoot, ticketDescription string, maxFiles
 
 	// If no keywords, return error to signal fallback needed
 	if len(keywords) == 0 {
-		return "", fmt.Errorf("no keywords extracted from ticket description, smart context unavailable")
+		return nil, fmt.Errorf("no keywords extracted from ticket description, smart context unavailable")
 	}
 
 	// Score files based on keywords
-	scores := indexer.ScoreFiles(fileIndex, keywords)
+	scores := indexer.ScoreFiles(fileIndex, keywords, scoreOpts...)
 
-	// Filter out low-relevance files (score < 18.0) to reduce context size
-	// This helps prevent timeouts while keeping highly relevant files
-	const minRelevanceThreshold = 18.0
-	filteredScores := make([]indexer.FileScore, 0, len(scores))
-	for _, score := range scores {
-		if score.Score >= minRelevanceThreshold {
-			filteredScores = append(filteredScores, score)
-		}
-	}
+	// Filter out files that matched nothing or trail far behind the best
+	// match, to reduce context size while keeping highly relevant files
+	filteredScores := indexer.RelevantFiles(scores)
 
 	// Log filtering results for debugging
 	if len(scores) > len(filteredScores) {
@@ -160,16 +215,6 @@ func BuildSmartRepoContextWithCache(repoRoot, ticketDescription string, maxFiles
 	// Select top files from filtered results
 	topScores := indexer.SelectTopFiles(filteredScores, maxFiles)
 
-	// Determine which files get rendered with full content: the top-scored
-	// tier, plus any files explicitly requested via forceFullContent (the
-	// retrieval pass for {"need_files":[...]}).
-	fullContent := make(map[string]bool, fullContentTierSize+len(forceFullContent))
-	for i, fileScore := range topScores {
-		if i < fullContentTierSize {
-			fullContent[fileScore.Path] = true
-		}
-	}
-
 	// Files forced into the full-content tier that weren't already selected
 	// must still be added to the rendered set, e.g. a file the AI asked for
 	// that didn't score highly enough for the initial top-N selection.
@@ -178,8 +223,9 @@ func BuildSmartRepoContextWithCache(repoRoot, ticketDescription string, maxFiles
 	for _, fileScore := range selected {
 		selectedPaths[fileScore.Path] = true
 	}
+	forced := make(map[string]bool, len(forceFullContent))
 	for _, p := range forceFullContent {
-		fullContent[p] = true
+		forced[p] = true
 		if selectedPaths[p] {
 			continue
 		}
@@ -194,6 +240,137 @@ func BuildSmartRepoContextWithCache(repoRoot, ticketDescription string, maxFiles
 		selectedPaths[p] = true
 	}
 
+	// Render each selected file every way, then decide which to show
+	full := make(map[string]string, len(selected))
+	signatures := make(map[string]string, len(selected))
+	excerpts := make(map[string]*indexer.Excerpt)
+	for _, fileScore := range selected {
+		filePath := filepath.Join(repoRoot, fileScore.Path)
+		data, readErr := os.ReadFile(filePath)
+		if readErr != nil {
+			continue
+		}
+		full[fileScore.Path] = string(data)
+
+		// Extract minimal context (signatures only) for Go files; if
+		// extraction fails, the file is shown as is
+		context, err := indexer.ExtractMinimalContext(filePath)
+		if err != nil {
+			context = string(data)
+		}
+		signatures[fileScore.Path] = context
+
+		if excerpt, err := indexer.ExtractExcerpt(filePath, keywords); err == nil && excerpt != nil {
+			excerpts[fileScore.Path] = excerpt
+		}
+	}
+	tooBig := func(path string) bool {
+		return budget.MaxBytesPerFile > 0 && len(full[path]) > budget.MaxBytesPerFile
+	}
+
+	shown := make(map[string]Representation, len(selected))
+	remaining := 0
+	if budget.Tokens > 0 {
+		candidates := make([]PackCandidate, 0, len(selected))
+		for _, fileScore := range selected {
+			data, ok := full[fileScore.Path]
+			if !ok {
+				continue
+			}
+			c := PackCandidate{
+				Path:  fileScore.Path,
+				Value: fileScore.Score,
+				Costs: map[Representation]int{SignaturesOnly: EstimateTokensFromText(signatures[fileScore.Path])},
+			}
+			if excerpt := excerpts[fileScore.Path]; excerpt != nil {
+				c.Costs[Excerpt] = EstimateTokensFromText(excerpt.Text)
+			}
+			if forced[fileScore.Path] {
+				c.Min = FullContent
+				if tooBig(fileScore.Path) && excerpts[fileScore.Path] != nil {
+					c.Min = Excerpt
+				}
+			}
+			if c.Min == FullContent || !tooBig(fileScore.Path) {
+				c.Costs[FullContent] = EstimateTokensFromText(data)
+			}
+			candidates = append(candidates, c)
+		}
+		var used int
+		available := budget.Tokens - EstimateTokensFromText(baseContext)
+		shown, used = PackContext(candidates, available)
+		remaining = available - used
+	} else {
+		for i, fileScore := range selected {
+			if _, ok := full[fileScore.Path]; !ok {
+				continue
+			}
+			shown[fileScore.Path] = SignaturesOnly
+			if i < fullContentTierSize || forced[fileScore.Path] {
+				shown[fileScore.Path] = FullContent
+				if tooBig(fileScore.Path) && excerpts[fileScore.Path] != nil {
+					shown[fileScore.Path] = Excerpt
+				}
+			}
+		}
+	}
+	packed := selected[:0:0]
+	for _, fileScore := range selected {
+		if shown[fileScore.Path] != Omitted {
+			packed = append(packed, fileScore)
+		}
+	}
+	if len(packed) < len(selected) {
+		logger.Info("Packed context into token budget",
+			"budget", budget.Tokens,
+			"selected", len(selected),
+			"shown", len(packed))
+	}
+
+	// Explain why each file is included, in the header and per file
+	reasons := make(map[string]string, len(packed))
+	for _, fileScore := range packed {
+		reasons[fileScore.Path] = fmt.Sprintf("matched the ticket, relevance %.1f", fileScore.Score)
+		if forced[fileScore.Path] {
+			reasons[fileScore.Path] = "requested for editing"
+		}
+	}
+
+	// Add what the full-content and excerpted files depend on and what
+	// depends on them, signatures only, so edits are made against the
+	// interfaces they must satisfy and the callers they could break
+	var seeds []string
+	for _, fileScore := range packed {
+		if shown[fileScore.Path] >= Excerpt {
+			seeds = append(seeds, fileScore.Path)
+		}
+	}
+	for _, p := range baseFiles {
+		selectedPaths[p] = true
+	}
+	var neighbors []indexer.Neighbor
+	neighborContext := make(map[string]string)
+	neighborBytes := neighborBudgetBytes
+	for _, n := range indexer.Neighbors(repoRoot, fileIndex, seeds) {
+		if selectedPaths[n.Path] {
+			continue
+		}
+		context, err := indexer.ExtractMinimalContext(filepath.Join(repoRoot, n.Path))
+		if err != nil || len(context) > neighborBytes {
+			continue
+		}
+		if budget.Tokens > 0 {
+			if EstimateTokensFromText(context) > remaining {
+				continue
+			}
+			remaining -= EstimateTokensFromText(context)
+		}
+		neighborBytes -= len(context)
+		neighbors = append(neighbors, n)
+		neighborContext[n.Path] = context
+		reasons[n.Path] = n.Reason
+	}
+
 	// Build ticket-specific context from selected files
 	var sb strings.Builder
 
@@ -207,38 +384,54 @@ func BuildSmartRepoContextWithCache(repoRoot, ticketDescription string, maxFiles
 	// Add ticket-specific context
 	sb.WriteString(fmt.Sprintf("# Ticket-Specific Context (Smart Selection)\n"))
 	sb.WriteString(fmt.Sprintf("# Based on keywords: %v\n", keywords[:util.Min(5, len(keywords))]))
-	sb.WriteString(fmt.Sprintf("# Selected %d most relevant files\n\n", len(selected)))
-
-	for _, fileScore := range selected {
-		filePath := filepath.Join(repoRoot, fileScore.Path)
+	sb.WriteString(fmt.Sprintf("# Selected %d most relevant files and %d related files\n", len(packed), len(neighbors)))
+	if budget.Tokens > 0 && len(packed) < len(selected) {
+		sb.WriteString(fmt.Sprintf("# %d less relevant files left out to fit the token budget\n", len(selected)-len(packed)))
+	}
+	sb.WriteString("# Why each file is included:\n")
+	for _, fileScore := range packed {
+		sb.WriteString(fmt.Sprintf("#   %s: %s\n", fileScore.Path, reasons[fileScore.Path]))
+	}
+	for _, n := range neighbors {
+		sb.WriteString(fmt.Sprintf("#   %s: %s\n", n.Path, n.Reason))
+	}
+	sb.WriteString("\n")
 
-		if fullContent[fileScore.Path] {
+	shownExcerpts := make(map[string][]indexer.LineRange)
+	for _, fileScore := range packed {
+		switch shown[fileScore.Path] {
+		case FullContent:
 			sb.WriteString(fmt.Sprintf("\n## FILE: %s (relevance: %.1f, full content)\n", fileScore.Path, fileScore.Score))
-			data, readErr := os.ReadFile(filePath)
-			if readErr != nil {
-				continue
-			}
-			sb.Write(data)
+			sb.WriteString(full[fileScore.Path])
+			sb.WriteString("\n")
+			continue
+		case Excerpt:
+			excerpt := excerpts[fileScore.Path]
+			shownExcerpts[fileScore.Path] = excerpt.Ranges
+			sb.WriteString(fmt.Sprintf("\n## FILE: %s (relevance: %.1f, excerpt: %s of %d)\n",
+				fileScore.Path, fileScore.Score, indexer.FormatLineRanges(excerpt.Ranges), excerpt.Lines))
+			sb.WriteString(excerpt.Text)
 			sb.WriteString("\n")
 			continue
 		}
 
 		sb.WriteString(fmt.Sprintf("\n## FILE: %s (relevance: %.1f, signatures only)\n", fileScore.Path, fileScore.Score))
+		sb.WriteString(signatures[fileScore.Path])
+		sb.WriteString("\n")
+	}
 
-		// Extract minimal context (signatures only) for Go files
-		context, err := indexer.ExtractMinimalContext(filePath)
-		if err != nil {
-			// If extraction fails, try reading file directly
-			data, readErr := os.ReadFile(filePath)
-			if readErr == nil {
-				sb.Write(data)
-			}
-			continue
-		}
-
-		sb.WriteString(context)
+	for _, n := range neighbors {
+		sb.WriteString(fmt.Sprintf("\n## FILE: %s (related: %s, signatures only)\n", n.Path, n.Reason))
+		sb.WriteString(neighborContext[n.Path])
 		sb.WriteString("\n")
 	}
 
-	return sb.String(), nil
+	text := sb.String()
+	return &PackedContext{
+		Text:     text,
+		Files:    len(packed) + len(neighbors),
+		Keywords: len(keywords),
+		Tokens:   EstimateTokensFromText(text),
+		Excerpts: shownExcerpts,
+	}, nil
 }
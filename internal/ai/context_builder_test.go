@@ -1,11 +1,14 @@
 package ai
 
 import (
+	"fmt"
 	"intern/internal/indexer"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	logger "github.com/jenish-jain/logger"
 	"github.com/stretchr/testify/assert"
@@ -81,6 +84,40 @@ func TestBuildRepoContext(t *testing.T) {
 	}
 }
 
+func TestBuildRepoContext_Ignore(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFiles := map[string]string{
+		".gitignore":          "fixtures/\n",
+		".ai-intern/ignore":   "legacy.go\n",
+		"main.go":             "package main\n",
+		"legacy.go":           "package main\n",
+		"fixtures/big.json":   "{}",
+		"mocks/mock_store.go": "// Code generated by MockGen. DO NOT EDIT.\npackage mocks\n",
+
+		"internal/config/config.go":       "package config\n",
+		"internal/config/zz_generated.go": "// Code generated by stringer. DO NOT EDIT.\npackage config\n",
+	}
+	for relPath, content := range testFiles {
+		fullPath := filepath.Join(tmpDir, relPath)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+
+	context := BuildRepoContext(tmpDir, 10, 500)
+	assert.Contains(t, context, "# FILE: main.go")
+	assert.Contains(t, context, "config.go")
+	assert.NotContains(t, context, "zz_generated.go")
+	assert.NotContains(t, context, "legacy.go")
+	assert.NotContains(t, context, "fixtures/big.json")
+	assert.NotContains(t, context, "mock_store.go")
+
+	// The cached base context skips them too
+	cache, err := NewContextCacheManager(CacheConfig{}).BuildBaseContext(tmpDir, 100000)
+	require.NoError(t, err)
+	assert.Contains(t, cache.FilesIncluded, filepath.Join("internal", "config", "config.go"))
+	assert.NotContains(t, cache.FilesIncluded, filepath.Join("internal", "config", "zz_generated.go"))
+}
+
 func TestBuildRepoContext_MaxFiles(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "repo-context-maxfiles")
 	require.NoError(t, err)
@@ -223,6 +260,211 @@ func main() {
 	}
 }
 
+func TestBuildSmartRepoContext_BehaviourTicket(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFiles := map[string]string{
+		"internal/client/http.go": `package client
+
+// Do sends a request, waiting and trying again while the server throttles us
+func Do() error {
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		if status == 429 { // rate limit exceeded
+			sleepBackoff(attempt)
+		}
+	}
+	return nil
+}
+`,
+		"internal/report/weekly.go": `package report
+
+// Weekly renders the weekly summary
+func Weekly() string { return "" }
+`,
+	}
+	for path, content := range testFiles {
+		fullPath := filepath.Join(tmpDir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+	idx := indexer.New(tmpDir)
+	fileIndex, err := idx.BuildIndex()
+	require.NoError(t, err)
+	require.NoError(t, idx.SaveIndex(fileIndex))
+
+	// Neither the file nor a symbol is named; only the content matches
+	context, err := BuildSmartRepoContextWithCache(tmpDir, "Retry when we hit the rate limit", 5, CacheConfig{}, nil)
+	require.NoError(t, err)
+	assert.Contains(t, context, "internal/client/http.go")
+	assert.NotContains(t, context, "internal/report/weekly.go")
+}
+
+func TestBuildSmartRepoContext_Neighbors(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFiles := map[string]string{
+		"go.mod": "module example.com/app\n",
+		"internal/billing/invoice.go": `package billing
+
+import "example.com/app/internal/store"
+
+// Invoice totals an order
+func Invoice(s store.Store) int { return s.Load() }
+`,
+		"internal/store/store.go": `package store
+
+// Store loads amounts
+type Store interface{ Load() int }
+`,
+		"cmd/app/main.go": `package main
+
+import "example.com/app/internal/billing"
+
+func main() { billing.Invoice(nil) }
+`,
+	}
+	for path, content := range testFiles {
+		fullPath := filepath.Join(tmpDir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+	idx := indexer.New(tmpDir)
+	fileIndex, err := idx.BuildIndex()
+	require.NoError(t, err)
+	require.NoError(t, idx.SaveIndex(fileIndex))
+
+	context, err := BuildSmartRepoContextWithCache(tmpDir, "Fix rounding in the order total", 5, CacheConfig{}, nil)
+	require.NoError(t, err)
+	// The interface it must satisfy and the caller it could break are
+	// shown as signatures, with the reason in the header
+	assert.Contains(t, context, "#   internal/store/store.go: declares Store, used by internal/billing/invoice.go")
+	assert.Contains(t, context, "#   cmd/app/main.go: uses Invoice from internal/billing/invoice.go")
+	assert.Contains(t, context, "## FILE: internal/store/store.go (related: declares Store, used by internal/billing/invoice.go, signatures only)")
+	assert.Contains(t, context, "type Store interface")
+}
+
+func TestPackSmartRepoContext_TokenBudget(t *testing.T) {
+	tmpDir := t.TempDir()
+	body := strings.Repeat("\t_ = \"padding the invoice body\"\n", 200)
+	testFiles := map[string]string{
+		"internal/billing/invoice.go":  "package billing\n\n// Invoice totals an invoice\nfunc Invoice() {\n" + body + "}\n",
+		"internal/billing/discount.go": "package billing\n\n// Discount applies to an invoice\nfunc Discount() {}\n",
+	}
+	for path, content := range testFiles {
+		fullPath := filepath.Join(tmpDir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+	idx := indexer.New(tmpDir)
+	fileIndex, err := idx.BuildIndex()
+	require.NoError(t, err)
+	require.NoError(t, idx.SaveIndex(fileIndex))
+
+	// Unbounded: both shown in full, as the top tier
+	packed, err := PackSmartRepoContext(tmpDir, "Fix the invoice", ContextBudget{MaxFiles: 5}, CacheConfig{}, nil)
+	require.NoError(t, err)
+	assert.Contains(t, packed.Text, "## FILE: internal/billing/invoice.go (relevance: ")
+	assert.Contains(t, packed.Text, "padding the invoice body")
+	assert.Equal(t, 2, packed.Files)
+	assert.Equal(t, EstimateTokensFromText(packed.Text), packed.Tokens)
+
+	// A budget too small for invoice.go's body shows its signature instead
+	packed, err = PackSmartRepoContext(tmpDir, "Fix the invoice", ContextBudget{MaxFiles: 5, Tokens: 500}, CacheConfig{}, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, packed.Text, "padding the invoice body")
+	assert.Contains(t, packed.Text, "func Invoice()")
+	assert.Contains(t, packed.Text, "## FILE: internal/billing/discount.go")
+	assert.LessOrEqual(t, packed.Tokens, 500)
+
+	// A file requested for editing is shown in full whatever the budget
+	packed, err = PackSmartRepoContext(tmpDir, "Fix the invoice", ContextBudget{MaxFiles: 5, Tokens: 500}, CacheConfig{}, []string{"internal/billing/invoice.go"})
+	require.NoError(t, err)
+	assert.Contains(t, packed.Text, "padding the invoice body")
+
+	// Files over MaxBytesPerFile are shown signatures-only
+	packed, err = PackSmartRepoContext(tmpDir, "Fix the invoice", ContextBudget{MaxFiles: 5, MaxBytesPerFile: 1024, Tokens: 100000}, CacheConfig{}, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, packed.Text, "padding the invoice body")
+	assert.Contains(t, packed.Text, "// Discount applies to an invoice")
+}
+
+func TestPackSmartRepoContext_CachedBaseContextWithinBudget(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFiles := map[string]string{
+		"internal/billing/invoice.go": "package billing\n\n// Invoice totals an invoice\nfunc Invoice() int { return 0 }\n",
+	}
+	for i := 0; i < 5; i++ {
+		testFiles[fmt.Sprintf("internal/config/config%d.go", i)] = "package config\n\n" + strings.Repeat("// A setting the base context carries\n", 50)
+	}
+	for path, content := range testFiles {
+		fullPath := filepath.Join(tmpDir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+	idx := indexer.New(tmpDir)
+	fileIndex, err := idx.BuildIndex()
+	require.NoError(t, err)
+	require.NoError(t, idx.SaveIndex(fileIndex))
+
+	// The base context takes a fifth of a small model's budget, leaving room
+	// for the ticket's own files
+	budget := ContextBudget{MaxFiles: 20, Tokens: 4096}
+	packed, err := PackSmartRepoContext(tmpDir, "Fix the invoice", budget, DefaultCacheConfig(), nil)
+	require.NoError(t, err)
+	assert.Contains(t, packed.Text, "# FILE: internal/config/config0.go")
+	assert.NotContains(t, packed.Text, "# FILE: internal/config/config4.go")
+	assert.Contains(t, packed.Text, "## FILE: internal/billing/invoice.go")
+	assert.LessOrEqual(t, packed.Tokens, budget.Tokens)
+
+	// A cache built for a larger budget isn't reused for a smaller one
+	_, err = PackSmartRepoContext(tmpDir, "Fix the invoice", ContextBudget{MaxFiles: 20, Tokens: 100000}, DefaultCacheConfig(), nil)
+	require.NoError(t, err)
+	packed, err = PackSmartRepoContext(tmpDir, "Fix the invoice", budget, DefaultCacheConfig(), nil)
+	require.NoError(t, err)
+	assert.NotContains(t, packed.Text, "# FILE: internal/config/config4.go")
+	assert.LessOrEqual(t, packed.Tokens, budget.Tokens)
+}
+
+func TestPackSmartRepoContext_Excerpts(t *testing.T) {
+	tmpDir := t.TempDir()
+	var handler strings.Builder
+	handler.WriteString("package billing\n")
+	for i := 0; i < 40; i++ {
+		handler.WriteString(fmt.Sprintf("\n// Ship%d dispatches an order\nfunc Ship%d() {\n\t_ = \"padding the shipping body\"\n}\n", i, i))
+	}
+	handler.WriteString("\n// Invoice totals an invoice\nfunc Invoice() int {\n\treturn 42\n}\n")
+	fullPath := filepath.Join(tmpDir, "internal/billing/handler.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+	require.NoError(t, os.WriteFile(fullPath, []byte(handler.String()), 0644))
+	idx := indexer.New(tmpDir)
+	fileIndex, err := idx.BuildIndex()
+	require.NoError(t, err)
+	require.NoError(t, idx.SaveIndex(fileIndex))
+
+	// Over MaxBytesPerFile, the top-tier file shows only the invoice
+	// function in full, with line numbers, and the rest as signatures
+	for _, tokens := range []int{0, 100000} {
+		packed, err := PackSmartRepoContext(tmpDir, "Fix the invoice", ContextBudget{MaxFiles: 5, MaxBytesPerFile: 1024, Tokens: tokens}, CacheConfig{}, nil)
+		require.NoError(t, err)
+		assert.Contains(t, packed.Text, "## FILE: internal/billing/handler.go (relevance: ")
+		assert.Contains(t, packed.Text, ", excerpt: lines ")
+		assert.Contains(t, packed.Text, "| \treturn 42\n")
+		assert.Contains(t, packed.Text, "func Ship0()")
+		assert.Equal(t, 1, strings.Count(packed.Text, "padding the shipping body"), "only as a context line")
+		require.Len(t, packed.Excerpts["internal/billing/handler.go"], 1)
+	}
+
+	// Requested for editing, it's still excerpted
+	packed, err := PackSmartRepoContext(tmpDir, "Fix the invoice", ContextBudget{MaxFiles: 5, MaxBytesPerFile: 1024, Tokens: 100}, CacheConfig{}, []string{"internal/billing/handler.go"})
+	require.NoError(t, err)
+	assert.Contains(t, packed.Text, "| \treturn 42\n")
+	assert.Equal(t, 1, strings.Count(packed.Text, "padding the shipping body"))
+
+	// Without a size limit it's shown in full
+	packed, err = PackSmartRepoContext(tmpDir, "Fix the invoice", ContextBudget{MaxFiles: 5}, CacheConfig{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 40, strings.Count(packed.Text, "padding the shipping body"))
+	assert.Empty(t, packed.Excerpts)
+}
+
 func TestBuildSmartRepoContext_NoIndex(t *testing.T) {
 	// Create a test repository without index
 	tmpDir := t.TempDir()
@@ -263,3 +505,36 @@ func TestMin(t *testing.T) {
 	assert.Equal(t, 5, min(5, 5))
 	assert.Equal(t, -1, min(-1, 0))
 }
+
+func TestContextCache_StaleOnWorkingTreeChange(t *testing.T) {
+	tmpDir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tmpDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Skipf("git %v failed: %v: %s", args, err, out)
+		}
+	}
+	git("init")
+	git("config", "user.email", "test@example.com")
+	git("config", "user.name", "Test User")
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "internal", "config"), 0755))
+	configPath := filepath.Join(tmpDir, "internal", "config", "config.go")
+	require.NoError(t, os.WriteFile(configPath, []byte("package config\n"), 0644))
+	git("add", ".")
+	git("commit", "-m", "Initial commit")
+
+	manager := NewContextCacheManager(CacheConfig{Enabled: true, TTL: time.Hour})
+	cache, err := manager.BuildBaseContext(tmpDir, 100000)
+	require.NoError(t, err)
+	assert.False(t, manager.IsStale(cache, tmpDir))
+
+	// An uncommitted edit leaves HEAD alone but makes the cache stale
+	require.NoError(t, os.WriteFile(configPath, []byte("package config\n\ntype Config struct{}\n"), 0644))
+	assert.True(t, manager.IsStale(cache, tmpDir))
+
+	cache, err = manager.BuildBaseContext(tmpDir, 100000)
+	require.NoError(t, err)
+	assert.Contains(t, cache.BaseContext, "type Config struct{}")
+	assert.False(t, manager.IsStale(cache, tmpDir))
+}
@@ -9,6 +9,8 @@ import (
 	"strings"
 	"time"
 
+	"intern/internal/indexer"
+
 	"github.com/jenish-jain/logger"
 )
 
@@ -31,9 +33,11 @@ type ContextCache struct {
 	BaseContext    string    `json:"base_context"`     // Common repository context
 	RepoPath       string    `json:"repo_path"`        // Repository path for validation
 	GitCommitHash  string    `json:"git_commit_hash"`  // Git commit when cache was built
+	WorkingTree    string    `json:"working_tree,omitempty"` // Uncommitted changes when cache was built (indexer.WorkingTreeFingerprint)
 	CreatedAt      time.Time `json:"created_at"`       // When cache was created
 	FilesIncluded  []string  `json:"files_included"`   // List of files in base context
 	ContextBytes   int       `json:"context_bytes"`    // Size of base context
+	MaxBytes       int       `json:"max_bytes"`        // Limit it was built for (see BuildBaseContext)
 }
 
 // CacheConfig configures context caching behavior
@@ -131,6 +135,12 @@ func (m *ContextCacheManager) IsStale(cache *ContextCache, repoPath string) bool
 		return true
 	}
 
+	// Check if uncommitted files changed, e.g. ones created mid-ticket
+	if indexer.WorkingTreeFingerprint(repoPath) != cache.WorkingTree {
+		logger.Debug("Cache is stale: working tree changed")
+		return true
+	}
+
 	// Check if repo path matches
 	if cache.RepoPath != repoPath {
 		logger.Debug("Cache is stale: repo path mismatch")
@@ -141,7 +151,9 @@ func (m *ContextCacheManager) IsStale(cache *ContextCache, repoPath string) bool
 }
 
 // BuildBaseContext creates a base context from core repository files
-// These are files that typically don't change often and are useful for most tickets
+// These are files that typically don't change often and are useful for most tickets.
+// It takes at most a fifth of maxBytes, headers included; files that don't
+// fit are left out.
 func (m *ContextCacheManager) BuildBaseContext(repoPath string, maxBytes int) (*ContextCache, error) {
 	var sb strings.Builder
 	var filesIncluded []string
@@ -159,6 +171,7 @@ func (m *ContextCacheManager) BuildBaseContext(repoPath string, maxBytes int) (*
 
 	totalBytes := 0
 	maxBaseBytes := maxBytes / 5 // Use only 20% of max bytes for base context
+	idx := indexer.New(repoPath)
 
 	for _, pattern := range corePatterns {
 		matches, err := filepath.Glob(filepath.Join(repoPath, pattern))
@@ -167,12 +180,8 @@ func (m *ContextCacheManager) BuildBaseContext(repoPath string, maxBytes int) (*
 		}
 
 		for _, filePath := range matches {
-			if totalBytes >= maxBaseBytes {
-				break
-			}
-
 			relPath, err := filepath.Rel(repoPath, filePath)
-			if err != nil {
+			if err != nil || idx.Excluded(relPath, false) {
 				continue
 			}
 
@@ -186,11 +195,15 @@ func (m *ContextCacheManager) BuildBaseContext(repoPath string, maxBytes int) (*
 				data = data[:10*1024]
 			}
 
-			sb.WriteString(fmt.Sprintf("\n\n# FILE: %s\n", relPath))
+			header := fmt.Sprintf("\n\n# FILE: %s\n", relPath)
+			if totalBytes+len(header)+len(data) > maxBaseBytes {
+				continue
+			}
+			sb.WriteString(header)
 			sb.Write(data)
 
 			filesIncluded = append(filesIncluded, relPath)
-			totalBytes += len(data)
+			totalBytes += len(header) + len(data)
 		}
 	}
 
@@ -198,9 +211,11 @@ func (m *ContextCacheManager) BuildBaseContext(repoPath string, maxBytes int) (*
 		BaseContext:   sb.String(),
 		RepoPath:      repoPath,
 		GitCommitHash: getGitCommitHash(repoPath),
+		WorkingTree:   indexer.WorkingTreeFingerprint(repoPath),
 		CreatedAt:     time.Now(),
 		FilesIncluded: filesIncluded,
 		ContextBytes:  totalBytes,
+		MaxBytes:      maxBytes,
 	}
 
 	commitHash := cache.GitCommitHash
@@ -218,8 +233,10 @@ func (m *ContextCacheManager) BuildBaseContext(repoPath string, maxBytes int) (*
 // GetOrBuildBaseContext retrieves cached base context or builds a new one
 func (m *ContextCacheManager) GetOrBuildBaseContext(repoPath string, maxBytes int) (*ContextCache, error) {
 	// Try to load existing cache
+	// A cache built for another limit (e.g. another model's token budget)
+	// could be too large or needlessly small
 	cache, err := m.LoadCache(repoPath)
-	if err == nil && !m.IsStale(cache, repoPath) {
+	if err == nil && cache.MaxBytes == maxBytes && !m.IsStale(cache, repoPath) {
 		logger.Debug("Using cached base context",
 			"files", len(cache.FilesIncluded),
 			"bytes", cache.ContextBytes,
@@ -0,0 +1,133 @@
+package ai
+
+import "strings"
+
+// Representation is how much of a file the context shows
+type Representation int
+
+const (
+	Omitted        Representation = iota // Not shown
+	SignaturesOnly                       // Declarations without bodies
+	Excerpt                              // The relevant functions, with line numbers
+	FullContent                          // The whole file
+)
+
+// representationValue is the share of a file's relevance each
+// representation delivers: the model can only edit what it sees in full,
+// but signatures already tell it what exists and how to call it
+var representationValue = map[Representation]float64{
+	Omitted:        0,
+	SignaturesOnly: 0.4,
+	Excerpt:        0.7,
+	FullContent:    1,
+}
+
+// PackCandidate is a file competing for space in the context
+type PackCandidate struct {
+	Path  string
+	Value float64                // Relevance when shown in full, e.g. its score
+	Costs map[Representation]int // Estimated tokens of each representation available
+	Min   Representation         // Shown at least this way whatever the budget, e.g. FullContent for a file requested for editing
+}
+
+// PackContext chooses a representation per candidate to maximize total
+// value within budget tokens. Candidates start at their Min (the cheapest
+// available representation at or above it), then the upgrade with the best
+// value per extra token that still fits is applied, repeatedly. Ties go to
+// the earlier candidate. Returns the choices (Omitted candidates are absent)
+// and the tokens used, which exceed budget only if the Min choices do.
+func PackContext(candidates []PackCandidate, budget int) (map[string]Representation, int) {
+	chosen := make(map[string]Representation, len(candidates))
+	used := 0
+	for _, c := range candidates {
+		if c.Min == Omitted {
+			continue
+		}
+		for rep := c.Min; rep <= FullContent; rep++ {
+			if cost, ok := c.Costs[rep]; ok {
+				chosen[c.Path] = rep
+				used += cost
+				break
+			}
+		}
+	}
+
+	for {
+		bestIdx, bestRep, bestRatio, bestCost := -1, Omitted, 0.0, 0
+		for i, c := range candidates {
+			cur := chosen[c.Path]
+			for rep := cur + 1; rep <= FullContent; rep++ {
+				cost, ok := c.Costs[rep]
+				if !ok {
+					continue
+				}
+				extra := cost - c.Costs[cur]
+				if used+extra > budget {
+					continue
+				}
+				gain := c.Value * (representationValue[rep] - representationValue[cur])
+				ratio := gain / float64(max(extra, 1))
+				if gain > 0 && ratio > bestRatio {
+					bestIdx, bestRep, bestRatio, bestCost = i, rep, ratio, extra
+				}
+			}
+		}
+		if bestIdx < 0 {
+			return chosen, used
+		}
+		chosen[candidates[bestIdx].Path] = bestRep
+		used += bestCost
+	}
+}
+
+// contextWindowShare is the part of a model's context window given to
+// repository context; the rest holds the instructions, the ticket and the
+// response
+const contextWindowShare = 0.5
+
+// maxContextTokens caps the budget on large-window models, where filling
+// the window costs more than the extra files are worth
+const maxContextTokens = 50_000
+
+// modelContextWindows are context windows in tokens by model name prefix;
+// the first match wins, so longer prefixes come first
+var modelContextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"claude", 200_000},
+	{"qwen2.5-coder", 32_768},
+	{"qwen3", 32_768},
+	{"deepseek-coder-v2", 131_072},
+	{"deepseek-coder", 16_384},
+	{"codellama", 16_384},
+	{"llama3.1", 131_072},
+	{"llama3", 8_192},
+	{"codestral", 32_768},
+}
+
+// providerContextWindows apply when the model is unknown
+var providerContextWindows = map[string]int{
+	"anthropic": 200_000,
+	"ollama":    8_192,
+}
+
+// ContextTokenBudget returns the tokens of repository context to pack for
+// a provider and model (either may be empty)
+func ContextTokenBudget(provider, model string) int {
+	window := 0
+	lower := strings.ToLower(model)
+	for _, w := range modelContextWindows {
+		if lower != "" && strings.HasPrefix(lower, w.prefix) {
+			window = w.tokens
+			break
+		}
+	}
+	if window == 0 {
+		window = providerContextWindows[strings.ToLower(provider)]
+	}
+	if window == 0 {
+		window = providerContextWindows["ollama"] // Assume the smallest
+	}
+	return min(int(float64(window)*contextWindowShare), maxContextTokens)
+}
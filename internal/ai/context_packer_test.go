@@ -0,0 +1,56 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackContext(t *testing.T) {
+	candidates := []PackCandidate{
+		{Path: "top.go", Value: 30, Costs: map[Representation]int{SignaturesOnly: 100, FullContent: 1000}},
+		{Path: "big.go", Value: 20, Costs: map[Representation]int{SignaturesOnly: 200, FullContent: 5000}},
+		{Path: "low.go", Value: 5, Costs: map[Representation]int{SignaturesOnly: 50, FullContent: 400}},
+		{Path: "asked.go", Value: 0, Costs: map[Representation]int{SignaturesOnly: 10, FullContent: 300}, Min: FullContent},
+	}
+
+	t.Run("fits the budget", func(t *testing.T) {
+		chosen, used := PackContext(candidates, 1700)
+		assert.Equal(t, FullContent, chosen["top.go"])
+		assert.Equal(t, SignaturesOnly, chosen["big.go"], "too big for full content")
+		assert.Equal(t, SignaturesOnly, chosen["low.go"])
+		assert.Equal(t, FullContent, chosen["asked.go"], "requested files are always full")
+		assert.Equal(t, 1550, used)
+		assert.LessOrEqual(t, used, 1700)
+	})
+
+	t.Run("drops what doesn't fit", func(t *testing.T) {
+		chosen, used := PackContext(candidates, 450)
+		assert.Equal(t, SignaturesOnly, chosen["top.go"])
+		assert.NotContains(t, chosen, "big.go")
+		assert.Equal(t, FullContent, chosen["asked.go"])
+		assert.Equal(t, 450, used)
+	})
+
+	t.Run("required choices may exceed the budget", func(t *testing.T) {
+		chosen, used := PackContext(candidates, 100)
+		assert.Equal(t, map[string]Representation{"asked.go": FullContent}, chosen)
+		assert.Equal(t, 300, used)
+	})
+
+	t.Run("no full content offered", func(t *testing.T) {
+		chosen, _ := PackContext([]PackCandidate{
+			{Path: "huge.go", Value: 50, Costs: map[Representation]int{SignaturesOnly: 100}},
+		}, 100000)
+		assert.Equal(t, SignaturesOnly, chosen["huge.go"])
+	})
+}
+
+func TestContextTokenBudget(t *testing.T) {
+	assert.Equal(t, 16384, ContextTokenBudget("ollama", "qwen2.5-coder:7b"))
+	assert.Equal(t, 8192, ContextTokenBudget("ollama", "deepseek-coder:6.7b"))
+	assert.Equal(t, 50000, ContextTokenBudget("ollama", "deepseek-coder-v2:16b"), "capped")
+	assert.Equal(t, 4096, ContextTokenBudget("ollama", "unknown-model"))
+	assert.Equal(t, 50000, ContextTokenBudget("anthropic", ""))
+	assert.Equal(t, 4096, ContextTokenBudget("", ""))
+}
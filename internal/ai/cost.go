@@ -77,10 +77,12 @@ func FormatTokens(tokens int) string {
 	return result
 }
 
+// charsPerToken is the common approximation of characters per token
+const charsPerToken = 4
+
 // EstimateTokensFromText provides a rough estimate of token count from text.
 // Uses the common approximation of ~4 characters per token.
 // This is a rough estimate and actual tokenization may vary.
 func EstimateTokensFromText(text string) int {
-	const charsPerToken = 4
 	return len(text) / charsPerToken
 }
@@ -0,0 +1,156 @@
+// Package codeowners parses GitHub CODEOWNERS files and resolves the owners
+// of repository paths.
+package codeowners
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Locations GitHub checks for a CODEOWNERS file, in precedence order
+var Locations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// Rule is one CODEOWNERS line: a path pattern and the owners of matching
+// paths. A rule with no owners explicitly un-owns the paths it matches.
+type Rule struct {
+	Pattern string
+	Owners  []string // "@user", "@org/team" or an email address
+	re      *regexp.Regexp
+}
+
+// File is a parsed CODEOWNERS file
+type File struct {
+	Rules []Rule
+}
+
+// Load reads the first CODEOWNERS file found in repoRoot. It returns nil
+// and no error if the repository has none.
+func Load(repoRoot string) (*File, error) {
+	for _, loc := range Locations {
+		data, err := os.ReadFile(filepath.Join(repoRoot, filepath.FromSlash(loc)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return Parse(string(data)), nil
+	}
+	return nil, nil
+}
+
+// Parse parses CODEOWNERS content. Blank lines, comments and patterns that
+// can't be compiled are skipped, as GitHub does.
+func Parse(content string) *File {
+	f := &File{}
+	sc := bufio.NewScanner(strings.NewReader(content))
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		re, err := CompilePattern(fields[0])
+		if err != nil {
+			continue
+		}
+		var owners []string
+		if len(fields) > 1 {
+			owners = fields[1:]
+		}
+		f.Rules = append(f.Rules, Rule{Pattern: fields[0], Owners: owners, re: re})
+	}
+	return f
+}
+
+// Owners returns the owners of a repo-relative, slash-separated path. As on
+// GitHub, the last matching rule wins.
+func (f *File) Owners(path string) []string {
+	if r := f.Match(path); r != nil {
+		return r.Owners
+	}
+	return nil
+}
+
+// Match returns the last rule matching path, or nil
+func (f *File) Match(path string) *Rule {
+	if f == nil {
+		return nil
+	}
+	path = strings.TrimPrefix(filepath.ToSlash(path), "/")
+	for i := len(f.Rules) - 1; i >= 0; i-- {
+		if f.Rules[i].re.MatchString(path) {
+			return &f.Rules[i]
+		}
+	}
+	return nil
+}
+
+// OwnersOf returns the de-duplicated owners of all paths, in first-seen order
+func (f *File) OwnersOf(paths []string) []string {
+	seen := make(map[string]bool)
+	var owners []string
+	for _, p := range paths {
+		for _, o := range f.Owners(p) {
+			if key := strings.ToLower(o); !seen[key] {
+				seen[key] = true
+				owners = append(owners, o)
+			}
+		}
+	}
+	return owners
+}
+
+// CompilePattern turns a gitignore-style CODEOWNERS pattern into a regexp
+// over slash-separated repo-relative paths. Other path rules (e.g. the
+// write policy) reuse it so every pattern in the repo reads the same way:
+//   - a leading "/" or any inner "/" anchors the pattern to the repo root,
+//     otherwise it matches at any depth
+//   - a trailing "/" matches everything under a directory
+//   - "*" and "?" stay within one path segment, "**" spans segments
+//   - a pattern that matches a directory also matches everything under it
+func CompilePattern(pattern string) (*regexp.Regexp, error) {
+	p := pattern
+	anchored := strings.HasPrefix(p, "/") || strings.Contains(strings.TrimSuffix(p, "/"), "/")
+	p = strings.TrimPrefix(p, "/")
+	dirOnly := strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; c {
+		case '*':
+			if i+1 < len(p) && p[i+1] == '*' {
+				i++
+				if i+1 < len(p) && p[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if dirOnly {
+		b.WriteString("/.*$")
+	} else {
+		b.WriteString("(?:/.*)?$")
+	}
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,79 @@
+package codeowners
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const sample = `# Default owners
+*                 @acme/platform
+
+*.md              @docs-team  # inline comment
+/build/           @acme/release
+docs/**/api.md    @api-writer
+internal/auth/    @alice @acme/security
+/cmd/*.go         bob@example.com
+apps/generated/
+`
+
+func TestOwners(t *testing.T) {
+	f := Parse(sample)
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"main.go", []string{"@acme/platform"}},
+		{"README.md", []string{"@docs-team"}},
+		{"internal/x/NOTES.md", []string{"@docs-team"}},
+		{"build/ci/Dockerfile", []string{"@acme/release"}},
+		{"tools/build/x.go", []string{"@acme/platform"}}, // "/build/" is anchored
+		{"docs/api.md", []string{"@api-writer"}},
+		{"docs/v1/ref/api.md", []string{"@api-writer"}},
+		{"internal/auth/jwt.go", []string{"@alice", "@acme/security"}},
+		{"internal/authz/jwt.go", []string{"@acme/platform"}},
+		{"cmd/main.go", []string{"bob@example.com"}},
+		{"cmd/agent/main.go", []string{"@acme/platform"}}, // "*" stays in one segment
+		{"apps/generated/x.go", nil},                      // un-owned by a rule with no owners
+	}
+	for _, tt := range tests {
+		if got := f.Owners(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Owners(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestOwnersOf(t *testing.T) {
+	f := Parse(sample)
+	got := f.OwnersOf([]string{"internal/auth/jwt.go", "main.go", "internal/auth/jwt_test.go", "README.md"})
+	want := []string{"@alice", "@acme/security", "@acme/platform", "@docs-team"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("OwnersOf() = %v, want %v", got, want)
+	}
+
+	var none *File
+	if got := none.OwnersOf([]string{"main.go"}); got != nil {
+		t.Errorf("Expected no owners from a nil file, got %v", got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	if f, err := Load(dir); f != nil || err != nil {
+		t.Fatalf("Expected nil, nil without a CODEOWNERS file, got %v, %v", f, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "CODEOWNERS"), []byte("* @root-owner\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, ".github"), 0755)
+	os.WriteFile(filepath.Join(dir, ".github", "CODEOWNERS"), []byte("* @github-owner\n"), 0644)
+
+	f, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Owners("x.go"); !reflect.DeepEqual(got, []string{"@github-owner"}) {
+		t.Errorf("Expected .github/CODEOWNERS to take precedence, got %v", got)
+	}
+}
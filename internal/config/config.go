@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"net/mail"
+	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -34,6 +36,37 @@ type Config struct {
 	GitHubOwner string
 	GitHubRepo  string
 
+	// Repos lists every repository the agent works on, loaded from the YAML
+	// file named by REPOS_FILE; each ticket is routed to one of them (see
+	// RepoConfig). Empty means just GitHubOwner/GitHubRepo.
+	ReposFile string
+	Repos     []RepoConfig
+
+	// GitHub App authentication, used instead of GitHubToken when
+	// GitHubAppID is set: short-lived installation tokens are minted from the
+	// app's private key (a PEM file path, or the PEM itself) and refreshed
+	// before they expire.
+	GitHubAppID             int64
+	GitHubAppInstallationID int64
+	GitHubAppPrivateKey     string
+
+	// GitHub Enterprise Server and other non-default endpoints. The git
+	// remote defaults to the host of GitHubAPIURL (github.com when unset),
+	// over SSH when GitHubSSHKey is set. GitHubCABundle adds trusted roots
+	// for both the API and HTTPS git.
+	GitHubAPIURL           string // REST API root, e.g. "https://ghe.acme.com/api/v3/"
+	GitHubGitURL           string // Full clone URL, overriding the derived one
+	GitHubCABundle         string // Path to a PEM bundle of extra CA certificates
+	GitHubSSHKey           string // Path to an SSH deploy key for git; the API still uses the token or app
+	GitHubSSHKeyPassphrase string // Passphrase for an encrypted deploy key
+	GitHubSSHKnownHosts    string // known_hosts file for the git host (default: ~/.ssh/known_hosts)
+
+	// Clone trimming for large repositories. Syncs fetch the base branch and
+	// reset onto it, keeping the same depth and sparse paths.
+	CloneDepth        int      // Commits of history to clone; 0 clones everything
+	CloneSingleBranch bool     // Clone only BaseBranch
+	CloneSparsePaths  []string // Only check out these path prefixes, e.g. "services/api,go.mod"
+
 	AnthropicAPIKey string
 
 	// AI Provider configuration
@@ -41,6 +74,12 @@ type Config struct {
 	OllamaBaseURL string // Ollama server URL (default: http://localhost:11434)
 	OllamaModel   string // Ollama model name (e.g., qwen2.5-coder:7b)
 
+	// AIEscalationLadder lists "provider[:model]" tiers from cheapest to most
+	// capable, e.g. ["ollama:qwen2.5-coder:7b", "anthropic"]. Self-healing
+	// uses one tier per attempt, and planning moves up a tier when a model's
+	// output can't be parsed. Empty means AIProvider only.
+	AIEscalationLadder []string
+
 	AgentUsername        string
 	PollingInterval      string
 	MaxConcurrentTickets int
@@ -50,9 +89,18 @@ type Config struct {
 	BranchPrefix string
 
 	ContextMaxFiles     int
-	ContextMaxBytes     int
+	ContextMaxBytes     int    // Files larger than this are shown signatures-only unless requested
+	ContextMaxTokens    int    // Token budget for repository context; 0 derives it from the planning models
 	ContextCacheEnabled bool   // Enable context caching
 	ContextCacheTTL     string // Cache time-to-live (e.g., "1h", "30m")
+	RepoMapEnabled      bool   // Open every planning prompt with the repository map (default: true)
+
+	// Optional semantic retrieval: file chunks are embedded and ranked by
+	// similarity to the ticket alongside keyword scoring
+	EmbeddingsProvider string // "ollama" or "openai" (any OpenAI-compatible API); empty disables
+	EmbeddingsBaseURL  string // Default: OLLAMA_BASE_URL for ollama, https://api.openai.com/v1 for openai
+	EmbeddingsModel    string // e.g. nomic-embed-text, text-embedding-3-small
+	EmbeddingsAPIKey   string // Bearer token for OpenAI-compatible APIs that need one
 
 	PlanMaxFiles     int
 	AllowedWriteDirs []string
@@ -67,6 +115,51 @@ type Config struct {
 	SelfHealOnVet       bool // Retry on vet failures
 	SelfHealOnBuild     bool // Retry on build failures
 
+	// RequireTests enforces that every package a ticket changes gets a new or
+	// modified _test.go and that coverage of those packages doesn't drop.
+	// Violations are fed back to the agent as error type "coverage" when
+	// self-healing is enabled, and block the PR otherwise.
+	RequireTests bool
+
+	// Sandbox configuration for quality-gate commands (go build/vet/test).
+	// These run AI-written code, so by default they get a scrubbed
+	// environment, a throwaway working copy, resource limits and no network.
+	SandboxEnabled       bool     // Isolate gate commands (default: true)
+	SandboxAllowNetwork  bool     // Allow network access from inside the sandbox (default: false)
+	SandboxAllowDegraded bool     // Run gates without namespaces if the host refuses them (default: false, gates fail)
+	SandboxTimeout       string   // Wall-clock limit per command (default: "10m")
+	SandboxCPUSeconds    int      // CPU time limit per command (default: 600)
+	SandboxMemoryMB      int      // Address-space limit per command in MiB (default: 4096)
+	SandboxEnvAllowlist  []string // Extra host env vars passed through to gate commands
+
+	// Pull request settings. Reviewers are requested from the CODEOWNERS of
+	// the changed paths and/or the ticket reporter, mapped to a GitHub login
+	// via PRReporterGitHubMap (keys match the ticket's reporter name,
+	// case-insensitively).
+	PRDraft                   bool              // Open PRs as drafts
+	PRLabels                  []string          // Labels applied to every PR, e.g. "ai-generated"
+	PRReviewersFromCodeowners bool              // Request review from CODEOWNERS of the changed paths
+	PRReporterGitHubMap       map[string]string // Ticket reporter -> GitHub login, requested as reviewer
+	PRAssignees               []string          // GitHub logins to assign; "reporter" means the mapped reporter
+
+	// Branch maintenance keeps the agent's open PRs current with the base
+	// branch: each polling cycle, branches behind the base are rebased (or
+	// merged), re-gated and force-pushed with lease.
+	BranchMaintenanceEnabled bool   // Refresh open PR branches when the base moves
+	BranchRefreshStrategy    string // "rebase" (default) or "merge"
+
+	// Commit identity and signing. Commits are signed when
+	// CommitSigningFormat is set: "gpg" takes an armored private key file,
+	// "ssh" an OpenSSH private key file. Every commit carries a Ticket trailer
+	// and Requested-by for the reporter; co-authors are "Name <email>".
+	CommitAuthorName        string            // Author and committer name (default: "AI Intern")
+	CommitAuthorEmail       string            // Author and committer email (default: "ai-intern@example.com")
+	CommitSigningFormat     string            // "", "gpg" or "ssh"
+	CommitSigningKey        string            // Path to the private signing key
+	CommitSigningPassphrase string            // Passphrase for an encrypted signing key
+	CommitCoAuthors         []string          // Added as Co-authored-by to every commit
+	CommitReporterEmailMap  map[string]string // Ticket reporter -> email, added as Co-authored-by
+
 	DryRun bool // If true, process tickets but don't create PRs (preview mode)
 
 	// Metrics server configuration
@@ -77,6 +170,8 @@ type Config struct {
 func LoadConfig() (*Config, error) {
 	_ = godotenv.Load()
 	viper.AutomaticEnv()
+	viper.SetDefault("SANDBOX_ENABLED", true) // opt-out: gates run untrusted code
+	viper.SetDefault("REPO_MAP_ENABLED", true)
 
 	cfg := &Config{
 		TicketingMode: viper.GetString("TICKETING_MODE"),
@@ -98,6 +193,22 @@ func LoadConfig() (*Config, error) {
 		GitHubToken: viper.GetString("GITHUB_TOKEN"),
 		GitHubOwner: viper.GetString("GITHUB_OWNER"),
 		GitHubRepo:  viper.GetString("GITHUB_REPO"),
+		ReposFile:   viper.GetString("REPOS_FILE"),
+
+		GitHubAppID:             viper.GetInt64("GITHUB_APP_ID"),
+		GitHubAppInstallationID: viper.GetInt64("GITHUB_APP_INSTALLATION_ID"),
+		GitHubAppPrivateKey:     viper.GetString("GITHUB_APP_PRIVATE_KEY"),
+
+		GitHubAPIURL:           viper.GetString("GITHUB_API_URL"),
+		GitHubGitURL:           viper.GetString("GITHUB_GIT_URL"),
+		GitHubCABundle:         viper.GetString("GITHUB_CA_BUNDLE"),
+		GitHubSSHKey:           viper.GetString("GITHUB_SSH_KEY"),
+		GitHubSSHKeyPassphrase: viper.GetString("GITHUB_SSH_KEY_PASSPHRASE"),
+		GitHubSSHKnownHosts:    viper.GetString("GITHUB_SSH_KNOWN_HOSTS"),
+
+		CloneDepth:        viper.GetInt("CLONE_DEPTH"),
+		CloneSingleBranch: viper.GetBool("CLONE_SINGLE_BRANCH"),
+		CloneSparsePaths:  splitList(viper.GetString("CLONE_SPARSE_PATHS")),
 
 		AnthropicAPIKey: viper.GetString("ANTHROPIC_API_KEY"),
 
@@ -115,8 +226,15 @@ func LoadConfig() (*Config, error) {
 
 		ContextMaxFiles:     viper.GetInt("CONTEXT_MAX_FILES"),
 		ContextMaxBytes:     viper.GetInt("CONTEXT_MAX_BYTES"),
+		ContextMaxTokens:    viper.GetInt("CONTEXT_MAX_TOKENS"),
 		ContextCacheEnabled: viper.GetBool("CONTEXT_CACHE_ENABLED"),
 		ContextCacheTTL:     viper.GetString("CONTEXT_CACHE_TTL"),
+		RepoMapEnabled:      viper.GetBool("REPO_MAP_ENABLED"),
+
+		EmbeddingsProvider: strings.ToLower(viper.GetString("EMBEDDINGS_PROVIDER")),
+		EmbeddingsBaseURL:  viper.GetString("EMBEDDINGS_BASE_URL"),
+		EmbeddingsModel:    viper.GetString("EMBEDDINGS_MODEL"),
+		EmbeddingsAPIKey:   viper.GetString("EMBEDDINGS_API_KEY"),
 
 		PlanMaxFiles: viper.GetInt("PLAN_MAX_FILES"),
 
@@ -129,6 +247,32 @@ func LoadConfig() (*Config, error) {
 		SelfHealOnVet:       viper.GetBool("SELF_HEAL_ON_VET"),
 		SelfHealOnBuild:     viper.GetBool("SELF_HEAL_ON_BUILD"),
 
+		RequireTests: viper.GetBool("REQUIRE_TESTS"),
+
+		SandboxEnabled:       viper.GetBool("SANDBOX_ENABLED"),
+		SandboxAllowNetwork:  viper.GetBool("SANDBOX_ALLOW_NETWORK"),
+		SandboxAllowDegraded: viper.GetBool("SANDBOX_ALLOW_DEGRADED"),
+		SandboxTimeout:       viper.GetString("SANDBOX_TIMEOUT"),
+		SandboxCPUSeconds:    viper.GetInt("SANDBOX_CPU_SECONDS"),
+		SandboxMemoryMB:      viper.GetInt("SANDBOX_MEMORY_MB"),
+
+		PRDraft:                   viper.GetBool("PR_DRAFT"),
+		PRLabels:                  splitList(viper.GetString("PR_LABELS")),
+		PRReviewersFromCodeowners: viper.GetBool("PR_REVIEWERS_FROM_CODEOWNERS"),
+		PRReporterGitHubMap:       parseKeyValueList(viper.GetString("PR_REPORTER_GITHUB_MAP")),
+		PRAssignees:               splitList(viper.GetString("PR_ASSIGNEES")),
+
+		BranchMaintenanceEnabled: viper.GetBool("BRANCH_MAINTENANCE_ENABLED"),
+		BranchRefreshStrategy:    viper.GetString("BRANCH_REFRESH_STRATEGY"),
+
+		CommitAuthorName:        viper.GetString("COMMIT_AUTHOR_NAME"),
+		CommitAuthorEmail:       viper.GetString("COMMIT_AUTHOR_EMAIL"),
+		CommitSigningFormat:     viper.GetString("COMMIT_SIGNING_FORMAT"),
+		CommitSigningKey:        viper.GetString("COMMIT_SIGNING_KEY"),
+		CommitSigningPassphrase: viper.GetString("COMMIT_SIGNING_PASSPHRASE"),
+		CommitCoAuthors:         splitList(viper.GetString("COMMIT_CO_AUTHORS")),
+		CommitReporterEmailMap:  parseKeyValueList(viper.GetString("COMMIT_REPORTER_EMAIL_MAP")),
+
 		DryRun: viper.GetBool("DRY_RUN"),
 
 		MetricsEnabled: viper.GetBool("METRICS_ENABLED"),
@@ -158,6 +302,14 @@ func LoadConfig() (*Config, error) {
 		cfg.ContextCacheTTL = "1h" // Default: cache for 1 hour
 	}
 	// ContextCacheEnabled defaults to false (opt-in)
+	if cfg.EmbeddingsBaseURL == "" {
+		switch cfg.EmbeddingsProvider {
+		case "ollama":
+			cfg.EmbeddingsBaseURL = cfg.OllamaBaseURL
+		case "openai":
+			cfg.EmbeddingsBaseURL = "https://api.openai.com/v1"
+		}
+	}
 	if cfg.PlanMaxFiles <= 0 {
 		cfg.PlanMaxFiles = 20
 	}
@@ -167,12 +319,52 @@ func LoadConfig() (*Config, error) {
 	}
 	// SelfHealEnabled defaults to false (opt-in)
 	// SelfHealOnTests, SelfHealOnVet, SelfHealOnBuild default to false
+	// RequireTests defaults to false (opt-in)
+
+	// Sandbox defaults
+	if cfg.SandboxTimeout == "" {
+		cfg.SandboxTimeout = "10m"
+	}
+	if cfg.SandboxCPUSeconds <= 0 {
+		cfg.SandboxCPUSeconds = 600
+	}
+	if cfg.SandboxMemoryMB <= 0 {
+		cfg.SandboxMemoryMB = 4096
+	}
+	if envAllow := viper.GetString("SANDBOX_ENV_ALLOWLIST"); strings.TrimSpace(envAllow) != "" {
+		for _, name := range strings.Split(envAllow, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				cfg.SandboxEnvAllowlist = append(cfg.SandboxEnvAllowlist, name)
+			}
+		}
+	}
+
+	// Branch maintenance defaults; BranchMaintenanceEnabled is opt-in
+	if cfg.BranchRefreshStrategy == "" {
+		cfg.BranchRefreshStrategy = "rebase"
+	}
+
+	// Commit identity defaults; signing is opt-in
+	if cfg.CommitAuthorName == "" {
+		cfg.CommitAuthorName = "AI Intern"
+	}
+	if cfg.CommitAuthorEmail == "" {
+		cfg.CommitAuthorEmail = "ai-intern@example.com"
+	}
 
 	// Metrics defaults
 	if cfg.MetricsPort <= 0 {
 		cfg.MetricsPort = 9090 // Default Prometheus port
 	}
 	// MetricsEnabled defaults to false (opt-in)
+	if ladder := viper.GetString("AI_ESCALATION_LADDER"); strings.TrimSpace(ladder) != "" {
+		for _, tier := range strings.Split(ladder, ",") {
+			if tier = strings.TrimSpace(tier); tier != "" {
+				cfg.AIEscalationLadder = append(cfg.AIEscalationLadder, tier)
+			}
+		}
+	}
+
 	// "*" disables the allowlist check entirely — see validatePlannedChanges.
 	allowed := viper.GetString("ALLOWED_WRITE_DIRS")
 	if strings.TrimSpace(allowed) == "" {
@@ -185,12 +377,46 @@ func LoadConfig() (*Config, error) {
 		cfg.AllowedWriteDirs = parts
 	}
 
+	if cfg.ReposFile != "" {
+		repos, err := loadRepos(cfg.ReposFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Repos = repos
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
 	return cfg, nil
 }
 
+// splitList splits a comma-separated env value, dropping blank entries
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// parseKeyValueList parses "k1=v1,k2=v2". A malformed entry is kept with an
+// empty value so Validate can report it.
+func parseKeyValueList(s string) map[string]string {
+	entries := splitList(s)
+	if len(entries) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		k, v, _ := strings.Cut(entry, "=")
+		m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return m
+}
+
 func (c *Config) Validate() error {
 	// Validate ticketing mode and its required configuration
 	switch c.TicketingMode {
@@ -229,14 +455,43 @@ func (c *Config) Validate() error {
 			"supported values: jira, slack")
 	}
 
-	// Validate required GitHub configuration
-	if c.GitHubToken == "" {
+	// Validate required GitHub configuration: a token or a GitHub App
+	if c.GitHubAppID != 0 {
+		if c.GitHubAppInstallationID == 0 {
+			return errors.NewConfigMissingError("GITHUB_APP_INSTALLATION_ID")
+		}
+		if c.GitHubAppPrivateKey == "" {
+			return errors.NewConfigMissingError("GITHUB_APP_PRIVATE_KEY")
+		}
+	} else if c.GitHubToken == "" {
 		return errors.NewConfigMissingError("GITHUB_TOKEN")
 	}
-	if c.GitHubOwner == "" {
+	if c.GitHubOwner == "" && len(c.Repos) == 0 {
 		return errors.NewConfigMissingError("GITHUB_OWNER")
 	}
-	if c.GitHubRepo == "" {
+	if c.GitHubAPIURL != "" {
+		if u, err := url.Parse(c.GitHubAPIURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return errors.NewConfigInvalidError("GITHUB_API_URL", c.GitHubAPIURL,
+				"must be an http(s) URL such as https://ghe.example.com/api/v3/")
+		}
+	}
+	for _, f := range []struct{ field, path string }{
+		{"GITHUB_CA_BUNDLE", c.GitHubCABundle},
+		{"GITHUB_SSH_KEY", c.GitHubSSHKey},
+		{"GITHUB_SSH_KNOWN_HOSTS", c.GitHubSSHKnownHosts},
+	} {
+		if f.path == "" {
+			continue
+		}
+		if _, err := os.Stat(f.path); err != nil {
+			return errors.NewConfigInvalidError(f.field, f.path, "file not readable")
+		}
+	}
+	if len(c.Repos) > 0 {
+		if err := c.validateRepos(); err != nil {
+			return err
+		}
+	} else if c.GitHubRepo == "" {
 		return errors.NewConfigMissingError("GITHUB_REPO")
 	}
 
@@ -258,6 +513,77 @@ func (c *Config) Validate() error {
 			"supported values: anthropic, ollama")
 	}
 
+	// Validate escalation ladder tiers
+	for _, tier := range c.AIEscalationLadder {
+		provider, model := ParseEscalationTier(tier)
+		switch provider {
+		case "anthropic":
+			if c.AnthropicAPIKey == "" {
+				return errors.NewConfigMissingError("ANTHROPIC_API_KEY")
+			}
+		case "ollama":
+			if model == "" && c.OllamaModel == "" {
+				return errors.NewConfigInvalidError("AI_ESCALATION_LADDER", tier,
+					"ollama tier needs a model (ollama:<model>) or OLLAMA_MODEL")
+			}
+			if c.OllamaBaseURL == "" {
+				return errors.NewConfigMissingError("OLLAMA_BASE_URL")
+			}
+		default:
+			return errors.NewConfigInvalidError("AI_ESCALATION_LADDER", tier,
+				"supported providers: anthropic, ollama")
+		}
+	}
+
+	// Validate reporter -> GitHub login mappings
+	for reporter, login := range c.PRReporterGitHubMap {
+		if reporter == "" || login == "" {
+			return errors.NewConfigInvalidError("PR_REPORTER_GITHUB_MAP", reporter+"="+login,
+				"entries must be reporter=github-login")
+		}
+	}
+
+	// Validate branch refresh strategy ("" is allowed for configs built in code)
+	switch c.BranchRefreshStrategy {
+	case "", "rebase", "merge":
+	default:
+		return errors.NewConfigInvalidError("BRANCH_REFRESH_STRATEGY", c.BranchRefreshStrategy,
+			"must be 'rebase' or 'merge'")
+	}
+
+	if c.CloneDepth < 0 {
+		return errors.NewConfigInvalidError("CLONE_DEPTH", c.CloneDepth,
+			"must be 0 (full history) or a positive number of commits")
+	}
+
+	// Validate commit signing and attribution
+	switch c.CommitSigningFormat {
+	case "":
+	case "gpg", "ssh":
+		if c.CommitSigningKey == "" {
+			return errors.NewConfigMissingError("COMMIT_SIGNING_KEY")
+		}
+		if _, err := os.Stat(c.CommitSigningKey); err != nil {
+			return errors.NewConfigInvalidError("COMMIT_SIGNING_KEY", c.CommitSigningKey,
+				"signing key file not readable")
+		}
+	default:
+		return errors.NewConfigInvalidError("COMMIT_SIGNING_FORMAT", c.CommitSigningFormat,
+			"must be 'gpg' or 'ssh'")
+	}
+	for _, coAuthor := range c.CommitCoAuthors {
+		if addr, err := mail.ParseAddress(coAuthor); err != nil || addr.Name == "" {
+			return errors.NewConfigInvalidError("COMMIT_CO_AUTHORS", coAuthor,
+				"entries must be 'Name <email>'")
+		}
+	}
+	for reporter, email := range c.CommitReporterEmailMap {
+		if _, err := mail.ParseAddress(email); reporter == "" || err != nil {
+			return errors.NewConfigInvalidError("COMMIT_REPORTER_EMAIL_MAP", reporter+"="+email,
+				"entries must be reporter=email")
+		}
+	}
+
 	// Validate concurrent tickets
 	if c.MaxConcurrentTickets <= 0 {
 		return errors.NewConfigInvalidError("MAX_CONCURRENT_TICKETS", c.MaxConcurrentTickets,
@@ -290,6 +616,14 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate sandbox timeout format
+	if c.SandboxTimeout != "" {
+		if _, err := time.ParseDuration(c.SandboxTimeout); err != nil {
+			return errors.NewConfigInvalidError("SANDBOX_TIMEOUT", c.SandboxTimeout,
+				fmt.Sprintf("invalid duration format: %v", err))
+		}
+	}
+
 	// Validate file limits
 	if c.ContextMaxFiles <= 0 {
 		return errors.NewConfigInvalidError("CONTEXT_MAX_FILES", c.ContextMaxFiles,
@@ -313,14 +647,30 @@ func (c *Config) Validate() error {
 		return errors.NewConfigInvalidError("CONTEXT_MAX_BYTES", c.ContextMaxBytes,
 			"must be greater than 0")
 	}
+	if c.ContextMaxTokens < 0 {
+		return errors.NewConfigInvalidError("CONTEXT_MAX_TOKENS", c.ContextMaxTokens,
+			"must be 0 (derive from the model) or greater")
+	}
+
+	// Validate semantic retrieval (optional)
+	switch c.EmbeddingsProvider {
+	case "":
+	case "ollama", "openai":
+		if c.EmbeddingsModel == "" {
+			return errors.NewConfigMissingError("EMBEDDINGS_MODEL")
+		}
+	default:
+		return errors.NewConfigInvalidError("EMBEDDINGS_PROVIDER", c.EmbeddingsProvider,
+			"must be one of: ollama, openai")
+	}
 
 	// Validate self-healing configuration for conflicts
 	if c.SelfHealEnabled {
 		// At least one healing gate must be enabled
-		if !c.SelfHealOnTests && !c.SelfHealOnVet && !c.SelfHealOnBuild {
+		if !c.SelfHealOnTests && !c.SelfHealOnVet && !c.SelfHealOnBuild && !c.RequireTests {
 			return errors.NewConfigConflictError(
-				[]string{"SELF_HEAL_ENABLED", "SELF_HEAL_ON_TESTS", "SELF_HEAL_ON_VET", "SELF_HEAL_ON_BUILD"},
-				"SELF_HEAL_ENABLED=true but no healing gates are enabled (set at least one SELF_HEAL_ON_* to true)")
+				[]string{"SELF_HEAL_ENABLED", "SELF_HEAL_ON_TESTS", "SELF_HEAL_ON_VET", "SELF_HEAL_ON_BUILD", "REQUIRE_TESTS"},
+				"SELF_HEAL_ENABLED=true but no healing gates are enabled (set at least one SELF_HEAL_ON_* or REQUIRE_TESTS to true)")
 		}
 
 		// Validate max attempts
@@ -371,3 +721,12 @@ func (c *Config) Validate() error {
 
 	return nil
 }
+
+// ParseEscalationTier splits an AI_ESCALATION_LADDER entry into provider and
+// model at the first colon, so Ollama tags survive: "ollama:qwen2.5-coder:7b"
+// is provider "ollama", model "qwen2.5-coder:7b". model is empty when the
+// entry names only a provider.
+func ParseEscalationTier(tier string) (provider, model string) {
+	provider, model, _ = strings.Cut(strings.TrimSpace(tier), ":")
+	return strings.ToLower(strings.TrimSpace(provider)), strings.TrimSpace(model)
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -187,6 +188,252 @@ func TestConfig_Validate_InvalidCacheTTL(t *testing.T) {
 	}
 }
 
+func TestConfig_Validate_EscalationLadder(t *testing.T) {
+	cfg := validConfig()
+	cfg.OllamaBaseURL = "http://localhost:11434"
+	cfg.AIEscalationLadder = []string{"ollama:qwen2.5-coder:7b", "anthropic"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Valid ladder should pass, got: %v", err)
+	}
+
+	cfg.AIEscalationLadder = []string{"openai:gpt-4o"}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "AI_ESCALATION_LADDER") {
+		t.Errorf("Unknown provider should fail mentioning AI_ESCALATION_LADDER, got: %v", err)
+	}
+
+	cfg.AIEscalationLadder = []string{"ollama"}
+	cfg.OllamaModel = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("Ollama tier without any model should fail")
+	}
+}
+
+func TestParseEscalationTier(t *testing.T) {
+	tests := []struct {
+		tier, provider, model string
+	}{
+		{"ollama:qwen2.5-coder:7b", "ollama", "qwen2.5-coder:7b"},
+		{" Anthropic ", "anthropic", ""},
+		{"anthropic:claude-sonnet-4-20250514", "anthropic", "claude-sonnet-4-20250514"},
+	}
+	for _, tt := range tests {
+		provider, model := ParseEscalationTier(tt.tier)
+		if provider != tt.provider || model != tt.model {
+			t.Errorf("ParseEscalationTier(%q) = (%q, %q), want (%q, %q)", tt.tier, provider, model, tt.provider, tt.model)
+		}
+	}
+}
+
+func TestParseKeyValueList(t *testing.T) {
+	got := parseKeyValueList(" Jane Doe = janed ,jsmith=john-smith,, broken ")
+	want := map[string]string{"Jane Doe": "janed", "jsmith": "john-smith", "broken": ""}
+	if len(got) != len(want) {
+		t.Fatalf("parseKeyValueList() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("parseKeyValueList()[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+	if parseKeyValueList("") != nil {
+		t.Error("Expected nil for an empty value")
+	}
+}
+
+func TestConfig_Validate_PRReporterGitHubMap(t *testing.T) {
+	cfg := validConfig()
+	cfg.PRReporterGitHubMap = map[string]string{"Jane Doe": "janed"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Valid mapping should pass, got: %v", err)
+	}
+
+	cfg.PRReporterGitHubMap = map[string]string{"Jane Doe": ""}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "PR_REPORTER_GITHUB_MAP") {
+		t.Errorf("Entry without a login should fail mentioning PR_REPORTER_GITHUB_MAP, got: %v", err)
+	}
+}
+
+func TestConfig_Validate_BranchRefreshStrategy(t *testing.T) {
+	cfg := validConfig()
+	for _, strategy := range []string{"", "rebase", "merge"} {
+		cfg.BranchRefreshStrategy = strategy
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Strategy %q should be valid, got: %v", strategy, err)
+		}
+	}
+
+	cfg.BranchRefreshStrategy = "squash"
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "BRANCH_REFRESH_STRATEGY") {
+		t.Errorf("Unknown strategy should fail mentioning BRANCH_REFRESH_STRATEGY, got: %v", err)
+	}
+}
+
+func TestConfig_Validate_GitHubApp(t *testing.T) {
+	cfg := validConfig()
+	cfg.GitHubToken = ""
+	cfg.GitHubAppID = 7
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "GITHUB_APP_INSTALLATION_ID") {
+		t.Errorf("App without an installation should fail mentioning GITHUB_APP_INSTALLATION_ID, got: %v", err)
+	}
+
+	cfg.GitHubAppInstallationID = 42
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "GITHUB_APP_PRIVATE_KEY") {
+		t.Errorf("App without a key should fail mentioning GITHUB_APP_PRIVATE_KEY, got: %v", err)
+	}
+
+	cfg.GitHubAppPrivateKey = "/secrets/app.pem"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("App auth should not require GITHUB_TOKEN, got: %v", err)
+	}
+}
+
+func TestConfig_Validate_GitHubEnterprise(t *testing.T) {
+	cfg := validConfig()
+	cfg.GitHubAPIURL = "https://ghe.acme.com/api/v3/"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("GHES API URL should be valid, got: %v", err)
+	}
+
+	cfg.GitHubAPIURL = "ghe.acme.com/api/v3"
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "GITHUB_API_URL") {
+		t.Errorf("URL without a scheme should fail mentioning GITHUB_API_URL, got: %v", err)
+	}
+
+	cfg.GitHubAPIURL = ""
+	cfg.GitHubSSHKey = filepath.Join(t.TempDir(), "missing")
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "GITHUB_SSH_KEY") {
+		t.Errorf("Missing deploy key should fail mentioning GITHUB_SSH_KEY, got: %v", err)
+	}
+}
+
+func TestConfig_Validate_CloneDepth(t *testing.T) {
+	cfg := validConfig()
+	cfg.CloneDepth = 1
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Depth 1 should be valid, got: %v", err)
+	}
+
+	cfg.CloneDepth = -1
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "CLONE_DEPTH") {
+		t.Errorf("Negative depth should fail mentioning CLONE_DEPTH, got: %v", err)
+	}
+}
+
+func TestLoadRepos(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repos.yaml")
+	os.WriteFile(path, []byte(`repos:
+  - repo: payments-api
+    base_branch: develop
+    components: [Payments]
+    run_tests: true
+  - name: web
+    owner: acme-frontend
+    repo: web-app
+    allowed_write_dirs: [src]
+`), 0644)
+
+	repos, err := loadRepos(path)
+	if err != nil {
+		t.Fatalf("loadRepos failed: %v", err)
+	}
+	if len(repos) != 2 || repos[0].Name != "payments-api" || repos[1].Name != "web" {
+		t.Fatalf("Expected names to default to the repo, got %+v", repos)
+	}
+
+	cfg := validConfig()
+	cfg.Repos = repos
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Repos config should be valid, got: %v", err)
+	}
+
+	payments := cfg.ForRepo(repos[0])
+	if payments.GitHubOwner != "testorg" || payments.GitHubRepo != "payments-api" || payments.BaseBranch != "develop" || !payments.RunTestsBeforePR {
+		t.Errorf("Expected payments-api to inherit the owner and override base and tests, got %+v", payments)
+	}
+	web := cfg.ForRepo(repos[1])
+	if web.GitHubOwner != "acme-frontend" || web.AllowedWriteDirs[0] != "src" || web.RunTestsBeforePR || len(web.Repos) != 0 {
+		t.Errorf("Expected web to keep its own owner and dirs only, got %+v", web)
+	}
+}
+
+func TestConfig_Validate_Repos(t *testing.T) {
+	cfg := validConfig()
+	cfg.GitHubRepo = ""
+	cfg.Repos = []RepoConfig{{Name: "api", Repo: "api"}, {Name: "API", Repo: "api-v2"}}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("Duplicate names should fail, got: %v", err)
+	}
+
+	cfg.Repos = []RepoConfig{{Name: "api"}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("A repository without a repo should fail")
+	}
+
+	cfg.Repos = nil
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "GITHUB_REPO") {
+		t.Errorf("Without REPOS_FILE, GITHUB_REPO is required, got: %v", err)
+	}
+}
+
+func TestConfig_Validate_CommitSigning(t *testing.T) {
+	cfg := validConfig()
+	cfg.CommitSigningFormat = "ssh"
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "COMMIT_SIGNING_KEY") {
+		t.Errorf("Signing without a key should fail mentioning COMMIT_SIGNING_KEY, got: %v", err)
+	}
+
+	key := filepath.Join(t.TempDir(), "id_ed25519")
+	os.WriteFile(key, []byte("key"), 0600)
+	cfg.CommitSigningKey = key
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("SSH signing with a key file should be valid, got: %v", err)
+	}
+
+	cfg.CommitSigningFormat = "x509"
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "COMMIT_SIGNING_FORMAT") {
+		t.Errorf("Unknown format should fail mentioning COMMIT_SIGNING_FORMAT, got: %v", err)
+	}
+}
+
+func TestConfig_Validate_CommitCoAuthors(t *testing.T) {
+	cfg := validConfig()
+	cfg.CommitCoAuthors = []string{"Jane Doe <jane@example.com>"}
+	cfg.CommitReporterEmailMap = map[string]string{"Jane Doe": "jane@example.com"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Valid co-authors should pass, got: %v", err)
+	}
+
+	cfg.CommitCoAuthors = []string{"jane@example.com"}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "COMMIT_CO_AUTHORS") {
+		t.Errorf("Co-author without a name should fail mentioning COMMIT_CO_AUTHORS, got: %v", err)
+	}
+}
+
+func TestConfig_Validate_InvalidSandboxTimeout(t *testing.T) {
+	cfg := validConfig()
+	cfg.SandboxTimeout = "forever"
+	err := cfg.Validate()
+	if err == nil {
+		t.Error("Should fail with invalid sandbox timeout")
+	}
+	if !strings.Contains(err.Error(), "SANDBOX_TIMEOUT") {
+		t.Errorf("Error should mention SANDBOX_TIMEOUT, got: %v", err)
+	}
+}
+
 func TestConfig_Validate_ContextMaxFilesTooHigh(t *testing.T) {
 	cfg := validConfig()
 	cfg.ContextMaxFiles = 1001
@@ -403,3 +650,37 @@ func TestConfig_Validate_EdgeCases(t *testing.T) {
 		}
 	})
 }
+
+func TestConfig_Validate_Embeddings(t *testing.T) {
+	cfg := validConfig()
+	cfg.EmbeddingsProvider = "ollama"
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "EMBEDDINGS_MODEL") {
+		t.Errorf("Provider without model should fail mentioning EMBEDDINGS_MODEL, got: %v", err)
+	}
+
+	cfg.EmbeddingsModel = "nomic-embed-text"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Ollama embeddings should be valid, got: %v", err)
+	}
+
+	cfg.EmbeddingsProvider = "cohere"
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "EMBEDDINGS_PROVIDER") {
+		t.Errorf("Unknown provider should fail mentioning EMBEDDINGS_PROVIDER, got: %v", err)
+	}
+}
+
+func TestConfig_Validate_ContextMaxTokens(t *testing.T) {
+	cfg := validConfig()
+	cfg.ContextMaxTokens = 8000
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Positive token budget should be valid, got: %v", err)
+	}
+
+	cfg.ContextMaxTokens = -1
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "CONTEXT_MAX_TOKENS") {
+		t.Errorf("Negative budget should fail mentioning CONTEXT_MAX_TOKENS, got: %v", err)
+	}
+}
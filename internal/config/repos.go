@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"intern/internal/errors"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RepoConfig is one repository the agent works on. Unset fields inherit the
+// top-level setting (GITHUB_OWNER, BASE_BRANCH, ALLOWED_WRITE_DIRS, the
+// quality gate toggles). Tickets are routed to a repository by a "repo:<name>"
+// hint in the description, then by JIRA component, then by label, then by how
+// well the ticket's keywords match the repository's index.
+type RepoConfig struct {
+	Name             string   `yaml:"name"` // Routing name; defaults to Repo
+	Owner            string   `yaml:"owner"`
+	Repo             string   `yaml:"repo"`
+	GitURL           string   `yaml:"git_url"` // Overrides the derived clone URL
+	BaseBranch       string   `yaml:"base_branch"`
+	AllowedWriteDirs []string `yaml:"allowed_write_dirs"`
+	RunVet           *bool    `yaml:"run_vet"`
+	RunTests         *bool    `yaml:"run_tests"`
+	RequireTests     *bool    `yaml:"require_tests"`
+	Components       []string `yaml:"components"` // JIRA components routed here
+	Labels           []string `yaml:"labels"`     // JIRA labels routed here
+}
+
+// loadRepos reads the "repos:" list from a REPOS_FILE YAML document
+func loadRepos(path string) ([]RepoConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.NewConfigInvalidError("REPOS_FILE", path, "file not readable")
+	}
+	var doc struct {
+		Repos []RepoConfig `yaml:"repos"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.NewConfigInvalidError("REPOS_FILE", path, fmt.Sprintf("invalid YAML: %v", err))
+	}
+	for i := range doc.Repos {
+		if doc.Repos[i].Name == "" {
+			doc.Repos[i].Name = doc.Repos[i].Repo
+		}
+	}
+	return doc.Repos, nil
+}
+
+// RepoConfigs returns the repositories the agent works on, in routing
+// priority order (the first is the fallback). Without REPOS_FILE this is the
+// single GITHUB_OWNER/GITHUB_REPO repository.
+func (c *Config) RepoConfigs() []RepoConfig {
+	if len(c.Repos) > 0 {
+		return c.Repos
+	}
+	return []RepoConfig{{Name: c.GitHubRepo, Owner: c.GitHubOwner, Repo: c.GitHubRepo, GitURL: c.GitHubGitURL}}
+}
+
+// ForRepo returns a copy of c targeting r: owner, repo, base branch, allowed
+// dirs and gates are taken from r where set. The copy has no Repos, so code
+// handed a per-repo config only ever sees that one repository.
+func (c *Config) ForRepo(r RepoConfig) *Config {
+	out := *c
+	out.Repos = nil
+	if r.Owner != "" {
+		out.GitHubOwner = r.Owner
+	}
+	out.GitHubRepo = r.Repo
+	if r.GitURL != "" || r.Repo != c.GitHubRepo {
+		out.GitHubGitURL = r.GitURL
+	}
+	if r.BaseBranch != "" {
+		out.BaseBranch = r.BaseBranch
+	}
+	if len(r.AllowedWriteDirs) > 0 {
+		out.AllowedWriteDirs = r.AllowedWriteDirs
+	}
+	if r.RunVet != nil {
+		out.RunVetBeforePR = *r.RunVet
+	}
+	if r.RunTests != nil {
+		out.RunTestsBeforePR = *r.RunTests
+	}
+	if r.RequireTests != nil {
+		out.RequireTests = *r.RequireTests
+	}
+	return &out
+}
+
+// validateRepos checks the REPOS_FILE entries
+func (c *Config) validateRepos() error {
+	seen := make(map[string]bool, len(c.Repos))
+	for _, r := range c.Repos {
+		if r.Repo == "" {
+			return errors.NewConfigInvalidError("REPOS_FILE", r.Name, "every repository needs a repo")
+		}
+		if r.Owner == "" && c.GitHubOwner == "" {
+			return errors.NewConfigMissingError("GITHUB_OWNER")
+		}
+		name := strings.ToLower(r.Name)
+		if seen[name] {
+			return errors.NewConfigInvalidError("REPOS_FILE", r.Name, "duplicate repository name")
+		}
+		seen[name] = true
+		for _, dir := range r.AllowedWriteDirs {
+			if dir == "/" || strings.Contains(dir, "..") {
+				return errors.NewConfigInvalidError("REPOS_FILE", dir,
+					"allowed_write_dirs may not be / or contain ..")
+			}
+		}
+	}
+	return nil
+}
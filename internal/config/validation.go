@@ -8,7 +8,7 @@ func ValidateConfig(cfg *Config) error {
 	if cfg.JiraURL == "" || cfg.JiraEmail == "" || cfg.JiraAPIToken == "" || cfg.JiraProject == "" {
 		return fmt.Errorf("missing JIRA configuration")
 	}
-	if cfg.GitHubToken == "" || cfg.GitHubOwner == "" || cfg.GitHubRepo == "" {
+	if (cfg.GitHubToken == "" && cfg.GitHubAppID == 0) || cfg.GitHubOwner == "" || cfg.GitHubRepo == "" {
 		return fmt.Errorf("missing GitHub configuration")
 	}
 	if cfg.AnthropicAPIKey == "" {
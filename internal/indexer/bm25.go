@@ -0,0 +1,239 @@
+package indexer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go/scanner"
+	"go/token"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TermIndexFileName holds the inverted index next to file_index.json
+const TermIndexFileName = "term_index.json"
+
+// BM25 parameters: k1 caps how much repeating a term keeps helping, b how
+// much long files are penalised. The usual defaults.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// contentWeight is the score the best content (BM25) match in the
+// repository adds before the category multiplier; every other file's BM25
+// score is scaled against it, so content ranks on the same footing as the
+// path and symbol tiers however long the ticket is
+const contentWeight = 20.0
+
+// wordPattern finds words in comments, strings and non-Go files
+var wordPattern = regexp.MustCompile(`[A-Za-z][A-Za-z0-9_]*`)
+
+// TermIndex is an inverted index over the identifiers, comments and string
+// literals of every indexed file, scored with BM25. It's persisted as
+// .ai-intern/term_index.json and loaded alongside the FileIndex.
+type TermIndex struct {
+	Postings   map[string]map[string]int `json:"postings"`    // Term -> path -> occurrences
+	DocLengths map[string]int            `json:"doc_lengths"` // Path -> total terms
+}
+
+// NewTermIndex returns an empty term index
+func NewTermIndex() *TermIndex {
+	return &TermIndex{
+		Postings:   make(map[string]map[string]int),
+		DocLengths: make(map[string]int),
+	}
+}
+
+// Add indexes a file's terms, replacing any it had
+func (t *TermIndex) Add(path string, terms map[string]int) {
+	t.Remove(path)
+	length := 0
+	for term, n := range terms {
+		if t.Postings[term] == nil {
+			t.Postings[term] = make(map[string]int)
+		}
+		t.Postings[term][path] = n
+		length += n
+	}
+	t.DocLengths[path] = length
+}
+
+// Remove drops a file from the index
+func (t *TermIndex) Remove(path string) {
+	if _, ok := t.DocLengths[path]; !ok {
+		return
+	}
+	delete(t.DocLengths, path)
+	for term, docs := range t.Postings {
+		delete(docs, path)
+		if len(docs) == 0 {
+			delete(t.Postings, term)
+		}
+	}
+}
+
+// Score returns each matching file's BM25 score for keywords. Keywords are
+// normalised the way file terms are, so "retrying" finds "Retry".
+func (t *TermIndex) Score(keywords []string) map[string]float64 {
+	if t == nil || len(t.DocLengths) == 0 {
+		return nil
+	}
+
+	total := 0
+	for _, n := range t.DocLengths {
+		total += n
+	}
+	n := float64(len(t.DocLengths))
+	avgLen := float64(total) / n
+
+	queried := make(map[string]bool)
+	scores := make(map[string]float64)
+	for _, keyword := range keywords {
+		for term := range queryTerms(keyword) {
+			if queried[term] {
+				continue
+			}
+			queried[term] = true
+
+			docs := t.Postings[term]
+			if len(docs) == 0 {
+				continue
+			}
+			df := float64(len(docs))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			for path, tf := range docs {
+				f := float64(tf)
+				norm := bm25K1 * (1 - bm25B + bm25B*float64(t.DocLengths[path])/avgLen)
+				scores[path] += idf * f * (bm25K1 + 1) / (f + norm)
+			}
+		}
+	}
+	return scores
+}
+
+// queryTerms normalises one extracted keyword into index terms; paths and
+// identifiers split the way file terms do
+func queryTerms(keyword string) map[string]int {
+	terms := make(map[string]int)
+	for _, word := range wordPattern.FindAllString(keyword, -1) {
+		addIdentifierTerms(terms, word)
+	}
+	return terms
+}
+
+// tokenizeFile returns a file's terms and their counts: for Go, every
+// identifier, comment word and string literal word (via go/scanner); for
+// other text files, every word. Binary files yield nothing.
+func tokenizeFile(absPath, relPath string) map[string]int {
+	src, err := os.ReadFile(absPath)
+	if err != nil || bytes.IndexByte(src, 0) >= 0 {
+		return nil
+	}
+
+	terms := make(map[string]int)
+	if !strings.HasSuffix(relPath, ".go") {
+		addTextTerms(terms, string(src))
+		return terms
+	}
+
+	fset := token.NewFileSet()
+	file := fset.AddFile(relPath, -1, len(src))
+	var s scanner.Scanner
+	s.Init(file, src, func(token.Position, string) {}, scanner.ScanComments)
+	for {
+		_, tok, lit := s.Scan()
+		switch tok {
+		case token.EOF:
+			return terms
+		case token.IDENT:
+			addIdentifierTerms(terms, lit)
+		case token.COMMENT:
+			addTextTerms(terms, lit)
+		case token.STRING:
+			if unquoted, err := strconv.Unquote(lit); err == nil {
+				lit = unquoted
+			}
+			addTextTerms(terms, lit)
+		}
+	}
+}
+
+// addTextTerms adds every word in free text
+func addTextTerms(terms map[string]int, text string) {
+	for _, word := range wordPattern.FindAllString(text, -1) {
+		addIdentifierTerms(terms, word)
+	}
+}
+
+// addIdentifierTerms adds an identifier whole and split into its camelCase
+// and snake_case parts, so RateLimiter matches both "ratelimiter" and
+// "rate limit"
+func addIdentifierTerms(terms map[string]int, ident string) {
+	addTerm(terms, ident)
+	for _, part := range strings.Split(ident, "_") {
+		parts := splitCamelCase(part)
+		if len(parts) < 2 && part == ident {
+			continue
+		}
+		for _, p := range parts {
+			addTerm(terms, p)
+		}
+	}
+}
+
+// addTerm counts one normalised term, skipping stop words and short noise
+func addTerm(terms map[string]int, word string) {
+	word = strings.ToLower(word)
+	if len(word) <= 2 || stopWords[word] {
+		return
+	}
+	terms[stemTerm(word)]++
+}
+
+// stemTerm strips the common English suffixes that separate a ticket's
+// wording from the code's ("retries", "retrying", "retried" -> "retry")
+func stemTerm(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "ied") && len(word) > 4:
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "ing") && len(word) > 5:
+		return word[:len(word)-3]
+	case strings.HasSuffix(word, "ed") && len(word) > 4:
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && len(word) > 3:
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+// saveTermIndex writes the term index next to the file index
+func (idx *Indexer) saveTermIndex(terms *TermIndex) error {
+	data, err := json.Marshal(terms)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(idx.repoRoot, IndexDirName, TermIndexFileName), data, 0644)
+}
+
+// loadTermIndex reads the term index, or returns nil if there is none
+func (idx *Indexer) loadTermIndex() (*TermIndex, error) {
+	data, err := os.ReadFile(filepath.Join(idx.repoRoot, IndexDirName, TermIndexFileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	terms := NewTermIndex()
+	if err := json.Unmarshal(data, terms); err != nil {
+		return nil, err
+	}
+	return terms, nil
+}
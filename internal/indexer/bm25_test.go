@@ -0,0 +1,145 @@
+package indexer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var contentRepo = map[string]string{
+	"internal/client/http.go": `package client
+
+// Do sends the request, backing off when the server says we're over quota
+func (c *Client) Do(req *Request) error {
+	for attempt := 0; attempt < c.maxRetries; attempt++ {
+		if resp.StatusCode == 429 {
+			log("rate limited, retrying")
+			c.waitForRateLimit()
+		}
+	}
+	return nil
+}
+`,
+	"internal/client/types.go": `package client
+
+type Request struct{ URL string }
+`,
+	"internal/report/report.go": `package report
+
+// Render formats the weekly report
+func Render() string { return "report" }
+`,
+	"docs/OPERATIONS.md": "# Operations\n\nThe client retries on rate limit errors.\n",
+}
+
+func TestTokenizeFile(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoFiles(t, dir, contentRepo)
+	idx := New(dir)
+
+	terms := tokenizeFile(idx.repoRoot+"/internal/client/http.go", "internal/client/http.go")
+	// Identifiers whole and split, comment and string words, stemmed
+	for _, term := range []string{"waitforratelimit", "wait", "rate", "limit", "maxretry", "retry", "quota", "server", "statuscode"} {
+		assert.Contains(t, terms, term)
+	}
+	assert.Equal(t, 2, terms["rate"], "from waitForRateLimit and the string literal")
+	assert.NotContains(t, terms, "the", "stop words are skipped")
+	assert.NotContains(t, terms, "do", "short words are skipped")
+
+	md := tokenizeFile(idx.repoRoot+"/docs/OPERATIONS.md", "docs/OPERATIONS.md")
+	assert.Equal(t, 1, md["retry"])
+}
+
+func TestStemTerm(t *testing.T) {
+	for word, want := range map[string]string{
+		"retries": "retry", "retried": "retry", "retrying": "retry", "retry": "retry",
+		"limits": "limit", "process": "process", "status": "statu",
+	} {
+		assert.Equal(t, want, stemTerm(word), word)
+	}
+}
+
+func TestTermIndex_Score(t *testing.T) {
+	terms := NewTermIndex()
+	terms.Add("a.go", map[string]int{"retry": 3, "rate": 2, "limit": 2})
+	terms.Add("b.go", map[string]int{"retry": 1, "report": 5})
+	terms.Add("c.go", map[string]int{"report": 1})
+
+	scores := terms.Score([]string{"retrying", "rate", "limit"})
+	assert.Greater(t, scores["a.go"], scores["b.go"])
+	assert.NotContains(t, scores, "c.go")
+
+	// Re-adding replaces, removing forgets
+	terms.Add("b.go", map[string]int{"report": 1})
+	terms.Remove("a.go")
+	assert.Empty(t, terms.Score([]string{"retry"}))
+	assert.NotContains(t, terms.Postings, "rate")
+	assert.Len(t, terms.DocLengths, 2)
+}
+
+func TestScoreFiles_Content(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoFiles(t, dir, contentRepo)
+	idx := New(dir)
+	index, err := idx.BuildIndex()
+	require.NoError(t, err)
+
+	// A ticket describing behaviour, naming no file or symbol
+	scores := ScoreFiles(index, ExtractKeywords("Retry on rate limit"))
+	require.NotEmpty(t, scores)
+	assert.Equal(t, "internal/client/http.go", scores[0].Path)
+
+	relevant := RelevantFiles(scores)
+	var paths []string
+	for _, s := range relevant {
+		paths = append(paths, s.Path)
+	}
+	assert.Contains(t, paths, "internal/client/http.go")
+	assert.NotContains(t, paths, "internal/report/report.go", "matched nothing")
+	assert.NotContains(t, paths, "internal/client/types.go", "matched nothing")
+
+	// The term index round-trips through SaveIndex/LoadIndex
+	require.NoError(t, idx.SaveIndex(index))
+	loaded, err := idx.LoadIndex()
+	require.NoError(t, err)
+	require.NotNil(t, loaded.Terms)
+	assert.Equal(t, index.Terms.DocLengths, loaded.Terms.DocLengths)
+	assert.Equal(t, scores[0].Path, ScoreFiles(loaded, ExtractKeywords("Retry on rate limit"))[0].Path)
+}
+
+func TestRelevantFiles(t *testing.T) {
+	scores := []FileScore{
+		{Path: "main.go", Score: 15, Matched: false}, // Important but unrelated
+		{Path: "a.go", Score: 12, Matched: true},
+		{Path: "b.go", Score: 4, Matched: true},
+		{Path: "c.go", Score: 3, Matched: true},
+	}
+	got := RelevantFiles(scores)
+	require.Len(t, got, 2)
+	assert.Equal(t, "a.go", got[0].Path)
+	assert.Equal(t, "b.go", got[1].Path)
+	assert.Empty(t, RelevantFiles(nil))
+}
+
+func TestUpdateIndex_Terms(t *testing.T) {
+	dir, git := initSymbolRepo(t)
+	idx := New(dir)
+	index, err := idx.BuildIndex()
+	require.NoError(t, err)
+	require.NoError(t, idx.SaveIndex(index))
+
+	writeRepoFiles(t, dir, map[string]string{
+		"internal/orchestrator/branch.go": "package orchestrator\n\n// Throttle requests to the quota\nfunc throttle() {}\n",
+	})
+	git("rm", "-q", "internal/other/other.go")
+	git("add", ".")
+	git("commit", "-m", "Throttle")
+
+	updated, err := idx.UpdateIndex()
+	require.NoError(t, err)
+	scores := updated.Terms.Score([]string{"quota"})
+	assert.Contains(t, scores, "internal/orchestrator/branch.go")
+	assert.NotContains(t, updated.Terms.DocLengths, "internal/other/other.go")
+	assert.Empty(t, updated.Terms.Score([]string{"feature"}), "old content is forgotten")
+}
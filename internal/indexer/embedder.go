@@ -0,0 +1,133 @@
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Embedder turns text into vectors for semantic retrieval. Implementations
+// must return one vector per text, in order.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Model names the embedding model; vectors from different models aren't
+	// comparable, so a change re-embeds the repository
+	Model() string
+}
+
+// NewEmbedder returns the embedder for a provider: "ollama" (POST
+// {baseURL}/api/embeddings) or "openai" (POST {baseURL}/embeddings on any
+// OpenAI-compatible endpoint)
+func NewEmbedder(provider, baseURL, model, apiKey string) (Embedder, error) {
+	client := &http.Client{Timeout: 60 * time.Second}
+	baseURL = strings.TrimRight(baseURL, "/")
+	switch provider {
+	case "ollama":
+		return &ollamaEmbedder{baseURL: baseURL, model: model, client: client}, nil
+	case "openai":
+		return &openAIEmbedder{baseURL: baseURL, model: model, apiKey: apiKey, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown embeddings provider %q", provider)
+	}
+}
+
+// ollamaEmbedder calls Ollama's embeddings API, one text per request
+type ollamaEmbedder struct {
+	baseURL string
+	model   string
+	client  *http.Client
+}
+
+func (e *ollamaEmbedder) Model() string { return "ollama:" + e.model }
+
+func (e *ollamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		var resp struct {
+			Embedding []float32 `json:"embedding"`
+		}
+		req := map[string]string{"model": e.model, "prompt": text}
+		if err := postJSON(ctx, e.client, e.baseURL+"/api/embeddings", "", req, &resp); err != nil {
+			return nil, err
+		}
+		if len(resp.Embedding) == 0 {
+			return nil, fmt.Errorf("ollama returned an empty embedding (is %s an embedding model?)", e.model)
+		}
+		vectors = append(vectors, resp.Embedding)
+	}
+	return vectors, nil
+}
+
+// openAIEmbedder calls an OpenAI-compatible embeddings API, batching texts
+type openAIEmbedder struct {
+	baseURL string
+	model   string
+	apiKey  string
+	client  *http.Client
+}
+
+// openAIBatchSize bounds the inputs per embeddings request
+const openAIBatchSize = 64
+
+func (e *openAIEmbedder) Model() string { return "openai:" + e.model }
+
+func (e *openAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += openAIBatchSize {
+		batch := texts[start:min(start+openAIBatchSize, len(texts))]
+		var resp struct {
+			Data []struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			} `json:"data"`
+		}
+		req := map[string]interface{}{"model": e.model, "input": batch}
+		if err := postJSON(ctx, e.client, e.baseURL+"/embeddings", e.apiKey, req, &resp); err != nil {
+			return nil, err
+		}
+		if len(resp.Data) != len(batch) {
+			return nil, fmt.Errorf("embeddings API returned %d vectors for %d inputs", len(resp.Data), len(batch))
+		}
+		out := make([][]float32, len(batch))
+		for _, d := range resp.Data {
+			if d.Index < 0 || d.Index >= len(batch) {
+				return nil, fmt.Errorf("embeddings API returned out-of-range index %d", d.Index)
+			}
+			out[d.Index] = d.Embedding
+		}
+		vectors = append(vectors, out...)
+	}
+	return vectors, nil
+}
+
+// postJSON posts body as JSON and decodes a 2xx response into out
+func postJSON(ctx context.Context, client *http.Client, url, apiKey string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("embeddings request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("embeddings request failed: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,82 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOllamaEmbedder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/embeddings", r.URL.Path)
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "nomic-embed-text", req["model"])
+		json.NewEncoder(w).Encode(map[string]interface{}{"embedding": []float32{float32(len(req["prompt"])), 1}})
+	}))
+	defer server.Close()
+
+	e, err := NewEmbedder("ollama", server.URL+"/", "nomic-embed-text", "")
+	require.NoError(t, err)
+	assert.Equal(t, "ollama:nomic-embed-text", e.Model())
+	vectors, err := e.Embed(context.Background(), []string{"a", "abc"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 1}, {3, 1}}, vectors)
+}
+
+func TestOpenAIEmbedder(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		var req struct {
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		// Answer out of order; the embedder places results by index
+		type datum struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		var data []datum
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, datum{Index: i, Embedding: []float32{float32(len(req.Input[i]))}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	e, err := NewEmbedder("openai", server.URL, "text-embedding-3-small", "sk-test")
+	require.NoError(t, err)
+	texts := make([]string, openAIBatchSize+1)
+	for i := range texts {
+		texts[i] = string(make([]byte, i%3))
+	}
+	vectors, err := e.Embed(context.Background(), texts)
+	require.NoError(t, err)
+	require.Len(t, vectors, len(texts))
+	assert.Equal(t, 2, requests, "batched")
+	assert.Equal(t, []float32{2}, vectors[2])
+	assert.Equal(t, []float32{1}, vectors[openAIBatchSize])
+}
+
+func TestEmbedder_Errors(t *testing.T) {
+	_, err := NewEmbedder("cohere", "", "m", "")
+	assert.Error(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+	e, err := NewEmbedder("ollama", server.URL, "missing", "")
+	require.NoError(t, err)
+	_, err = e.Embed(context.Background(), []string{"x"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "model not found")
+}
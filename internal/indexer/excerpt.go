@@ -0,0 +1,168 @@
+package indexer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// excerptContextLines are shown before and after each relevant declaration
+const excerptContextLines = 3
+
+// maxExcerptShare is the largest excerpt, as a share of the file, worth
+// showing instead of the whole file
+const maxExcerptShare = 0.6
+
+// LineRange is an inclusive range of 1-based line numbers
+type LineRange struct {
+	Start int
+	End   int
+}
+
+// Contains reports whether lines start..end lie within r
+func (r LineRange) Contains(start, end int) bool {
+	return start >= r.Start && end <= r.End
+}
+
+// FormatLineRanges lists line ranges, e.g. "lines 10-42, 90-120"
+func FormatLineRanges(ranges []LineRange) string {
+	parts := make([]string, len(ranges))
+	for i, r := range ranges {
+		parts[i] = fmt.Sprintf("%d-%d", r.Start, r.End)
+	}
+	return "lines " + strings.Join(parts, ", ")
+}
+
+// Excerpt is the part of a file relevant to a ticket: the declarations
+// mentioning its keywords, verbatim with line numbers, and the signatures
+// of the rest
+type Excerpt struct {
+	Ranges []LineRange // Lines shown verbatim, in order
+	Lines  int         // Lines in the whole file
+	Text   string
+}
+
+// excerptLinePattern matches the line number prefix ExtractExcerpt adds
+var excerptLinePattern = regexp.MustCompile(`^ *\d+\| ?`)
+
+// ExtractExcerpt returns the top-level declarations of a Go file whose
+// names, comments, identifiers or strings mention a keyword (matched the
+// way BM25 terms are), with their doc comments and excerptContextLines of
+// surrounding lines. Returns nil if the file isn't Go, doesn't parse,
+// nothing is relevant, or the excerpt would be more than maxExcerptShare of
+// the file.
+func ExtractExcerpt(filePath string, keywords []string) (*Excerpt, error) {
+	if !strings.HasSuffix(filePath, ".go") {
+		return nil, nil
+	}
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filePath, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, nil
+	}
+
+	wanted := make(map[string]bool)
+	for _, keyword := range keywords {
+		for term := range queryTerms(keyword) {
+			wanted[term] = true
+		}
+	}
+
+	lines := strings.Split(string(src), "\n")
+	var ranges []LineRange
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			continue
+		}
+		start := decl.Pos()
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		case *ast.GenDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		}
+		from, to := fset.Position(start), fset.Position(decl.End())
+		if !mentionsTerm(string(src[from.Offset:to.Offset]), wanted) {
+			continue
+		}
+		r := LineRange{
+			Start: max(from.Line-excerptContextLines, 1),
+			End:   min(to.Line+excerptContextLines, len(lines)),
+		}
+		// Merge with the previous range when they touch
+		if n := len(ranges); n > 0 && r.Start <= ranges[n-1].End+1 {
+			ranges[n-1].End = max(ranges[n-1].End, r.End)
+			continue
+		}
+		ranges = append(ranges, r)
+	}
+	if len(ranges) == 0 {
+		return nil, nil
+	}
+
+	shown := 0
+	for _, r := range ranges {
+		shown += r.End - r.Start + 1
+	}
+	if float64(shown) > maxExcerptShare*float64(len(lines)) {
+		return nil, nil
+	}
+
+	var sb strings.Builder
+	for _, r := range ranges {
+		sb.WriteString(fmt.Sprintf("// Lines %d-%d of %d:\n", r.Start, r.End, len(lines)))
+		for n := r.Start; n <= r.End; n++ {
+			sb.WriteString(fmt.Sprintf("%5d| %s\n", n, lines[n-1]))
+		}
+		sb.WriteString("\n")
+	}
+	if signatures, err := ExtractMinimalContext(filePath); err == nil {
+		sb.WriteString("// Signatures of the whole file:\n")
+		sb.WriteString(signatures)
+	}
+	return &Excerpt{Ranges: ranges, Lines: len(lines), Text: sb.String()}, nil
+}
+
+// mentionsTerm reports whether source text contains any of terms
+func mentionsTerm(text string, terms map[string]bool) bool {
+	found := make(map[string]int)
+	addTextTerms(found, text)
+	for term := range found {
+		if terms[term] {
+			return true
+		}
+	}
+	return false
+}
+
+// StripLineNumbers removes the line number prefixes ExtractExcerpt adds,
+// for edit blocks copied from an excerpt with them. Returns text unchanged
+// and false unless every non-blank line has a prefix.
+func StripLineNumbers(text string) (string, bool) {
+	lines := strings.Split(text, "\n")
+	prefixed := false
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		loc := excerptLinePattern.FindStringIndex(line)
+		if loc == nil {
+			return text, false
+		}
+		lines[i] = line[loc[1]:]
+		prefixed = true
+	}
+	return strings.Join(lines, "\n"), prefixed
+}
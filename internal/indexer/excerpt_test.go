@@ -0,0 +1,89 @@
+package indexer
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// handlerSource is a file with one invoice function among unrelated ones
+func handlerSource() string {
+	var sb strings.Builder
+	sb.WriteString("package handler\n\nimport \"fmt\"\n")
+	for i := 0; i < 10; i++ {
+		sb.WriteString("\n// Unrelated" + string(rune('A'+i)) + " does something else\n")
+		sb.WriteString("func Unrelated" + string(rune('A'+i)) + "() {\n\tfmt.Println(\"other\")\n\tfmt.Println(\"other\")\n}\n")
+	}
+	sb.WriteString("\n// Refund reverses an invoice payment\nfunc Refund(amount int) int {\n\treturn -amount\n}\n")
+	return sb.String()
+}
+
+func TestExtractExcerpt(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoFiles(t, dir, map[string]string{"handler.go": handlerSource()})
+
+	excerpt, err := ExtractExcerpt(filepath.Join(dir, "handler.go"), []string{"invoice"})
+	require.NoError(t, err)
+	require.NotNil(t, excerpt)
+
+	// Refund and its doc comment, with context lines before it, to the end
+	require.Len(t, excerpt.Ranges, 1)
+	lines := strings.Split(handlerSource(), "\n")
+	assert.Equal(t, len(lines), excerpt.Lines)
+	assert.Equal(t, len(lines), excerpt.Ranges[0].End)
+	assert.Equal(t, "// Refund reverses an invoice payment", lines[excerpt.Ranges[0].Start+excerptContextLines-1])
+
+	assert.Contains(t, excerpt.Text, "func Refund(amount int) int {\n")
+	assert.Contains(t, excerpt.Text, "| \treturn -amount\n")
+	assert.Equal(t, 1, strings.Count(excerpt.Text, "fmt.Println(\"other\")"), "only as a context line")
+	assert.Contains(t, excerpt.Text, "// Signatures of the whole file:")
+	assert.Contains(t, excerpt.Text, "func UnrelatedA()")
+}
+
+func TestExtractExcerpt_MergesAdjacent(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoFiles(t, dir, map[string]string{"handler.go": strings.Replace(handlerSource(),
+		"// UnrelatedJ does something else", "// UnrelatedJ checks the invoice", 1)})
+
+	excerpt, err := ExtractExcerpt(filepath.Join(dir, "handler.go"), []string{"invoice"})
+	require.NoError(t, err)
+	require.NotNil(t, excerpt)
+	assert.Len(t, excerpt.Ranges, 1, "touching ranges are merged")
+	assert.Contains(t, excerpt.Text, "func UnrelatedJ() {\n")
+}
+
+func TestExtractExcerpt_None(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoFiles(t, dir, map[string]string{
+		"handler.go": handlerSource(),
+		"small.go":   "package handler\n\n// Invoice totals an invoice\nfunc Invoice() {}\n",
+		"notes.md":   "# Invoice\n",
+	})
+
+	for name, keywords := range map[string][]string{
+		"handler.go": {"shipping"}, // Nothing relevant
+		"small.go":   {"invoice"},  // Most of the file
+		"notes.md":   {"invoice"},  // Not Go
+	} {
+		excerpt, err := ExtractExcerpt(filepath.Join(dir, name), keywords)
+		require.NoError(t, err)
+		assert.Nil(t, excerpt, name)
+	}
+}
+
+func TestStripLineNumbers(t *testing.T) {
+	stripped, ok := StripLineNumbers("   41| func Refund(amount int) int {\n   42| \treturn -amount\n\n   43| }")
+	assert.True(t, ok)
+	assert.Equal(t, "func Refund(amount int) int {\n\treturn -amount\n\n}", stripped)
+
+	stripped, ok = StripLineNumbers("func Refund(amount int) int {\n   42| \treturn -amount")
+	assert.False(t, ok)
+	assert.Equal(t, "func Refund(amount int) int {\n   42| \treturn -amount", stripped)
+}
+
+func TestFormatLineRanges(t *testing.T) {
+	assert.Equal(t, "lines 10-42, 90-120", FormatLineRanges([]LineRange{{10, 42}, {90, 120}}))
+}
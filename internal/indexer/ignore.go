@@ -0,0 +1,203 @@
+package indexer
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"intern/internal/codeowners"
+)
+
+// IgnoreFileName is the target repository's own list of paths to keep out
+// of the index and the context, in .gitignore syntax, under IndexDirName.
+// The write policy always protects it.
+const IgnoreFileName = "ignore"
+
+// generatedHeaderBytes is how much of a file is searched for a generated
+// code header
+const generatedHeaderBytes = 4 * 1024
+
+// generatedHeader matches the "Code generated ... DO NOT EDIT." comment that
+// Go (and most other code generators) put at the top of generated files
+var generatedHeader = regexp.MustCompile(`(?m)^\s*(//|#|/?\*|--|;)\s*Code generated .*DO NOT EDIT`)
+
+// ignoreRule is one pattern of a .gitignore-style file
+type ignoreRule struct {
+	base   string // Directory of the file it came from, "" for the root
+	re     *regexp.Regexp
+	negate bool
+}
+
+// Ignore decides which paths of a repository it asks to keep out of the
+// index and the context: those matched by .gitignore files at any depth or
+// by IndexDirName/IgnoreFileName, and those marked linguist-generated or
+// linguist-vendored in .gitattributes files. Nested files are read as the
+// paths under them are checked.
+type Ignore struct {
+	repoRoot   string
+	ignores    map[string][]ignoreRule // Directory -> its .gitignore's rules
+	attributes map[string][]ignoreRule // Directory -> its .gitattributes' generated/vendored rules
+	repoRules  []ignoreRule            // IndexDirName/IgnoreFileName, which override .gitignore
+}
+
+// LoadIgnore reads the ignore rules at the root of a repository
+func LoadIgnore(repoRoot string) *Ignore {
+	ig := &Ignore{
+		repoRoot:   repoRoot,
+		ignores:    make(map[string][]ignoreRule),
+		attributes: make(map[string][]ignoreRule),
+	}
+	ig.repoRules = readIgnoreRules(filepath.Join(repoRoot, IndexDirName, IgnoreFileName), "")
+	return ig
+}
+
+// Matches reports whether relPath, a directory if isDir, is ignored by a
+// .gitignore, IndexDirName/IgnoreFileName or .gitattributes rule. Rules
+// deeper in the tree, then IndexDirName/IgnoreFileName, take precedence.
+func (ig *Ignore) Matches(relPath string, isDir bool) bool {
+	slash := filepath.ToSlash(relPath)
+	if slash == "." || slash == "" {
+		return false
+	}
+
+	ignored := false
+	for _, dir := range ancestors(slash) {
+		ignored = applyRules(ig.rulesIn(ig.ignores, dir, ".gitignore", readIgnoreRules), dir, slash, isDir, ignored)
+	}
+	ignored = applyRules(ig.repoRules, "", slash, isDir, ignored)
+	if ignored || isDir {
+		return ignored
+	}
+
+	generated := false
+	for _, dir := range ancestors(slash) {
+		generated = applyRules(ig.rulesIn(ig.attributes, dir, ".gitattributes", readGeneratedAttributes), dir, slash, false, generated)
+	}
+	return generated
+}
+
+// rulesIn returns the rules of a directory's file of the given name,
+// reading it the first time
+func (ig *Ignore) rulesIn(cache map[string][]ignoreRule, dir, name string, read func(string, string) []ignoreRule) []ignoreRule {
+	rules, ok := cache[dir]
+	if !ok {
+		rules = read(filepath.Join(ig.repoRoot, filepath.FromSlash(dir), name), dir)
+		cache[dir] = rules
+	}
+	return rules
+}
+
+// applyRules returns whether slashPath is matched by rules, the last
+// matching rule winning, or matched if none match
+func applyRules(rules []ignoreRule, base, slashPath string, isDir, matched bool) bool {
+	rel := slashPath
+	if base != "" {
+		rel = strings.TrimPrefix(slashPath, base+"/")
+	}
+	if isDir {
+		rel += "/" // So "build/" matches the directory itself
+	}
+	for _, rule := range rules {
+		if rule.re.MatchString(rel) {
+			matched = !rule.negate
+		}
+	}
+	return matched
+}
+
+// ancestors returns the directories whose rules apply to slashPath, from
+// the root ("") down to its parent
+func ancestors(slashPath string) []string {
+	dirs := []string{""}
+	for i, c := range slashPath {
+		if c == '/' {
+			dirs = append(dirs, slashPath[:i])
+		}
+	}
+	return dirs
+}
+
+// readIgnoreRules reads a .gitignore-style file; patterns are read the way
+// codeowners.CompilePattern reads them, and "!" negates
+func readIgnoreRules(file, base string) []ignoreRule {
+	var rules []ignoreRule
+	forEachLine(file, func(line string) {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			return
+		}
+		negate := strings.HasPrefix(line, "!")
+		line = strings.TrimPrefix(line, "!")
+		line = strings.TrimPrefix(line, `\`) // "\#" and "\!" escape a leading # or !
+		if re, err := codeowners.CompilePattern(line); err == nil {
+			rules = append(rules, ignoreRule{base: base, re: re, negate: negate})
+		}
+	})
+	return rules
+}
+
+// readGeneratedAttributes reads the linguist-generated and
+// linguist-vendored attributes of a .gitattributes file as ignore rules:
+// set ignores, unset ("-attr" or "attr=false") negates
+func readGeneratedAttributes(file, base string) []ignoreRule {
+	var rules []ignoreRule
+	forEachLine(file, func(line string) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			return
+		}
+		for _, attr := range fields[1:] {
+			name, value, _ := strings.Cut(attr, "=")
+			negate := strings.HasPrefix(name, "-") || value == "false"
+			if name = strings.TrimPrefix(name, "-"); name != "linguist-generated" && name != "linguist-vendored" {
+				continue
+			}
+			if re, err := codeowners.CompilePattern(fields[0]); err == nil {
+				rules = append(rules, ignoreRule{base: base, re: re, negate: negate})
+			}
+		}
+	})
+	return rules
+}
+
+// forEachLine calls fn with each line of file, if it exists
+func forEachLine(file string, fn func(string)) {
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+}
+
+// IsGenerated reports whether a file starts with a generated code header
+func IsGenerated(absPath string) bool {
+	f, err := os.Open(absPath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, generatedHeaderBytes)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return false
+	}
+	return generatedHeader.Match(head[:n])
+}
+
+// isIgnoreFile reports whether a changed path can change what's ignored
+func isIgnoreFile(relPath string) bool {
+	slash := filepath.ToSlash(relPath)
+	switch path.Base(slash) {
+	case ".gitignore", ".gitattributes":
+		return true
+	}
+	return slash == IndexDirName+"/"+IgnoreFileName
+}
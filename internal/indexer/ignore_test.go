@@ -0,0 +1,122 @@
+package indexer
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ignoreRepo = map[string]string{
+	".gitignore":         "# Build output\n/bin/\n*.log\n!keep.log\ntmp\n",
+	"web/.gitignore":     "fixtures/\n",
+	".gitattributes":     "api/*.pb.go linguist-generated\nsdk/** linguist-vendored=true\nsdk/core.go -linguist-vendored\n",
+	".ai-intern/ignore":  "testdata/golden/\n!debug.log\n",
+	"main.go":            "package main\n",
+	"bin/app":            "binary",
+	"server.log":         "log",
+	"keep.log":           "log",
+	"debug.log":          "log",
+	"internal/tmp/x.go":  "package tmp\n",
+	"web/app.ts":         "export const app = 1;\n",
+	"web/fixtures/a.ts":  "export const fixture = 1;\n",
+	"fixtures/b.ts":      "export const notIgnored = 1;\n",
+	"api/invoice.pb.go":  "package api\n",
+	"api/invoice.go":     "package api\n",
+	"sdk/client.go":      "package sdk\n",
+	"sdk/core.go":        "package sdk\n",
+	"testdata/golden/1":  "golden",
+	"mocks/mock_repo.go": "// Code generated by MockGen. DO NOT EDIT.\n// Source: repo.go\n\npackage mocks\n",
+	"gen/types.py":       "# Code generated by protoc-gen-py. DO NOT EDIT.\nclass Invoice: pass\n",
+	"docs/generated.md":  "The Code generated here is fine to edit.\n",
+}
+
+func TestIgnore_Matches(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoFiles(t, dir, ignoreRepo)
+	ig := LoadIgnore(dir)
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"main.go", false, false},
+		{"bin", true, true},
+		{"bin/app", false, true},
+		{"server.log", false, true},
+		{"keep.log", false, false},  // Negated in .gitignore
+		{"debug.log", false, false}, // Negated in .ai-intern/ignore
+		{"internal/tmp", true, true},
+		{"web/app.ts", false, false},
+		{"web/fixtures", true, true}, // Nested .gitignore
+		{"fixtures", true, false},    // ...only applies under web/
+		{"api/invoice.pb.go", false, true},
+		{"api/invoice.go", false, false},
+		{"sdk/client.go", false, true},
+		{"sdk/core.go", false, false}, // Attribute unset
+		{"testdata/golden", true, true},
+		{".", true, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.ignored, ig.Matches(tt.path, tt.isDir), tt.path)
+	}
+}
+
+func TestIsGenerated(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoFiles(t, dir, ignoreRepo)
+
+	assert.True(t, IsGenerated(filepath.Join(dir, "mocks/mock_repo.go")))
+	assert.True(t, IsGenerated(filepath.Join(dir, "gen/types.py")))
+	assert.False(t, IsGenerated(filepath.Join(dir, "docs/generated.md")))
+	assert.False(t, IsGenerated(filepath.Join(dir, "main.go")))
+	assert.False(t, IsGenerated(filepath.Join(dir, "missing.go")))
+}
+
+func TestBuildIndex_Ignore(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoFiles(t, dir, ignoreRepo)
+
+	index, err := New(dir).BuildIndex()
+	require.NoError(t, err)
+
+	for _, path := range []string{"main.go", "keep.log", "debug.log", "web/app.ts", "fixtures/b.ts", "api/invoice.go", "sdk/core.go", "docs/generated.md"} {
+		assert.Contains(t, index.Files, path)
+	}
+	for _, path := range []string{"bin/app", "server.log", "internal/tmp/x.go", "web/fixtures/a.ts", "api/invoice.pb.go", "sdk/client.go", "testdata/golden/1", "mocks/mock_repo.go", "gen/types.py"} {
+		assert.NotContains(t, index.Files, path)
+	}
+}
+
+func TestUpdateIndex_IgnoreChanges(t *testing.T) {
+	dir, git := initSymbolRepo(t)
+	idx := New(dir)
+	index, err := idx.BuildIndex()
+	require.NoError(t, err)
+	require.NoError(t, idx.SaveIndex(index))
+	require.Contains(t, index.Files, "internal/other/other.go")
+	require.Contains(t, index.Files, "internal/orchestrator/branch.go")
+
+	// A file that became generated is dropped
+	writeRepoFiles(t, dir, map[string]string{
+		"internal/orchestrator/branch.go": "// Code generated by hand. DO NOT EDIT.\n\npackage orchestrator\n",
+	})
+	git("add", ".")
+	git("commit", "-m", "Generate branch")
+	updated, err := idx.UpdateIndex()
+	require.NoError(t, err)
+	assert.NotContains(t, updated.Files, "internal/orchestrator/branch.go")
+	assert.Contains(t, updated.Files, "internal/other/other.go")
+	require.NoError(t, idx.SaveIndex(updated))
+
+	// A new ignore rule drops a file that didn't change
+	writeRepoFiles(t, dir, map[string]string{".gitignore": "internal/other/\n"})
+	git("add", ".")
+	git("commit", "-m", "Ignore other")
+	updated, err = idx.UpdateIndex()
+	require.NoError(t, err)
+	assert.NotContains(t, updated.Files, "internal/other/other.go")
+	assert.Contains(t, updated.Files, ".gitignore")
+}
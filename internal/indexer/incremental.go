@@ -2,10 +2,14 @@ package indexer
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -69,7 +73,112 @@ func (idx *Indexer) getChangedFiles(oldCommit, newCommit string) ([]string, erro
 	return changedFiles, nil
 }
 
-// UpdateIndex incrementally updates the index based on git changes
+// deletedFingerprint is the fingerprint of a working-tree change that
+// removed the file
+const deletedFingerprint = "deleted"
+
+// WorkingTreeChanges returns the files whose working tree differs from HEAD
+// (modified, added, untracked, deleted, and both paths of a rename), each
+// with a fingerprint of its content, so a file edited again without a
+// change in git status still reads as changed. Files in directories that
+// are never indexed (e.g. IndexDirName) are left out.
+func WorkingTreeChanges(repoRoot string) (map[string]string, error) {
+	cmd := exec.Command("git", "status", "--porcelain", "-z", "--untracked-files=all")
+	cmd.Dir = repoRoot
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git status failed: %w", err)
+	}
+
+	changes := make(map[string]string)
+	entries := strings.Split(stdout.String(), "\x00")
+	for i := 0; i < len(entries); i++ {
+		// Entries are "XY path"; renames and copies are followed by the
+		// original path as an entry of its own
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		status, paths := entry[:2], []string{entry[3:]}
+		if (status[0] == 'R' || status[0] == 'C') && i+1 < len(entries) {
+			i++
+			paths = append(paths, entries[i])
+		}
+
+		for _, relPath := range paths {
+			if inExcludedDir(relPath) {
+				continue
+			}
+			if fp, ok := fingerprint(filepath.Join(repoRoot, relPath)); ok {
+				changes[relPath] = fp
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+// WorkingTreeFingerprint summarises WorkingTreeChanges in one string that
+// changes whenever an uncommitted file does; "" for a clean tree or if git
+// status fails
+func WorkingTreeFingerprint(repoRoot string) string {
+	changes, err := WorkingTreeChanges(repoRoot)
+	if err != nil || len(changes) == 0 {
+		return ""
+	}
+
+	hash := sha256.New()
+	for _, relPath := range slices.Sorted(maps.Keys(changes)) {
+		fmt.Fprintf(hash, "%s\x00%s\n", relPath, changes[relPath])
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// fingerprint returns a short hash of a file's content, or
+// deletedFingerprint if it no longer exists; false for anything that isn't
+// a readable regular file (e.g. a submodule)
+func fingerprint(absPath string) (string, bool) {
+	info, err := os.Stat(absPath)
+	if os.IsNotExist(err) {
+		return deletedFingerprint, true
+	}
+	if err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16], true
+}
+
+// workingTreeDiff returns the files whose working-tree state differs
+// between two WorkingTreeChanges results: edited, created, deleted or
+// reverted in between
+func workingTreeDiff(before, after map[string]string) []string {
+	var changed []string
+	for relPath, fp := range after {
+		if before[relPath] != fp {
+			changed = append(changed, relPath)
+		}
+	}
+	for relPath := range before {
+		if _, ok := after[relPath]; !ok {
+			changed = append(changed, relPath)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+// UpdateIndex incrementally updates the index based on git changes: those
+// between the indexed commit and HEAD, and uncommitted changes to the
+// working tree since the index was written.
 // Returns the updated index or error
 func (idx *Indexer) UpdateIndex() (*FileIndex, error) {
 	// Load existing index
@@ -92,33 +201,67 @@ func (idx *Indexer) UpdateIndex() (*FileIndex, error) {
 		return idx.BuildIndex()
 	}
 
-	// If commits are the same, index is up to date
-	if existingIndex.GitCommitHash == currentCommit {
+	// Indexes written by an older version lack data (e.g. Symbols, the term
+	// index) that unchanged files would never get incrementally
+	if existingIndex.Version != IndexVersion || existingIndex.Terms == nil {
+		logger.Info("Index version changed, rebuilding", "old", existingIndex.Version, "new", IndexVersion)
+		return idx.BuildIndex()
+	}
+
+	// Uncommitted changes are indexed too, so context built mid-ticket sees
+	// files just created or edited; the index records them, so a later
+	// update (e.g. back on the base branch) re-reads them once they're
+	// committed, discarded or reverted
+	workingTree, err := WorkingTreeChanges(idx.repoRoot)
+	if err != nil {
+		logger.Warn("Failed to get working tree changes, will build from scratch", "error", err)
+		return idx.BuildIndex()
+	}
+	treeChanged := workingTreeDiff(existingIndex.WorkingTree, workingTree)
+
+	// If commits and the working tree are the same, index is up to date
+	if existingIndex.GitCommitHash == currentCommit && len(treeChanged) == 0 {
 		logger.Info("Index is up to date", "commit", currentCommit[:8])
 		return existingIndex, nil
 	}
 
 	logger.Info("Updating index incrementally",
 		"old_commit", existingIndex.GitCommitHash[:8],
-		"new_commit", currentCommit[:8])
+		"new_commit", currentCommit[:8],
+		"working_tree_changes", len(treeChanged))
 
 	// Get changed files
-	changedFiles, err := idx.getChangedFiles(existingIndex.GitCommitHash, currentCommit)
-	if err != nil {
-		logger.Warn("Failed to get changed files, will build from scratch", "error", err)
-		return idx.BuildIndex()
+	var changedFiles []string
+	if existingIndex.GitCommitHash != currentCommit {
+		changedFiles, err = idx.getChangedFiles(existingIndex.GitCommitHash, currentCommit)
+		if err != nil {
+			logger.Warn("Failed to get changed files, will build from scratch", "error", err)
+			return idx.BuildIndex()
+		}
 	}
+	changedFiles = dedupe(append(changedFiles, treeChanged...))
 
 	logger.Info("Processing changed files", "count", len(changedFiles))
 
+	// Changed ignore rules can exclude or include files that didn't change
+	idx.ignore = nil
+	for _, relPath := range changedFiles {
+		if isIgnoreFile(relPath) {
+			logger.Info("Ignore rules changed, building from scratch", "file", relPath)
+			return idx.BuildIndex()
+		}
+	}
+
 	// Create updated index based on existing index
 	updatedIndex := &FileIndex{
 		Version:       IndexVersion,
 		IndexedAt:     time.Now(),
 		RepoRoot:      idx.repoRoot,
 		GitCommitHash: currentCommit,
+		WorkingTree:   workingTree,
 		Files:         make(map[string]FileMetadata),
 		Modules:       make(map[string][]string),
+		Terms:         existingIndex.Terms,
 	}
 
 	// Copy existing files
@@ -128,8 +271,13 @@ func (idx *Indexer) UpdateIndex() (*FileIndex, error) {
 
 	// Update changed files
 	for _, relPath := range changedFiles {
-		// Skip if should be excluded
+		// Skip if should be excluded, dropping it if it was indexed before
+		// (e.g. it's now generated)
 		if idx.shouldSkipFile(relPath) {
+			if _, ok := updatedIndex.Files[relPath]; ok {
+				delete(updatedIndex.Files, relPath)
+				updatedIndex.Terms.Remove(relPath)
+			}
 			continue
 		}
 
@@ -139,6 +287,7 @@ func (idx *Indexer) UpdateIndex() (*FileIndex, error) {
 		if _, err := os.Stat(absPath); os.IsNotExist(err) {
 			// File was deleted, remove from index
 			delete(updatedIndex.Files, relPath)
+			updatedIndex.Terms.Remove(relPath)
 			logger.Debug("Removed deleted file from index", "path", relPath)
 			continue
 		}
@@ -147,10 +296,15 @@ func (idx *Indexer) UpdateIndex() (*FileIndex, error) {
 		metadata := idx.analyzeFile(absPath, relPath)
 		if metadata != nil {
 			updatedIndex.Files[relPath] = *metadata
+			updatedIndex.Terms.Add(relPath, tokenizeFile(absPath, relPath))
 			logger.Debug("Updated file in index", "path", relPath)
 		}
 	}
 
+	// Re-resolve symbols in every package a changed file belongs to, since
+	// its siblings' references may point at renamed or removed symbols
+	idx.indexSymbols(updatedIndex, goDirs(changedFiles))
+
 	// Rebuild module mapping
 	updatedIndex.Modules = make(map[string][]string)
 	for relPath := range updatedIndex.Files {
@@ -187,9 +341,13 @@ func (idx *Indexer) RebuildIfStale() (*FileIndex, bool, error) {
 		return nil, false, err
 	}
 
-	// Check if index was already up to date
+	// Check if index was already up to date (an outdated version or missing
+	// term index is rebuilt even at the same commit, and working-tree
+	// changes are indexed without a new commit)
 	existingIndex, loadErr := idx.LoadIndex()
-	if loadErr == nil && existingIndex.GitCommitHash == index.GitCommitHash {
+	if loadErr == nil && existingIndex.GitCommitHash == index.GitCommitHash &&
+		existingIndex.Version == index.Version && existingIndex.Terms != nil &&
+		maps.Equal(existingIndex.WorkingTree, index.WorkingTree) {
 		// Index was already up to date
 		return index, false, nil
 	}
@@ -347,3 +347,114 @@ func TestRebuildIfStale_NoIndex(t *testing.T) {
 		t.Errorf("Expected index to be returned")
 	}
 }
+
+// symbolNames returns the names of a file's indexed symbols
+func symbolNames(index *FileIndex, relPath string) []string {
+	var names []string
+	for _, sym := range index.Files[relPath].Symbols {
+		names = append(names, sym.Name)
+	}
+	return names
+}
+
+func TestUpdateIndex_WorkingTreeChanges(t *testing.T) {
+	dir, git := initSymbolRepo(t)
+	idx := New(dir)
+	initialIndex, err := idx.BuildIndex()
+	if err != nil {
+		t.Fatalf("Failed to build initial index: %v", err)
+	}
+	if len(initialIndex.WorkingTree) != 0 {
+		t.Errorf("Expected a clean working tree, got %v", initialIndex.WorkingTree)
+	}
+	if err := idx.SaveIndex(initialIndex); err != nil {
+		t.Fatalf("Failed to save initial index: %v", err)
+	}
+
+	// A new untracked file and an uncommitted edit, at the same HEAD
+	writeRepoFiles(t, dir, map[string]string{
+		"internal/other/extra.go": "package other\n\nfunc ExtraHelper() {}\n",
+		"internal/other/other.go": "package other\n\nfunc ProcessTicketAgain() {}\n",
+	})
+
+	updatedIndex, err := idx.UpdateIndex()
+	if err != nil {
+		t.Fatalf("Failed to update index: %v", err)
+	}
+	if updatedIndex.GitCommitHash != initialIndex.GitCommitHash {
+		t.Errorf("Expected same commit hash, got %s vs %s", updatedIndex.GitCommitHash, initialIndex.GitCommitHash)
+	}
+	if _, ok := updatedIndex.Files["internal/other/extra.go"]; !ok {
+		t.Errorf("Expected untracked file to be indexed")
+	}
+	if names := symbolNames(updatedIndex, "internal/other/other.go"); len(names) != 1 || names[0] != "ProcessTicketAgain" {
+		t.Errorf("Expected edited file to be re-read, got symbols %v", names)
+	}
+	if len(updatedIndex.WorkingTree) != 2 {
+		t.Errorf("Expected 2 working tree changes, got %v", updatedIndex.WorkingTree)
+	}
+	if err := idx.SaveIndex(updatedIndex); err != nil {
+		t.Fatalf("Failed to save updated index: %v", err)
+	}
+
+	// Discarding the changes (e.g. back on the base branch) restores the
+	// committed tree's index
+	if err := os.Remove(filepath.Join(dir, "internal/other/extra.go")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	git("checkout", "--", "internal/other/other.go")
+
+	revertedIndex, err := idx.UpdateIndex()
+	if err != nil {
+		t.Fatalf("Failed to update index: %v", err)
+	}
+	if _, ok := revertedIndex.Files["internal/other/extra.go"]; ok {
+		t.Errorf("Expected discarded file to be removed from index")
+	}
+	if _, ok := revertedIndex.Terms.DocLengths["internal/other/extra.go"]; ok {
+		t.Errorf("Expected discarded file to be removed from term index")
+	}
+	if names := symbolNames(revertedIndex, "internal/other/other.go"); len(names) != 1 || names[0] != "ProcessTicket" {
+		t.Errorf("Expected reverted file to be re-read, got symbols %v", names)
+	}
+	if len(revertedIndex.WorkingTree) != 0 {
+		t.Errorf("Expected a clean working tree, got %v", revertedIndex.WorkingTree)
+	}
+}
+
+func TestRebuildIfStale_WorkingTreeChanges(t *testing.T) {
+	dir, _ := initSymbolRepo(t)
+	idx := New(dir)
+
+	rebuild := func() bool {
+		t.Helper()
+		index, wasUpdated, err := idx.RebuildIfStale()
+		if err != nil {
+			t.Fatalf("Failed to rebuild: %v", err)
+		}
+		if err := idx.SaveIndex(index); err != nil {
+			t.Fatalf("Failed to save index: %v", err)
+		}
+		return wasUpdated
+	}
+
+	rebuild()
+	if rebuild() {
+		t.Errorf("Expected clean index to be up to date")
+	}
+
+	writeRepoFiles(t, dir, map[string]string{"internal/other/extra.go": "package other\n"})
+	if !rebuild() {
+		t.Errorf("Expected new untracked file to update the index")
+	}
+	if rebuild() {
+		t.Errorf("Expected index to be up to date with the working tree")
+	}
+
+	// Editing an already-modified file again changes its fingerprint, though
+	// not its git status
+	writeRepoFiles(t, dir, map[string]string{"internal/other/extra.go": "package other\n\nfunc More() {}\n"})
+	if !rebuild() {
+		t.Errorf("Expected re-edited file to update the index")
+	}
+}
@@ -3,16 +3,19 @@ package indexer
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
 	"io/fs"
 	"os"
 	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
 const (
-	IndexVersion     = "1.0"
+	IndexVersion     = "1.1"
 	IndexFileName    = "file_index.json"
 	IndexDirName     = ".ai-intern"
 	ProjectIndexName = "PROJECT_INDEX.md"
@@ -34,6 +37,7 @@ var binaryExts = []string{
 // Indexer generates and manages repository file indexes
 type Indexer struct {
 	repoRoot string
+	ignore   *Ignore // Loaded on first use; reset by each build or update
 }
 
 // New creates a new repository indexer
@@ -43,16 +47,21 @@ func New(repoRoot string) *Indexer {
 
 // BuildIndex scans the repository and creates a complete file index
 func (idx *Indexer) BuildIndex() (*FileIndex, error) {
-	// Get current git commit hash
+	idx.ignore = nil // Pick up changed ignore rules
+
+	// Get current git commit hash, and what's uncommitted on top of it
 	gitHash, _ := idx.getGitCommitHash()
+	workingTree, _ := WorkingTreeChanges(idx.repoRoot)
 
 	index := &FileIndex{
 		Version:       IndexVersion,
 		IndexedAt:     time.Now(),
 		RepoRoot:      idx.repoRoot,
 		GitCommitHash: gitHash,
+		WorkingTree:   workingTree,
 		Files:         make(map[string]FileMetadata),
 		Modules:       make(map[string][]string),
+		Terms:         NewTermIndex(),
 	}
 
 	err := filepath.WalkDir(idx.repoRoot, func(path string, d fs.DirEntry, err error) error {
@@ -82,6 +91,7 @@ func (idx *Indexer) BuildIndex() (*FileIndex, error) {
 		metadata := idx.analyzeFile(path, relPath)
 		if metadata != nil {
 			index.Files[relPath] = *metadata
+			index.Terms.Add(relPath, tokenizeFile(path, relPath))
 
 			// Group by module
 			module := idx.extractModule(relPath)
@@ -97,20 +107,43 @@ func (idx *Indexer) BuildIndex() (*FileIndex, error) {
 		return nil, err
 	}
 
+	// Symbols and references are resolved per package, after the walk
+	paths := make([]string, 0, len(index.Files))
+	for relPath := range index.Files {
+		paths = append(paths, relPath)
+	}
+	idx.indexSymbols(index, goDirs(paths))
+
 	return index, nil
 }
 
+// Excluded reports whether a path, a directory if isDir, is kept out of the
+// index and the context: built-in noise directories, binaries, files over
+// 1MB, and what the repository ignores (see Ignore). The context builder
+// and cache use it so they agree with the index.
+func (idx *Indexer) Excluded(relPath string, isDir bool) bool {
+	if isDir {
+		return idx.shouldSkipDir(relPath)
+	}
+	return idx.shouldSkipFile(relPath)
+}
+
+// rules returns the repository's ignore rules, loading them on first use
+func (idx *Indexer) rules() *Ignore {
+	if idx.ignore == nil {
+		idx.ignore = LoadIgnore(idx.repoRoot)
+	}
+	return idx.ignore
+}
+
 // shouldSkipDir determines if a directory should be excluded from indexing
 func (idx *Indexer) shouldSkipDir(relPath string) bool {
-	lower := strings.ToLower(relPath)
-
-	for _, excluded := range excludedDirs {
-		if lower == excluded || strings.HasPrefix(lower, excluded+"/") {
-			return true
-		}
+	if inExcludedDir(relPath) {
+		return true
 	}
 
-	return false
+	// Skip what the repository ignores
+	return idx.rules().Matches(relPath, true)
 }
 
 // shouldSkipFile determines if a file should be excluded from indexing
@@ -121,10 +154,8 @@ func (idx *Indexer) shouldSkipFile(relPath string) bool {
 	// updater (UpdateIndex) checks files individually via git diff rather than
 	// walking the tree with shouldSkipDir, so e.g. .ai-intern/file_index.json
 	// would otherwise get indexed as a regular changed file.
-	for _, excluded := range excludedDirs {
-		if lower == excluded || strings.HasPrefix(lower, excluded+"/") {
-			return true
-		}
+	if inExcludedDir(relPath) {
+		return true
 	}
 
 	// Skip binary and media files
@@ -135,11 +166,25 @@ func (idx *Indexer) shouldSkipFile(relPath string) bool {
 	}
 
 	// Skip very large files (>1MB)
-	info, err := os.Stat(filepath.Join(idx.repoRoot, relPath))
+	absPath := filepath.Join(idx.repoRoot, relPath)
+	info, err := os.Stat(absPath)
 	if err == nil && info.Size() > 1*1024*1024 {
 		return true
 	}
 
+	// Skip what the repository ignores, and generated code
+	return idx.rules().Matches(relPath, false) || IsGenerated(absPath)
+}
+
+// inExcludedDir reports whether relPath is, or is inside, one of the
+// directories never indexed
+func inExcludedDir(relPath string) bool {
+	lower := strings.ToLower(filepath.ToSlash(relPath))
+	for _, excluded := range excludedDirs {
+		if lower == excluded || strings.HasPrefix(lower, excluded+"/") {
+			return true
+		}
+	}
 	return false
 }
 
@@ -234,48 +279,36 @@ func (idx *Indexer) calculateImportance(relPath string) float64 {
 	return score
 }
 
-// extractDependencies finds Go imports or module dependencies
+// extractDependencies finds Go imports, aliased or not, and the imports of
+// the other languages with signatures (see languages.go)
 func (idx *Indexer) extractDependencies(absPath, relPath string) []string {
+	if lang := languageFor(relPath); lang != nil {
+		if lang.imports == nil {
+			return nil
+		}
+		data, err := os.ReadFile(absPath)
+		if err != nil {
+			return nil
+		}
+		return lang.imports(string(data))
+	}
 	if !strings.HasSuffix(relPath, ".go") {
 		return nil
 	}
 
-	content, err := os.ReadFile(absPath)
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, absPath, nil, parser.ImportsOnly)
 	if err != nil {
 		return nil
 	}
 
-	// Extract import statements using regex
-	importRegex := regexp.MustCompile(`import\s+(?:\(([^)]+)\)|"([^"]+)")`)
-	matches := importRegex.FindAllStringSubmatch(string(content), -1)
-
-	deps := make(map[string]bool)
-	for _, match := range matches {
-		// Handle both single import and import blocks
-		if match[1] != "" {
-			// Import block
-			lines := strings.Split(match[1], "\n")
-			for _, line := range lines {
-				line = strings.TrimSpace(line)
-				if strings.HasPrefix(line, "\"") && strings.HasSuffix(line, "\"") {
-					dep := strings.Trim(line, "\"")
-					if dep != "" {
-						deps[dep] = true
-					}
-				}
-			}
-		} else if match[2] != "" {
-			// Single import
-			deps[match[2]] = true
+	result := make([]string, 0, len(file.Imports))
+	for _, imp := range file.Imports {
+		if dep, err := strconv.Unquote(imp.Path.Value); err == nil && dep != "" {
+			result = append(result, dep)
 		}
 	}
 
-	// Convert to sorted slice
-	result := make([]string, 0, len(deps))
-	for dep := range deps {
-		result = append(result, dep)
-	}
-
 	return result
 }
 
@@ -332,6 +365,9 @@ func (idx *Indexer) SaveIndex(index *FileIndex) error {
 		return err
 	}
 
+	if index.Terms != nil {
+		return idx.saveTermIndex(index.Terms)
+	}
 	return nil
 }
 
@@ -352,6 +388,11 @@ func (idx *Indexer) LoadIndex() (*FileIndex, error) {
 		return nil, err
 	}
 
+	// Without a term index, scoring falls back to paths and symbols
+	if index.Terms, err = idx.loadTermIndex(); err != nil {
+		return nil, fmt.Errorf("failed to load term index: %w", err)
+	}
+
 	return &index, nil
 }
 
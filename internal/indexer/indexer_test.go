@@ -24,7 +24,7 @@ func TestIndexer_BuildIndex(t *testing.T) {
 		"internal/service/svc_test.go": "package service",
 		"cmd/app/main.go":              "package main",
 	}
-	const IndexVersion = "1.0"
+	const IndexVersion = "1.1"
 
 	for path, content := range files {
 		fullPath := filepath.Join(tmpDir, path)
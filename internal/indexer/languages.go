@@ -0,0 +1,388 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// language extracts signatures and imports from source files other than Go,
+// line by line. It's an approximation of a parser: good enough to tell the
+// model what a file declares and to link it to what it imports, not to
+// rewrite it.
+type language struct {
+	signatures func(lines []string) []string
+	imports    func(src string) []string
+}
+
+// languages by file extension
+var languages = map[string]*language{
+	".ts":    {signatures: braceSignatures(tsSyntax), imports: tsImports},
+	".tsx":   {signatures: braceSignatures(tsSyntax), imports: tsImports},
+	".mts":   {signatures: braceSignatures(tsSyntax), imports: tsImports},
+	".js":    {signatures: braceSignatures(tsSyntax), imports: tsImports},
+	".jsx":   {signatures: braceSignatures(tsSyntax), imports: tsImports},
+	".mjs":   {signatures: braceSignatures(tsSyntax), imports: tsImports},
+	".cjs":   {signatures: braceSignatures(tsSyntax), imports: tsImports},
+	".py":    {signatures: pythonSignatures, imports: pythonImports},
+	".java":  {signatures: braceSignatures(javaSyntax), imports: javaImports},
+	".tf":    {signatures: braceSignatures(hclSyntax), imports: hclImports},
+	".hcl":   {signatures: braceSignatures(hclSyntax), imports: hclImports},
+	".proto": {signatures: braceSignatures(protoSyntax), imports: protoImports},
+	".yaml":  {signatures: yamlSignatures},
+	".yml":   {signatures: yamlSignatures},
+}
+
+// languageFor returns the language of a file, or nil if it has none
+func languageFor(path string) *language {
+	return languages[strings.ToLower(filepath.Ext(path))]
+}
+
+// extractLanguageContext returns the signatures of a non-Go source file,
+// falling back to extractNonGoContext if none are found
+func extractLanguageContext(filePath string, lang *language) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	signatures := lang.signatures(strings.Split(string(data), "\n"))
+	if len(signatures) == 0 {
+		return extractNonGoContext(filePath)
+	}
+	return strings.Join(signatures, "\n") + "\n", nil
+}
+
+// braceSyntax describes a language whose blocks are delimited by braces
+type braceSyntax struct {
+	header     *regexp.Regexp // Top-level lines kept verbatim: package, imports
+	container  *regexp.Regexp // Declarations whose members are listed too: classes, interfaces, messages
+	allMembers *regexp.Regexp // Containers every member line of which is kept: interfaces, enums
+	member     *regexp.Regexp // Declarations shown without their bodies: functions, fields
+	method     *regexp.Regexp // Members of containers shown without their bodies, if unlike member
+	comment    *regexp.Regexp // Comment or annotation lines kept when followed by a declaration
+	hashes     bool           // Whether # starts a comment
+}
+
+var (
+	tsSyntax = braceSyntax{
+		header:     regexp.MustCompile(`^(import\s|export\s.*\sfrom\s|export\s*\*|['"]use )`),
+		container:  regexp.MustCompile(`^(export\s+)?(default\s+)?(declare\s+)?(abstract\s+)?(class|interface|enum|namespace|module)\s+[\w$.]+`),
+		allMembers: regexp.MustCompile(`^(export\s+)?(default\s+)?(declare\s+)?(interface|enum)\s`),
+		member: regexp.MustCompile(`^((export\s+)?(default\s+)?(declare\s+)?(async\s+)?function\b|(export\s+)?(declare\s+)?type\s+[\w$]+|` +
+			`(export\s+)?(declare\s+)?(const|let|var)\s+[\w${}\[\], ]+|export\s+default\s|module\.exports\b|exports\.[\w$]+)`),
+		method:  regexp.MustCompile(`^((public|private|protected|static|readonly|async|abstract|override|declare|get|set)\s+)*[#\w$]+[?!]?\s*(<[^>]*>)?\s*[(:=]`),
+		comment: regexp.MustCompile(`^(//|/\*|\*|@\w)`),
+	}
+	javaSyntax = braceSyntax{
+		header:     regexp.MustCompile(`^(package|import)\s`),
+		container:  regexp.MustCompile(`^(@\w+\s+)*((public|protected|private|abstract|static|final|sealed|non-sealed|strictfp)\s+)*(class|interface|enum|record|@interface)\s+\w+`),
+		allMembers: regexp.MustCompile(`^(@\w+\s+)*((public|protected|private|abstract|static|sealed)\s+)*(interface|@interface)\s`),
+		member: regexp.MustCompile(`^(@\w+(\([^)]*\))?\s+)*((public|protected|private|abstract|static|final|synchronized|native|default|transient|volatile)\s+)*` +
+			`(<[^>]+>\s+)?[\w.<>\[\], ?]+\s+\w+\s*(\(|=|;)`),
+		comment: regexp.MustCompile(`^(//|/\*|\*|@\w)`),
+	}
+	hclSyntax = braceSyntax{
+		header:    regexp.MustCompile(`^\w+\s*=`),
+		container: regexp.MustCompile(`^([\w-]+(\s+"[^"]*")+|locals|terraform|moved|import)\s*\{`),
+		member:    regexp.MustCompile(`^(description|type|default|source|version|value|count|for_each|sensitive)\s*=`),
+		comment:   regexp.MustCompile(`^(#|//|/\*|\*)`),
+		hashes:    true,
+	}
+	protoSyntax = braceSyntax{
+		header:     regexp.MustCompile(`^(syntax|edition|package|import|option)\b`),
+		container:  regexp.MustCompile(`^(message|enum|service|oneof|extend)\s+[\w.]+`),
+		allMembers: regexp.MustCompile(`^(message|enum|oneof|extend)\s`),
+		member:     regexp.MustCompile(`^rpc\s+\w+`),
+		comment:    regexp.MustCompile(`^(//|/\*|\*)`),
+	}
+)
+
+// controlKeywords look like method calls to braceSyntax.member but aren't
+// declarations
+var controlKeywords = regexp.MustCompile(`^(if|for|while|switch|catch|return|throw|new|else|do|try|case|await|yield|super|this)\b`)
+
+// stringOrComment and hashComment match what braces are not counted in
+var (
+	stringOrComment = regexp.MustCompile("\"(\\\\.|[^\"\\\\])*\"|'(\\\\.|[^'\\\\])*'|`[^`]*`|//.*$|/\\*.*?\\*/")
+	hashComment     = regexp.MustCompile(`#.*$`)
+)
+
+// braceSignatures lists the top-level declarations of a brace-delimited
+// language, with the members of its classes, interfaces and messages, each
+// shown up to its opening brace and preceded by its comments
+func braceSignatures(syntax braceSyntax) func(lines []string) []string {
+	return func(lines []string) []string {
+		var out, pending []string
+		type container struct {
+			depth int  // Brace depth of its members
+			all   bool // Keep every member line
+		}
+		var open []container
+		depth := 0
+		for _, line := range lines {
+			trimmed := strings.TrimSpace(line)
+			code := stringOrComment.ReplaceAllString(trimmed, "")
+			if syntax.hashes {
+				code = hashComment.ReplaceAllString(code, "")
+			}
+			delta := strings.Count(code, "{") - strings.Count(code, "}")
+
+			inContainer := len(open) > 0 && depth == open[len(open)-1].depth
+			member := syntax.member
+			if inContainer && syntax.method != nil {
+				member = syntax.method
+			}
+			if trimmed != "" && (depth == 0 || inContainer) {
+				switch {
+				case syntax.container.MatchString(trimmed) && delta > 0:
+					out = append(append(out, pending...), strings.TrimRight(line, " \t"))
+					pending = nil
+					open = append(open, container{depth: depth + 1, all: syntax.allMembers != nil && syntax.allMembers.MatchString(trimmed)})
+				case depth == 0 && syntax.header.MatchString(trimmed),
+					inContainer && open[len(open)-1].all && !strings.HasPrefix(trimmed, "}"),
+					member.MatchString(trimmed) && !controlKeywords.MatchString(trimmed):
+					out = append(append(out, pending...), signatureLine(line))
+					pending = nil
+				case syntax.comment.MatchString(trimmed):
+					pending = append(pending, line)
+				default:
+					pending = nil
+				}
+			}
+
+			depth = max(depth+delta, 0)
+			for len(open) > 0 && depth < open[len(open)-1].depth {
+				indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+				if !strings.HasPrefix(trimmed, "}") {
+					indent = ""
+				}
+				out = append(out, indent+"}")
+				open = open[:len(open)-1]
+			}
+		}
+		return out
+	}
+}
+
+// signatureLine cuts a declaration line at the body it opens
+func signatureLine(line string) string {
+	line = strings.TrimRight(line, " \t")
+	if i := strings.LastIndex(line, "{"); i > 0 && strings.Count(line[i:], "}") == 0 {
+		line = strings.TrimRight(line[:i], " \t")
+	}
+	if strings.HasSuffix(line, "[") || strings.HasSuffix(line, "(") || strings.HasSuffix(line, "=") {
+		line += " ..."
+	}
+	return line
+}
+
+var (
+	pythonDecl      = regexp.MustCompile(`^\s*((async\s+)?def|class)\s+\w+`)
+	pythonHeader    = regexp.MustCompile(`^(import|from)\s`)
+	pythonConstant  = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*\s*(:[^=]+)?=`)
+	pythonDecorator = regexp.MustCompile(`^\s*@`)
+	pythonComment   = regexp.MustCompile(`^\s*#`)
+)
+
+// maxPythonSignatureLines bounds a statement split over lines
+const maxPythonSignatureLines = 10
+
+// pythonSignatures lists imports, module constants, classes and functions
+// with their decorators, comments and the first line of their docstrings
+func pythonSignatures(lines []string) []string {
+	var out, pending []string
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t\r")
+		switch {
+		case pythonComment.MatchString(line), pythonDecorator.MatchString(line):
+			pending = append(pending, line)
+		case pythonHeader.MatchString(line):
+			var statement string
+			statement, i = pythonStatement(lines, i)
+			out = append(append(out, pending...), statement)
+			pending = nil
+		case pythonConstant.MatchString(line):
+			out = append(append(out, pending...), signatureLine(line))
+			pending = nil
+		case pythonDecl.MatchString(line):
+			var statement string
+			statement, i = pythonStatement(lines, i)
+			out = append(append(out, pending...), statement)
+			pending = nil
+			if doc := docstringAfter(lines, i); doc != "" {
+				out = append(out, doc)
+			}
+		case strings.TrimSpace(line) != "":
+			pending = nil
+		}
+	}
+	return out
+}
+
+// pythonStatement returns the statement starting at line i, which runs
+// until its parentheses close, and the index of its last line
+func pythonStatement(lines []string, i int) (string, int) {
+	statement := strings.TrimRight(lines[i], " \t\r")
+	parens := strings.Count(statement, "(") - strings.Count(statement, ")")
+	for n := 1; parens > 0 && i+1 < len(lines) && n < maxPythonSignatureLines; n++ {
+		i++
+		next := strings.TrimRight(lines[i], " \t\r")
+		statement += "\n" + next
+		parens += strings.Count(next, "(") - strings.Count(next, ")")
+	}
+	return statement, i
+}
+
+// docstringAfter returns the first line of the docstring following line i,
+// closed if it continues
+func docstringAfter(lines []string, i int) string {
+	for j := i + 1; j < len(lines); j++ {
+		trimmed := strings.TrimSpace(lines[j])
+		if trimmed == "" {
+			continue
+		}
+		for _, quote := range []string{`"""`, `'''`} {
+			if strings.HasPrefix(trimmed, quote) {
+				line := strings.TrimRight(lines[j], " \t\r")
+				if strings.Count(trimmed, quote) < 2 {
+					line += " ..." + quote
+				}
+				return line
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+var (
+	yamlKey       = regexp.MustCompile(`^(\s*)(- )?([\w.\-/"']+):(\s+(.*))?$`)
+	yamlNamedKeys = map[string]bool{"name": true, "kind": true, "image": true, "namespace": true}
+)
+
+// yamlMaxIndent is the deepest indentation at which every key is kept
+const yamlMaxIndent = 4
+
+// maxYAMLValueLength is the longest value shown; longer ones are elided
+const maxYAMLValueLength = 60
+
+// yamlSignatures outlines YAML documents such as Kubernetes manifests: the
+// keys of the first three levels, plus names, kinds and images at any depth
+// under the keys leading to them. Block scalars are elided.
+func yamlSignatures(lines []string) []string {
+	type key struct {
+		indent int
+		line   string
+		shown  bool
+	}
+	var out []string
+	var parents []key
+	blockIndent := -1 // Indentation of the key whose block scalar is being skipped
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if blockIndent >= 0 && (strings.TrimSpace(line) == "" || indent > blockIndent) {
+			continue
+		}
+		blockIndent = -1
+		if line == "---" {
+			out = append(out, line)
+			parents = nil
+			continue
+		}
+		m := yamlKey.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		name, value := strings.Trim(m[3], `"'`), m[5]
+		if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+			blockIndent = indent
+		}
+		if len(value) > maxYAMLValueLength || blockIndent >= 0 {
+			line = strings.TrimSuffix(line, value) + "..."
+		}
+
+		for len(parents) > 0 && parents[len(parents)-1].indent >= indent {
+			parents = parents[:len(parents)-1]
+		}
+		current := key{indent: indent, line: line}
+		if indent <= yamlMaxIndent || yamlNamedKeys[name] {
+			for i := range parents {
+				if !parents[i].shown {
+					out = append(out, parents[i].line)
+					parents[i].shown = true
+				}
+			}
+			out = append(out, line)
+			current.shown = true
+		}
+		parents = append(parents, current)
+	}
+	return out
+}
+
+var (
+	tsImportPattern = regexp.MustCompile(`(?m)(?:^\s*import\s[^;'"]*?\bfrom\s*|^\s*export\s[^;'"]*?\bfrom\s*|^\s*import\s*|\brequire\(\s*|\bimport\(\s*)['"]([^'"]+)['"]`)
+	pythonImport    = regexp.MustCompile(`(?m)^\s*import\s+([\w., ]+)`)
+	pythonFrom      = regexp.MustCompile(`(?m)^\s*from\s+([\w.]+)\s+import\b`)
+	javaImport      = regexp.MustCompile(`(?m)^\s*import\s+(?:static\s+)?([\w.]+(?:\.\*)?)\s*;`)
+	hclSource       = regexp.MustCompile(`(?m)^\s*source\s*=\s*"([^"]+)"`)
+	protoImport     = regexp.MustCompile(`(?m)^\s*import\s+(?:public\s+|weak\s+)?"([^"]+)"\s*;`)
+)
+
+// tsImports finds ES module imports and re-exports, require calls and
+// dynamic imports
+func tsImports(src string) []string {
+	return submatches(tsImportPattern, src)
+}
+
+// pythonImports finds imported modules, e.g. "os.path" or ".models"
+func pythonImports(src string) []string {
+	var result []string
+	for _, m := range pythonImport.FindAllStringSubmatch(src, -1) {
+		for _, name := range strings.Split(m[1], ",") {
+			if fields := strings.Fields(name); len(fields) > 0 {
+				result = append(result, fields[0]) // Drop "as alias"
+			}
+		}
+	}
+	return dedupe(append(result, submatches(pythonFrom, src)...))
+}
+
+// javaImports finds imported classes and packages
+func javaImports(src string) []string {
+	return submatches(javaImport, src)
+}
+
+// hclImports finds module sources
+func hclImports(src string) []string {
+	return submatches(hclSource, src)
+}
+
+// protoImports finds imported .proto files
+func protoImports(src string) []string {
+	return submatches(protoImport, src)
+}
+
+// submatches returns the distinct first submatches of pattern in src, in order
+func submatches(pattern *regexp.Regexp, src string) []string {
+	var result []string
+	for _, m := range pattern.FindAllStringSubmatch(src, -1) {
+		result = append(result, m[1])
+	}
+	return dedupe(result)
+}
+
+// dedupe drops repeated strings, keeping the first of each
+func dedupe(list []string) []string {
+	seen := make(map[string]bool, len(list))
+	result := make([]string, 0, len(list))
+	for _, s := range list {
+		if s != "" && !seen[s] {
+			seen[s] = true
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,211 @@
+package indexer
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var languageFiles = map[string]string{
+	"web/invoice.ts": `import { Injectable } from '@angular/core';
+export { helper } from './helper';
+
+/** Options for the invoice service */
+export interface InvoiceOptions {
+  currency: string;
+}
+
+@Injectable()
+export class InvoiceService {
+  private readonly cache = new Map<string, number>();
+
+  /** Totals an invoice */
+  async total(id: string): Promise<number> {
+    if (this.cache.has(id)) {
+      return this.cache.get(id)!;
+    }
+    return 42;
+  }
+}
+
+export const round = (n: number): number => {
+  return Math.round(n);
+};
+const legacy = require("legacy-lib");
+`,
+	"app/invoice.py": `import os, sys as system
+from .models import Invoice
+
+TAX_RATE = 0.2
+
+# Totals invoices
+class InvoiceService(Base):
+    """Totals invoices."""
+
+    def total(
+        self,
+        invoice_id: str,
+    ) -> int:
+        return compute_secret(invoice_id)
+`,
+	"src/InvoiceService.java": `package com.example.billing;
+
+import java.util.List;
+import static java.lang.Math.round;
+
+/** Totals invoices */
+public class InvoiceService {
+    private final Repo repo;
+
+    @Override
+    public int total(String id) throws IOException {
+        return repo.computeSecret(id);
+    }
+}
+`,
+	"infra/main.tf": `# Bucket for logs
+variable "bucket_name" {
+  description = "Name of the bucket"
+  type        = string
+}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = var.secret_bucket_name
+}
+
+module "vpc" {
+  source = "terraform-aws-modules/vpc/aws"
+}
+`,
+	"proto/billing.proto": `syntax = "proto3";
+import "google/protobuf/timestamp.proto";
+
+// An invoice
+message Invoice {
+  string id = 1;
+}
+
+service Billing {
+  rpc Total(TotalRequest) returns (TotalResponse) {
+    option (google.api.http) = { get: "/v1/secret" };
+  }
+}
+`,
+	"deploy/billing.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: billing
+spec:
+  template:
+    spec:
+      containers:
+        - name: billing
+          image: example/billing:1.2
+          args:
+            - --secret
+data:
+  config.yaml: |
+    secret: 1
+`,
+}
+
+func TestExtractMinimalContext_Languages(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoFiles(t, dir, languageFiles)
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"web/invoice.ts", []string{
+			"import { Injectable } from '@angular/core';",
+			"/** Options for the invoice service */\nexport interface InvoiceOptions {\n  currency: string;\n}",
+			"@Injectable()\nexport class InvoiceService {",
+			"  private readonly cache = new Map<string, number>();",
+			"  /** Totals an invoice */\n  async total(id: string): Promise<number>\n}",
+			"export const round = (n: number): number =>\n",
+		}},
+		{"app/invoice.py", []string{
+			"import os, sys as system\nfrom .models import Invoice\n",
+			"TAX_RATE = 0.2",
+			"# Totals invoices\nclass InvoiceService(Base):\n    \"\"\"Totals invoices.\"\"\"\n",
+			"    def total(\n        self,\n        invoice_id: str,\n    ) -> int:\n",
+		}},
+		{"src/InvoiceService.java", []string{
+			"package com.example.billing;\nimport java.util.List;",
+			"/** Totals invoices */\npublic class InvoiceService {",
+			"    private final Repo repo;",
+			"    @Override\n    public int total(String id) throws IOException\n}",
+		}},
+		{"infra/main.tf", []string{
+			"# Bucket for logs\nvariable \"bucket_name\" {\n  description = \"Name of the bucket\"\n  type        = string\n}",
+			"resource \"aws_s3_bucket\" \"logs\" {\n}",
+			"module \"vpc\" {\n  source = \"terraform-aws-modules/vpc/aws\"\n}",
+		}},
+		{"proto/billing.proto", []string{
+			"syntax = \"proto3\";",
+			"// An invoice\nmessage Invoice {\n  string id = 1;\n}",
+			"service Billing {\n  rpc Total(TotalRequest) returns (TotalResponse)\n}",
+		}},
+		{"deploy/billing.yaml", []string{
+			"kind: Deployment\nmetadata:\n  name: billing\n",
+			"      containers:\n        - name: billing\n          image: example/billing:1.2\n",
+			"  config.yaml: ...",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result, err := ExtractMinimalContext(filepath.Join(dir, tt.path))
+			require.NoError(t, err)
+			for _, want := range tt.want {
+				assert.Contains(t, result, want)
+			}
+			// Bodies and values deep in the file are left out
+			assert.NotContains(t, result, "secret")
+			assert.NotContains(t, result, "# File:")
+		})
+	}
+}
+
+func TestExtractMinimalContext_LanguageWithoutSignatures(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoFiles(t, dir, map[string]string{"web/empty.ts": "// nothing declared yet\n"})
+
+	result, err := ExtractMinimalContext(filepath.Join(dir, "web/empty.ts"))
+	require.NoError(t, err)
+	assert.Contains(t, result, "# File:")
+	assert.Contains(t, result, "nothing declared yet")
+}
+
+func TestBuildIndex_LanguageDependencies(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoFiles(t, dir, languageFiles)
+
+	index, err := New(dir).BuildIndex()
+	require.NoError(t, err)
+
+	want := map[string][]string{
+		"web/invoice.ts":          {"@angular/core", "./helper", "legacy-lib"},
+		"app/invoice.py":          {"os", "sys", ".models"},
+		"src/InvoiceService.java": {"java.util.List", "java.lang.Math.round"},
+		"infra/main.tf":           {"terraform-aws-modules/vpc/aws"},
+		"proto/billing.proto":     {"google/protobuf/timestamp.proto"},
+		"deploy/billing.yaml":     nil,
+	}
+	for path, deps := range want {
+		require.Contains(t, index.Files, path)
+		assert.Equal(t, deps, index.Files[path].Dependencies, path)
+	}
+}
+
+func TestTSImports(t *testing.T) {
+	src := "import {\n  a,\n  b,\n} from './multi';\nimport './side-effect';\nexport * from \"./all\";\nconst m = await import('./lazy');\n"
+	assert.Equal(t, []string{"./multi", "./side-effect", "./all", "./lazy"}, tsImports(src))
+}
+
+func TestPythonImports(t *testing.T) {
+	assert.Equal(t, []string{"os", "os.path", "typing"}, pythonImports("import os\nimport os.path as p\nfrom typing import (\n    List,\n)\n"))
+}
@@ -0,0 +1,353 @@
+package indexer
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// neighborReasonNames caps the symbols named in a neighbor's reason
+const neighborReasonNames = 3
+
+// Neighbor is a file one hop from a selected file in the import or
+// reference graph, and why it's related
+type Neighbor struct {
+	Path   string
+	Reason string // e.g. "declares Config, used by internal/app/app.go"
+}
+
+// Neighbors returns the indexed Go files one hop from paths, excluding
+// paths themselves: first what they depend on (files declaring the
+// same-package symbols they reference, or the symbols they use from
+// imported in-repo packages), then what depends on them (files referencing
+// or using their symbols), with tests last. Imports are resolved to
+// directories through the repository's go.mod files.
+func Neighbors(repoRoot string, index *FileIndex, paths []string) []Neighbor {
+	if index == nil || len(paths) == 0 {
+		return nil
+	}
+	g := newGraph(repoRoot, index)
+
+	selected := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		selected[p] = true
+	}
+	var deps, dependents, tests []Neighbor
+	seen := make(map[string]bool)
+	add := func(list *[]Neighbor, found map[string][]string, relation, from string) {
+		for _, p := range sortedKeys(found) {
+			if selected[p] || seen[p] {
+				continue
+			}
+			seen[p] = true
+			n := Neighbor{Path: p, Reason: fmt.Sprintf(relation, joinNames(found[p]), from)}
+			if strings.HasSuffix(p, "_test.go") {
+				tests = append(tests, n)
+			} else {
+				*list = append(*list, n)
+			}
+		}
+	}
+
+	for _, p := range paths {
+		if !strings.HasSuffix(p, ".go") {
+			continue
+		}
+		if _, ok := index.Files[p]; !ok {
+			continue
+		}
+		add(&deps, g.referencedFiles(p), "declares %s, referenced by %s", p)
+		add(&deps, g.usedFiles(p), "declares %s, used by %s", p)
+	}
+	for _, p := range paths {
+		if !strings.HasSuffix(p, ".go") {
+			continue
+		}
+		if _, ok := index.Files[p]; !ok {
+			continue
+		}
+		add(&dependents, g.referencingFiles(p), "references %s from %s", p)
+		add(&dependents, g.usingFiles(p), "uses %s from %s", p)
+	}
+
+	return append(append(deps, dependents...), tests...)
+}
+
+// graph answers one-hop questions over an index, parsing files lazily
+type graph struct {
+	repoRoot string
+	index    *FileIndex
+	byDir    map[string][]string            // Package directory -> Go files
+	modules  map[string]string              // go.mod directory -> module path
+	usages   map[string]map[string][]string // File -> import path -> selectors used
+}
+
+func newGraph(repoRoot string, index *FileIndex) *graph {
+	g := &graph{
+		repoRoot: repoRoot,
+		index:    index,
+		byDir:    make(map[string][]string),
+		modules:  make(map[string]string),
+		usages:   make(map[string]map[string][]string),
+	}
+	for relPath := range index.Files {
+		slash := filepath.ToSlash(relPath)
+		switch {
+		case strings.HasSuffix(slash, ".go"):
+			g.byDir[path.Dir(slash)] = append(g.byDir[path.Dir(slash)], relPath)
+		case path.Base(slash) == "go.mod":
+			if module := readModulePath(filepath.Join(repoRoot, relPath)); module != "" {
+				g.modules[path.Dir(slash)] = module
+			}
+		}
+	}
+	return g
+}
+
+// referencedFiles returns the files of relPath's package declaring the
+// symbols its symbols reference
+func (g *graph) referencedFiles(relPath string) map[string][]string {
+	refs := make(map[string]bool)
+	for _, sym := range g.index.Files[relPath].Symbols {
+		for _, ref := range sym.Refs {
+			refs[ref] = true
+		}
+	}
+	found := make(map[string][]string)
+	for _, other := range g.siblings(relPath) {
+		for _, sym := range g.index.Files[other].Symbols {
+			if refs[sym.Name] {
+				found[other] = append(found[other], sym.Name)
+			}
+		}
+	}
+	return found
+}
+
+// referencingFiles returns the files of relPath's package whose symbols
+// reference its symbols
+func (g *graph) referencingFiles(relPath string) map[string][]string {
+	declared := make(map[string]bool)
+	for _, sym := range g.index.Files[relPath].Symbols {
+		declared[sym.Name] = true
+	}
+	found := make(map[string][]string)
+	for _, other := range g.siblings(relPath) {
+		names := make(map[string]bool)
+		for _, sym := range g.index.Files[other].Symbols {
+			for _, ref := range sym.Refs {
+				if declared[ref] {
+					names[ref] = true
+				}
+			}
+		}
+		if len(names) > 0 {
+			found[other] = sortedKeys(names)
+		}
+	}
+	return found
+}
+
+// usedFiles returns the files of imported in-repo packages declaring the
+// symbols relPath uses from them
+func (g *graph) usedFiles(relPath string) map[string][]string {
+	found := make(map[string][]string)
+	for importPath, names := range g.usage(relPath) {
+		dir, ok := g.resolve(importPath)
+		if !ok {
+			continue
+		}
+		used := make(map[string]bool, len(names))
+		for _, name := range names {
+			used[name] = true
+		}
+		for _, other := range g.byDir[dir] {
+			if strings.HasSuffix(other, "_test.go") {
+				continue
+			}
+			for _, sym := range g.index.Files[other].Symbols {
+				if used[sym.Name] {
+					found[other] = append(found[other], sym.Name)
+				}
+			}
+		}
+	}
+	return found
+}
+
+// usingFiles returns the files in other packages that import relPath's
+// package and use its exported symbols
+func (g *graph) usingFiles(relPath string) map[string][]string {
+	importPath, ok := g.importPath(path.Dir(filepath.ToSlash(relPath)))
+	if !ok {
+		return nil
+	}
+	exported := make(map[string]bool)
+	for _, sym := range g.index.Files[relPath].Symbols {
+		if ast.IsExported(sym.Name) && !strings.Contains(sym.Name, ".") {
+			exported[sym.Name] = true
+		}
+	}
+	if len(exported) == 0 {
+		return nil
+	}
+
+	found := make(map[string][]string)
+	for other, metadata := range g.index.Files {
+		if !containsString(metadata.Dependencies, importPath) {
+			continue
+		}
+		for _, name := range g.usage(other)[importPath] {
+			if exported[name] {
+				found[other] = append(found[other], name)
+			}
+		}
+	}
+	return found
+}
+
+// siblings returns the other Go files of relPath's package directory
+func (g *graph) siblings(relPath string) []string {
+	var out []string
+	for _, other := range g.byDir[path.Dir(filepath.ToSlash(relPath))] {
+		if other != relPath {
+			out = append(out, other)
+		}
+	}
+	return out
+}
+
+// usage returns, per import path, the selectors relPath uses from it
+// (e.g. "intern/internal/indexer" -> ["FileIndex", "ScoreFiles"])
+func (g *graph) usage(relPath string) map[string][]string {
+	if u, ok := g.usages[relPath]; ok {
+		return u
+	}
+	u := make(map[string][]string)
+	g.usages[relPath] = u
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filepath.Join(g.repoRoot, relPath), nil, parser.SkipObjectResolution)
+	if err != nil {
+		return u
+	}
+	byName := make(map[string]string)
+	for _, imp := range file.Imports {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := path.Base(importPath)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		byName[name] = importPath
+	}
+
+	seen := make(map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if x, ok := sel.X.(*ast.Ident); ok {
+			if importPath, ok := byName[x.Name]; ok && !seen[importPath+"."+sel.Sel.Name] {
+				seen[importPath+"."+sel.Sel.Name] = true
+				u[importPath] = append(u[importPath], sel.Sel.Name)
+			}
+		}
+		return true
+	})
+	return u
+}
+
+// resolve maps an import path to an indexed package directory
+func (g *graph) resolve(importPath string) (string, bool) {
+	best, bestModule := "", ""
+	for dir, module := range g.modules {
+		if (importPath == module || strings.HasPrefix(importPath, module+"/")) && len(module) > len(bestModule) {
+			best, bestModule = path.Join(dir, strings.TrimPrefix(importPath, module)), module
+		}
+	}
+	if bestModule == "" {
+		return "", false
+	}
+	_, ok := g.byDir[best]
+	return best, ok
+}
+
+// importPath maps a package directory to its import path
+func (g *graph) importPath(dir string) (string, bool) {
+	bestDir, found := "", false
+	for modDir := range g.modules {
+		within := modDir == "." || dir == modDir || strings.HasPrefix(dir, modDir+"/")
+		if within && (!found || len(modDir) > len(bestDir)) {
+			bestDir, found = modDir, true
+		}
+	}
+	if !found {
+		return "", false
+	}
+	rel := dir
+	if bestDir != "." {
+		rel = strings.TrimPrefix(strings.TrimPrefix(dir, bestDir), "/")
+	}
+	if rel == "" || rel == "." {
+		return g.modules[bestDir], true
+	}
+	return g.modules[bestDir] + "/" + rel, true
+}
+
+// readModulePath returns the module path declared in a go.mod file
+func readModulePath(goMod string) string {
+	f, err := os.Open(goMod)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
+
+// joinNames lists up to neighborReasonNames names
+func joinNames(names []string) string {
+	sort.Strings(names)
+	if len(names) > neighborReasonNames {
+		return strings.Join(names[:neighborReasonNames], ", ") + fmt.Sprintf(" and %d more", len(names)-neighborReasonNames)
+	}
+	return strings.Join(names, ", ")
+}
+
+// sortedKeys returns a map's keys in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
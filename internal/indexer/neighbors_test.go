@@ -0,0 +1,110 @@
+package indexer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var neighborRepo = map[string]string{
+	"go.mod": "module example.com/app\n\ngo 1.23\n",
+	"internal/store/store.go": `package store
+
+// Store persists records
+type Store interface{ Save(r Record) error }
+
+type Record struct{ ID string }
+`,
+	"internal/store/memory.go": `package store
+
+type Memory struct{}
+
+func (m *Memory) Save(r Record) error { return nil }
+`,
+	"internal/store/unrelated.go": `package store
+
+func Vacuum() {}
+`,
+	"internal/service/service.go": `package service
+
+import "example.com/app/internal/store"
+
+type Service struct{ s store.Store }
+
+func (s *Service) Create(id string) error { return s.s.Save(store.Record{ID: id}) }
+`,
+	"internal/service/helpers.go": `package service
+
+func newService() *Service { return &Service{} }
+`,
+	"cmd/app/main.go": `package main
+
+import svc "example.com/app/internal/service"
+
+func main() { _ = svc.Service{} }
+`,
+	"internal/service/service_test.go": `package service
+
+import "testing"
+
+func TestCreate(t *testing.T) { _ = newService().Create("1") }
+`,
+}
+
+func TestNeighbors(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoFiles(t, dir, neighborRepo)
+	index, err := New(dir).BuildIndex()
+	require.NoError(t, err)
+
+	got := Neighbors(dir, index, []string{"internal/service/service.go"})
+	reasons := make(map[string]string)
+	var order []string
+	for _, n := range got {
+		reasons[n.Path] = n.Reason
+		order = append(order, n.Path)
+	}
+
+	// What it depends on: the declarations it uses from store
+	assert.Equal(t, "declares Record, Store, used by internal/service/service.go", reasons["internal/store/store.go"])
+	assert.NotContains(t, reasons, "internal/store/memory.go", "only the declaring files")
+	assert.NotContains(t, reasons, "internal/store/unrelated.go")
+	// What depends on it: same-package references and importers
+	assert.Equal(t, "references Service from internal/service/service.go", reasons["internal/service/helpers.go"])
+	assert.Equal(t, "uses Service from internal/service/service.go", reasons["cmd/app/main.go"])
+	// Dependencies first, tests last
+	require.Len(t, order, 4)
+	assert.Equal(t, "internal/store/store.go", order[0])
+	assert.Equal(t, "internal/service/service_test.go", order[3])
+
+	assert.Empty(t, Neighbors(dir, index, []string{"README.md"}))
+}
+
+func TestGraph_ImportPaths(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoFiles(t, dir, map[string]string{
+		"go.mod":           "module example.com/app\n",
+		"tools/go.mod":     "module example.com/tools\n",
+		"tools/gen/gen.go": "package gen\n",
+		"pkg/util/util.go": "package util\n",
+		"main.go":          "package main\n",
+	})
+	index, err := New(dir).BuildIndex()
+	require.NoError(t, err)
+	g := newGraph(dir, index)
+
+	resolved, ok := g.resolve("example.com/tools/gen")
+	assert.True(t, ok)
+	assert.Equal(t, "tools/gen", resolved)
+	resolved, ok = g.resolve("example.com/app")
+	assert.True(t, ok)
+	assert.Equal(t, ".", resolved)
+	_, ok = g.resolve("github.com/other/util")
+	assert.False(t, ok)
+
+	importPath, _ := g.importPath("pkg/util")
+	assert.Equal(t, "example.com/app/pkg/util", importPath)
+	importPath, _ = g.importPath("tools/gen")
+	assert.Equal(t, "example.com/tools/gen", importPath)
+}
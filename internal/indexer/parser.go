@@ -14,9 +14,14 @@ import (
 
 // ExtractMinimalContext extracts only the essential parts of a Go file
 // Returns: package declaration, imports, type definitions, function signatures (no bodies)
-// This reduces token usage by 60-80% compared to full file content
+// This reduces token usage by 60-80% compared to full file content.
+// TypeScript/JavaScript, Python, Java, Terraform/HCL, Protobuf and YAML
+// files get the equivalent (see languages.go).
 func ExtractMinimalContext(filePath string) (string, error) {
-	// For non-Go files, return a simple summary
+	if lang := languageFor(filePath); lang != nil {
+		return extractLanguageContext(filePath, lang)
+	}
+	// For other non-Go files, return a simple summary
 	if !strings.HasSuffix(filePath, ".go") {
 		return extractNonGoContext(filePath)
 	}
@@ -422,6 +427,10 @@ func receiverTypeName(expr ast.Expr) string {
 		return receiverTypeName(t.X)
 	case *ast.Ident:
 		return t.Name
+	case *ast.IndexExpr: // Generic receiver, e.g. List[T]
+		return receiverTypeName(t.X)
+	case *ast.IndexListExpr:
+		return receiverTypeName(t.X)
 	default:
 		return ""
 	}
@@ -431,7 +440,7 @@ func receiverTypeName(expr ast.Expr) string {
 // in full rather than truncated to a prefix, matching this function's
 // documented (previously unimplemented) intent. Unlike Go source - where a
 // package/import/signature header is genuinely representative of the whole
-// file - declarative config files (Terraform, YAML, JSON) have no "head"
+// file - declarative config files (JSON, .env, INI) have no "head"
 // that's more relevant than the rest: a variable/resource block near the
 // end is exactly as load-bearing as one at the top. A blind prefix cut
 // silently hides real content from the model instead of summarizing it,
@@ -439,7 +448,8 @@ func receiverTypeName(expr ast.Expr) string {
 // file - it has no way to know that what it can't see even exists.
 const nonGoFullContentMaxBytes = 16 * 1024
 
-// extractNonGoContext extracts context from non-Go files (config, docs, etc.).
+// extractNonGoContext extracts context from files without signatures to
+// extract (config, docs, etc.).
 // For small files (<16KB), it returns the full content. For larger files,
 // it returns the first 100 lines to avoid excessive context.
 func extractNonGoContext(filePath string) (string, error) {
@@ -0,0 +1,303 @@
+package indexer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// repoMapMaxBytes caps the repository map, which is sent with every
+// planning prompt
+const repoMapMaxBytes = 8 * 1024
+
+// repoMapMoreBytes is kept free for the line counting the directories left
+// out
+const repoMapMoreBytes = 48
+
+// repoMapKeyTypes caps the exported types listed per package
+const repoMapKeyTypes = 6
+
+// repoMapCommitPrefix starts the first line of the cached map, recording
+// the commit it describes and the size it was built for
+const repoMapCommitPrefix = "<!-- commit: "
+
+// entryPointNames are the non-Go files that usually start a program or
+// configuration (Go entry points are found by their package main)
+var entryPointNames = map[string]bool{
+	"main.py": true, "__main__.py": true, "manage.py": true, "app.py": true,
+	"index.js": true, "index.ts": true, "main.js": true, "main.ts": true,
+	"server.js": true, "server.ts": true, "main.tf": true,
+}
+
+// mapDir is one directory of indexed source files in the repository map
+type mapDir struct {
+	path     string
+	pkg      string // Go package name, "" for other languages
+	doc      string // First paragraph of the Go package doc comment
+	files    int
+	tests    int
+	exts     map[string]bool
+	goFiles  []string // Non-test Go files
+	keyTypes []string
+}
+
+// BuildRepoMap summarises how the repository is organised, for a model
+// that otherwise only sees the files selected for a ticket: its source
+// directories (package tree) with their package doc comments and key
+// exported types, its entry points and where its tests live. It's capped at
+// maxBytes (at most, and by default, repoMapMaxBytes), dropping the
+// directories that don't fit, then the entry points and tests.
+func (idx *Indexer) BuildRepoMap(index *FileIndex, maxBytes int) string {
+	maxBytes = repoMapLimit(maxBytes)
+
+	dirs := make(map[string]*mapDir)
+	var entryPoints []string
+	testDirs := make(map[string]int) // Dedicated test directories -> test files
+	goTests, goTestPkgs := 0, make(map[string]bool)
+
+	for _, relPath := range sortedKeys(index.Files) {
+		if index.Files[relPath].Category == "doc" {
+			continue
+		}
+
+		dirPath := path.Dir(relPath)
+		dir := dirs[dirPath]
+		if dir == nil {
+			dir = &mapDir{path: dirPath, exts: make(map[string]bool)}
+			dirs[dirPath] = dir
+		}
+		dir.files++
+		if ext := path.Ext(relPath); ext != "" {
+			dir.exts[ext] = true
+		}
+
+		if isTestPath(relPath) {
+			dir.tests++
+			if strings.HasSuffix(relPath, "_test.go") {
+				goTests++
+				goTestPkgs[dirPath] = true
+			} else if testDir := testDirOf(relPath); testDir != "" {
+				testDirs[testDir]++
+			}
+			continue
+		}
+
+		if strings.HasSuffix(relPath, ".go") {
+			dir.goFiles = append(dir.goFiles, relPath)
+		} else if entryPointNames[path.Base(relPath)] {
+			entryPoints = append(entryPoints, "`"+relPath+"`")
+		}
+	}
+
+	for _, dir := range dirs {
+		if len(dir.goFiles) > 0 {
+			idx.describeGoPackage(dir, index)
+			if dir.pkg == "main" {
+				entryPoints = append(entryPoints, "`"+dir.path+"` (package main)")
+			}
+		}
+	}
+	sort.Strings(entryPoints)
+
+	commit := index.GitCommitHash
+	if len(commit) > 8 {
+		commit = commit[:8]
+	}
+
+	var tail strings.Builder
+	if len(entryPoints) > 0 {
+		tail.WriteString("\n## Entry Points\n")
+		for _, entry := range entryPoints {
+			tail.WriteString("- " + entry + "\n")
+		}
+	}
+	if goTests > 0 || len(testDirs) > 0 {
+		tail.WriteString("\n## Tests\n")
+		if goTests > 0 {
+			fmt.Fprintf(&tail, "- Go tests sit beside the code they test (`*_test.go`): %s in %s\n", plural(goTests, "file"), plural(len(goTestPkgs), "package"))
+		}
+		for _, testDir := range sortedKeys(testDirs) {
+			fmt.Fprintf(&tail, "- `%s/`: %s\n", testDir, plural(testDirs[testDir], "test file"))
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Repository Map (commit %s)\n\n## Packages\n", commit)
+	if sb.Len()+tail.Len()+repoMapMoreBytes > maxBytes {
+		tail.Reset()
+	}
+	if sb.Len()+repoMapMoreBytes > maxBytes {
+		return "" // Too small a budget for any of it
+	}
+	paths := sortedKeys(dirs)
+	for i, dirPath := range paths {
+		line := dirs[dirPath].line()
+		if sb.Len()+len(line)+tail.Len()+repoMapMoreBytes > maxBytes {
+			fmt.Fprintf(&sb, "- ... and %d more directories\n", len(paths)-i)
+			break
+		}
+		sb.WriteString(line)
+	}
+	sb.WriteString(tail.String())
+	return sb.String()
+}
+
+// line renders a directory as a repository map entry
+func (d *mapDir) line() string {
+	var sb strings.Builder
+	if d.path == "." {
+		sb.WriteString("- (root)")
+	} else {
+		fmt.Fprintf(&sb, "- `%s`", d.path)
+	}
+
+	var facts []string
+	if d.pkg != "" && d.pkg != path.Base(d.path) {
+		facts = append(facts, "package "+d.pkg)
+	}
+	counts := plural(d.files, "file")
+	if d.tests > 0 {
+		counts += ", " + plural(d.tests, "test")
+	}
+	facts = append(facts, counts)
+	if d.pkg == "" {
+		facts = append(facts, strings.Join(sortedKeys(d.exts), " "))
+	}
+	fmt.Fprintf(&sb, " (%s)", strings.Join(facts, "; "))
+
+	if d.doc != "" {
+		sb.WriteString(": " + d.doc)
+	}
+	if len(d.keyTypes) > 0 {
+		fmt.Fprintf(&sb, " Key types: %s.", strings.Join(d.keyTypes, ", "))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// repoMapLimit returns the size a repository map is built for: maxBytes,
+// or repoMapMaxBytes if that's unset or larger
+func repoMapLimit(maxBytes int) int {
+	if maxBytes <= 0 || maxBytes > repoMapMaxBytes {
+		return repoMapMaxBytes
+	}
+	return maxBytes
+}
+
+// plural formats a count of things
+func plural(n int, thing string) string {
+	if n == 1 {
+		return "1 " + thing
+	}
+	return fmt.Sprintf("%d %ss", n, thing)
+}
+
+// describeGoPackage fills in a Go directory's package name and doc comment
+// from its non-test files, and its key types: the exported types its other
+// symbols reference most
+func (idx *Indexer) describeGoPackage(dir *mapDir, index *FileIndex) {
+	fset := token.NewFileSet()
+	used := make(map[string]int)
+	var exported []string
+
+	for _, relPath := range dir.goFiles {
+		file, err := parser.ParseFile(fset, filepath.Join(idx.repoRoot, relPath), nil, parser.PackageClauseOnly|parser.ParseComments)
+		if err == nil {
+			dir.pkg = file.Name.Name
+			if doc := symbolDoc(file.Doc); doc != "" && (dir.doc == "" || path.Base(relPath) == "doc.go") {
+				dir.doc = doc
+			}
+		}
+
+		for _, sym := range index.Files[relPath].Symbols {
+			if sym.Kind == SymbolType && ast.IsExported(sym.Name) {
+				exported = append(exported, sym.Name)
+			}
+			for _, ref := range sym.Refs {
+				used[ref]++
+			}
+		}
+	}
+
+	sort.SliceStable(exported, func(i, j int) bool {
+		if used[exported[i]] != used[exported[j]] {
+			return used[exported[i]] > used[exported[j]]
+		}
+		return exported[i] < exported[j]
+	})
+	if len(exported) > repoMapKeyTypes {
+		exported = exported[:repoMapKeyTypes]
+	}
+	dir.keyTypes = exported
+}
+
+// isTestPath reports whether a file holds tests, by the naming conventions
+// of the languages the index reads
+func isTestPath(relPath string) bool {
+	base := path.Base(relPath)
+	name := strings.TrimSuffix(base, path.Ext(base))
+	switch {
+	case strings.HasSuffix(base, "_test.go"), strings.HasSuffix(name, "_test"),
+		strings.HasPrefix(name, "test_"), strings.HasSuffix(name, ".test"), strings.HasSuffix(name, ".spec"):
+		return true
+	case strings.HasSuffix(name, "Test") && name != "Test":
+		return true // FooTest.java
+	}
+	return testDirOf(relPath) != ""
+}
+
+// testDirOf returns the nearest enclosing directory dedicated to tests
+// (test, tests, __tests__ or spec), or ""
+func testDirOf(relPath string) string {
+	parts := strings.Split(path.Dir(relPath), "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		switch parts[i] {
+		case "test", "tests", "__tests__", "spec":
+			return strings.Join(parts[:i+1], "/")
+		}
+	}
+	return ""
+}
+
+// GetOrBuildRepoMap returns the repository map for index's commit within
+// maxBytes (see BuildRepoMap), reusing the one cached in
+// IndexDirName/ProjectIndexName if it was built for the same commit and
+// size, else building and caching it
+func (idx *Indexer) GetOrBuildRepoMap(index *FileIndex, maxBytes int) (string, error) {
+	mapPath := filepath.Join(idx.repoRoot, IndexDirName, ProjectIndexName)
+	key := fmt.Sprintf("%s max_bytes: %d", index.GitCommitHash, repoMapLimit(maxBytes))
+	if cached, cachedKey := readRepoMap(mapPath); index.GitCommitHash != "" && cachedKey == key {
+		return cached, nil
+	}
+
+	repoMap := idx.BuildRepoMap(index, maxBytes)
+	if err := os.MkdirAll(filepath.Dir(mapPath), 0755); err != nil {
+		return repoMap, err
+	}
+	data := repoMapCommitPrefix + key + " -->\n" + repoMap
+	if err := os.WriteFile(mapPath, []byte(data), 0644); err != nil {
+		return repoMap, fmt.Errorf("failed to write repository map: %w", err)
+	}
+	return repoMap, nil
+}
+
+// readRepoMap returns a cached repository map and the commit and size it
+// was built for, or "" if there is none
+func readRepoMap(mapPath string) (string, string) {
+	data, err := os.ReadFile(mapPath)
+	if err != nil {
+		return "", ""
+	}
+	header, body, ok := strings.Cut(string(data), "\n")
+	if !ok || !strings.HasPrefix(header, repoMapCommitPrefix) {
+		return "", ""
+	}
+	key := strings.TrimSuffix(strings.TrimPrefix(header, repoMapCommitPrefix), " -->")
+	return body, key
+}
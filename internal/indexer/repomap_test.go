@@ -0,0 +1,128 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildRepoMap(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoFiles(t, dir, symbolRepo)
+	writeRepoFiles(t, dir, map[string]string{
+		"internal/other/doc.go":        "// Package other holds helpers shared by the orchestrator.\n//\n// Details nobody needs in the map.\npackage other\n",
+		"internal/other/other_test.go": "package other\n",
+		"cmd/tool/main.go":             "package main\n\nfunc main() {}\n",
+		"web/src/index.ts":             "export const app = 1;\n",
+		"web/tests/app.test.ts":        "test('app', () => {});\n",
+		"README.md":                    "# Repo\n",
+	})
+
+	idx := New(dir)
+	index, err := idx.BuildIndex()
+	require.NoError(t, err)
+	repoMap := idx.BuildRepoMap(index, 0)
+
+	// Package tree, with package docs (first paragraph only) and key types
+	assert.Contains(t, repoMap, "- `internal/other` (3 files, 1 test): Package other holds helpers shared by the orchestrator.\n")
+	assert.NotContains(t, repoMap, "Details nobody needs")
+	assert.Contains(t, repoMap, "- `internal/orchestrator` (4 files, 1 test) Key types: Coordinator.")
+	assert.Contains(t, repoMap, "- `cmd/tool` (package main; 1 file)")
+	assert.Contains(t, repoMap, "- `web/src` (1 file; .ts)")
+	assert.NotContains(t, repoMap, "README.md")
+
+	// Entry points and test layout
+	assert.Contains(t, repoMap, "## Entry Points\n- `cmd/tool` (package main)\n- `web/src/index.ts`\n")
+	assert.Contains(t, repoMap, "- Go tests sit beside the code they test (`*_test.go`): 2 files in 2 packages\n")
+	assert.Contains(t, repoMap, "- `web/tests/`: 1 test file\n")
+}
+
+func TestBuildRepoMap_Capped(t *testing.T) {
+	files := make(map[string]FileMetadata)
+	for i := 0; i < 500; i++ {
+		relPath := filepath.ToSlash(filepath.Join("pkg", strings.Repeat("x", 20)+string(rune('a'+i%26)), strings.Repeat("d", i/26+1), "file.py"))
+		files[relPath] = FileMetadata{Path: relPath, Category: "other"}
+	}
+
+	repoMap := New(t.TempDir()).BuildRepoMap(&FileIndex{Files: files}, 0)
+	assert.LessOrEqual(t, len(repoMap), repoMapMaxBytes)
+	assert.Contains(t, repoMap, "more directories\n")
+}
+
+func TestBuildRepoMap_SizedForBudget(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoFiles(t, dir, symbolRepo)
+	writeRepoFiles(t, dir, map[string]string{"cmd/tool/main.go": "package main\n\nfunc main() {}\n"})
+	idx := New(dir)
+	index, err := idx.BuildIndex()
+	require.NoError(t, err)
+
+	full := idx.BuildRepoMap(index, 0)
+	require.Contains(t, full, "## Entry Points")
+
+	// Directories that don't fit are dropped first
+	small := idx.BuildRepoMap(index, len(full)-1)
+	assert.LessOrEqual(t, len(small), len(full)-1)
+	assert.Contains(t, small, "more directories\n")
+	assert.Contains(t, small, "## Entry Points")
+
+	// Then the entry points and tests
+	header := "# Repository Map (commit )\n\n## Packages\n"
+	smaller := idx.BuildRepoMap(index, len(header)+repoMapMoreBytes)
+	assert.Equal(t, header+"- ... and 3 more directories\n", smaller)
+
+	// Too small for anything
+	assert.Empty(t, idx.BuildRepoMap(index, 16))
+}
+
+func TestIsTestPath(t *testing.T) {
+	for relPath, want := range map[string]bool{
+		"internal/app/app_test.go":            true,
+		"app/test_models.py":                  true,
+		"app/models_test.py":                  true,
+		"web/src/app.spec.ts":                 true,
+		"web/src/app.test.tsx":                true,
+		"src/test/java/com/acme/AppTest.java": true,
+		"web/__tests__/app.js":                true,
+		"internal/app/app.go":                 false,
+		"app/contest.py":                      false,
+		"src/main/java/com/acme/Test.java":    false,
+	} {
+		assert.Equal(t, want, isTestPath(relPath), relPath)
+	}
+}
+
+func TestGetOrBuildRepoMap_CachedPerCommit(t *testing.T) {
+	dir, git := initSymbolRepo(t)
+	idx := New(dir)
+	index, err := idx.BuildIndex()
+	require.NoError(t, err)
+
+	repoMap, err := idx.GetOrBuildRepoMap(index, 0)
+	require.NoError(t, err)
+	assert.Contains(t, repoMap, "# Repository Map (commit "+index.GitCommitHash[:8]+")")
+
+	// The cached map is reused for the same commit
+	mapPath := filepath.Join(dir, IndexDirName, ProjectIndexName)
+	data, err := os.ReadFile(mapPath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(mapPath, []byte(strings.Replace(string(data), "## Packages", "## Cached Packages", 1)), 0644))
+	cached, err := idx.GetOrBuildRepoMap(index, 0)
+	require.NoError(t, err)
+	assert.Contains(t, cached, "## Cached Packages")
+
+	// A new commit rebuilds it
+	writeRepoFiles(t, dir, map[string]string{"internal/extra/extra.go": "package extra\n"})
+	git("add", ".")
+	git("commit", "-m", "Add extra")
+	index, err = idx.UpdateIndex()
+	require.NoError(t, err)
+	rebuilt, err := idx.GetOrBuildRepoMap(index, 0)
+	require.NoError(t, err)
+	assert.NotContains(t, rebuilt, "## Cached Packages")
+	assert.Contains(t, rebuilt, "`internal/extra`")
+}
@@ -1,26 +1,63 @@
 package indexer
 
 import (
+	"math"
+	"path/filepath"
 	"sort"
 	"strings"
 )
 
+// ScoreOption adjusts how ScoreFiles ranks files
+type ScoreOption func(*scoreOptions)
+
+type scoreOptions struct {
+	semantic map[string]float64
+}
+
+// WithSemantic blends each file's embedding similarity to the ticket (see
+// SemanticRetriever.Scores) into the score. Only files more similar than
+// the repository's average gain anything, up to semanticWeight for the
+// most similar.
+func WithSemantic(similarity map[string]float64) ScoreOption {
+	return func(o *scoreOptions) { o.semantic = similarity }
+}
+
 // ScoreFiles ranks files in the index based on relevance to the given keywords
 // Returns a sorted slice of FileScore (highest scores first)
-func ScoreFiles(index *FileIndex, keywords []string) []FileScore {
+func ScoreFiles(index *FileIndex, keywords []string, opts ...ScoreOption) []FileScore {
 	if index == nil || len(keywords) == 0 {
 		return nil
 	}
+	var o scoreOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	semantic := aboveAverage(o.semantic)
 
 	scores := make([]FileScore, 0, len(index.Files))
+	matched := matchedSymbols(index, keywords)
+
+	// Content scores are scaled against the best one (see contentWeight)
+	content := index.Terms.Score(keywords)
+	bestContent := 0.0
+	for _, s := range content {
+		bestContent = math.Max(bestContent, s)
+	}
 
 	// Score each file
 	for path, metadata := range index.Files {
+		multiplier := getCategoryMultiplier(metadata.Category)
 		score := scoreFile(path, metadata, keywords)
+		score += referenceScore(path, metadata, matched) * multiplier
+		if bestContent > 0 {
+			score += contentWeight * content[path] / bestContent * multiplier
+		}
+		score += semanticWeight * semantic[path] * multiplier
 		if score > 0 {
 			scores = append(scores, FileScore{
-				Path:  path,
-				Score: score,
+				Path:    path,
+				Score:   score,
+				Matched: score > metadata.Importance*multiplier,
 			})
 		}
 	}
@@ -33,12 +70,42 @@ func ScoreFiles(index *FileIndex, keywords []string) []FileScore {
 	return scores
 }
 
+// aboveAverage maps similarities to 0..1: 0 at or below the mean, 1 for the
+// best. Embedding similarities of unrelated text are rarely near zero, so
+// the mean is the baseline rather than 0.
+func aboveAverage(similarity map[string]float64) map[string]float64 {
+	if len(similarity) == 0 {
+		return nil
+	}
+	mean, best := 0.0, math.Inf(-1)
+	for _, s := range similarity {
+		mean += s
+		best = math.Max(best, s)
+	}
+	mean /= float64(len(similarity))
+	if best <= mean {
+		return nil
+	}
+	out := make(map[string]float64, len(similarity))
+	for path, s := range similarity {
+		if s > mean {
+			out[path] = (s - mean) / (best - mean)
+		}
+	}
+	return out
+}
+
 // scoreFile calculates relevance score for a single file based on keyword matches.
 // It uses a tiered scoring system:
 //   - Exact path match: +15 points
 //   - Path contains keyword: +8 points
 //   - Path segment matches keyword: +5 points
 //   - Segment contains keyword: +2 points
+//
+// and, independently, on the file's Go symbols:
+//   - Declares a symbol named by the keyword: +10 points
+//   - A symbol's doc comment mentions the keyword: +1 point
+//
 // The final score is multiplied by a category-based multiplier.
 func scoreFile(path string, metadata FileMetadata, keywords []string) float64 {
 	score := 0.0
@@ -91,12 +158,90 @@ func scoreFile(path string, metadata FileMetadata, keywords []string) float64 {
 		}
 	}
 
+	score += symbolScore(metadata, keywords)
+
 	// Apply category multipliers
 	score *= getCategoryMultiplier(metadata.Category)
 
 	return score
 }
 
+// symbolScore scores a file's declared symbols against keywords, once per
+// keyword: a ticket mentioning ProcessTicket finds the file declaring it
+// whatever the file is called
+func symbolScore(metadata FileMetadata, keywords []string) float64 {
+	score := 0.0
+	for _, keyword := range keywords {
+		keyword = strings.ToLower(keyword)
+		named, documented := false, false
+		for _, sym := range metadata.Symbols {
+			if symbolNamed(sym.Name, keyword) {
+				named = true
+				break
+			}
+			if sym.Doc != "" && strings.Contains(strings.ToLower(sym.Doc), keyword) {
+				documented = true
+			}
+		}
+		if named {
+			score += 10.0
+		} else if documented {
+			score += 1.0
+		}
+	}
+	return score
+}
+
+// symbolNamed reports whether a lower-cased keyword names a symbol, either
+// whole ("coordinator.processticket") or by its method part ("processticket")
+func symbolNamed(name, keyword string) bool {
+	lower := strings.ToLower(name)
+	if lower == keyword {
+		return true
+	}
+	i := strings.LastIndex(lower, ".")
+	return i >= 0 && lower[i+1:] == keyword
+}
+
+// matchedSymbols returns, per package directory, the symbols named by a
+// keyword and the file declaring each
+func matchedSymbols(index *FileIndex, keywords []string) map[string]map[string]string {
+	matched := make(map[string]map[string]string)
+	for path, metadata := range index.Files {
+		for _, sym := range metadata.Symbols {
+			for _, keyword := range keywords {
+				if symbolNamed(sym.Name, strings.ToLower(keyword)) {
+					dir := filepath.Dir(path)
+					if matched[dir] == nil {
+						matched[dir] = make(map[string]string)
+					}
+					matched[dir][sym.Name] = path
+					break
+				}
+			}
+		}
+	}
+	return matched
+}
+
+// referenceScore gives +3 points to a file whose symbols reference (call,
+// embed, use) a keyword-matched symbol declared in another file of the same
+// package, following the index's reference graph one hop
+func referenceScore(path string, metadata FileMetadata, matched map[string]map[string]string) float64 {
+	declared := matched[filepath.Dir(path)]
+	if len(declared) == 0 {
+		return 0
+	}
+	for _, sym := range metadata.Symbols {
+		for _, ref := range sym.Refs {
+			if from, ok := declared[ref]; ok && from != path {
+				return 3.0
+			}
+		}
+	}
+	return 0
+}
+
 // getCategoryMultiplier returns a relevance multiplier based on file category.
 // Categories are prioritized as follows:
 //   - core: 1.5x (most important for understanding codebase)
@@ -121,6 +266,29 @@ func getCategoryMultiplier(category string) float64 {
 	}
 }
 
+// minRelativeScore is the fraction of the best file's score a file needs to
+// be kept by RelevantFiles
+const minRelativeScore = 0.3
+
+// RelevantFiles drops, from scores sorted by ScoreFiles, the files that
+// matched no keyword at all (they score only their base importance) and
+// those far behind the best match. The cutoff is relative because absolute
+// scores depend on how many keywords a ticket has and how many of them
+// name paths, symbols or only behaviour.
+func RelevantFiles(scores []FileScore) []FileScore {
+	var relevant []FileScore
+	for _, s := range scores {
+		if !s.Matched {
+			continue
+		}
+		if len(relevant) > 0 && s.Score < relevant[0].Score*minRelativeScore {
+			break
+		}
+		relevant = append(relevant, s)
+	}
+	return relevant
+}
+
 // SelectTopFiles returns the top N files by score
 func SelectTopFiles(scores []FileScore, n int) []FileScore {
 	if n <= 0 || len(scores) == 0 {
@@ -0,0 +1,340 @@
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenish-jain/logger"
+)
+
+// VectorStoreFileName holds the embedded chunks next to file_index.json
+const VectorStoreFileName = "vectors.json"
+
+// Chunking limits: a chunk is truncated to chunkMaxBytes before embedding,
+// plain text is split every chunkLines lines, and a file contributes at most
+// maxChunksPerFile chunks
+const (
+	chunkMaxBytes    = 2000
+	chunkLines       = 60
+	maxChunksPerFile = 40
+)
+
+// semanticWeight is the score the file most similar to the ticket adds
+// before the category multiplier (see ScoreFiles)
+const semanticWeight = 15.0
+
+// Chunk is an embeddable piece of a file: a Go declaration, a Markdown
+// section, or a run of lines
+type Chunk struct {
+	Name string // Declaration name or heading; "" for plain text
+	Line int    // First line, 1-based
+	Text string
+}
+
+// ChunkVector is one chunk's embedding
+type ChunkVector struct {
+	Name   string    `json:"name,omitempty"`
+	Line   int       `json:"line"`
+	Vector []float32 `json:"vector"`
+}
+
+// VectorStore holds every indexed file's chunk embeddings. It's persisted
+// as .ai-intern/vectors.json and tracks the commit it was embedded at, so
+// updates only re-embed files changed since.
+type VectorStore struct {
+	Model         string                   `json:"model"`
+	GitCommitHash string                   `json:"git_commit_hash"`
+	Files         map[string][]ChunkVector `json:"files"`
+}
+
+// SemanticRetriever ranks a repository's files by embedding similarity to
+// a ticket. Optional: keyword and BM25 scoring work without it.
+type SemanticRetriever struct {
+	idx      *Indexer
+	embedder Embedder
+}
+
+// NewSemanticRetriever returns a retriever for repoRoot using embedder
+func NewSemanticRetriever(repoRoot string, embedder Embedder) *SemanticRetriever {
+	return &SemanticRetriever{idx: New(repoRoot), embedder: embedder}
+}
+
+// Update embeds the files of index that the store doesn't have yet, or
+// that changed (per git) since the store's commit, and drops files no
+// longer indexed. A different embedding model starts the store over.
+func (r *SemanticRetriever) Update(ctx context.Context, index *FileIndex) error {
+	store, err := r.load()
+	if err != nil || store == nil || store.Model != r.embedder.Model() {
+		store = &VectorStore{Model: r.embedder.Model(), Files: make(map[string][]ChunkVector)}
+	}
+
+	stale := make(map[string]bool)
+	if store.GitCommitHash != "" && store.GitCommitHash != index.GitCommitHash {
+		changed, err := r.idx.getChangedFiles(store.GitCommitHash, index.GitCommitHash)
+		if err != nil {
+			// Can't tell what changed; re-embed everything
+			store.Files = make(map[string][]ChunkVector)
+		}
+		for _, path := range changed {
+			stale[path] = true
+		}
+	}
+	for path := range store.Files {
+		if _, ok := index.Files[path]; !ok || stale[path] {
+			delete(store.Files, path)
+		}
+	}
+
+	embedded := 0
+	for path := range index.Files {
+		if _, ok := store.Files[path]; ok {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		vectors, err := r.embedFile(ctx, path)
+		if err != nil {
+			// Save what's done so the next update resumes from here
+			_ = r.save(store)
+			return fmt.Errorf("failed to embed %s: %w", path, err)
+		}
+		store.Files[path] = vectors
+		embedded++
+	}
+
+	store.GitCommitHash = index.GitCommitHash
+	if embedded > 0 {
+		logger.Info("Embedded files for semantic retrieval", "files", embedded, "total", len(store.Files))
+	}
+	return r.save(store)
+}
+
+// Scores returns each file's similarity to text: the best cosine similarity
+// of any of its chunks. Files with no chunks are omitted.
+func (r *SemanticRetriever) Scores(ctx context.Context, text string) (map[string]float64, error) {
+	store, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	if store == nil || store.Model != r.embedder.Model() || len(store.Files) == 0 {
+		return nil, errors.New("no vector store for this model; run an index update first")
+	}
+
+	query, err := r.embedder.Embed(ctx, []string{truncateChunk(text)})
+	if err != nil {
+		return nil, err
+	}
+	if len(query) != 1 {
+		return nil, fmt.Errorf("embedder returned %d vectors for 1 query", len(query))
+	}
+
+	scores := make(map[string]float64, len(store.Files))
+	for path, chunks := range store.Files {
+		best := math.Inf(-1)
+		for _, c := range chunks {
+			best = math.Max(best, cosine(query[0], c.Vector))
+		}
+		if len(chunks) > 0 {
+			scores[path] = best
+		}
+	}
+	return scores, nil
+}
+
+// embedFile chunks and embeds one file
+func (r *SemanticRetriever) embedFile(ctx context.Context, relPath string) ([]ChunkVector, error) {
+	chunks := chunkFile(filepath.Join(r.idx.repoRoot, relPath), relPath)
+	if len(chunks) == 0 {
+		return []ChunkVector{}, nil
+	}
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		// The path gives the model context a bare declaration lacks
+		texts[i] = truncateChunk(relPath + "\n" + c.Text)
+	}
+	vectors, err := r.embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(chunks) {
+		return nil, fmt.Errorf("embedder returned %d vectors for %d chunks", len(vectors), len(chunks))
+	}
+	out := make([]ChunkVector, len(chunks))
+	for i, c := range chunks {
+		out[i] = ChunkVector{Name: c.Name, Line: c.Line, Vector: vectors[i]}
+	}
+	return out, nil
+}
+
+// load reads the vector store, or returns nil if there is none
+func (r *SemanticRetriever) load() (*VectorStore, error) {
+	data, err := os.ReadFile(filepath.Join(r.idx.repoRoot, IndexDirName, VectorStoreFileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var store VectorStore
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, err
+	}
+	if store.Files == nil {
+		store.Files = make(map[string][]ChunkVector)
+	}
+	return &store, nil
+}
+
+// save writes the vector store
+func (r *SemanticRetriever) save(store *VectorStore) error {
+	dir := filepath.Join(r.idx.repoRoot, IndexDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(store)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, VectorStoreFileName), data, 0644)
+}
+
+// chunkFile splits a file for embedding: Go by top-level declaration (with
+// its doc comment), Markdown by heading, anything else every chunkLines
+// lines. Binary files yield nothing.
+func chunkFile(absPath, relPath string) []Chunk {
+	src, err := os.ReadFile(absPath)
+	if err != nil || len(bytes.TrimSpace(src)) == 0 || bytes.IndexByte(src, 0) >= 0 {
+		return nil
+	}
+
+	var chunks []Chunk
+	lower := strings.ToLower(relPath)
+	switch {
+	case strings.HasSuffix(lower, ".go"):
+		chunks = chunkGo(src, relPath)
+	case strings.HasSuffix(lower, ".md"):
+		chunks = chunkMarkdown(string(src))
+	}
+	if len(chunks) == 0 {
+		chunks = chunkLinesOf(string(src))
+	}
+	if len(chunks) > maxChunksPerFile {
+		chunks = chunks[:maxChunksPerFile]
+	}
+	return chunks
+}
+
+// chunkGo returns one chunk per top-level declaration; nil if the file
+// doesn't parse
+func chunkGo(src []byte, relPath string) []Chunk {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, relPath, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+
+	var chunks []Chunk
+	for _, decl := range file.Decls {
+		start, name := decl.Pos(), ""
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			name = d.Name.Name
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				name = receiverTypeName(d.Recv.List[0].Type) + "." + name
+			}
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			if len(d.Specs) > 0 {
+				switch s := d.Specs[0].(type) {
+				case *ast.TypeSpec:
+					name = s.Name.Name
+				case *ast.ValueSpec:
+					name = s.Names[0].Name
+				}
+			}
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		}
+		from, to := fset.Position(start).Offset, fset.Position(decl.End()).Offset
+		chunks = append(chunks, Chunk{Name: name, Line: fset.Position(start).Line, Text: string(src[from:to])})
+	}
+	return chunks
+}
+
+// chunkMarkdown returns one chunk per heading-led section
+func chunkMarkdown(text string) []Chunk {
+	var chunks []Chunk
+	for i, line := range strings.Split(text, "\n") {
+		heading := strings.HasPrefix(line, "#")
+		if heading || len(chunks) == 0 {
+			name := ""
+			if heading {
+				name = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			}
+			chunks = append(chunks, Chunk{Name: name, Line: i + 1})
+		}
+		chunks[len(chunks)-1].Text += line + "\n"
+	}
+	return chunks
+}
+
+// chunkLinesOf splits text every chunkLines lines
+func chunkLinesOf(text string) []Chunk {
+	lines := strings.Split(text, "\n")
+	var chunks []Chunk
+	for start := 0; start < len(lines); start += chunkLines {
+		end := min(start+chunkLines, len(lines))
+		chunk := strings.Join(lines[start:end], "\n")
+		if strings.TrimSpace(chunk) != "" {
+			chunks = append(chunks, Chunk{Line: start + 1, Text: chunk})
+		}
+	}
+	return chunks
+}
+
+// truncateChunk caps text at chunkMaxBytes without splitting a UTF-8 rune
+func truncateChunk(text string) string {
+	if len(text) <= chunkMaxBytes {
+		return text
+	}
+	cut := chunkMaxBytes
+	for cut > 0 && text[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return text[:cut]
+}
+
+// cosine returns the cosine similarity of two vectors; 0 if their lengths
+// differ or either is zero
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
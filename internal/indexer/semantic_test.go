@@ -0,0 +1,141 @@
+package indexer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubEmbedder embeds text as counts of the concepts its words map to,
+// standing in for what a real model learns, and records the first line
+// (the path) of each text it was asked to embed
+type stubEmbedder struct {
+	model    string
+	concepts map[string]int // Word -> dimension
+	calls    []string
+}
+
+func (s *stubEmbedder) Model() string { return s.model }
+
+func (s *stubEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		s.calls = append(s.calls, strings.SplitN(text, "\n", 2)[0])
+		v := make([]float32, 4)
+		for _, w := range wordPattern.FindAllString(strings.ToLower(text), -1) {
+			if dim, ok := s.concepts[w]; ok {
+				v[dim]++
+			}
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func TestChunkFile(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoFiles(t, dir, map[string]string{
+		"svc.go":    "package svc\n\nimport \"fmt\"\n\n// Greet says hi\nfunc Greet() { fmt.Println(\"hi\") }\n\ntype Server struct{}\n\nfunc (s *Server) Start() {}\n",
+		"README.md": "intro\n# Setup\nrun it\n## Usage\nuse it\n",
+		"run.sh":    strings.Repeat("echo hi\n", 130),
+	})
+
+	goChunks := chunkFile(dir+"/svc.go", "svc.go")
+	require.Len(t, goChunks, 3)
+	assert.Equal(t, Chunk{Name: "Greet", Line: 5, Text: "// Greet says hi\nfunc Greet() { fmt.Println(\"hi\") }"}, goChunks[0])
+	assert.Equal(t, "Server", goChunks[1].Name)
+	assert.Equal(t, "Server.Start", goChunks[2].Name)
+
+	md := chunkFile(dir+"/README.md", "README.md")
+	require.Len(t, md, 3)
+	assert.Equal(t, "", md[0].Name)
+	assert.Equal(t, "Setup", md[1].Name)
+	assert.Equal(t, 2, md[1].Line)
+	assert.Equal(t, "## Usage\nuse it\n\n", md[2].Text)
+
+	assert.Len(t, chunkFile(dir+"/run.sh", "run.sh"), 3)
+}
+
+func TestSemanticRetriever(t *testing.T) {
+	dir, git := initSymbolRepo(t)
+	writeRepoFiles(t, dir, map[string]string{
+		"internal/client/backoff.go": "package client\n\n// pause tries again later when the server throttles us\nfunc pause() {}\n",
+	})
+	git("add", ".")
+	git("commit", "-m", "Add client")
+
+	idx := New(dir)
+	index, err := idx.BuildIndex()
+	require.NoError(t, err)
+
+	// retry ~ "tries again", rate limited ~ throttled
+	embedder := &stubEmbedder{model: "stub", concepts: map[string]int{"retry": 0, "again": 0, "rate": 1, "throttles": 1}}
+	r := NewSemanticRetriever(dir, embedder)
+	require.NoError(t, r.Update(context.Background(), index))
+	assert.Contains(t, embedder.calls, "internal/client/backoff.go")
+
+	// Prose with no words in common with the file's path or identifiers
+	similarity, err := r.Scores(context.Background(), "retry when rate limited")
+	require.NoError(t, err)
+	best := ""
+	for path, s := range similarity {
+		if best == "" || s > similarity[best] {
+			best = path
+		}
+	}
+	assert.Equal(t, "internal/client/backoff.go", best)
+
+	// Keywords alone miss the file; similarity makes it relevant
+	keywords := ExtractKeywords("Retry when rate limited")
+	assert.NotContains(t, relevantPaths(ScoreFiles(index, keywords)), "internal/client/backoff.go")
+	assert.Contains(t, relevantPaths(ScoreFiles(index, keywords, WithSemantic(similarity))), "internal/client/backoff.go")
+
+	// Only files changed since the stored commit are embedded again
+	writeRepoFiles(t, dir, map[string]string{"internal/other/other.go": "package other\n\nfunc Other() {}\n"})
+	git("commit", "-am", "Rename")
+	index, err = idx.BuildIndex()
+	require.NoError(t, err)
+	embedder.calls = nil
+	require.NoError(t, r.Update(context.Background(), index))
+	assert.Equal(t, []string{"internal/other/other.go"}, embedder.calls)
+
+	// A new model starts over
+	other := &stubEmbedder{model: "stub-2"}
+	require.NoError(t, NewSemanticRetriever(dir, other).Update(context.Background(), index))
+	assert.Len(t, other.calls, countChunks(dir, index))
+}
+
+// relevantPaths returns the paths RelevantFiles keeps
+func relevantPaths(scores []FileScore) []string {
+	var paths []string
+	for _, s := range RelevantFiles(scores) {
+		paths = append(paths, s.Path)
+	}
+	return paths
+}
+
+// countChunks returns how many chunks embedding every indexed file takes
+func countChunks(dir string, index *FileIndex) int {
+	n := 0
+	for path := range index.Files {
+		n += len(chunkFile(dir+"/"+path, path))
+	}
+	return n
+}
+
+func TestAboveAverage(t *testing.T) {
+	got := aboveAverage(map[string]float64{"a": 0.9, "b": 0.5, "c": 0.4})
+	assert.InDelta(t, 1.0, got["a"], 1e-9)
+	assert.NotContains(t, got, "b")
+	assert.NotContains(t, got, "c")
+	assert.Nil(t, aboveAverage(map[string]float64{"a": 0.5, "b": 0.5}))
+}
+
+func TestCosine(t *testing.T) {
+	assert.InDelta(t, 1.0, cosine([]float32{1, 2}, []float32{2, 4}), 1e-9)
+	assert.InDelta(t, 0.0, cosine([]float32{1, 0}, []float32{0, 1}), 1e-9)
+	assert.Equal(t, 0.0, cosine([]float32{1}, []float32{1, 2}))
+}
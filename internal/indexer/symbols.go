@@ -0,0 +1,235 @@
+package indexer
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// symbolDocMaxLen caps the doc comment stored per symbol; the first
+// sentence or two is what keyword matching needs, and file_index.json is
+// loaded on every ticket
+const symbolDocMaxLen = 200
+
+// Symbol kinds recorded in FileMetadata.Symbols
+const (
+	SymbolType   = "type"
+	SymbolFunc   = "func"
+	SymbolMethod = "method"
+	SymbolConst  = "const"
+	SymbolVar    = "var"
+)
+
+// indexSymbols (re)computes Symbols for every indexed Go file in dirs.
+// References are resolved with go/types one package at a time, so a change
+// to any file in a package is reflected in its siblings' reference lists.
+func (idx *Indexer) indexSymbols(index *FileIndex, dirs map[string]bool) {
+	byDir := make(map[string][]string)
+	for relPath := range index.Files {
+		dir := path.Dir(filepath.ToSlash(relPath))
+		if strings.HasSuffix(relPath, ".go") && dirs[dir] {
+			byDir[dir] = append(byDir[dir], relPath)
+		}
+	}
+
+	for _, files := range byDir {
+		sort.Strings(files)
+		for relPath, symbols := range idx.packageSymbols(files) {
+			metadata := index.Files[relPath]
+			metadata.Symbols = symbols
+			index.Files[relPath] = metadata
+		}
+	}
+}
+
+// goDirs returns the directories holding Go files among relPaths
+func goDirs(relPaths []string) map[string]bool {
+	dirs := make(map[string]bool)
+	for _, relPath := range relPaths {
+		if strings.HasSuffix(relPath, ".go") {
+			dirs[path.Dir(filepath.ToSlash(relPath))] = true
+		}
+	}
+	return dirs
+}
+
+// packageSymbols parses the Go files of one directory and returns each
+// file's declared symbols. Files are grouped by package clause (so external
+// _test packages are checked on their own) and type-checked without
+// resolving imports: only same-package references are needed, and type
+// errors from unresolved imports are ignored.
+func (idx *Indexer) packageSymbols(relPaths []string) map[string][]Symbol {
+	fset := token.NewFileSet()
+	groups := make(map[string][]*ast.File)
+	names := make(map[*ast.File]string)
+	for _, relPath := range relPaths {
+		src, err := os.ReadFile(filepath.Join(idx.repoRoot, relPath))
+		if err != nil {
+			continue
+		}
+		file, err := parser.ParseFile(fset, relPath, src, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil && file == nil {
+			continue
+		}
+		groups[file.Name.Name] = append(groups[file.Name.Name], file)
+		names[file] = relPath
+	}
+
+	result := make(map[string][]Symbol, len(names))
+	for pkgName, files := range groups {
+		info := &types.Info{
+			Defs: make(map[*ast.Ident]types.Object),
+			Uses: make(map[*ast.Ident]types.Object),
+		}
+		conf := types.Config{
+			Importer:    emptyImporter{},
+			Error:       func(error) {},
+			FakeImportC: true,
+		}
+		pkg, _ := conf.Check(pkgName, fset, files, info)
+		for _, file := range files {
+			result[names[file]] = fileSymbols(fset, file, pkg, info)
+		}
+	}
+	return result
+}
+
+// fileSymbols lists a file's top-level declarations with their doc comments
+// and the same-package symbols each one references
+func fileSymbols(fset *token.FileSet, file *ast.File, pkg *types.Package, info *types.Info) []Symbol {
+	var symbols []Symbol
+	add := func(name, kind string, doc *ast.CommentGroup, node ast.Node) {
+		symbols = append(symbols, Symbol{
+			Name: name,
+			Kind: kind,
+			Line: fset.Position(node.Pos()).Line,
+			Doc:  symbolDoc(doc),
+			Refs: symbolRefs(node, name, pkg, info),
+		})
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				add(receiverTypeName(d.Recv.List[0].Type)+"."+d.Name.Name, SymbolMethod, d.Doc, d)
+			} else {
+				add(d.Name.Name, SymbolFunc, d.Doc, d)
+			}
+
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					doc := s.Doc
+					if doc == nil && len(d.Specs) == 1 {
+						doc = d.Doc
+					}
+					add(s.Name.Name, SymbolType, doc, s)
+				case *ast.ValueSpec:
+					kind := SymbolVar
+					if d.Tok == token.CONST {
+						kind = SymbolConst
+					}
+					doc := s.Doc
+					if doc == nil {
+						doc = s.Comment
+					}
+					if doc == nil && len(d.Specs) == 1 {
+						doc = d.Doc
+					}
+					for _, name := range s.Names {
+						if name.Name != "_" {
+							add(name.Name, kind, doc, s)
+						}
+					}
+				}
+			}
+		}
+	}
+	return symbols
+}
+
+// symbolDoc returns the first paragraph of a doc comment, capped at
+// symbolDocMaxLen
+func symbolDoc(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	text := strings.TrimSpace(doc.Text())
+	if i := strings.Index(text, "\n\n"); i >= 0 {
+		text = text[:i]
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > symbolDocMaxLen {
+		text = text[:symbolDocMaxLen]
+	}
+	return text
+}
+
+// symbolRefs returns the sorted package-level symbols of pkg that node
+// references, qualified like Symbol.Name, excluding self
+func symbolRefs(node ast.Node, self string, pkg *types.Package, info *types.Info) []string {
+	if pkg == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	ast.Inspect(node, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		obj := info.Uses[ident]
+		if obj == nil || obj.Pkg() != pkg {
+			return true
+		}
+		if name := qualifiedName(obj, pkg); name != "" && name != self {
+			seen[name] = true
+		}
+		return true
+	})
+
+	refs := make([]string, 0, len(seen))
+	for name := range seen {
+		refs = append(refs, name)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// qualifiedName names a package-level object as Symbol.Name does
+// ("Type.Method" for methods), or "" for locals, fields and the like
+func qualifiedName(obj types.Object, pkg *types.Package) string {
+	if fn, ok := obj.(*types.Func); ok {
+		if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+			t := recv.Type()
+			if ptr, ok := t.(*types.Pointer); ok {
+				t = ptr.Elem()
+			}
+			if named, ok := t.(*types.Named); ok {
+				return named.Obj().Name() + "." + fn.Name()
+			}
+			return ""
+		}
+	}
+	if obj.Parent() != pkg.Scope() {
+		return ""
+	}
+	return obj.Name()
+}
+
+// emptyImporter satisfies every import with an empty package, so a package
+// can be type-checked for its own references without its dependencies
+type emptyImporter struct{}
+
+func (emptyImporter) Import(importPath string) (*types.Package, error) {
+	pkg := types.NewPackage(importPath, path.Base(importPath))
+	pkg.MarkComplete()
+	return pkg, nil
+}
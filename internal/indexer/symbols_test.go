@@ -0,0 +1,188 @@
+package indexer
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeRepoFiles writes files (relative path -> content) under dir
+func writeRepoFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		full := filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0644))
+	}
+}
+
+// symbolsByName indexes a file's symbols by name
+func symbolsByName(symbols []Symbol) map[string]Symbol {
+	out := make(map[string]Symbol, len(symbols))
+	for _, s := range symbols {
+		out[s.Name] = s
+	}
+	return out
+}
+
+var symbolRepo = map[string]string{
+	"internal/orchestrator/coordinator.go": `package orchestrator
+
+import "fmt"
+
+// Coordinator runs tickets end to end.
+//
+// Details nobody needs in the index.
+type Coordinator struct{ name string }
+
+// ProcessTicket plans and applies a ticket, retrying on rate limit
+func (c *Coordinator) ProcessTicket(key string) string {
+	return fmt.Sprint(buildBranch(key), maxRetries)
+}
+`,
+	"internal/orchestrator/branch.go": `package orchestrator
+
+const maxRetries = 3 // Attempts per ticket
+
+func buildBranch(key string) string { return "feature/" + key }
+`,
+	"internal/orchestrator/dispatcher.go": `package orchestrator
+
+func dispatch(c *Coordinator) { c.ProcessTicket("PROJ-1") }
+`,
+	"internal/orchestrator/coordinator_test.go": `package orchestrator_test
+
+func helper() {}
+`,
+	"internal/other/other.go": `package other
+
+func ProcessTicket() {}
+`,
+}
+
+func TestBuildIndex_Symbols(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoFiles(t, dir, symbolRepo)
+
+	index, err := New(dir).BuildIndex()
+	require.NoError(t, err)
+
+	coord := symbolsByName(index.Files["internal/orchestrator/coordinator.go"].Symbols)
+	require.Contains(t, coord, "Coordinator")
+	assert.Equal(t, SymbolType, coord["Coordinator"].Kind)
+	assert.Equal(t, "Coordinator runs tickets end to end.", coord["Coordinator"].Doc)
+	require.Contains(t, coord, "Coordinator.ProcessTicket")
+	method := coord["Coordinator.ProcessTicket"]
+	assert.Equal(t, SymbolMethod, method.Kind)
+	assert.Equal(t, 11, method.Line)
+	assert.Equal(t, []string{"Coordinator", "buildBranch", "maxRetries"}, method.Refs)
+
+	branch := symbolsByName(index.Files["internal/orchestrator/branch.go"].Symbols)
+	assert.Equal(t, SymbolConst, branch["maxRetries"].Kind)
+	assert.Equal(t, "Attempts per ticket", branch["maxRetries"].Doc)
+	assert.Equal(t, SymbolFunc, branch["buildBranch"].Kind)
+
+	dispatch := symbolsByName(index.Files["internal/orchestrator/dispatcher.go"].Symbols)
+	assert.Equal(t, []string{"Coordinator", "Coordinator.ProcessTicket"}, dispatch["dispatch"].Refs)
+
+	// The external test package is checked on its own
+	assert.Contains(t, symbolsByName(index.Files["internal/orchestrator/coordinator_test.go"].Symbols), "helper")
+	assert.Empty(t, index.Files["README.md"].Symbols)
+}
+
+func TestScoreFiles_Symbols(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoFiles(t, dir, symbolRepo)
+	index, err := New(dir).BuildIndex()
+	require.NoError(t, err)
+
+	scores := ScoreFiles(index, ExtractKeywords("Fix retries in ProcessTicket"))
+	byPath := make(map[string]float64)
+	for _, s := range scores {
+		byPath[s.Path] = s.Score
+	}
+
+	// coordinator.go declares ProcessTicket even though its name doesn't match
+	assert.Equal(t, "internal/orchestrator/coordinator.go", scores[0].Path)
+	// dispatcher.go calls it, so ranks above an unrelated file in the package
+	assert.Greater(t, byPath["internal/orchestrator/dispatcher.go"], byPath["internal/orchestrator/branch.go"])
+	// The same name in another package matches, but nothing there calls it
+	assert.Greater(t, byPath["internal/other/other.go"], byPath["internal/orchestrator/branch.go"])
+}
+
+// initSymbolRepo commits symbolRepo to a new git repository, returning its
+// root and a git runner
+func initSymbolRepo(t *testing.T) (string, func(args ...string) string) {
+	t.Helper()
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Skipf("git %v failed: %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init")
+	git("config", "user.email", "test@example.com")
+	git("config", "user.name", "Test User")
+	writeRepoFiles(t, dir, symbolRepo)
+	git("add", ".")
+	git("commit", "-m", "Initial commit")
+	return dir, git
+}
+
+func TestUpdateIndex_Symbols(t *testing.T) {
+	dir, git := initSymbolRepo(t)
+	idx := New(dir)
+	index, err := idx.BuildIndex()
+	require.NoError(t, err)
+	require.NoError(t, idx.SaveIndex(index))
+
+	// Only branch.go changes, but its whole package is re-resolved
+	writeRepoFiles(t, dir, map[string]string{
+		"internal/orchestrator/branch.go": `package orchestrator
+
+func branchName(key string) string { return "feature/" + key }
+
+func buildBranch(key string) string { return branchName(key) }
+`,
+	})
+	git("commit", "-am", "Extract branchName")
+
+	updated, err := idx.UpdateIndex()
+	require.NoError(t, err)
+	branch := symbolsByName(updated.Files["internal/orchestrator/branch.go"].Symbols)
+	require.Contains(t, branch, "branchName")
+	assert.Equal(t, []string{"branchName"}, branch["buildBranch"].Refs)
+	assert.Equal(t, 5, branch["buildBranch"].Line)
+	// coordinator.go is unchanged, but maxRetries no longer resolves
+	coord := symbolsByName(updated.Files["internal/orchestrator/coordinator.go"].Symbols)
+	assert.Equal(t, []string{"Coordinator", "buildBranch"}, coord["Coordinator.ProcessTicket"].Refs)
+}
+
+func TestUpdateIndex_RebuildsOldVersion(t *testing.T) {
+	dir, git := initSymbolRepo(t)
+	idx := New(dir)
+	// An index from before symbols existed, at the current commit
+	old := &FileIndex{Version: "1.0", GitCommitHash: git("rev-parse", "HEAD"), Files: map[string]FileMetadata{
+		"internal/orchestrator/coordinator.go": {Path: "internal/orchestrator/coordinator.go"},
+	}}
+	require.NoError(t, idx.SaveIndex(old))
+
+	index, err := idx.UpdateIndex()
+	require.NoError(t, err)
+	assert.Equal(t, IndexVersion, index.Version)
+	assert.NotEmpty(t, index.Files["internal/orchestrator/coordinator.go"].Symbols)
+
+	// RebuildIfStale reports the rebuild so callers save it
+	_, updated, err := idx.RebuildIfStale()
+	require.NoError(t, err)
+	assert.True(t, updated)
+}
@@ -7,9 +7,11 @@ type FileIndex struct {
 	Version       string                  `json:"version"`
 	IndexedAt     time.Time               `json:"indexed_at"`
 	RepoRoot      string                  `json:"repo_root"`
-	GitCommitHash string                  `json:"git_commit_hash"` // Git commit at indexing time
+	GitCommitHash string                  `json:"git_commit_hash"`        // Git commit at indexing time
+	WorkingTree   map[string]string       `json:"working_tree,omitempty"` // Uncommitted files at indexing time -> content fingerprint (see WorkingTreeChanges)
 	Files         map[string]FileMetadata `json:"files"`
 	Modules       map[string][]string     `json:"modules"`
+	Terms         *TermIndex              `json:"-"` // Saved separately as term_index.json; nil if missing
 }
 
 // FileMetadata contains metadata about a single file
@@ -20,7 +22,17 @@ type FileMetadata struct {
 	Category     string    `json:"category"`     // "core", "util", "test", "config", "doc"
 	Dependencies []string  `json:"dependencies"` // List of imported packages/modules
 	LastModified time.Time `json:"last_modified"`
-	Summary      string    `json:"summary"` // Brief description of file purpose
+	Summary      string    `json:"summary"`           // Brief description of file purpose
+	Symbols      []Symbol  `json:"symbols,omitempty"` // Top-level declarations (Go files only)
+}
+
+// Symbol is a top-level declaration in a Go file
+type Symbol struct {
+	Name string   `json:"name"` // "Name", or "Type.Method" for methods
+	Kind string   `json:"kind"` // SymbolType, SymbolFunc, SymbolMethod, SymbolConst or SymbolVar
+	Line int      `json:"line"`
+	Doc  string   `json:"doc,omitempty"`  // First paragraph of the doc comment
+	Refs []string `json:"refs,omitempty"` // Same-package symbols it references (the call/reference graph)
 }
 
 // ContextStrategy defines how context should be loaded
@@ -35,7 +47,7 @@ const (
 type ContextTier int
 
 const (
-	ContextTierBaseline ContextTier = 0 // PROJECT_INDEX.md + CLAUDE.md (~5-10KB)
+	ContextTierBaseline ContextTier = 0 // PROJECT_INDEX.md (repository map, see BuildRepoMap) + CLAUDE.md (~5-10KB)
 	ContextTierSmart    ContextTier = 1 // Smart subset based on ticket (~50-100KB)
 	ContextTierFull     ContextTier = 2 // Full context fallback (~500KB-1MB)
 )
@@ -53,6 +65,7 @@ type ContextBuildOptions struct {
 
 // FileScore represents a file's relevance score for a given ticket
 type FileScore struct {
-	Path  string
-	Score float64
+	Path    string
+	Score   float64
+	Matched bool // Some keyword matched the path, a symbol or the content
 }
@@ -22,12 +22,18 @@ type Entry struct {
 	Summary      string    `json:"summary"`
 	Branch       string    `json:"branch"`
 	PRURL        string    `json:"pr_url,omitempty"`
-	Merged       bool      `json:"merged"` // updated lazily, see below
+	LinkedPRs    []string  `json:"linked_prs,omitempty"` // same ticket's PRs in other repositories
+	Merged       bool      `json:"merged"`               // updated lazily, see below
 	FilesChanged []string  `json:"files_changed"`
 	PublicAPIs   []string  `json:"public_apis,omitempty"` // new exported symbols
 	Notes        string    `json:"notes,omitempty"`       // model-written, <=5 lines
 	Keywords     []string  `json:"keywords"`              // precomputed at write time
 	Timestamp    time.Time `json:"timestamp"`
+
+	// RefreshBlockedAt is the base commit at which updating the branch was
+	// left for a human (conflict or failing gates), so branch maintenance
+	// doesn't retry - or comment again - until the base moves further.
+	RefreshBlockedAt string `json:"refresh_blocked_at,omitempty"`
 }
 
 type Journal struct {
@@ -47,6 +53,25 @@ func Load(repoRoot string) *Journal {
 func (j *Journal) Append(e Entry) error {
 	j.mu.Lock()
 	defer j.mu.Unlock()
+	return j.appendLocked(e)
+}
+
+// Upsert records e as the entry for its ticket, replacing any earlier one,
+// so a ticket re-run after a failure still has a single, latest entry.
+func (j *Journal) Upsert(e Entry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	kept := j.Entries[:0]
+	for _, old := range j.Entries {
+		if old.TicketKey != e.TicketKey {
+			kept = append(kept, old)
+		}
+	}
+	j.Entries = kept
+	return j.appendLocked(e)
+}
+
+func (j *Journal) appendLocked(e Entry) error {
 	e.Keywords = indexer.ExtractKeywords(e.Summary + " " + e.Notes + " " + strings.Join(e.FilesChanged, " "))
 	j.Entries = append(j.Entries, e)
 	if len(j.Entries) > maxEntries {
@@ -100,6 +125,44 @@ func (j *Journal) Find(ticketKey string) (Entry, bool) {
 	return Entry{}, false
 }
 
+// Open returns the latest entry for each branch whose PR hasn't merged,
+// oldest first. Whether the PR is still open (not closed unmerged) is up to
+// the caller to check against the VCS.
+func (j *Journal) Open() []Entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	seen := make(map[string]bool)
+	var out []Entry
+	for i := len(j.Entries) - 1; i >= 0; i-- {
+		e := j.Entries[i]
+		if e.PRURL == "" || e.Branch == "" || seen[e.Branch] {
+			continue
+		}
+		seen[e.Branch] = true
+		if !e.Merged {
+			out = append(out, e)
+		}
+	}
+	for i, k := 0, len(out)-1; i < k; i, k = i+1, k-1 {
+		out[i], out[k] = out[k], out[i]
+	}
+	return out
+}
+
+// SetRefreshBlocked records on the latest entry for branch that refreshing
+// it onto baseHash needs a human; see Entry.RefreshBlockedAt.
+func (j *Journal) SetRefreshBlocked(branch, baseHash string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i := len(j.Entries) - 1; i >= 0; i-- {
+		if j.Entries[i].Branch == branch {
+			j.Entries[i].RefreshBlockedAt = baseHash
+			return j.saveLocked()
+		}
+	}
+	return nil
+}
+
 // saveLocked performs an atomic write of the journal file (temp file +
 // rename, mirroring State.saveUnlocked). Must be called with j.mu held.
 func (j *Journal) saveLocked() error {
@@ -203,6 +266,9 @@ func Render(entries []Entry) string {
 			merged = "merged"
 		}
 		b.WriteString("- Status: " + merged + " | Branch: " + e.Branch + "\n")
+		if len(e.LinkedPRs) > 0 {
+			b.WriteString("- Linked PRs in other repositories: " + strings.Join(e.LinkedPRs, ", ") + "\n")
+		}
 		b.WriteString("- Files: " + strings.Join(e.FilesChanged, ", ") + "\n")
 		if len(e.PublicAPIs) > 0 {
 			b.WriteString("- New APIs: " + strings.Join(e.PublicAPIs, ", ") + "\n")
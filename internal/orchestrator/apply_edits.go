@@ -7,22 +7,42 @@ import (
 	"strings"
 
 	"intern/internal/ai/agent"
+	"intern/internal/indexer"
 )
 
-// applyEditChange applies search/replace hunks to an existing file.
+// applyEditChange applies search/replace hunks to an existing file. shown
+// lists the lines of the file the AI saw when it was shown as an excerpt
+// (nil when it saw the whole file): each old block must then lie within one
+// of them, and may carry the excerpt's line number prefixes.
 // Returns a descriptive error suitable for feeding back to the AI on failure.
-func applyEditChange(repoRoot string, ch agent.CodeChange) error {
+func applyEditChange(repoRoot string, ch agent.CodeChange, shown []indexer.LineRange) error {
 	abs := filepath.Join(repoRoot, ch.Path)
 	data, err := os.ReadFile(abs)
 	if err != nil {
 		return fmt.Errorf("edit target %s: %w (did you mean operation=create?)", ch.Path, err)
 	}
 	content := string(data)
+	original := content
 
 	for i, hunk := range ch.Edits {
 		if strings.TrimSpace(hunk.Old) == "" {
 			return fmt.Errorf("%s hunk %d: empty old block", ch.Path, i+1)
 		}
+		if len(shown) > 0 {
+			if !strings.Contains(content, hunk.Old) {
+				if old, ok := indexer.StripLineNumbers(hunk.Old); ok {
+					hunk.Old = old
+					if new, ok := indexer.StripLineNumbers(hunk.New); ok {
+						hunk.New = new
+					}
+				}
+			}
+			if start, end, ok := locateBlock(original, hunk.Old); ok && !withinRanges(shown, start, end) {
+				return fmt.Errorf(
+					"%s hunk %d: old block (lines %d-%d) lies outside the excerpt shown (%s); edit only within the lines shown",
+					ch.Path, i+1, start, end, indexer.FormatLineRanges(shown))
+			}
+		}
 		switch n := strings.Count(content, hunk.Old); n {
 		case 1:
 			content = strings.Replace(content, hunk.Old, hunk.New, 1)
@@ -52,10 +72,24 @@ func applyEditChange(repoRoot string, ch agent.CodeChange) error {
 func replaceNormalized(content, old, new string) (string, bool) {
 	cLines := strings.Split(content, "\n")
 	oLines := strings.Split(strings.TrimRight(old, "\n"), "\n")
-	if len(oLines) == 0 || len(oLines) > len(cLines) {
+	matchStart := matchNormalized(cLines, oLines)
+	if matchStart == -1 {
 		return content, false
 	}
 
+	out := append([]string{}, cLines[:matchStart]...)
+	out = append(out, strings.Split(strings.TrimRight(new, "\n"), "\n")...)
+	out = append(out, cLines[matchStart+len(oLines):]...)
+	return strings.Join(out, "\n"), true
+}
+
+// matchNormalized returns the index of the only run of cLines equal to
+// oLines with per-line whitespace trimmed, or -1 if there are zero or
+// several.
+func matchNormalized(cLines, oLines []string) int {
+	if len(oLines) == 0 || len(oLines) > len(cLines) {
+		return -1
+	}
 	matchStart := -1
 	for i := 0; i+len(oLines) <= len(cLines); i++ {
 		ok := true
@@ -67,19 +101,42 @@ func replaceNormalized(content, old, new string) (string, bool) {
 		}
 		if ok {
 			if matchStart != -1 {
-				return content, false // ambiguous
+				return -1 // ambiguous
 			}
 			matchStart = i
 		}
 	}
-	if matchStart == -1 {
-		return content, false
+	return matchStart
+}
+
+// locateBlock returns the 1-based lines old occupies in content, matched
+// the way applyEditChange matches it. ok is false unless it matches exactly
+// one place.
+func locateBlock(content, old string) (start, end int, ok bool) {
+	oLines := strings.Split(strings.TrimRight(old, "\n"), "\n")
+	switch strings.Count(content, old) {
+	case 1:
+		start = strings.Count(content[:strings.Index(content, old)], "\n") + 1
+	case 0:
+		i := matchNormalized(strings.Split(content, "\n"), oLines)
+		if i == -1 {
+			return 0, 0, false
+		}
+		start = i + 1
+	default:
+		return 0, 0, false
 	}
+	return start, start + len(oLines) - 1, true
+}
 
-	out := append([]string{}, cLines[:matchStart]...)
-	out = append(out, strings.Split(strings.TrimRight(new, "\n"), "\n")...)
-	out = append(out, cLines[matchStart+len(oLines):]...)
-	return strings.Join(out, "\n"), true
+// withinRanges reports whether lines start..end lie within one of ranges
+func withinRanges(ranges []indexer.LineRange, start, end int) bool {
+	for _, r := range ranges {
+		if r.Contains(start, end) {
+			return true
+		}
+	}
+	return false
 }
 
 func truncateForErr(s string, max int) string {
@@ -0,0 +1,84 @@
+package orchestrator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"intern/internal/ai/agent"
+	"intern/internal/indexer"
+)
+
+const excerptedSource = `package billing
+
+func Ship() {
+	dispatch()
+}
+
+// Invoice totals an invoice
+func Invoice() int {
+	return 42
+}
+`
+
+func TestApplyEditChange_Excerpt(t *testing.T) {
+	shown := []indexer.LineRange{{Start: 6, End: 11}} // Invoice, not Ship
+
+	tests := []struct {
+		name    string
+		edit    agent.EditHunk
+		shown   []indexer.LineRange
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "within the excerpt",
+			edit:  agent.EditHunk{Old: "\treturn 42\n", New: "\treturn 43\n"},
+			shown: shown,
+			want:  strings.Replace(excerptedSource, "42", "43", 1),
+		},
+		{
+			name:  "copied with line numbers",
+			edit:  agent.EditHunk{Old: "    8| func Invoice() int {\n    9| \treturn 42", New: "    8| func Invoice() int {\n    9| \treturn 43"},
+			shown: shown,
+			want:  strings.Replace(excerptedSource, "42", "43", 1),
+		},
+		{
+			name:    "outside the excerpt",
+			edit:    agent.EditHunk{Old: "\tdispatch()\n", New: "\tdispatch(true)\n"},
+			shown:   shown,
+			wantErr: "old block (lines 4-4) lies outside the excerpt shown (lines 6-11)",
+		},
+		{
+			name: "whole file shown",
+			edit: agent.EditHunk{Old: "\tdispatch()\n", New: "\tdispatch(true)\n"},
+			want: strings.Replace(excerptedSource, "dispatch()", "dispatch(true)", 1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "billing.go"), []byte(excerptedSource), 0644); err != nil {
+				t.Fatal(err)
+			}
+			change := agent.CodeChange{Path: "billing.go", Operation: agent.OperationEdit, Edits: []agent.EditHunk{tt.edit}}
+
+			err := applyEditChange(dir, change, tt.shown)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("applyEditChange() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyEditChange() error = %v", err)
+			}
+			got, _ := os.ReadFile(filepath.Join(dir, "billing.go"))
+			if string(got) != tt.want {
+				t.Errorf("content = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
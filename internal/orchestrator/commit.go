@@ -0,0 +1,64 @@
+package orchestrator
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"intern/internal/config"
+	"intern/internal/ticketing"
+)
+
+// commitMessage appends git trailers to subject linking the commit to its
+// ticket and the people behind it: Ticket, Requested-by for the reporter,
+// and Co-authored-by for COMMIT_CO_AUTHORS plus the reporter when
+// COMMIT_REPORTER_EMAIL_MAP has their email (which is what makes GitHub
+// credit them on the commit).
+func commitMessage(cfg *config.Config, ticket ticketing.Ticket, subject string) string {
+	var trailers []string
+	if ticket.Key != "" {
+		trailers = append(trailers, "Ticket: "+ticket.Key)
+	}
+
+	var coAuthors []string
+	seen := make(map[string]bool)
+	addCoAuthor := func(name, email string) {
+		if key := strings.ToLower(email); !seen[key] {
+			seen[key] = true
+			coAuthors = append(coAuthors, fmt.Sprintf("Co-authored-by: %s <%s>", name, email))
+		}
+	}
+
+	if reporter := strings.TrimSpace(ticket.Reporter); reporter != "" {
+		if email := reporterEmail(cfg.CommitReporterEmailMap, reporter); email != "" {
+			trailers = append(trailers, fmt.Sprintf("Requested-by: %s <%s>", reporter, email))
+			addCoAuthor(reporter, email)
+		} else {
+			trailers = append(trailers, "Requested-by: "+reporter)
+		}
+	}
+	for _, entry := range cfg.CommitCoAuthors {
+		if addr, err := mail.ParseAddress(entry); err == nil {
+			addCoAuthor(addr.Name, addr.Address)
+		}
+	}
+	trailers = append(trailers, coAuthors...)
+
+	if len(trailers) == 0 {
+		return subject
+	}
+	return subject + "\n\n" + strings.Join(trailers, "\n") + "\n"
+}
+
+// reporterEmail looks reporter up in emails case-insensitively, like
+// reporterLogin does for GitHub logins
+func reporterEmail(emails map[string]string, reporter string) string {
+	for name, email := range emails {
+		if strings.EqualFold(name, reporter) {
+			if addr, err := mail.ParseAddress(email); err == nil {
+				return addr.Address
+			}
+		}
+	}
+	return ""
+}
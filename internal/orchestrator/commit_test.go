@@ -0,0 +1,29 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"intern/internal/config"
+	"intern/internal/ticketing"
+)
+
+func TestCommitMessage(t *testing.T) {
+	cfg := &config.Config{
+		CommitCoAuthors:        []string{"Ops Bot <ops@example.com>", "Jane Doe <JANE@example.com>"},
+		CommitReporterEmailMap: map[string]string{"jane doe": "jane@example.com"},
+	}
+	got := commitMessage(cfg, ticketing.Ticket{Key: "PROJ-1", Reporter: "Jane Doe"}, "feat(PROJ-1): apply planned changes")
+	want := "feat(PROJ-1): apply planned changes\n\n" +
+		"Ticket: PROJ-1\n" +
+		"Requested-by: Jane Doe <jane@example.com>\n" +
+		"Co-authored-by: Jane Doe <jane@example.com>\n" +
+		"Co-authored-by: Ops Bot <ops@example.com>\n"
+	if got != want {
+		t.Errorf("Unexpected message:\n%s\nwant:\n%s", got, want)
+	}
+
+	got = commitMessage(&config.Config{}, ticketing.Ticket{Key: "PROJ-2", Reporter: "Bob"}, "fix(PROJ-2): x")
+	if want := "fix(PROJ-2): x\n\nTicket: PROJ-2\nRequested-by: Bob\n"; got != want {
+		t.Errorf("Expected an unmapped reporter without a co-author trailer, got:\n%s", got)
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"intern/internal/indexer"
 	"intern/internal/journal"
 	"intern/internal/repository"
+	"intern/internal/sandbox"
 	"intern/internal/ticketing"
 
 	logger "github.com/jenish-jain/logger"
@@ -30,6 +31,9 @@ type Coordinator struct {
 	Metrics    *Metrics
 	RepoPaths  *repository.RepositoryPath // Centralized path management
 	Journal    *journal.Journal           // Cross-ticket continuity log
+	Executor   sandbox.Executor           // Runs quality-gate commands (sandboxed unless disabled)
+	Escalation []agent.Tier               // Model ladder for planning/self-healing; empty means Agent only
+	Semantic   *indexer.SemanticRetriever // Embedding-based file retrieval; nil disables it
 
 	ticketMetricsMu sync.Mutex
 	ticketMetrics   map[string]*TicketMetrics // last-known metrics per ticket key, for request-driven callers (see LastTicketMetrics)
@@ -45,6 +49,7 @@ func NewCoordinator(ticketing *ticketing.Service, repository *repository.Reposit
 		Metrics:       NewMetrics(),
 		RepoPaths:     repoPaths,
 		Journal:       journal.Load(repoPaths.Root()),
+		Executor:      sandbox.New(sandboxConfig(cfg)),
 		ticketMetrics: make(map[string]*TicketMetrics),
 	}
 }
@@ -61,152 +66,29 @@ func (c *Coordinator) LastTicketMetrics(key string) (*TicketMetrics, bool) {
 	return tm, ok
 }
 
+// gateExecutor returns the executor for quality-gate commands, building one
+// from config for Coordinators constructed without NewCoordinator.
+func (c *Coordinator) gateExecutor() sandbox.Executor {
+	if c.Executor == nil {
+		return sandbox.New(sandboxConfig(c.Cfg))
+	}
+	return c.Executor
+}
+
 func (c *Coordinator) storeTicketMetrics(key string, tm *TicketMetrics) {
 	c.ticketMetricsMu.Lock()
 	defer c.ticketMetricsMu.Unlock()
 	c.ticketMetrics[key] = tm
 }
 
+// Run polls for tickets assigned to the agent and processes them against
+// this Coordinator's repository until ctx is cancelled. Deployments working
+// on several repositories use a Dispatcher instead.
 func (c *Coordinator) Run(ctx context.Context) {
-	interval, err := time.ParseDuration(c.Cfg.PollingInterval)
-	if err != nil {
-		interval = 30 * time.Second
-	}
-
-	// Ensure working directory exists
-	_ = os.MkdirAll(c.RepoPaths.WorkingDir(), 0755)
-
-	// Start metrics server if enabled
-	if c.Cfg.MetricsEnabled {
-		metricsServer := NewMetricsServer(c.Metrics, c.Cfg.MetricsPort)
-		go func() {
-			if err := metricsServer.Start(ctx); err != nil {
-				logger.Error("Metrics server failed", "error", err)
-			}
-		}()
-	}
-
-	// Print final summary and save metrics on shutdown
-	defer func() {
-		snapshot := c.Metrics.Snapshot()
-
-		// Skip if no tickets were processed
-		if snapshot.TicketsProcessed == 0 {
-			logger.Info("Agent shutting down (no tickets processed)")
-			return
-		}
-
-		logger.Info("Agent shutting down - generating final report")
-
-		// Print summary report to console
-		report := GenerateReport(snapshot)
-		fmt.Println("\n" + report)
-
-		// Save metrics to JSON
-		repoRoot := c.RepoPaths.Root()
-		// Note: We don't have access to individual ticket metrics here yet
-		// This will be enhanced in a future iteration to collect them
-		metricsFile, err := SaveMetrics(snapshot, []TicketMetrics{}, repoRoot)
-		if err != nil {
-			logger.Error("Failed to save metrics", "error", err)
-		} else {
-			fmt.Printf("\nDetailed metrics saved to: %s\n\n", metricsFile)
-		}
-	}()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			// Ensure local repo is up to date before each cycle
-			if err := c.prepareRepository(ctx); err != nil {
-				logger.Error("Repository preparation failed", "error", err)
-				backoffSleep(interval)
-				continue
-			}
-
-			// Reconcile journal entries: flip Merged for PRs that landed since
-			// the last cycle, so deferred tickets can unblock.
-			if updated, err := c.Journal.Reconcile(ctx, c.Repository.IsPRMerged); err != nil {
-				logger.Warn("Journal reconciliation failed", "error", err)
-			} else if updated > 0 {
-				logger.Info("Journal reconciliation: marked PRs as merged", "count", updated)
-			}
-
-			tickets, err := func() ([]ticketing.Ticket, error) {
-				var out []ticketing.Ticket
-				err, attempts := Retry(ctx, BackoffConfig{Initial: time.Second, Max: 10 * time.Second, Multiplier: 2, Jitter: 0.2, MaxRetries: 3}, func() error {
-					t, e := c.Ticketing.GetTickets(ctx, c.Cfg.AgentUsername, c.Cfg.JiraProject)
-					if e != nil {
-						return MakeTransient(e)
-					}
-					out = t
-					return nil
-				})
-				c.Metrics.AddRetries(attempts)
-				return out, err
-			}()
-			if err != nil {
-				logger.Error("Failed to fetch tickets", "error", err)
-				backoffSleep(interval)
-				continue
-			}
-			if len(tickets) == 0 {
-				logger.Info("No tickets to process; sleeping", "interval", interval.String())
-				time.Sleep(interval)
-				continue
-			}
-
-			maxWorkers := c.Cfg.MaxConcurrentTickets
-			if maxWorkers <= 0 {
-				maxWorkers = 1
-			}
-			sem := make(chan struct{}, maxWorkers)
-			var wg sync.WaitGroup
-			for _, t := range tickets {
-				if c.State.IsProcessed(t.Key) {
-					continue
-				}
-				if blocker := c.journalBlocker(t.Summary + " " + t.Description); blocker != "" {
-					logger.Info("Deferring ticket - related work not yet merged", "ticket", t.Key, "waiting_on", blocker)
-					continue
-				}
-				sem <- struct{}{}
-				wg.Add(1)
-				go func(key, summary, description string) {
-					// Ensure cleanup happens even on panic
-					defer wg.Done()
-					defer func() { <-sem }()
-
-					// Panic recovery - catch and log panics without crashing agent
-					defer func() {
-						if r := recover(); r != nil {
-							logger.Error("Worker panic recovered", "ticket", key, "panic", r)
-							c.Metrics.IncTicketsFailed()
-							// Panic recovered - ticket will not be marked as processed
-							// It will be retried in next polling cycle
-						}
-					}()
-
-					// Process the ticket
-					if err := c.processTicket(ctx, key, summary, description); err != nil {
-						logger.Error("Failed processing ticket", "key", key, "error", err)
-						c.Metrics.IncTicketsFailed()
-						return
-					}
-
-					// Only mark as processed if successful
-					c.State.MarkProcessed(key)
-				}(t.Key, t.Summary, t.Description)
-			}
-			wg.Wait()
-			// log metrics summary
-			s := c.Metrics.Snapshot()
-			logger.Info("Run summary", "tickets", s.TicketsProcessed, "prs", s.PRsCreated, "retries", s.Retries, "ai_failures", s.AIPlanFailures)
-			time.Sleep(interval)
-		}
-	}
+	NewDispatcher(c.Ticketing, c.Cfg, c.State, RepoTarget{
+		Config:      config.RepoConfig{Name: c.Cfg.GitHubRepo, Owner: c.Cfg.GitHubOwner, Repo: c.Cfg.GitHubRepo},
+		Coordinator: c,
+	}).Run(ctx)
 }
 
 func backoffSleep(base time.Duration) {
@@ -244,7 +126,7 @@ func (c *Coordinator) PrepareRepository(ctx context.Context) error {
 // driven trigger sources (Slack webhook, Cloud Run handler) can invoke it
 // directly for one ticket without going through Run()'s JIRA polling loop.
 func (c *Coordinator) ProcessTicket(ctx context.Context, key, summary, description string) error {
-	if err := c.processTicket(ctx, key, summary, description); err != nil {
+	if err := c.processTicket(ctx, ticketing.Ticket{Key: key, Summary: summary, Description: description}); err != nil {
 		return err
 	}
 	c.State.MarkProcessed(key)
@@ -259,10 +141,7 @@ func (c *Coordinator) prepareRepository(ctx context.Context) error {
 			return err
 		}
 	}
-	base := c.Cfg.BaseBranch
-	if base == "" {
-		base = "main"
-	}
+	base := c.baseBranch()
 	if err := c.Repository.SwitchBranch(ctx, base); err != nil {
 		// Switching to base branch is critical - we need to be on the right branch
 		// before creating feature branches
@@ -277,7 +156,126 @@ func (c *Coordinator) prepareRepository(ctx context.Context) error {
 	return nil
 }
 
-func (c *Coordinator) processTicket(ctx context.Context, key, summary, description string) error {
+// refreshIndex builds or incrementally updates the repository's file index,
+// which drives smart context selection and ticket routing, and the
+// embeddings behind semantic retrieval if enabled. It returns the index, or
+// nil if it couldn't be built.
+func (c *Coordinator) refreshIndex(ctx context.Context) *indexer.FileIndex {
+	idx := indexer.New(c.RepoPaths.Root())
+	fileIndex, wasUpdated, indexErr := idx.RebuildIfStale()
+	if indexErr != nil {
+		logger.Warn("Failed to build/update index, smart context may fall back to simple", "error", indexErr)
+		return nil
+	}
+	if wasUpdated {
+		logger.Info("Index built/updated successfully", "files", len(fileIndex.Files))
+		// Save the updated index
+		if saveErr := idx.SaveIndex(fileIndex); saveErr != nil {
+			logger.Warn("Failed to save index", "error", saveErr)
+		}
+	} else {
+		logger.Debug("Index already up to date")
+	}
+
+	if c.Semantic != nil {
+		if err := c.Semantic.Update(ctx, fileIndex); err != nil {
+			logger.Warn("Failed to update embeddings, semantic retrieval may be incomplete", "error", err)
+		}
+	}
+	return fileIndex
+}
+
+// repoMap returns the repository map that heads every planning prompt's
+// context, giving the model the overall layout of the repository the
+// selected files sit in, sized for a context of tokens; "" when disabled or
+// there is no index
+func (c *Coordinator) repoMap(fileIndex *indexer.FileIndex, tokens int) string {
+	if !c.Cfg.RepoMapEnabled || fileIndex == nil {
+		return ""
+	}
+	repoMap, err := indexer.New(c.RepoPaths.Root()).GetOrBuildRepoMap(fileIndex, ai.RepoMapBytes(tokens))
+	if err != nil {
+		// Only caching failed; the map is still usable
+		logger.Warn("Failed to cache repository map", "error", err)
+	}
+	if repoMap == "" {
+		return ""
+	}
+	return repoMap + "\n"
+}
+
+// semanticScoring returns the option blending embedding similarity to the
+// ticket into file scoring, or nothing when semantic retrieval is disabled
+// or unavailable (keyword scoring carries on alone)
+func (c *Coordinator) semanticScoring(ctx context.Context, key, text string) []indexer.ScoreOption {
+	if c.Semantic == nil {
+		return nil
+	}
+	similarity, err := c.Semantic.Scores(ctx, text)
+	if err != nil {
+		logger.Warn("Semantic retrieval unavailable for ticket", "ticket", key, "error", err)
+		return nil
+	}
+	return []indexer.ScoreOption{indexer.WithSemantic(similarity)}
+}
+
+// processTicket runs the full pipeline for a ticket in this repository:
+// stage the change, then push it and open a PR (or just log, in dry-run)
+func (c *Coordinator) processTicket(ctx context.Context, ticket ticketing.Ticket) error {
+	st, err := c.stageTicket(ctx, ticket, "")
+	if err == errGatesFailed {
+		// Left for a human: retrying would only spend more on the same failure
+		return nil
+	}
+	if err != nil || st == nil {
+		return err
+	}
+
+	if c.Cfg.DryRun {
+		c.logDryRun(st)
+		// MarkProcessed automatically saves the state, so no need to call Save() explicitly
+		c.State.MarkProcessed(st.ticket.Key)
+	} else {
+		if err := c.publishTicket(ctx, st); err != nil {
+			return err
+		}
+		c.recordTicket(st, nil)
+	}
+
+	// Mark Done (even in dry-run, to avoid reprocessing)
+	if err := c.Ticketing.UpdateTicketStatus(ctx, st.ticket.Key, "Done", c.Cfg.JiraTransitions); err != nil {
+		logger.Error("Failed to move ticket to Done", "error", err)
+	}
+	return nil
+}
+
+// stagedTicket is a ticket whose changes are committed on its local branch
+// and pass the quality gates, ready to be pushed and opened as a PR
+type stagedTicket struct {
+	ticket     ticketing.Ticket
+	branch     string
+	startTime  time.Time
+	metrics    *TicketMetrics
+	usage      *agent.UsageMetrics
+	valid      []agent.CodeChange
+	beforeAPIs map[string][]string
+	healResult *SelfHealingResult
+	gateNotes  []string
+
+	// Set by publishTicket
+	prURL   string
+	prTitle string
+	prData  PRBodyData
+	prDraft bool // Opened as a draft on purpose (PR_DRAFT or pending approvals)
+}
+
+// stageTicket runs the pipeline up to the point of no return: branch, plan,
+// apply, commit, self-heal and gate. preamble is prepended to the planning
+// context. Returns nil without error when the plan has no effective
+// changes and the branch has none against the base either, and
+// errGatesFailed when the gates still fail after healing.
+func (c *Coordinator) stageTicket(ctx context.Context, ticket ticketing.Ticket, preamble string) (*stagedTicket, error) {
+	key, summary, description := ticket.Key, ticket.Summary, ticket.Description
 	startTime := time.Now()
 
 	// Recorded early and updated in place (rather than replaced) as the
@@ -288,48 +286,59 @@ func (c *Coordinator) processTicket(ctx context.Context, key, summary, descripti
 	c.storeTicketMetrics(key, ticketMetrics)
 
 	branchName := buildBranchName(c.Cfg.BranchPrefix, key)
-	logger.Info("Creating branch", "branch", branchName)
-	if err := c.Repository.CreateBranch(ctx, branchName); err != nil {
-		return errors.NewRepoBranchError(err, branchName, "create").
-			WithContext("ticket_key", key)
+	// Failed tickets are retried every cycle, so a branch (and PR) from an
+	// earlier run may already exist: continue on top of it rather than
+	// cutting a fresh branch that could only be pushed by clobbering it.
+	existing, err := c.Repository.CheckoutRemoteBranch(ctx, branchName)
+	if err != nil {
+		logger.Warn("Failed to check for an existing remote branch, creating it fresh", "branch", branchName, "error", err)
+		existing = false
 	}
-	if err := c.Repository.SwitchBranch(ctx, branchName); err != nil {
-		return errors.NewRepoBranchError(err, branchName, "switch to").
-			WithContext("ticket_key", key)
+	if existing {
+		logger.Info("Continuing existing branch", "branch", branchName)
+	} else {
+		logger.Info("Creating branch", "branch", branchName)
+		if err := c.Repository.CreateBranch(ctx, branchName); err != nil {
+			return nil, errors.NewRepoBranchError(err, branchName, "create").
+				WithContext("ticket_key", key)
+		}
+		if err := c.Repository.SwitchBranch(ctx, branchName); err != nil {
+			return nil, errors.NewRepoBranchError(err, branchName, "switch to").
+				WithContext("ticket_key", key)
+		}
 	}
 
 	// Checkpoint 1: Check for cancellation before expensive operations
 	if err := checkContext(ctx, key, "after branch setup"); err != nil {
-		return err
+		return nil, err
 	}
 
 	repoRoot := c.RepoPaths.Root()
 
 	// Build or update index for smart context selection
-	idx := indexer.New(repoRoot)
-	fileIndex, wasUpdated, indexErr := idx.RebuildIfStale()
-	if indexErr != nil {
-		logger.Warn("Failed to build/update index, smart context may fall back to simple", "error", indexErr)
-	} else {
-		if wasUpdated {
-			logger.Info("Index built/updated successfully", "files", len(fileIndex.Files))
-			// Save the updated index
-			if saveErr := idx.SaveIndex(fileIndex); saveErr != nil {
-				logger.Warn("Failed to save index", "error", saveErr)
-			}
-		} else {
-			logger.Debug("Index already up to date")
-		}
+	fileIndex := c.refreshIndex(ctx)
+	scoreOpts := c.semanticScoring(ctx, key, summary+"\n"+description)
+
+	budget := ai.ContextBudget{
+		MaxFiles:        c.Cfg.ContextMaxFiles,
+		MaxBytesPerFile: c.Cfg.ContextMaxBytes,
+		Tokens:          c.contextTokenBudget(),
 	}
 
 	// Prior-work continuity: surface recent related tickets (and whether their
 	// PRs have merged) so the model builds on existing work instead of
-	// duplicating or contradicting it.
-	priorWork := journal.Render(c.Journal.Relevant(summary+" "+description, 3))
+	// duplicating or contradicting it. The repository map comes first, so
+	// every planning prompt opens with how the repository is organised. All
+	// of it comes out of the token budget before files are packed.
+	priorWork := c.repoMap(fileIndex, budget.Tokens) + preamble + journal.Render(c.Journal.Relevant(summary+" "+description, 3))
+	if budget.Tokens > 0 {
+		budget.Tokens = max(budget.Tokens-ai.EstimateTokensFromText(priorWork), 1)
+	}
 
 	// Use smart context builder with ticket description for better file selection
 	usedSmartContext := false
-	ctxStr, ctxErr := ai.BuildSmartRepoContext(repoRoot, description, c.Cfg.ContextMaxFiles, nil)
+	var ctxStr string
+	packed, ctxErr := ai.PackSmartRepoContext(repoRoot, description, budget, ai.DefaultCacheConfig(), nil, scoreOpts...)
 	if ctxErr != nil {
 		// Fall back to simple context builder on error
 		logger.Warn("Smart context builder failed, falling back to simple builder", "error", ctxErr)
@@ -337,7 +346,11 @@ func (c *Coordinator) processTicket(ctx context.Context, key, summary, descripti
 		c.Metrics.IncSimpleContextUsed()
 	} else {
 		usedSmartContext = true
-		logger.Info("Smart context selection succeeded", "context_size", len(ctxStr))
+		ctxStr = packed.Text
+		logger.Info("Smart context selection succeeded",
+			"context_size", len(ctxStr),
+			"tokens", packed.Tokens,
+			"token_budget", budget.Tokens)
 		c.Metrics.IncSmartContextUsed()
 	}
 	ctxStr = priorWork + ctxStr
@@ -347,20 +360,23 @@ func (c *Coordinator) processTicket(ctx context.Context, key, summary, descripti
 	var changes []agent.CodeChange
 	var needFiles []string
 	var usageMetrics *agent.UsageMetrics
+	// Usage is summed over every call, failed ones included, since each
+	// was billed
 	planErr, attempts := Retry(ctx, planBackoff, func() error {
-		ch, nf, metrics, e := c.Agent.PlanChanges(ctx, key, summary, description, ctxStr)
+		ch, nf, metrics, e := c.planChanges(ctx, key, summary, description, ctxStr)
+		usageMetrics = sumUsageMetrics(usageMetrics, metrics)
 		if e != nil {
 			return MakeTransient(e)
 		}
 		changes = ch
 		needFiles = nf
-		usageMetrics = metrics
 		return nil
 	})
 	c.Metrics.AddRetries(attempts)
 	if planErr != nil {
 		c.Metrics.IncAIPlanFailures()
-		return fmt.Errorf("AI planning failed: %w", planErr)
+		c.chargeFailedPlan(ticketMetrics, usageMetrics)
+		return nil, fmt.Errorf("AI planning failed: %w", planErr)
 	}
 
 	// Retrieval pass: the model asked to see the full content of files shown
@@ -376,47 +392,98 @@ func (c *Coordinator) processTicket(ctx context.Context, key, summary, descripti
 	for round := 0; len(fullContentFiles) > 0 && usedSmartContext && round < maxRetrievalRounds; round++ {
 		logger.Info("AI requested full content for additional files", "ticket", key, "files", fullContentFiles, "round", round+1)
 
-		ctxStr2, ctxErr2 := ai.BuildSmartRepoContext(repoRoot, description, c.Cfg.ContextMaxFiles, fullContentFiles)
+		// Pick up anything written to the working tree since the index was
+		// refreshed; a no-op when nothing changed
+		c.refreshIndex(ctx)
+
+		packed2, ctxErr2 := ai.PackSmartRepoContext(repoRoot, description, budget, ai.DefaultCacheConfig(), fullContentFiles, scoreOpts...)
 		if ctxErr2 != nil {
 			logger.Warn("Failed to rebuild context for requested files, proceeding without further retrieval",
 				"ticket", key, "error", ctxErr2)
 			break
 		}
-		ctxStr = priorWork + ctxStr2
+		packed = packed2
+		ctxStr = priorWork + packed.Text
 
 		var changes2 []agent.CodeChange
 		var needFiles2 []string
-		var usageMetrics2 *agent.UsageMetrics
 		planErr2, attempts2 := Retry(ctx, planBackoff, func() error {
-			ch, nf, metrics, e := c.Agent.PlanChanges(ctx, key, summary, description, ctxStr)
+			ch, nf, metrics, e := c.planChanges(ctx, key, summary, description, ctxStr)
+			usageMetrics = sumUsageMetrics(usageMetrics, metrics)
 			if e != nil {
 				return MakeTransient(e)
 			}
 			changes2 = ch
 			needFiles2 = nf
-			usageMetrics2 = metrics
 			return nil
 		})
 		c.Metrics.AddRetries(attempts2)
 		if planErr2 != nil {
 			c.Metrics.IncAIPlanFailures()
-			return fmt.Errorf("AI planning failed (retrieval pass): %w", planErr2)
+			c.chargeFailedPlan(ticketMetrics, usageMetrics)
+			return nil, fmt.Errorf("AI planning failed (retrieval pass): %w", planErr2)
 		}
 		changes = changes2
-		usageMetrics = sumUsageMetrics(usageMetrics, usageMetrics2)
 
 		fullContentFiles = mergeUnique(fullContentFiles, needFiles2)
 	}
 
+	// Policy pass: changes refused by a write rule (allowlist, protected
+	// files, .ai-intern/policy.yaml, CODEOWNERS) are explained to the model,
+	// which plans again around them. Rejections accumulate across rounds so
+	// a refused path isn't proposed again; malformed changes alone don't
+	// trigger a re-plan.
+	const maxPolicyReplans = 2
+	valid, rejections, verr := validatePlannedChanges(repoRoot, changes, c.Cfg.AllowedWriteDirs, c.Cfg.PlanMaxFiles)
+	refused := policyRejections(rejections)
+	for round := 0; len(refused) > 0 && round < maxPolicyReplans; round++ {
+		logger.Info("Plan included changes refused by write policy, re-planning",
+			"ticket", key, "rejected", len(refused), "round", round+1)
+
+		replanCtx := formatRejections(refused) + ctxStr
+		var changes2 []agent.CodeChange
+		planErr2, attempts2 := Retry(ctx, planBackoff, func() error {
+			ch, _, metrics, e := c.planChanges(ctx, key, summary, description, replanCtx)
+			usageMetrics = sumUsageMetrics(usageMetrics, metrics)
+			if e != nil {
+				return MakeTransient(e)
+			}
+			changes2 = ch
+			return nil
+		})
+		c.Metrics.AddRetries(attempts2)
+		if planErr2 != nil {
+			logger.Warn("Re-planning around rejected changes failed, keeping the previous plan",
+				"ticket", key, "error", planErr2)
+			break
+		}
+		valid, rejections, verr = validatePlannedChanges(repoRoot, changes2, c.Cfg.AllowedWriteDirs, c.Cfg.PlanMaxFiles)
+		newlyRefused := policyRejections(rejections)
+		if len(newlyRefused) == 0 {
+			break
+		}
+		refused = append(refused, newlyRefused...)
+	}
+	// An empty plan on a branch carried over from an earlier run usually
+	// means the work is already there; whether it still needs publishing is
+	// decided below
+	if verr != nil && !(existing && len(changes) == 0) {
+		return nil, fmt.Errorf("validation failed: %w", verr)
+	}
+
 	// Checkpoint 2: Check for cancellation after AI planning (expensive operation)
 	if err := checkContext(ctx, key, "after AI planning"); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Update context strategy in usage metrics
 	if usageMetrics != nil {
 		if usedSmartContext {
 			usageMetrics.ContextStats.Strategy = "smart"
+			usageMetrics.ContextStats.FilesIncluded = packed.Files
+			usageMetrics.ContextStats.Keywords = packed.Keywords
+			usageMetrics.ContextStats.PackedTokens = packed.Tokens
+			usageMetrics.ContextStats.TokenBudget = budget.Tokens
 		} else {
 			usageMetrics.ContextStats.Strategy = "simple"
 		}
@@ -460,82 +527,98 @@ func (c *Coordinator) processTicket(ctx context.Context, key, summary, descripti
 			"output_tokens", ai.FormatTokens(usageMetrics.OutputTokens),
 			"context", usageMetrics.ContextStats.Strategy)
 	}
-	valid, verr := validatePlannedChanges(repoRoot, changes, c.Cfg.AllowedWriteDirs, c.Cfg.PlanMaxFiles)
-	if verr != nil {
-		return fmt.Errorf("validation failed: %w", verr)
-	}
 
 	// Snapshot exported APIs of edited Go files before applying changes, so
 	// the journal entry can record which public APIs are new (see
 	// diffExportedAPIs below).
 	beforeAPIs := capturePublicAPIs(repoRoot, valid)
 
+	// Baseline coverage of the packages this ticket touches, measured on the
+	// untouched tree for the RequireTests policy.
+	var coverageBaseline *CoverageSnapshot
+	if c.Cfg.RequireTests {
+		coverageBaseline = measureCoverage(ctx, c.gateExecutor(), repoRoot, affectedPackageDirs(valid))
+	}
+
+	// Files shown as excerpts may only be edited within the lines shown
+	var excerpts map[string][]indexer.LineRange
+	if packed != nil {
+		excerpts = packed.Excerpts
+	}
+
 	for _, ch := range valid {
 		abs := filepath.Join(repoRoot, ch.Path)
 		switch ch.Operation {
 		case agent.OperationDelete:
 			// Delete the file
 			if err := os.Remove(abs); err != nil && !os.IsNotExist(err) {
-				return fmt.Errorf("delete %s: %w", ch.Path, err)
+				return nil, fmt.Errorf("delete %s: %w", ch.Path, err)
 			}
 			// Stage the deletion in git
 			if err := c.Repository.AddFile(ctx, ch.Path); err != nil {
-				return fmt.Errorf("git add (delete) %s: %w", ch.Path, err)
+				return nil, fmt.Errorf("git add (delete) %s: %w", ch.Path, err)
 			}
 			logger.Debug("Deleted file", "path", ch.Path)
 		case agent.OperationEdit:
-			if err := applyEditChange(repoRoot, ch); err != nil {
-				return fmt.Errorf("edit %s: %w", ch.Path, err)
+			if err := applyEditChange(repoRoot, ch, excerpts[ch.Path]); err != nil {
+				return nil, fmt.Errorf("edit %s: %w", ch.Path, err)
 			}
 			if err := c.Repository.AddFile(ctx, ch.Path); err != nil {
-				return fmt.Errorf("git add %s: %w", ch.Path, err)
+				return nil, fmt.Errorf("git add %s: %w", ch.Path, err)
 			}
 			logger.Debug("Edited file", "path", ch.Path)
 		case agent.OperationCreate:
 			if _, err := os.Stat(abs); err == nil {
-				return fmt.Errorf("create %s: file already exists (use operation=edit)", ch.Path)
+				return nil, fmt.Errorf("create %s: file already exists (use operation=edit)", ch.Path)
 			} else if !os.IsNotExist(err) {
-				return fmt.Errorf("stat %s: %w", ch.Path, err)
+				return nil, fmt.Errorf("stat %s: %w", ch.Path, err)
 			}
 			if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
-				return fmt.Errorf("mkdir: %w", err)
+				return nil, fmt.Errorf("mkdir: %w", err)
 			}
 			if err := os.WriteFile(abs, []byte(ch.Content), 0644); err != nil {
-				return fmt.Errorf("write %s: %w", ch.Path, err)
+				return nil, fmt.Errorf("write %s: %w", ch.Path, err)
 			}
 			if err := c.Repository.AddFile(ctx, ch.Path); err != nil {
-				return fmt.Errorf("git add %s: %w", ch.Path, err)
+				return nil, fmt.Errorf("git add %s: %w", ch.Path, err)
 			}
 			logger.Debug("Created file", "path", ch.Path)
 		default:
-			return fmt.Errorf("%s: unknown operation %q", ch.Path, ch.Operation)
+			return nil, fmt.Errorf("%s: unknown operation %q", ch.Path, ch.Operation)
 		}
 	}
 
 	// Checkpoint 3: Check for cancellation after file operations (before commit)
 	if err := checkContext(ctx, key, "after file operations"); err != nil {
-		return err
+		return nil, err
 	}
 
-	if len(valid) > 0 {
-		if err := c.Repository.Commit(ctx, fmt.Sprintf("feat(%s): apply planned changes", key)); err != nil {
-			return fmt.Errorf("commit: %w", err)
-		}
-	}
+	// Edits that are already on the branch leave nothing to commit
 	changed, err := c.Repository.HasLocalChanges(ctx)
 	if err != nil {
 		logger.Error("status failed", "error", err)
+		changed = true
 	}
-	if !changed && len(valid) == 0 {
-		logger.Info("No effective changes; skipping push/PR", "key", key)
-		return nil
+	if changed && len(valid) > 0 {
+		if err := c.Repository.Commit(ctx, commitMessage(c.Cfg, ticket, fmt.Sprintf("feat(%s): apply planned changes", key))); err != nil {
+			return nil, fmt.Errorf("commit: %w", err)
+		}
+	} else {
+		// A branch an earlier run pushed (say, before opening its PR
+		// failed) already holds the work, so the re-plan finds nothing
+		// left to do: it's still published, or its PR would never open
+		if !existing || !c.aheadOfBase(ctx, key) {
+			logger.Info("No effective changes; skipping push/PR", "key", key)
+			return nil, nil
+		}
+		logger.Info("No new changes, publishing the work already on the branch", "key", key, "branch", branchName)
 	}
 
 	// Run self-healing pipeline (includes quality gates)
-	healResult, err := c.selfHealingPipeline(ctx, key, summary, valid, repoRoot)
+	healResult, err := c.selfHealingPipeline(ctx, key, summary, valid, repoRoot, coverageBaseline)
 	if err != nil {
 		logger.Error("Self-healing pipeline failed", "key", key, "error", err)
-		return fmt.Errorf("self-healing failed: %w", err)
+		return nil, fmt.Errorf("self-healing failed: %w", err)
 	}
 
 	// Track healing metrics
@@ -546,6 +629,9 @@ func (c *Coordinator) processTicket(ctx context.Context, key, summary, descripti
 		} else {
 			c.Metrics.IncHealFailures()
 		}
+		if healResult.Oscillated {
+			c.Metrics.IncHealOscillations()
+		}
 	}
 
 	// If healing failed, skip push/PR
@@ -554,12 +640,21 @@ func (c *Coordinator) processTicket(ctx context.Context, key, summary, descripti
 			"key", key,
 			"attempts", healResult.TotalAttempts,
 			"cost", healResult.TotalCost)
-		return nil
+		return nil, errGatesFailed
 	}
 
 	// If healing was needed and succeeded, commit the fixes
 	if len(healResult.Attempts) > 0 {
-		if err := c.Repository.Commit(ctx, fmt.Sprintf("fix(%s): self-healing fixes after %d attempts", key, healResult.TotalAttempts)); err != nil {
+		// Fixes are written straight to disk; stage them (including any
+		// tests created to satisfy the coverage policy) before committing.
+		for _, attempt := range healResult.Attempts {
+			for _, ch := range attempt.FixedChanges {
+				if err := c.Repository.AddFile(ctx, ch.Path); err != nil {
+					logger.Warn("Failed to stage healing fix", "path", ch.Path, "error", err)
+				}
+			}
+		}
+		if err := c.Repository.Commit(ctx, commitMessage(c.Cfg, ticket, fmt.Sprintf("fix(%s): self-healing fixes after %d attempts", key, healResult.TotalAttempts))); err != nil {
 			logger.Warn("Failed to commit healing fixes", "error", err)
 			// Continue anyway - fixes are already applied
 		}
@@ -571,43 +666,63 @@ func (c *Coordinator) processTicket(ctx context.Context, key, summary, descripti
 
 	// Checkpoint 4: Check for cancellation before push/PR (point of no return)
 	if err := checkContext(ctx, key, "before push/PR"); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Run final quality gates check for PR notes (should pass now)
-	notes, ok := runQualityGates(ctx, c.Cfg, repoRoot)
+	notes, ok := runQualityGates(ctx, c.Cfg, c.gateExecutor(), repoRoot)
 	if !ok {
 		// This shouldn't happen after successful healing, but check anyway
 		logger.Error("Quality gates failed after successful healing; skipping push/PR", "key", key)
-		return nil
-	}
-	// Check for dry-run mode
-	if c.Cfg.DryRun {
-		logger.Warn("DRY RUN MODE: Skipping push and PR creation",
-			"ticket", key,
-			"branch", branchName,
-			"files_changed", len(valid),
-			"cost", usageMetrics.EstimatedCost)
+		return nil, errGatesFailed
+	}
+
+	return &stagedTicket{
+		ticket:     ticket,
+		branch:     branchName,
+		startTime:  startTime,
+		metrics:    ticketMetrics,
+		usage:      usageMetrics,
+		valid:      valid,
+		beforeAPIs: beforeAPIs,
+		healResult: healResult,
+		gateNotes:  notes,
+	}, nil
+}
 
-		// In dry-run, log what would have been done
-		logger.Info("DRY RUN: Would have created PR",
-			"ticket", key,
-			"branch", branchName,
-			"base_branch", c.Cfg.BaseBranch,
-			"files", len(valid),
-			"summary", summary)
-
-		// Mark Done even in dry-run (to avoid reprocessing)
-		if err := c.Ticketing.UpdateTicketStatus(ctx, key, "Done", c.Cfg.JiraTransitions); err != nil {
-			logger.Error("Failed to move ticket to Done", "error", err)
-		}
+// aheadOfBase reports whether the checked-out branch has changes against
+// the base branch, i.e. whether a PR from it would show anything
+func (c *Coordinator) aheadOfBase(ctx context.Context, key string) bool {
+	stats, err := c.Repository.DiffStat(ctx, c.baseBranch())
+	if err != nil {
+		logger.Warn("Failed to compare the branch with its base", "key", key, "error", err)
+		return false
+	}
+	return len(stats) > 0
+}
 
-		// Update state to avoid reprocessing
-		// MarkProcessed automatically saves the state, so no need to call Save() explicitly
-		c.State.MarkProcessed(key)
+// logDryRun logs what publishing st would have done
+func (c *Coordinator) logDryRun(st *stagedTicket) {
+	logger.Warn("DRY RUN MODE: Skipping push and PR creation",
+		"ticket", st.ticket.Key,
+		"branch", st.branch,
+		"files_changed", len(st.valid),
+		"cost", st.usage.EstimatedCost)
+
+	// In dry-run, log what would have been done
+	logger.Info("DRY RUN: Would have created PR",
+		"ticket", st.ticket.Key,
+		"branch", st.branch,
+		"base_branch", c.Cfg.BaseBranch,
+		"files", len(st.valid),
+		"summary", st.ticket.Summary)
+}
 
-		return nil // Exit early without creating PR
-	}
+// publishTicket pushes a staged ticket's branch and opens its PR, or
+// updates the one an earlier run left open
+func (c *Coordinator) publishTicket(ctx context.Context, st *stagedTicket) error {
+	key, branchName, valid := st.ticket.Key, st.branch, st.valid
+	repoRoot := c.RepoPaths.Root()
 
 	pushErr, pushAttempts := Retry(ctx, BackoffConfig{Initial: time.Second, Max: 10 * time.Second, Multiplier: 2, Jitter: 0.2, MaxRetries: 3}, func() error {
 		return MakeTransient(c.Repository.Push(ctx, branchName))
@@ -616,64 +731,113 @@ func (c *Coordinator) processTicket(ctx context.Context, key, summary, descripti
 	if pushErr != nil {
 		return fmt.Errorf("push: %w", pushErr)
 	}
-	base := c.Cfg.BaseBranch
-	if base == "" {
-		base = "main"
-	}
+	base := c.baseBranch()
 	// Surface any judgment calls the AI made while planning (e.g. renaming a
 	// resource to avoid a naming collision) so a human can confirm or
 	// override them, rather than the ticket silently failing on ambiguity.
+	var aiNotes []string
 	for _, ch := range valid {
 		if ch.Note != "" {
-			notes = append(notes, fmt.Sprintf("%s: %s", ch.Path, ch.Note))
+			aiNotes = append(aiNotes, fmt.Sprintf("%s: %s", ch.Path, ch.Note))
 		}
 	}
 
-	title := buildPRTitle(key, summary)
-	body := buildPRBody(key, summary, description, valid, notes)
-	var prURL string
-	prErr, prAttempts := Retry(ctx, BackoffConfig{Initial: time.Second, Max: 10 * time.Second, Multiplier: 2, Jitter: 0.2, MaxRetries: 3}, func() error {
-		u, e := c.Repository.CreatePullRequest(ctx, base, branchName, title, body)
-		if e != nil {
-			return MakeTransient(e)
+	newAPIs := diffPublicAPIs(repoRoot, valid, st.beforeAPIs)
+	allChanges := valid
+	for _, attempt := range st.healResult.Attempts {
+		allChanges = mergeChanges(allChanges, attempt.FixedChanges)
+	}
+	stats, statErr := c.Repository.DiffStat(ctx, base)
+	if statErr != nil {
+		logger.Warn("Failed to compute diff stats for PR body", "ticket", key, "error", statErr)
+	}
+	files, lineCounts := prFileSummaries(allChanges, stats)
+	prData := PRBodyData{
+		Ticket:     st.ticket,
+		TicketURL:  ticketBrowseURL(st.ticket),
+		Files:      files,
+		LineCounts: lineCounts,
+		NewAPIs:    newAPIs,
+		AINotes:    aiNotes,
+		GateNotes:  st.gateNotes,
+		Healing:    prHealAttempts(st.healResult),
+		Coverage:   formatCoverageDelta(st.healResult.Coverage),
+		Usage:      prUsage(st.usage, st.healResult),
+	}
+	for _, f := range files {
+		prData.LinesAdded += f.Added
+		prData.LinesRemoved += f.Removed
+	}
+
+	paths := changedPaths(allChanges)
+	prData.Approvals = requiredApprovals(repoRoot, paths)
+
+	title := buildPRTitle(key, st.ticket.Summary)
+	body := buildPRBody(repoRoot, prData)
+	prOpts := pullRequestOptions(c.Cfg, repoRoot, st.ticket, paths, prData.Approvals)
+	// A PR left open by an earlier run of this ticket is updated in place;
+	// creating another from the same head would fail.
+	prURL, err := c.Repository.FindOpenPullRequest(ctx, branchName)
+	if err != nil {
+		logger.Warn("Failed to look up an existing PR, creating one", "branch", branchName, "error", err)
+		prURL = ""
+	}
+	if prURL != "" {
+		prErr, prAttempts := Retry(ctx, BackoffConfig{Initial: time.Second, Max: 10 * time.Second, Multiplier: 2, Jitter: 0.2, MaxRetries: 3}, func() error {
+			return MakeTransient(c.Repository.UpdatePullRequest(ctx, prURL, title, body, prOpts))
+		})
+		c.Metrics.AddRetries(prAttempts)
+		if prErr != nil {
+			return fmt.Errorf("update PR: %w", prErr)
 		}
-		prURL = u
-		return nil
-	})
-	c.Metrics.AddRetries(prAttempts)
-	if prErr != nil {
-		return fmt.Errorf("create PR: %w", prErr)
+		logger.Info("Updated existing PR", "url", prURL)
+	} else {
+		prErr, prAttempts := Retry(ctx, BackoffConfig{Initial: time.Second, Max: 10 * time.Second, Multiplier: 2, Jitter: 0.2, MaxRetries: 3}, func() error {
+			u, e := c.Repository.CreatePullRequest(ctx, base, branchName, title, body, prOpts)
+			if e != nil {
+				return MakeTransient(e)
+			}
+			prURL = u
+			return nil
+		})
+		c.Metrics.AddRetries(prAttempts)
+		if prErr != nil {
+			return fmt.Errorf("create PR: %w", prErr)
+		}
+		logger.Info("Created PR", "url", prURL)
+		c.Metrics.IncPRsCreated()
 	}
-	logger.Info("Created PR", "url", prURL)
-	c.Metrics.IncPRsCreated()
 
-	// Record this ticket's work in the journal for future tickets'
-	// continuity injection and dependency deferral.
-	if err := c.Journal.Append(journal.Entry{
+	st.prURL, st.prTitle, st.prData, st.prDraft = prURL, title, prData, prOpts.Draft
+	return nil
+}
+
+// recordTicket journals a published ticket for future tickets' continuity
+// injection and dependency deferral, and records its metrics. linked lists
+// the PRs opened for the same ticket in other repositories.
+func (c *Coordinator) recordTicket(st *stagedTicket, linked []string) {
+	key := st.ticket.Key
+	if err := c.Journal.Upsert(journal.Entry{
 		TicketKey:    key,
-		Summary:      summary,
-		Branch:       branchName,
-		PRURL:        prURL,
+		Summary:      st.ticket.Summary,
+		Branch:       st.branch,
+		PRURL:        st.prURL,
+		LinkedPRs:    linked,
 		Merged:       false,
-		FilesChanged: changedPaths(valid),
-		PublicAPIs:   diffPublicAPIs(repoRoot, valid, beforeAPIs),
+		FilesChanged: changedPaths(st.valid),
+		PublicAPIs:   st.prData.NewAPIs,
 		Timestamp:    time.Now(),
 	}); err != nil {
-		logger.Warn("Failed to append journal entry", "ticket", key, "error", err)
-	}
-
-	// Mark Done
-	if err := c.Ticketing.UpdateTicketStatus(ctx, key, "Done", c.Cfg.JiraTransitions); err != nil {
-		logger.Error("Failed to move ticket to Done", "error", err)
+		logger.Warn("Failed to record journal entry", "ticket", key, "error", err)
 	}
 
 	// Update metrics with execution time and files changed
-	executionTime := time.Since(startTime)
-	filesChanged := len(valid)
+	executionTime := time.Since(st.startTime)
+	filesChanged := len(st.valid)
 
-	ticketMetrics.SetExecutionTime(executionTime)
-	ticketMetrics.SetFilesChanged(filesChanged)
-	ticketMetrics.Status = "success"
+	st.metrics.SetExecutionTime(executionTime)
+	st.metrics.SetFilesChanged(filesChanged)
+	st.metrics.Status = "success"
 
 	c.Metrics.IncTicketsProcessed()
 	c.Metrics.AddExecutionTime(executionTime)
@@ -684,9 +848,15 @@ func (c *Coordinator) processTicket(ctx context.Context, key, summary, descripti
 		"ticket", key,
 		"duration", executionTime.Round(time.Second),
 		"files_changed", filesChanged,
-		"pr_url", prURL)
+		"pr_url", st.prURL)
+}
 
-	return nil
+// baseBranch returns the configured base branch, "main" if unset
+func (c *Coordinator) baseBranch() string {
+	if c.Cfg.BaseBranch == "" {
+		return "main"
+	}
+	return c.Cfg.BaseBranch
 }
 
 // changedPaths returns the repo-relative paths touched by changes, in order.
@@ -752,9 +922,20 @@ func readExportedAPIs(path string) []string {
 // journalBlocker returns the ticket key of a related prior entry whose PR
 // hasn't been merged yet, or "" if this ticket has no such dependency.
 // Tickets with a blocker are deferred until that PR merges, avoiding the
-// "ticket N+1 doesn't see ticket N's work" class of failures.
-func (c *Coordinator) journalBlocker(ticketText string) string {
-	for _, e := range c.Journal.Relevant(ticketText, 3) {
+// "ticket N+1 doesn't see ticket N's work" class of failures. The ticket's
+// own entry, from an earlier run, is the closest match but no dependency:
+// it's skipped, or a re-run would wait on its own PR.
+func (c *Coordinator) journalBlocker(ticketKey, ticketText string) string {
+	const related = 3
+	checked := 0
+	for _, e := range c.Journal.Relevant(ticketText, related+1) {
+		if e.TicketKey == ticketKey {
+			continue
+		}
+		if checked == related {
+			break
+		}
+		checked++
 		if !e.Merged {
 			return e.TicketKey
 		}
@@ -763,9 +944,10 @@ func (c *Coordinator) journalBlocker(ticketText string) string {
 }
 
 // sumUsageMetrics combines usage metrics from the initial PlanChanges call and
-// a retrieval-pass call triggered by a need_files response into one total.
-// Token counts and cost are summed across both calls; ContextStats reflects
-// the final (retrieval-pass) context, with ContextBytes summed across both.
+// a retrieval-pass call triggered by a need_files response (or an escalated
+// retry) into one total. Token counts and cost are summed across both calls;
+// Model and ContextStats reflect the later call, with ContextBytes summed
+// across both.
 func sumUsageMetrics(a, b *agent.UsageMetrics) *agent.UsageMetrics {
 	if a == nil {
 		return b
@@ -778,15 +960,29 @@ func sumUsageMetrics(a, b *agent.UsageMetrics) *agent.UsageMetrics {
 		OutputTokens:  a.OutputTokens + b.OutputTokens,
 		TotalTokens:   a.TotalTokens + b.TotalTokens,
 		EstimatedCost: a.EstimatedCost + b.EstimatedCost,
+		Model:         b.Model,
 		ContextStats: agent.ContextStats{
 			Strategy:      b.ContextStats.Strategy,
 			FilesIncluded: b.ContextStats.FilesIncluded,
 			ContextBytes:  a.ContextStats.ContextBytes + b.ContextStats.ContextBytes,
 			Keywords:      b.ContextStats.Keywords,
+			PackedTokens:  b.ContextStats.PackedTokens,
+			TokenBudget:   b.ContextStats.TokenBudget,
 		},
 	}
 }
 
+// chargeFailedPlan records the usage of planning that failed on the ticket
+// and in the global metrics, so the tiers and retries it paid for still
+// count towards cost
+func (c *Coordinator) chargeFailedPlan(tm *TicketMetrics, usage *agent.UsageMetrics) {
+	if usage == nil {
+		return
+	}
+	tm.ApplyUsage(usage)
+	c.Metrics.AddTokenUsage(usage.InputTokens, usage.OutputTokens, usage.EstimatedCost)
+}
+
 // mergeUnique returns the union of a and b, preserving a's order and
 // appending any elements of b not already present in a.
 func mergeUnique(a, b []string) []string {
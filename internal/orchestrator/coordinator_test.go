@@ -0,0 +1,137 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"intern/internal/config"
+	"intern/internal/journal"
+	"intern/internal/repository"
+	"intern/internal/ticketing"
+)
+
+// fakeRerunRepo is a repository a ticket is re-run against: the branch of
+// an earlier run may exist on the remote, holding diff against the base,
+// with no PR open for it
+type fakeRerunRepo struct {
+	repository.RepositoryClient
+	remoteBranch bool
+	diff         []repository.FileStat
+	calls        []string
+}
+
+func (f *fakeRerunRepo) CheckoutRemoteBranch(ctx context.Context, branch string) (bool, error) {
+	return f.remoteBranch, nil
+}
+
+func (f *fakeRerunRepo) CreateBranch(ctx context.Context, branch string) error {
+	f.calls = append(f.calls, "create "+branch)
+	return nil
+}
+
+func (f *fakeRerunRepo) SwitchBranch(ctx context.Context, branch string) error { return nil }
+
+func (f *fakeRerunRepo) HasLocalChanges(ctx context.Context) (bool, error) { return false, nil }
+
+func (f *fakeRerunRepo) DiffStat(ctx context.Context, base string) ([]repository.FileStat, error) {
+	return f.diff, nil
+}
+
+func (f *fakeRerunRepo) Push(ctx context.Context, branch string) error {
+	f.calls = append(f.calls, "push "+branch)
+	return nil
+}
+
+func (f *fakeRerunRepo) FindOpenPullRequest(ctx context.Context, head string) (string, error) {
+	return "", nil
+}
+
+func (f *fakeRerunRepo) CreatePullRequest(ctx context.Context, base, head, title, body string, opts repository.PullRequestOptions) (string, error) {
+	f.calls = append(f.calls, "pr "+base+" <- "+head)
+	return "https://github.com/acme/api/pull/7", nil
+}
+
+func rerunCoordinator(t *testing.T, repo *fakeRerunRepo) *Coordinator {
+	t.Helper()
+	paths, err := repository.NewRepositoryPath(t.TempDir(), "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(paths.Root(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// The agent plans no changes
+	return NewCoordinator(nil, repository.NewRepositoryService(repo), &mockHealingAgent{},
+		&config.Config{BaseBranch: "main", BranchPrefix: "feature/"}, nil, paths)
+}
+
+func TestStageTicket_RerunPublishesPushedBranch(t *testing.T) {
+	repo := &fakeRerunRepo{
+		remoteBranch: true,
+		diff:         []repository.FileStat{{Path: "refunds.go", Added: 40}},
+	}
+	c := rerunCoordinator(t, repo)
+	ctx := context.Background()
+	ticket := ticketing.Ticket{Key: "PROJ-1", Summary: "Add refunds"}
+
+	st, err := c.stageTicket(ctx, ticket, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if st == nil {
+		t.Fatal("Expected the pushed branch to be staged for a PR, got nothing to publish")
+	}
+	if err := c.publishTicket(ctx, st); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	branch := buildBranchName("feature/", "PROJ-1")
+	want := []string{"push " + branch, "pr main <- " + branch}
+	if len(repo.calls) != len(want) || repo.calls[0] != want[0] || repo.calls[1] != want[1] {
+		t.Errorf("Expected %v, got %v", want, repo.calls)
+	}
+	if len(st.prData.Files) != 1 || st.prData.Files[0].Path != "refunds.go" {
+		t.Errorf("Expected the PR to list the branch's files, got %+v", st.prData.Files)
+	}
+}
+
+func TestStageTicket_RerunOfMergedWork(t *testing.T) {
+	// The branch is level with the base: its earlier PR has merged
+	repo := &fakeRerunRepo{remoteBranch: true}
+	st, err := rerunCoordinator(t, repo).stageTicket(context.Background(), ticketing.Ticket{Key: "PROJ-1"}, "")
+	if err != nil || st != nil {
+		t.Errorf("Expected nothing staged, got %+v, %v", st, err)
+	}
+}
+
+func TestJournalBlocker_SkipsOwnEntry(t *testing.T) {
+	c := rerunCoordinator(t, &fakeRerunRepo{})
+	c.Journal.Append(journal.Entry{TicketKey: "PROJ-1", Summary: "Add refunds endpoint", Branch: "feature/PROJ-1", PRURL: "https://github.com/acme/api/pull/7"})
+
+	if got := c.journalBlocker("PROJ-1", "Add refunds endpoint"); got != "" {
+		t.Errorf("Expected a re-run not to wait on its own PR, got blocked on %s", got)
+	}
+	if got := c.journalBlocker("PROJ-2", "Add refunds endpoint validation"); got != "PROJ-1" {
+		t.Errorf("Expected related ticket to wait on PROJ-1, got %q", got)
+	}
+}
+
+func TestRecordTicket_ReplacesEarlierRun(t *testing.T) {
+	c := rerunCoordinator(t, &fakeRerunRepo{})
+	for _, prURL := range []string{"https://github.com/acme/api/pull/7", "https://github.com/acme/api/pull/8"} {
+		c.recordTicket(&stagedTicket{
+			ticket:  ticketing.Ticket{Key: "PROJ-1", Summary: "Add refunds"},
+			branch:  "feature/PROJ-1",
+			metrics: &TicketMetrics{TicketKey: "PROJ-1"},
+			prURL:   prURL,
+		}, nil)
+	}
+
+	if len(c.Journal.Entries) != 1 {
+		t.Fatalf("Expected one journal entry for the ticket, got %d", len(c.Journal.Entries))
+	}
+	if got := c.Journal.Entries[0].PRURL; got != "https://github.com/acme/api/pull/8" {
+		t.Errorf("Expected the latest run's PR, got %s", got)
+	}
+}
@@ -0,0 +1,251 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"intern/internal/ai/agent"
+	"intern/internal/sandbox"
+
+	"github.com/jenish-jain/logger"
+)
+
+// coverageDropTolerance absorbs rounding in go test's one-decimal coverage
+// percentages so an unchanged package never reads as a drop.
+const coverageDropTolerance = 0.05
+
+// CoverageSnapshot is the statement coverage of a set of packages at one
+// point in time, as reported by `go test -coverprofile`.
+type CoverageSnapshot struct {
+	Packages map[string]float64 // import path -> percent of statements covered
+	Total    float64            // combined percent across all measured packages
+	HasTotal bool               // false when go test failed or produced no profile
+}
+
+// CoverageDelta compares coverage before and after a ticket's changes
+type CoverageDelta struct {
+	Before *CoverageSnapshot
+	After  *CoverageSnapshot
+}
+
+// coverageScript writes the profile to a temp file (never into the repo) and
+// prints the combined total after the per-package lines.
+const coverageScript = `f=$(mktemp) || exit 1
+go test -coverprofile="$f" "$@"
+rc=$?
+[ $rc -eq 0 ] && go tool cover -func="$f" | tail -n 1
+rm -f "$f"
+exit $rc`
+
+var (
+	// Matches "ok  \tpkg\t0.01s\tcoverage: 50.0% of statements" as well as
+	// the "\tpkg\t\tcoverage: 0.0% of statements" line newer toolchains print
+	// for packages without tests.
+	pkgCoverageRe   = regexp.MustCompile(`(?m)^(?:ok\s+)?\s*(\S+)\s+(?:\S+\s+)?coverage: ([0-9.]+)% of statements`)
+	totalCoverageRe = regexp.MustCompile(`(?m)^total:\s+\(statements\)\s+([0-9.]+)%`)
+)
+
+// parseCoverageOutput extracts per-package and total coverage from the
+// output of coverageScript
+func parseCoverageOutput(output string) *CoverageSnapshot {
+	snap := &CoverageSnapshot{Packages: make(map[string]float64)}
+	for _, m := range pkgCoverageRe.FindAllStringSubmatch(output, -1) {
+		if pct, err := strconv.ParseFloat(m[2], 64); err == nil {
+			snap.Packages[m[1]] = pct
+		}
+	}
+	if m := totalCoverageRe.FindStringSubmatch(output); m != nil {
+		if pct, err := strconv.ParseFloat(m[1], 64); err == nil {
+			snap.Total = pct
+			snap.HasTotal = true
+		}
+	}
+	return snap
+}
+
+// measureCoverage runs go test with a coverprofile over the given package
+// directories (repo-relative, e.g. "internal/foo"). Directories that don't
+// currently hold Go files are skipped, so the same list can be measured
+// before a ticket creates a package and after. Returns nil if there's
+// nothing to measure or the run failed.
+func measureCoverage(ctx context.Context, executor sandbox.Executor, repoPath string, pkgDirs []string) *CoverageSnapshot {
+	var patterns []string
+	for _, dir := range pkgDirs {
+		matches, _ := filepath.Glob(filepath.Join(repoPath, filepath.FromSlash(dir), "*.go"))
+		if len(matches) == 0 {
+			continue
+		}
+		if dir == "." {
+			patterns = append(patterns, ".")
+		} else {
+			patterns = append(patterns, "./"+dir)
+		}
+	}
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	args := append([]string{"-c", coverageScript, "sh"}, patterns...)
+	out, err := executor.Run(ctx, repoPath, "sh", args...)
+	if err != nil {
+		logger.Warn("Coverage measurement failed", "packages", patterns, "error", err, "output", truncateMiddle(out, 2000))
+		return nil
+	}
+	return parseCoverageOutput(out)
+}
+
+// affectedPackageDirs returns the repo-relative directories of non-test Go
+// files created or edited by changes ("." for the repository root)
+func affectedPackageDirs(changes []agent.CodeChange) []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, ch := range changes {
+		if ch.Operation == agent.OperationDelete || !strings.HasSuffix(ch.Path, ".go") || strings.HasSuffix(ch.Path, "_test.go") {
+			continue
+		}
+		dir := path.Dir(filepath.ToSlash(ch.Path))
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// packagesMissingTests returns the affected package directories in which
+// changes neither create nor edit a _test.go file
+func packagesMissingTests(changes []agent.CodeChange) []string {
+	tested := make(map[string]bool)
+	for _, ch := range changes {
+		if ch.Operation != agent.OperationDelete && strings.HasSuffix(ch.Path, "_test.go") {
+			tested[path.Dir(filepath.ToSlash(ch.Path))] = true
+		}
+	}
+	var missing []string
+	for _, dir := range affectedPackageDirs(changes) {
+		if !tested[dir] {
+			missing = append(missing, dir)
+		}
+	}
+	return missing
+}
+
+// coverageDrops lists packages whose coverage fell compared to the baseline.
+// Packages without a baseline (new, or untested before) can't drop.
+func (d *CoverageDelta) coverageDrops() []string {
+	if d == nil || d.Before == nil || d.After == nil {
+		return nil
+	}
+	var drops []string
+	for pkg, before := range d.Before.Packages {
+		after, ok := d.After.Packages[pkg]
+		if ok && after < before-coverageDropTolerance {
+			drops = append(drops, fmt.Sprintf("%s: %.1f%% -> %.1f%%", pkg, before, after))
+		}
+	}
+	sort.Strings(drops)
+	return drops
+}
+
+// coverageGate enforces the REQUIRE_TESTS policy: every package the ticket
+// touched must get a new or modified _test.go, and no package's coverage may
+// fall below baseline. Returns the measured delta (for the PR body) and a
+// description of what's wrong, written for FixErrors, or "" if the policy
+// is satisfied.
+func (c *Coordinator) coverageGate(ctx context.Context, repoPath string, changes []agent.CodeChange, baseline *CoverageSnapshot) (*CoverageDelta, string) {
+	delta := &CoverageDelta{
+		Before: baseline,
+		After:  measureCoverage(ctx, c.gateExecutor(), repoPath, affectedPackageDirs(changes)),
+	}
+
+	var problems []string
+	if missing := packagesMissingTests(changes); len(missing) > 0 {
+		var b strings.Builder
+		b.WriteString("No tests were added or modified for these changed packages:\n")
+		for _, dir := range missing {
+			b.WriteString(fmt.Sprintf("  - %s (changed: %s)\n", dir, strings.Join(changedFilesInDir(changes, dir), ", ")))
+		}
+		b.WriteString("Add or extend a _test.go file in each package that exercises the new or changed code.")
+		problems = append(problems, b.String())
+	}
+	if drops := delta.coverageDrops(); len(drops) > 0 {
+		problems = append(problems, "Statement coverage dropped below the base branch:\n  - "+strings.Join(drops, "\n  - ")+
+			"\nAdd tests for the new code paths; do not delete or weaken existing tests.")
+	}
+
+	return delta, strings.Join(problems, "\n\n")
+}
+
+func changedFilesInDir(changes []agent.CodeChange, dir string) []string {
+	var files []string
+	for _, ch := range changes {
+		p := filepath.ToSlash(ch.Path)
+		if ch.Operation != agent.OperationDelete && strings.HasSuffix(p, ".go") && path.Dir(p) == dir {
+			files = append(files, p)
+		}
+	}
+	return files
+}
+
+// mergeChanges overlays later onto earlier by path, keeping first-seen
+// order, so the coverage policy sees every file a ticket touched across the
+// initial plan and all healing attempts.
+func mergeChanges(earlier, later []agent.CodeChange) []agent.CodeChange {
+	merged := make([]agent.CodeChange, 0, len(earlier)+len(later))
+	index := make(map[string]int, len(earlier)+len(later))
+	for _, ch := range append(append([]agent.CodeChange{}, earlier...), later...) {
+		if i, ok := index[ch.Path]; ok {
+			// A later edit of a file the plan created is still a creation.
+			if ch.Operation == agent.OperationEdit && merged[i].Operation == agent.OperationCreate {
+				continue
+			}
+			merged[i] = ch
+			continue
+		}
+		index[ch.Path] = len(merged)
+		merged = append(merged, ch)
+	}
+	return merged
+}
+
+// formatCoverageDelta renders a markdown table of per-package coverage
+func formatCoverageDelta(d *CoverageDelta) string {
+	if d == nil || d.After == nil {
+		return ""
+	}
+	pkgs := make([]string, 0, len(d.After.Packages))
+	for pkg := range d.After.Packages {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+
+	var b strings.Builder
+	b.WriteString("| Package | Before | After | Delta |\n|---|---|---|---|\n")
+	for _, pkg := range pkgs {
+		after := d.After.Packages[pkg]
+		before, ok := 0.0, false
+		if d.Before != nil {
+			before, ok = d.Before.Packages[pkg]
+		}
+		if ok {
+			b.WriteString(fmt.Sprintf("| %s | %.1f%% | %.1f%% | %+.1f |\n", pkg, before, after, after-before))
+		} else {
+			b.WriteString(fmt.Sprintf("| %s | (new) | %.1f%% | - |\n", pkg, after))
+		}
+	}
+	if d.After.HasTotal {
+		if d.Before != nil && d.Before.HasTotal {
+			b.WriteString(fmt.Sprintf("| **total** | %.1f%% | %.1f%% | %+.1f |\n", d.Before.Total, d.After.Total, d.After.Total-d.Before.Total))
+		} else {
+			b.WriteString(fmt.Sprintf("| **total** | - | %.1f%% | - |\n", d.After.Total))
+		}
+	}
+	return b.String()
+}
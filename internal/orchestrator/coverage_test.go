@@ -0,0 +1,197 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"intern/internal/ai/agent"
+	"intern/internal/config"
+)
+
+func TestParseCoverageOutput(t *testing.T) {
+	output := "ok  \ttest/calc\t0.003s\tcoverage: 75.0% of statements\n" +
+		"ok  \ttest/cached\t(cached)\tcoverage: 10.5% of statements\n" +
+		"\ttest/untested\t\tcoverage: 0.0% of statements\n" +
+		"total:\t\t\t\t(statements)\t\t61.2%\n"
+
+	snap := parseCoverageOutput(output)
+
+	want := map[string]float64{"test/calc": 75.0, "test/cached": 10.5, "test/untested": 0.0}
+	if !reflect.DeepEqual(snap.Packages, want) {
+		t.Errorf("Packages = %v, want %v", snap.Packages, want)
+	}
+	if !snap.HasTotal || snap.Total != 61.2 {
+		t.Errorf("Total = %v (has=%v), want 61.2", snap.Total, snap.HasTotal)
+	}
+}
+
+func TestPackagesMissingTests(t *testing.T) {
+	changes := []agent.CodeChange{
+		{Path: "internal/a/a.go", Operation: agent.OperationEdit},
+		{Path: "internal/a/a_test.go", Operation: agent.OperationCreate},
+		{Path: "internal/b/b.go", Operation: agent.OperationCreate},
+		{Path: "internal/c/c.go", Operation: agent.OperationDelete},
+		{Path: "main.go", Operation: agent.OperationEdit},
+		{Path: "docs/README.md", Operation: agent.OperationEdit},
+	}
+
+	got := packagesMissingTests(changes)
+	want := []string{".", "internal/b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("packagesMissingTests() = %v, want %v", got, want)
+	}
+}
+
+func TestCoverageDelta_Drops(t *testing.T) {
+	d := &CoverageDelta{
+		Before: &CoverageSnapshot{Packages: map[string]float64{"p/a": 80.0, "p/b": 50.0, "p/gone": 90.0}},
+		After:  &CoverageSnapshot{Packages: map[string]float64{"p/a": 79.96, "p/b": 42.0, "p/new": 0}},
+	}
+
+	drops := d.coverageDrops()
+	if len(drops) != 1 || !strings.HasPrefix(drops[0], "p/b:") {
+		t.Errorf("Expected only p/b to drop, got %v", drops)
+	}
+
+	if drops := (&CoverageDelta{After: d.After}).coverageDrops(); drops != nil {
+		t.Errorf("Expected no drops without a baseline, got %v", drops)
+	}
+}
+
+func TestMergeChanges(t *testing.T) {
+	initial := []agent.CodeChange{
+		{Path: "a.go", Operation: agent.OperationCreate, Content: "v1"},
+		{Path: "b.go", Operation: agent.OperationEdit},
+	}
+	fixes := []agent.CodeChange{
+		{Path: "a.go", Operation: agent.OperationEdit},
+		{Path: "a_test.go", Operation: agent.OperationCreate},
+	}
+
+	merged := mergeChanges(initial, fixes)
+
+	if len(merged) != 3 {
+		t.Fatalf("Expected 3 merged changes, got %d", len(merged))
+	}
+	if merged[0].Path != "a.go" || merged[0].Operation != agent.OperationCreate {
+		t.Errorf("Expected a.go to remain a create, got %+v", merged[0])
+	}
+	if merged[2].Path != "a_test.go" {
+		t.Errorf("Expected a_test.go appended last, got %s", merged[2].Path)
+	}
+}
+
+func TestFormatCoverageDelta(t *testing.T) {
+	if got := formatCoverageDelta(nil); got != "" {
+		t.Errorf("Expected empty output for nil delta, got %q", got)
+	}
+
+	table := formatCoverageDelta(&CoverageDelta{
+		Before: &CoverageSnapshot{Packages: map[string]float64{"p/a": 50.0}, Total: 50.0, HasTotal: true},
+		After:  &CoverageSnapshot{Packages: map[string]float64{"p/a": 62.5, "p/new": 80.0}, Total: 70.0, HasTotal: true},
+	})
+
+	for _, want := range []string{"| p/a | 50.0% | 62.5% | +12.5 |", "| p/new | (new) | 80.0% | - |", "| **total** | 50.0% | 70.0% | +20.0 |"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("Expected table to contain %q, got:\n%s", want, table)
+		}
+	}
+}
+
+func writeCoverageProject(t *testing.T, dir string, withTest bool) {
+	t.Helper()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "calc"), 0755)
+	os.WriteFile(filepath.Join(dir, "calc", "calc.go"), []byte("package calc\n\nfunc Add(a, b int) int { return a + b }\n\nfunc Sub(a, b int) int { return a - b }\n"), 0644)
+	if withTest {
+		os.WriteFile(filepath.Join(dir, "calc", "calc_test.go"), []byte("package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(2, 3) != 5 {\n\t\tt.Error(\"failed\")\n\t}\n}\n"), 0644)
+	}
+}
+
+func TestMeasureCoverage(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeCoverageProject(t, tmpDir, true)
+
+	cfg := &config.Config{}
+	snap := measureCoverage(context.Background(), (&Coordinator{Cfg: cfg}).gateExecutor(), tmpDir, []string{"calc", "missing"})
+	if snap == nil {
+		t.Fatal("Expected a coverage snapshot")
+	}
+	if got := snap.Packages["test/calc"]; got != 50.0 {
+		t.Errorf("Expected 50%% coverage for test/calc, got %v (%v)", got, snap.Packages)
+	}
+	if !snap.HasTotal {
+		t.Error("Expected total coverage from the profile")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "calc", "coverage.out")); !os.IsNotExist(err) {
+		t.Error("Coverage profile should not be written into the repository")
+	}
+}
+
+func TestSelfHealingPipeline_RequireTestsHealsMissingTest(t *testing.T) {
+	tmpDir := t.TempDir()
+	ctx := context.Background()
+	writeCoverageProject(t, tmpDir, false)
+
+	mockAgent := &mockHealingAgent{
+		fixesResponse: []agent.CodeChange{
+			{
+				Path:      "calc/calc_test.go",
+				Operation: agent.OperationCreate,
+				Content:   "package calc\n\nimport \"testing\"\n\nfunc TestSub(t *testing.T) {\n\tif Sub(3, 2) != 1 {\n\t\tt.Error(\"failed\")\n\t}\n}\n",
+			},
+		},
+	}
+	coord := &Coordinator{
+		Agent: mockAgent,
+		Cfg: &config.Config{
+			SelfHealEnabled:     true,
+			SelfHealMaxAttempts: 2,
+			RequireTests:        true,
+			AllowedWriteDirs:    []string{"calc"},
+			PlanMaxFiles:        20,
+		},
+	}
+
+	initial := []agent.CodeChange{{Path: "calc/calc.go", Operation: agent.OperationCreate}}
+	result, err := coord.selfHealingPipeline(ctx, "TEST-123", "Test", initial, tmpDir, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !mockAgent.fixesCalled {
+		t.Fatal("Expected FixErrors to be called for the missing test")
+	}
+	if len(result.Attempts) != 1 || result.Attempts[0].ErrorType != "coverage" {
+		t.Fatalf("Expected one coverage heal attempt, got %+v", result.Attempts)
+	}
+	if !result.Success {
+		t.Error("Expected success once the test was written")
+	}
+	if result.Coverage == nil || result.Coverage.After == nil || result.Coverage.After.Packages["test/calc"] != 50.0 {
+		t.Errorf("Expected coverage to be recorded, got %+v", result.Coverage)
+	}
+}
+
+func TestSelfHealingPipeline_RequireTestsWithoutHealing(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeCoverageProject(t, tmpDir, false)
+
+	coord := &Coordinator{
+		Agent: &mockHealingAgent{},
+		Cfg:   &config.Config{RequireTests: true},
+	}
+
+	initial := []agent.CodeChange{{Path: "calc/calc.go", Operation: agent.OperationCreate}}
+	result, err := coord.selfHealingPipeline(context.Background(), "TEST-123", "Test", initial, tmpDir, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Success {
+		t.Error("Expected failure when no test was written and healing is disabled")
+	}
+}
@@ -0,0 +1,296 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"intern/internal/config"
+	"intern/internal/journal"
+	"intern/internal/ticketing"
+
+	logger "github.com/jenish-jain/logger"
+)
+
+// Dispatcher runs the polling loop for one or more repositories: tickets are
+// fetched once per cycle and each is routed (see routeTicket) to the
+// Coordinator of the repository it belongs to. All Coordinators share the
+// Dispatcher's State and Metrics.
+type Dispatcher struct {
+	Ticketing *ticketing.Service
+	Cfg       *config.Config
+	State     *State
+	Metrics   *Metrics
+	Targets   []RepoTarget // Routing priority order; the first is the fallback
+}
+
+// NewDispatcher returns a Dispatcher over targets, which must not be empty.
+// The first target's Metrics are shared with the rest.
+func NewDispatcher(ticketing *ticketing.Service, cfg *config.Config, state *State, targets ...RepoTarget) *Dispatcher {
+	metrics := targets[0].Coordinator.Metrics
+	if metrics == nil {
+		metrics = NewMetrics()
+	}
+	for _, t := range targets {
+		t.Coordinator.Metrics = metrics
+		t.Coordinator.State = state
+	}
+	return &Dispatcher{Ticketing: ticketing, Cfg: cfg, State: state, Metrics: metrics, Targets: targets}
+}
+
+// Route returns the targets a ticket is routed to; more than one makes it
+// a linked change (see processLinked)
+func (d *Dispatcher) Route(ticket ticketing.Ticket) []RepoTarget {
+	targets, reason := routeTicket(d.Targets, ticket)
+	if len(d.Targets) > 1 {
+		names := make([]string, len(targets))
+		for i, t := range targets {
+			names[i] = t.Config.Name
+		}
+		logger.Info("Routed ticket", "ticket", ticket.Key, "repos", names, "by", reason)
+	}
+	return targets
+}
+
+// ProcessTicket routes a single ticket, prepares its repositories and runs
+// the pipeline on them. Exported for request-driven callers (e.g. the Slack
+// webhook handler) that work outside of Run()'s polling loop.
+func (d *Dispatcher) ProcessTicket(ctx context.Context, key, summary, description string) error {
+	ticket := ticketing.Ticket{Key: key, Summary: summary, Description: description}
+	targets := d.Route(ticket)
+	for _, t := range targets {
+		if err := t.Coordinator.PrepareRepository(ctx); err != nil {
+			return fmt.Errorf("failed to prepare repository %s: %w", t.Config.Name, err)
+		}
+	}
+	if err := d.process(ctx, ticket, targets); err != nil {
+		return err
+	}
+	d.State.MarkProcessed(key)
+	return nil
+}
+
+// process runs a routed ticket: on its one repository, or as a linked
+// change across several
+func (d *Dispatcher) process(ctx context.Context, ticket ticketing.Ticket, targets []RepoTarget) error {
+	if len(targets) == 1 {
+		return targets[0].Coordinator.processTicket(ctx, ticket)
+	}
+	return d.processLinked(ctx, ticket, targets)
+}
+
+// LastTicketMetrics returns the most recently recorded metrics for a ticket
+// key from whichever repository processed it (see
+// Coordinator.LastTicketMetrics)
+func (d *Dispatcher) LastTicketMetrics(key string) (*TicketMetrics, bool) {
+	for _, t := range d.Targets {
+		if tm, ok := t.Coordinator.LastTicketMetrics(key); ok {
+			return tm, true
+		}
+	}
+	return nil, false
+}
+
+// JournalEntry returns a ticket's journal entry from whichever repository
+// processed it
+func (d *Dispatcher) JournalEntry(key string) (journal.Entry, bool) {
+	for _, t := range d.Targets {
+		if e, ok := t.Coordinator.Journal.Find(key); ok {
+			return e, true
+		}
+	}
+	return journal.Entry{}, false
+}
+
+// Run polls for tickets and processes them until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	interval, err := time.ParseDuration(d.Cfg.PollingInterval)
+	if err != nil {
+		interval = 30 * time.Second
+	}
+
+	// Ensure working directories exist
+	for _, t := range d.Targets {
+		_ = os.MkdirAll(t.Coordinator.RepoPaths.WorkingDir(), 0755)
+	}
+
+	// Start metrics server if enabled
+	if d.Cfg.MetricsEnabled {
+		metricsServer := NewMetricsServer(d.Metrics, d.Cfg.MetricsPort)
+		go func() {
+			if err := metricsServer.Start(ctx); err != nil {
+				logger.Error("Metrics server failed", "error", err)
+			}
+		}()
+	}
+
+	// Print final summary and save metrics on shutdown
+	defer func() {
+		snapshot := d.Metrics.Snapshot()
+
+		// Skip if no tickets were processed
+		if snapshot.TicketsProcessed == 0 {
+			logger.Info("Agent shutting down (no tickets processed)")
+			return
+		}
+
+		logger.Info("Agent shutting down - generating final report")
+
+		// Print summary report to console
+		report := GenerateReport(snapshot)
+		fmt.Println("\n" + report)
+
+		// Save metrics to JSON
+		repoRoot := d.Targets[0].Coordinator.RepoPaths.Root()
+		// Note: We don't have access to individual ticket metrics here yet
+		// This will be enhanced in a future iteration to collect them
+		metricsFile, err := SaveMetrics(snapshot, []TicketMetrics{}, repoRoot)
+		if err != nil {
+			logger.Error("Failed to save metrics", "error", err)
+		} else {
+			fmt.Printf("\nDetailed metrics saved to: %s\n\n", metricsFile)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			// Ensure local repos are up to date before each cycle. A repository
+			// that can't be prepared sits the cycle out; its tickets wait.
+			ready := d.prepareTargets(ctx)
+			if len(ready) == 0 {
+				backoffSleep(interval)
+				continue
+			}
+
+			tickets, err := func() ([]ticketing.Ticket, error) {
+				var out []ticketing.Ticket
+				err, attempts := Retry(ctx, BackoffConfig{Initial: time.Second, Max: 10 * time.Second, Multiplier: 2, Jitter: 0.2, MaxRetries: 3}, func() error {
+					t, e := d.Ticketing.GetTickets(ctx, d.Cfg.AgentUsername, d.Cfg.JiraProject)
+					if e != nil {
+						return MakeTransient(e)
+					}
+					out = t
+					return nil
+				})
+				d.Metrics.AddRetries(attempts)
+				return out, err
+			}()
+			if err != nil {
+				logger.Error("Failed to fetch tickets", "error", err)
+				backoffSleep(interval)
+				continue
+			}
+			if len(tickets) == 0 {
+				logger.Info("No tickets to process; sleeping", "interval", interval.String())
+				time.Sleep(interval)
+				continue
+			}
+
+			maxWorkers := d.Cfg.MaxConcurrentTickets
+			if maxWorkers <= 0 {
+				maxWorkers = 1
+			}
+			sem := make(chan struct{}, maxWorkers)
+			var wg sync.WaitGroup
+			for _, t := range tickets {
+				if d.State.IsProcessed(t.Key) {
+					continue
+				}
+				targets := d.Route(t)
+				if d.blocked(t, targets, ready) {
+					continue
+				}
+				sem <- struct{}{}
+				wg.Add(1)
+				go func(ticket ticketing.Ticket, targets []RepoTarget) {
+					key := ticket.Key
+					// Ensure cleanup happens even on panic
+					defer wg.Done()
+					defer func() { <-sem }()
+
+					// Panic recovery - catch and log panics without crashing agent
+					defer func() {
+						if r := recover(); r != nil {
+							logger.Error("Worker panic recovered", "ticket", key, "panic", r)
+							d.Metrics.IncTicketsFailed()
+							// Panic recovered - ticket will not be marked as processed
+							// It will be retried in next polling cycle
+						}
+					}()
+
+					// Process the ticket
+					if err := d.process(ctx, ticket, targets); err != nil {
+						logger.Error("Failed processing ticket", "key", key, "error", err)
+						d.Metrics.IncTicketsFailed()
+						return
+					}
+
+					// Only mark as processed if successful
+					d.State.MarkProcessed(key)
+				}(t, targets)
+			}
+			wg.Wait()
+			// log metrics summary
+			s := d.Metrics.Snapshot()
+			logger.Info("Run summary", "tickets", s.TicketsProcessed, "prs", s.PRsCreated, "retries", s.Retries, "ai_failures", s.AIPlanFailures)
+			time.Sleep(interval)
+		}
+	}
+}
+
+// blocked reports whether a ticket has to wait: one of its repositories
+// couldn't be prepared this cycle, or related work there hasn't merged yet
+func (d *Dispatcher) blocked(t ticketing.Ticket, targets []RepoTarget, ready map[string]bool) bool {
+	for _, target := range targets {
+		if !ready[target.Config.Name] {
+			logger.Info("Deferring ticket - repository not ready", "ticket", t.Key, "repo", target.Config.Name)
+			return true
+		}
+		if blocker := target.Coordinator.journalBlocker(t.Key, t.Summary+" "+t.Description); blocker != "" {
+			logger.Info("Deferring ticket - related work not yet merged", "ticket", t.Key, "waiting_on", blocker)
+			return true
+		}
+	}
+	return false
+}
+
+// prepareTargets syncs every repository, reconciles its journal and runs
+// branch maintenance, returning the names of the repositories that are
+// ready for new work
+func (d *Dispatcher) prepareTargets(ctx context.Context) map[string]bool {
+	ready := make(map[string]bool, len(d.Targets))
+	for _, t := range d.Targets {
+		c := t.Coordinator
+		if err := c.prepareRepository(ctx); err != nil {
+			logger.Error("Repository preparation failed", "repo", t.Config.Name, "error", err)
+			continue
+		}
+		ready[t.Config.Name] = true
+
+		// Reconcile journal entries: flip Merged for PRs that landed since
+		// the last cycle, so deferred tickets can unblock.
+		if updated, err := c.Journal.Reconcile(ctx, c.Repository.IsPRMerged); err != nil {
+			logger.Warn("Journal reconciliation failed", "repo", t.Config.Name, "error", err)
+		} else if updated > 0 {
+			logger.Info("Journal reconciliation: marked PRs as merged", "repo", t.Config.Name, "count", updated)
+		}
+
+		// Keep open PR branches current with the base before picking up
+		// new work (see maintainBranches).
+		if c.Cfg.BranchMaintenanceEnabled && !c.Cfg.DryRun {
+			c.maintainBranches(ctx)
+		}
+
+		// Routing by keyword needs every repository indexed, not just
+		// the ones that have already had a ticket.
+		if len(d.Targets) > 1 {
+			c.refreshIndex(ctx)
+		}
+	}
+	return ready
+}
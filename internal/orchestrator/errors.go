@@ -7,6 +7,11 @@ var (
 	ErrTransient = errors.New("transient")
 	// ErrPermanent is a wrapper to mark permanent failures (do not retry)
 	ErrPermanent = errors.New("permanent")
+
+	// errGatesFailed marks a ticket whose quality gates still fail after
+	// self-healing. A lone ticket is left for a human; in a cross-repository
+	// unit it fails the whole unit.
+	errGatesFailed = errors.New("quality gates failed after self-healing")
 )
 
 // MakeTransient wraps an error as transient
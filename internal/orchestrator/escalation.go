@@ -0,0 +1,92 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+
+	"intern/internal/ai"
+	"intern/internal/ai/agent"
+	"intern/internal/config"
+
+	"github.com/jenish-jain/logger"
+)
+
+// tiers returns the model escalation ladder, cheapest first. Without a
+// configured ladder it's a single tier wrapping Agent.
+func (c *Coordinator) tiers() []agent.Tier {
+	if len(c.Escalation) > 0 {
+		return c.Escalation
+	}
+	name := c.Cfg.AIProvider
+	if name == "ollama" && c.Cfg.OllamaModel != "" {
+		name += ":" + c.Cfg.OllamaModel
+	}
+	return []agent.Tier{{Name: name, Agent: c.Agent}}
+}
+
+// contextTokenBudget returns the tokens of repository context to pack:
+// CONTEXT_MAX_TOKENS if set, otherwise what the smallest planning model
+// takes, since every tier may be sent the same context
+func (c *Coordinator) contextTokenBudget() int {
+	if c.Cfg.ContextMaxTokens > 0 {
+		return c.Cfg.ContextMaxTokens
+	}
+	budget := 0
+	for _, tier := range c.tiers() {
+		provider, model := config.ParseEscalationTier(tier.Name)
+		if b := ai.ContextTokenBudget(provider, model); budget == 0 || b < budget {
+			budget = b
+		}
+	}
+	return budget
+}
+
+// healTier returns the tier for a 1-based self-healing attempt: one attempt
+// per tier, staying on the top tier once the ladder is exhausted.
+func (c *Coordinator) healTier(attempt int) agent.Tier {
+	tiers := c.tiers()
+	i := attempt - 1
+	if i >= len(tiers) {
+		i = len(tiers) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	return tiers[i]
+}
+
+// planChanges calls PlanChanges on each tier in turn, moving up only when a
+// tier answers with output that can't be parsed - transport and API errors
+// are returned as-is for the caller's retry loop. Once a ladder of several
+// tiers is exhausted on unparseable output the error is permanent, so a
+// retry doesn't climb (and pay for) the whole ladder again. Usage is summed
+// across every tier that was tried, since each one was billed, and returned
+// on error too.
+func (c *Coordinator) planChanges(ctx context.Context, key, summary, description, repoContext string) ([]agent.CodeChange, []string, *agent.UsageMetrics, error) {
+	tiers := c.tiers()
+	var total *agent.UsageMetrics
+	var err error
+	for i, tier := range tiers {
+		changes, needFiles, metrics, e := tier.Agent.PlanChanges(ctx, key, summary, description, repoContext)
+		total = sumUsageMetrics(total, metrics)
+		if e == nil {
+			return changes, needFiles, total, nil
+		}
+		err = e
+		if !errors.Is(e, agent.ErrInvalidResponse) {
+			break
+		}
+		if i == len(tiers)-1 {
+			if len(tiers) > 1 {
+				err = MakePermanent(e)
+			}
+			break
+		}
+		logger.Warn("Unparseable plan, escalating to next model",
+			"ticket", key,
+			"from", tier.Name,
+			"to", tiers[i+1].Name,
+			"error", e)
+	}
+	return nil, nil, total, err
+}
@@ -0,0 +1,194 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"intern/internal/ai/agent"
+	"intern/internal/config"
+)
+
+// tierAgent is a scripted agent for escalation tests: each call pops the
+// next error (nil = success) and always reports cost.
+type tierAgent struct {
+	model     string
+	cost      float64
+	planErrs  []error
+	fixErrs   []error
+	fixes     []agent.CodeChange
+	planCalls int
+	fixCalls  int
+}
+
+func (a *tierAgent) next(errs []error, n int) error {
+	if n < len(errs) {
+		return errs[n]
+	}
+	return nil
+}
+
+func (a *tierAgent) PlanChanges(ctx context.Context, ticketKey, ticketSummary, ticketDescription, repoContext string) ([]agent.CodeChange, []string, *agent.UsageMetrics, error) {
+	err := a.next(a.planErrs, a.planCalls)
+	a.planCalls++
+	metrics := &agent.UsageMetrics{EstimatedCost: a.cost, Model: a.model}
+	if err != nil {
+		return nil, nil, metrics, err
+	}
+	return []agent.CodeChange{{Path: "plan.go", Operation: agent.OperationCreate}}, nil, metrics, nil
+}
+
+func (a *tierAgent) FixErrors(ctx context.Context, ticketKey, ticketSummary, errorType, errorOutput string, previousChanges []agent.CodeChange, fileContents map[string]string, history []agent.HealAttempt) ([]agent.CodeChange, *agent.UsageMetrics, error) {
+	err := a.next(a.fixErrs, a.fixCalls)
+	a.fixCalls++
+	metrics := &agent.UsageMetrics{EstimatedCost: a.cost, Model: a.model}
+	if err != nil {
+		return nil, metrics, err
+	}
+	return a.fixes, metrics, nil
+}
+
+func invalidResponse() error {
+	return fmt.Errorf("%w: unexpected end of JSON input", agent.ErrInvalidResponse)
+}
+
+func TestPlanChanges_EscalatesOnUnparseableOutput(t *testing.T) {
+	cheap := &tierAgent{model: "qwen", planErrs: []error{invalidResponse()}}
+	strong := &tierAgent{model: "claude", cost: 0.25}
+	coord := &Coordinator{
+		Cfg:        &config.Config{},
+		Escalation: []agent.Tier{{Name: "ollama:qwen", Agent: cheap}, {Name: "anthropic", Agent: strong}},
+	}
+
+	changes, _, metrics, err := coord.planChanges(context.Background(), "T-1", "s", "d", "ctx")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(changes) != 1 || strong.planCalls != 1 {
+		t.Errorf("Expected the strong tier to produce the plan, got %d changes, %d calls", len(changes), strong.planCalls)
+	}
+	if metrics.Model != "claude" || metrics.EstimatedCost != 0.25 {
+		t.Errorf("Expected summed metrics ending on claude, got %+v", metrics)
+	}
+}
+
+func TestPlanChanges_DoesNotEscalateOnTransportError(t *testing.T) {
+	cheap := &tierAgent{planErrs: []error{errors.New("connection refused")}}
+	strong := &tierAgent{}
+	coord := &Coordinator{
+		Cfg:        &config.Config{},
+		Escalation: []agent.Tier{{Name: "cheap", Agent: cheap}, {Name: "strong", Agent: strong}},
+	}
+
+	if _, _, _, err := coord.planChanges(context.Background(), "T-1", "s", "d", "ctx"); err == nil {
+		t.Fatal("Expected transport error to be returned")
+	}
+	if strong.planCalls != 0 {
+		t.Error("Transport errors should be retried by the caller, not escalated")
+	}
+}
+
+func TestPlanChanges_ExhaustedLadderIsNotRetried(t *testing.T) {
+	cheap := &tierAgent{model: "qwen", cost: 0.01, planErrs: []error{invalidResponse(), invalidResponse()}}
+	strong := &tierAgent{model: "claude", cost: 0.25, planErrs: []error{invalidResponse(), invalidResponse()}}
+	coord := &Coordinator{
+		Cfg:        &config.Config{},
+		Escalation: []agent.Tier{{Name: "ollama:qwen", Agent: cheap}, {Name: "anthropic", Agent: strong}},
+	}
+
+	var usage *agent.UsageMetrics
+	err, retries := Retry(context.Background(), BackoffConfig{MaxRetries: 3}, func() error {
+		_, _, metrics, e := coord.planChanges(context.Background(), "T-1", "s", "d", "ctx")
+		usage = sumUsageMetrics(usage, metrics)
+		return MakeTransient(e)
+	})
+	if !errors.Is(err, agent.ErrInvalidResponse) || !IsPermanent(err) {
+		t.Fatalf("Expected a permanent invalid response error, got %v", err)
+	}
+	if retries != 0 || cheap.planCalls != 1 || strong.planCalls != 1 {
+		t.Errorf("Expected the ladder to be climbed once, got %d retries, %d/%d calls", retries, cheap.planCalls, strong.planCalls)
+	}
+	if usage == nil || usage.EstimatedCost != 0.26 {
+		t.Errorf("Expected the failed tiers' cost to be returned, got %+v", usage)
+	}
+}
+
+func TestHealTier(t *testing.T) {
+	coord := &Coordinator{
+		Cfg:        &config.Config{},
+		Escalation: []agent.Tier{{Name: "a"}, {Name: "b"}},
+	}
+	for attempt, want := range map[int]string{1: "a", 2: "b", 3: "b"} {
+		if got := coord.healTier(attempt).Name; got != want {
+			t.Errorf("healTier(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+
+	single := &Coordinator{Cfg: &config.Config{AIProvider: "ollama", OllamaModel: "qwen"}}
+	if got := single.healTier(2).Name; got != "ollama:qwen" {
+		t.Errorf("Expected default tier named after provider, got %s", got)
+	}
+}
+
+func TestContextTokenBudget(t *testing.T) {
+	coord := &Coordinator{
+		Cfg:        &config.Config{},
+		Escalation: []agent.Tier{{Name: "ollama:qwen2.5-coder:7b"}, {Name: "anthropic"}},
+	}
+	if got := coord.contextTokenBudget(); got != 16384 {
+		t.Errorf("Expected the smallest tier's budget, got %d", got)
+	}
+
+	coord.Cfg.ContextMaxTokens = 9000
+	if got := coord.contextTokenBudget(); got != 9000 {
+		t.Errorf("Expected CONTEXT_MAX_TOKENS to win, got %d", got)
+	}
+}
+
+func TestSelfHealingPipeline_EscalatesAcrossTiers(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n\nfunc main() {\n// missing brace\n"), 0644)
+
+	cheap := &tierAgent{model: "qwen", fixErrs: []error{invalidResponse()}}
+	strong := &tierAgent{
+		model: "claude",
+		cost:  0.10,
+		fixes: []agent.CodeChange{{
+			Path:      "main.go",
+			Operation: agent.OperationEdit,
+			Edits:     []agent.EditHunk{{Old: "func main() {\n// missing brace\n", New: "func main() {}\n"}},
+		}},
+	}
+	coord := &Coordinator{
+		Cfg: &config.Config{
+			SelfHealEnabled:     true,
+			SelfHealMaxAttempts: 3,
+			SelfHealOnBuild:     true,
+			AllowedWriteDirs:    []string{"."},
+			PlanMaxFiles:        20,
+		},
+		Escalation: []agent.Tier{{Name: "ollama:qwen", Agent: cheap}, {Name: "anthropic", Agent: strong}},
+	}
+
+	result, err := coord.selfHealingPipeline(context.Background(), "T-1", "s", nil, tmpDir, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Expected the strong tier to heal the build, got %+v", result.Attempts)
+	}
+	if len(result.Attempts) != 2 {
+		t.Fatalf("Expected 2 attempts, got %d", len(result.Attempts))
+	}
+	if result.Attempts[0].Model != "ollama:qwen" || result.Attempts[1].Model != "anthropic" {
+		t.Errorf("Expected per-attempt models [ollama:qwen anthropic], got [%s %s]",
+			result.Attempts[0].Model, result.Attempts[1].Model)
+	}
+	if result.TotalCost != 0.10 {
+		t.Errorf("Expected TotalCost 0.10, got %v", result.TotalCost)
+	}
+}
@@ -0,0 +1,166 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"intern/internal/repository"
+	"intern/internal/ticketing"
+
+	logger "github.com/jenish-jain/logger"
+)
+
+// linkedStage is one repository's part of a cross-repository change
+type linkedStage struct {
+	target RepoTarget
+	st     *stagedTicket
+}
+
+// processLinked runs a ticket routed to several repositories as one unit.
+// Every repository is staged (planned, applied, committed and gated) before
+// anything is pushed; if one fails, all of them are rolled back. If
+// publishing fails partway, the PRs already opened are marked as drafts with
+// a comment saying why. Once all PRs are open, each body links the others,
+// any drafted by an earlier failed attempt is marked ready for review again,
+// and every repository's journal records the whole set.
+func (d *Dispatcher) processLinked(ctx context.Context, ticket ticketing.Ticket, targets []RepoTarget) error {
+	key := ticket.Key
+	names := make([]string, len(targets))
+	for i, t := range targets {
+		names[i] = t.Config.Name
+	}
+	logger.Info("Processing linked change", "ticket", key, "repos", names)
+
+	var staged []linkedStage
+	for _, t := range targets {
+		st, err := t.Coordinator.stageTicket(ctx, ticket, linkedPreamble(t.Config.Name, names))
+		if err != nil {
+			d.rollbackLinked(ctx, key, targets)
+			if err == errGatesFailed {
+				// Left for a human, as for a single-repository ticket
+				logger.Error("Linked change failed quality gates; rolled back all repositories",
+					"ticket", key, "repo", t.Config.Name, "repos", names)
+				return nil
+			}
+			return fmt.Errorf("linked change failed in %s, rolled back %s: %w", t.Config.Name, strings.Join(names, ", "), err)
+		}
+		if st == nil {
+			logger.Info("No changes needed in linked repository", "ticket", key, "repo", t.Config.Name)
+			continue
+		}
+		staged = append(staged, linkedStage{target: t, st: st})
+	}
+	if len(staged) == 0 {
+		logger.Info("No effective changes in any linked repository; skipping push/PR", "key", key)
+		return nil
+	}
+
+	if d.Cfg.DryRun {
+		for _, s := range staged {
+			s.target.Coordinator.logDryRun(s.st)
+		}
+	} else {
+		for i, s := range staged {
+			if err := s.target.Coordinator.publishTicket(ctx, s.st); err != nil {
+				d.draftLinked(ctx, staged[:i], s.target.Config.Name, err)
+				for _, rest := range staged[i:] {
+					d.rollbackLinked(ctx, key, []RepoTarget{rest.target})
+				}
+				return fmt.Errorf("linked change failed to publish in %s: %w", s.target.Config.Name, err)
+			}
+		}
+		d.crossLink(ctx, staged)
+		d.readyLinked(ctx, staged)
+		for _, s := range staged {
+			s.target.Coordinator.recordTicket(s.st, otherPRURLs(staged, s))
+		}
+	}
+
+	// Mark Done once for the whole unit
+	if err := d.Ticketing.UpdateTicketStatus(ctx, key, "Done", d.Cfg.JiraTransitions); err != nil {
+		logger.Error("Failed to move ticket to Done", "error", err)
+	}
+	return nil
+}
+
+// linkedPreamble tells the planner which part of a linked change it's
+// working on, so each repository only gets its own side of the change
+func linkedPreamble(repo string, all []string) string {
+	return fmt.Sprintf("# Linked change\n# This ticket spans the repositories %s; each gets its own PR.\n"+
+		"# You are working in %s: plan only the changes that belong in this repository.\n\n",
+		strings.Join(all, ", "), repo)
+}
+
+// rollbackLinked discards the ticket's local branch in each target, so a
+// failed unit leaves nothing behind to be pushed by a later run
+func (d *Dispatcher) rollbackLinked(ctx context.Context, key string, targets []RepoTarget) {
+	for _, t := range targets {
+		c := t.Coordinator
+		branch := buildBranchName(c.Cfg.BranchPrefix, key)
+		if err := c.Repository.DiscardBranch(ctx, branch, c.baseBranch()); err != nil {
+			logger.Warn("Failed to roll back linked branch", "ticket", key, "repo", t.Config.Name, "branch", branch, "error", err)
+		}
+	}
+}
+
+// draftLinked marks the PRs already opened for a unit as drafts, with a
+// comment naming the repository that failed, so none of them is merged alone
+func (d *Dispatcher) draftLinked(ctx context.Context, published []linkedStage, failedRepo string, cause error) {
+	for _, s := range published {
+		repo := s.target.Coordinator.Repository
+		if err := repo.MarkPullRequestDraft(ctx, s.st.prURL); err != nil {
+			logger.Warn("Failed to mark linked PR as draft", "url", s.st.prURL, "error", err)
+		}
+		comment := fmt.Sprintf("This PR is part of a change spanning several repositories, and its counterpart in `%s` failed: %v\n\n"+
+			"It has been marked as a draft so it isn't merged on its own. The ticket will be retried.", failedRepo, cause)
+		if err := repo.CommentOnPullRequest(ctx, s.st.prURL, comment); err != nil {
+			logger.Warn("Failed to comment on linked PR", "url", s.st.prURL, "error", err)
+		}
+	}
+}
+
+// readyLinked marks a unit's PRs ready for review once all of them are open,
+// undoing draftLinked from an earlier attempt; PRs meant to be drafts
+// (PR_DRAFT, or paths awaiting approval) are left as they are
+func (d *Dispatcher) readyLinked(ctx context.Context, staged []linkedStage) {
+	for _, s := range staged {
+		if s.st.prDraft {
+			continue
+		}
+		if err := s.target.Coordinator.Repository.MarkPullRequestReady(ctx, s.st.prURL); err != nil {
+			logger.Warn("Failed to mark linked PR ready for review", "url", s.st.prURL, "error", err)
+		}
+	}
+}
+
+// crossLink rewrites each PR body in a unit to list the other PRs
+func (d *Dispatcher) crossLink(ctx context.Context, staged []linkedStage) {
+	if len(staged) < 2 {
+		return
+	}
+	for _, s := range staged {
+		s.st.prData.LinkedPRs = nil
+		for _, other := range staged {
+			if other.st != s.st {
+				s.st.prData.LinkedPRs = append(s.st.prData.LinkedPRs, PRLink{Repo: other.target.Config.Name, URL: other.st.prURL})
+			}
+		}
+		c := s.target.Coordinator
+		body := buildPRBody(c.RepoPaths.Root(), s.st.prData)
+		if err := c.Repository.UpdatePullRequest(ctx, s.st.prURL, s.st.prTitle, body, repository.PullRequestOptions{}); err != nil {
+			logger.Warn("Failed to link PR to the rest of its change", "url", s.st.prURL, "error", err)
+		}
+	}
+}
+
+// otherPRURLs returns the PR URLs of every stage in a unit except s
+func otherPRURLs(staged []linkedStage, s linkedStage) []string {
+	var out []string
+	for _, other := range staged {
+		if other.st != s.st {
+			out = append(out, other.st.prURL)
+		}
+	}
+	return out
+}
@@ -0,0 +1,137 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"intern/internal/config"
+	"intern/internal/repository"
+	"intern/internal/ticketing"
+)
+
+// fakeLinkedRepo records the calls a linked change makes on one repository
+type fakeLinkedRepo struct {
+	repository.RepositoryClient
+	calls    []string
+	comments []string
+	body     string
+}
+
+func (f *fakeLinkedRepo) DiscardBranch(ctx context.Context, branch, base string) error {
+	f.calls = append(f.calls, "discard "+branch+" "+base)
+	return nil
+}
+
+func (f *fakeLinkedRepo) MarkPullRequestDraft(ctx context.Context, prURL string) error {
+	f.calls = append(f.calls, "draft "+prURL)
+	return nil
+}
+
+func (f *fakeLinkedRepo) MarkPullRequestReady(ctx context.Context, prURL string) error {
+	f.calls = append(f.calls, "ready "+prURL)
+	return nil
+}
+
+func (f *fakeLinkedRepo) CommentOnPullRequest(ctx context.Context, prURL, body string) error {
+	f.comments = append(f.comments, body)
+	return nil
+}
+
+func (f *fakeLinkedRepo) UpdatePullRequest(ctx context.Context, prURL, title, body string, opts repository.PullRequestOptions) error {
+	f.calls = append(f.calls, "update "+prURL)
+	f.body = body
+	return nil
+}
+
+// linkedStages returns one published stage per repository name
+func linkedStages(t *testing.T, names ...string) ([]linkedStage, map[string]*fakeLinkedRepo) {
+	t.Helper()
+	workDir := t.TempDir()
+	fakes := make(map[string]*fakeLinkedRepo)
+	var staged []linkedStage
+	for _, name := range names {
+		paths, err := repository.NewRepositoryPath(workDir, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fakes[name] = &fakeLinkedRepo{}
+		staged = append(staged, linkedStage{
+			target: RepoTarget{
+				Config: config.RepoConfig{Name: name},
+				Coordinator: &Coordinator{
+					Repository: repository.NewRepositoryService(fakes[name]),
+					RepoPaths:  paths,
+					Cfg:        &config.Config{BaseBranch: "main", BranchPrefix: "feature/"},
+				},
+			},
+			st: &stagedTicket{
+				prURL:   "https://github.com/acme/" + name + "/pull/1",
+				prTitle: "PROJ-1: Add refunds",
+				prData:  PRBodyData{Ticket: ticketing.Ticket{Key: "PROJ-1", Summary: "Add refunds"}},
+			},
+		})
+	}
+	return staged, fakes
+}
+
+func TestCrossLink(t *testing.T) {
+	staged, fakes := linkedStages(t, "api", "web")
+	d := &Dispatcher{}
+	d.crossLink(context.Background(), staged)
+
+	if !strings.Contains(fakes["api"].body, "- web: https://github.com/acme/web/pull/1") ||
+		strings.Contains(fakes["api"].body, "- api:") {
+		t.Errorf("Expected the api PR to link only the web PR, got:\n%s", fakes["api"].body)
+	}
+	if !strings.Contains(fakes["web"].body, "- api: https://github.com/acme/api/pull/1") {
+		t.Errorf("Expected the web PR to link the api PR, got:\n%s", fakes["web"].body)
+	}
+	if got := otherPRURLs(staged, staged[0]); len(got) != 1 || got[0] != staged[1].st.prURL {
+		t.Errorf("otherPRURLs = %v", got)
+	}
+}
+
+func TestDraftAndRollbackLinked(t *testing.T) {
+	staged, fakes := linkedStages(t, "api", "web")
+	d := &Dispatcher{}
+	ctx := context.Background()
+
+	d.draftLinked(ctx, staged[:1], "web", context.DeadlineExceeded)
+	if len(fakes["api"].calls) != 1 || fakes["api"].calls[0] != "draft https://github.com/acme/api/pull/1" {
+		t.Errorf("Expected the published PR to be drafted, got %v", fakes["api"].calls)
+	}
+	if len(fakes["api"].comments) != 1 || !strings.Contains(fakes["api"].comments[0], "`web` failed") {
+		t.Errorf("Expected a comment naming the failed repository, got %v", fakes["api"].comments)
+	}
+	if len(fakes["web"].calls) != 0 {
+		t.Errorf("Expected the failed repository's PR to be left alone, got %v", fakes["web"].calls)
+	}
+
+	d.rollbackLinked(ctx, "PROJ-1", []RepoTarget{staged[1].target})
+	if len(fakes["web"].calls) != 1 || fakes["web"].calls[0] != "discard feature/proj-1 main" {
+		t.Errorf("Expected the ticket branch to be discarded, got %v", fakes["web"].calls)
+	}
+}
+
+func TestReadyLinked_Retry(t *testing.T) {
+	staged, fakes := linkedStages(t, "api", "web", "docs")
+	d := &Dispatcher{}
+	ctx := context.Background()
+
+	// The first attempt published api, then failed in web
+	d.draftLinked(ctx, staged[:1], "web", context.DeadlineExceeded)
+
+	// The retry publishes the whole unit; docs needs approval, so it stays a draft
+	staged[2].st.prDraft = true
+	d.readyLinked(ctx, staged)
+	if calls := fakes["api"].calls; len(calls) != 2 || calls[1] != "ready https://github.com/acme/api/pull/1" {
+		t.Errorf("Expected the drafted PR to be marked ready again, got %v", calls)
+	}
+	if calls := fakes["web"].calls; len(calls) != 1 || calls[0] != "ready https://github.com/acme/web/pull/1" {
+		t.Errorf("Expected the web PR to be marked ready, got %v", calls)
+	}
+	if calls := fakes["docs"].calls; len(calls) != 0 {
+		t.Errorf("Expected a PR meant to be a draft to be left alone, got %v", calls)
+	}
+}
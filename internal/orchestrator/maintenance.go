@@ -0,0 +1,182 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"intern/internal/journal"
+	"intern/internal/repository"
+	"intern/internal/ticketing"
+
+	"github.com/jenish-jain/logger"
+)
+
+// maintainBranches keeps the agent's open PRs current with the base branch,
+// so they don't sit as "out of date" (or conflicting) waiting for a human
+// to click update. For every open journal entry whose base has moved, the
+// branch is rebased or merged onto it, re-gated and force-pushed with lease.
+// Conflicts go to the agent like any other error when self-healing is
+// enabled; anything it can't settle is left for a human with a PR comment.
+// Best-effort: failures are logged and retried next cycle.
+func (c *Coordinator) maintainBranches(ctx context.Context) {
+	entries := c.Journal.Open()
+	if len(entries) == 0 {
+		return
+	}
+	base := c.Cfg.BaseBranch
+	if base == "" {
+		base = "main"
+	}
+	// Ticket branches are cut from HEAD, so always end back on the base.
+	defer func() {
+		if err := c.Repository.SwitchBranch(ctx, base); err != nil {
+			logger.Warn("Failed to switch back to base branch after maintenance", "base", base, "error", err)
+		}
+	}()
+
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := c.refreshBranch(ctx, e, base); err != nil {
+			logger.Warn("Branch maintenance failed", "ticket", e.TicketKey, "branch", e.Branch, "error", err)
+		}
+	}
+}
+
+// refreshBranch brings one PR branch up to date with base
+func (c *Coordinator) refreshBranch(ctx context.Context, e journal.Entry, base string) error {
+	open, err := c.Repository.IsPROpen(ctx, e.PRURL)
+	if err != nil {
+		return fmt.Errorf("check PR state: %w", err)
+	}
+	if !open {
+		return nil
+	}
+
+	res, err := c.Repository.RefreshBranch(ctx, e.Branch, base, repository.RefreshStrategy(c.Cfg.BranchRefreshStrategy))
+	if err != nil {
+		return err
+	}
+	if res.UpToDate {
+		logger.Debug("PR branch is up to date with base", "ticket", e.TicketKey, "branch", e.Branch)
+		return nil
+	}
+	if res.BaseHash == e.RefreshBlockedAt {
+		// Already handed to a human at this base commit; wait for it to move.
+		if len(res.Conflicts) > 0 {
+			if err := c.Repository.AbortRefresh(ctx); err != nil {
+				logger.Warn("Failed to abort merge", "branch", e.Branch, "error", err)
+			}
+		}
+		return nil
+	}
+	logger.Info("Base branch moved, refreshing PR branch",
+		"ticket", e.TicketKey, "branch", e.Branch, "strategy", res.Strategy, "conflicts", len(res.Conflicts))
+
+	if len(res.Conflicts) > 0 {
+		if err := c.resolveConflicts(ctx, e, base, res.Conflicts); err != nil {
+			if abortErr := c.Repository.AbortRefresh(ctx); abortErr != nil {
+				logger.Warn("Failed to abort merge", "branch", e.Branch, "error", abortErr)
+			}
+			c.blockRefresh(ctx, e, res.BaseHash, fmt.Sprintf(
+				"This branch conflicts with `%s` and couldn't be updated automatically (%v).\n\nConflicting files:\n%s\nPlease resolve the conflicts manually.",
+				base, err, markdownFileList(res.Conflicts)))
+			return nil
+		}
+	}
+
+	notes, ok := runQualityGates(ctx, c.Cfg, c.gateExecutor(), c.RepoPaths.Root())
+	if !ok {
+		// Leave the remote branch as it was rather than push a broken update.
+		c.blockRefresh(ctx, e, res.BaseHash, fmt.Sprintf(
+			"`%s` has moved on, but quality gates fail on this branch once it's updated (%s), so it was not pushed:\n\n%s",
+			base, res.Strategy, strings.Join(notes, "\n")))
+		return nil
+	}
+
+	if err := c.Repository.ForcePushWithLease(ctx, e.Branch, res.LeaseHash); err != nil {
+		// Most likely someone pushed to the branch meanwhile; the next
+		// cycle refreshes from their commit instead.
+		return fmt.Errorf("push refreshed branch: %w", err)
+	}
+	c.Metrics.IncBranchesRefreshed()
+	logger.Info("PR branch refreshed", "ticket", e.TicketKey, "branch", e.Branch, "strategy", res.Strategy)
+	return nil
+}
+
+// resolveConflicts asks the agent to resolve an in-progress merge, using the
+// conflicted files (markers included) as the error to fix, then commits the
+// merge. Each attempt moves up the escalation ladder, as in self-healing.
+func (c *Coordinator) resolveConflicts(ctx context.Context, e journal.Entry, base string, conflicts []string) error {
+	if !c.Cfg.SelfHealEnabled {
+		return fmt.Errorf("self-healing is disabled")
+	}
+	repoRoot := c.RepoPaths.Root()
+	errorOutput := fmt.Sprintf("Merging %s into %s left conflict markers in:\n%s", base, e.Branch, markdownFileList(conflicts))
+
+	var lastErr error
+	for attempt := 1; attempt <= c.Cfg.SelfHealMaxAttempts; attempt++ {
+		fileContents := make(map[string]string, len(conflicts))
+		for _, path := range conflicts {
+			if data, err := os.ReadFile(filepath.Join(repoRoot, path)); err == nil {
+				fileContents[path] = string(data)
+			}
+		}
+
+		tier := c.healTier(attempt)
+		fixes, metrics, err := tier.Agent.FixErrors(ctx, e.TicketKey, e.Summary, "merge_conflict", errorOutput, nil, fileContents, nil)
+		if metrics != nil {
+			c.Metrics.AddTokenUsage(metrics.InputTokens, metrics.OutputTokens, metrics.EstimatedCost)
+		}
+		if err != nil {
+			lastErr = fmt.Errorf("AI conflict resolution failed: %w", err)
+			continue
+		}
+		valid, _, err := validatePlannedChanges(repoRoot, fixes, c.Cfg.AllowedWriteDirs, c.Cfg.PlanMaxFiles)
+		if err != nil {
+			lastErr = fmt.Errorf("conflict resolution validation failed: %w", err)
+			continue
+		}
+		lastErr = nil
+		for _, ch := range valid {
+			if err := applyCodeChange(repoRoot, ch); err != nil {
+				lastErr = err
+				break
+			}
+		}
+		if lastErr == nil {
+			lastErr = c.Repository.ContinueRefresh(ctx, commitMessage(c.Cfg, ticketing.Ticket{Key: e.TicketKey}, fmt.Sprintf("Merge %s into %s", base, e.Branch)), changedPaths(valid))
+		}
+		if lastErr == nil {
+			logger.Info("Resolved merge conflicts", "ticket", e.TicketKey, "branch", e.Branch, "attempt", attempt, "model", tier.Name)
+			return nil
+		}
+		logger.Warn("Conflict resolution attempt failed", "ticket", e.TicketKey, "attempt", attempt, "error", lastErr)
+	}
+	return lastErr
+}
+
+// blockRefresh hands a branch that can't be refreshed onto baseHash to a
+// human: it comments on the PR once and records the base commit so later
+// cycles skip the branch until the base moves again.
+func (c *Coordinator) blockRefresh(ctx context.Context, e journal.Entry, baseHash, comment string) {
+	c.Metrics.IncBranchRefreshFailures()
+	if err := c.Repository.CommentOnPullRequest(ctx, e.PRURL, comment); err != nil {
+		logger.Warn("Failed to comment on PR", "ticket", e.TicketKey, "pr", e.PRURL, "error", err)
+	}
+	if err := c.Journal.SetRefreshBlocked(e.Branch, baseHash); err != nil {
+		logger.Warn("Failed to record blocked refresh in journal", "ticket", e.TicketKey, "error", err)
+	}
+}
+
+func markdownFileList(paths []string) string {
+	var b strings.Builder
+	for _, p := range paths {
+		fmt.Fprintf(&b, "- `%s`\n", p)
+	}
+	return b.String()
+}
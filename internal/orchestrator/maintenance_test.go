@@ -0,0 +1,147 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"intern/internal/ai/agent"
+	"intern/internal/config"
+	"intern/internal/journal"
+	"intern/internal/repository"
+)
+
+// fakeRefreshRepo stubs the repository calls branch maintenance makes
+type fakeRefreshRepo struct {
+	repository.RepositoryClient
+	open     bool
+	result   repository.RefreshResult
+	calls    []string
+	comments []string
+	lease    string
+}
+
+func (f *fakeRefreshRepo) IsPROpen(ctx context.Context, prURL string) (bool, error) {
+	return f.open, nil
+}
+
+func (f *fakeRefreshRepo) RefreshBranch(ctx context.Context, branch, base string, strategy repository.RefreshStrategy) (*repository.RefreshResult, error) {
+	f.calls = append(f.calls, "refresh")
+	res := f.result
+	return &res, nil
+}
+
+func (f *fakeRefreshRepo) ContinueRefresh(ctx context.Context, message string, paths []string) error {
+	f.calls = append(f.calls, "continue")
+	return nil
+}
+
+func (f *fakeRefreshRepo) AbortRefresh(ctx context.Context) error {
+	f.calls = append(f.calls, "abort")
+	return nil
+}
+
+func (f *fakeRefreshRepo) ForcePushWithLease(ctx context.Context, branch, expectedHash string) error {
+	f.calls = append(f.calls, "push")
+	f.lease = expectedHash
+	return nil
+}
+
+func (f *fakeRefreshRepo) CommentOnPullRequest(ctx context.Context, prURL, body string) error {
+	f.comments = append(f.comments, body)
+	return nil
+}
+
+func (f *fakeRefreshRepo) SwitchBranch(ctx context.Context, branch string) error {
+	return nil
+}
+
+func newMaintenanceCoordinator(t *testing.T, repo *fakeRefreshRepo, a agent.Agent, selfHeal bool) *Coordinator {
+	t.Helper()
+	paths, err := repository.NewRepositoryPath(t.TempDir(), "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(paths.Root(), 0755)
+	j := journal.Load(paths.Root())
+	j.Append(journal.Entry{TicketKey: "PROJ-1", Summary: "Add Sub", Branch: "feature/PROJ-1", PRURL: "https://github.com/acme/app/pull/7"})
+	return &Coordinator{
+		Repository: repository.NewRepositoryService(repo),
+		Agent:      a,
+		Cfg: &config.Config{
+			BaseBranch:          "main",
+			SelfHealEnabled:     selfHeal,
+			SelfHealMaxAttempts: 2,
+			AllowedWriteDirs:    []string{"*"},
+			PlanMaxFiles:        20,
+		},
+		Metrics:   NewMetrics(),
+		RepoPaths: paths,
+		Journal:   j,
+	}
+}
+
+func TestMaintainBranches_ResolvesConflictAndPushes(t *testing.T) {
+	repo := &fakeRefreshRepo{open: true, result: repository.RefreshResult{
+		Strategy: repository.RefreshMerge, Conflicts: []string{"calc.go"}, LeaseHash: "abc123", BaseHash: "base1",
+	}}
+	conflicted := "package calc\n<<<<<<< HEAD\nfunc Add(a, b int) int { return b + a }\n=======\nfunc Add(x, y int) int { return x + y }\n>>>>>>> origin/main\n"
+	fixer := &mockHealingAgent{fixesResponse: []agent.CodeChange{{
+		Path: "calc.go", Operation: agent.OperationEdit,
+		Edits: []agent.EditHunk{{Old: strings.TrimPrefix(conflicted, "package calc\n"), New: "func Add(x, y int) int { return y + x }\n"}},
+	}}}
+	c := newMaintenanceCoordinator(t, repo, fixer, true)
+	os.WriteFile(filepath.Join(c.RepoPaths.Root(), "calc.go"), []byte(conflicted), 0644)
+
+	c.maintainBranches(context.Background())
+
+	if got := strings.Join(repo.calls, ","); got != "refresh,continue,push" {
+		t.Errorf("Expected refresh, merge commit and push, got %s", got)
+	}
+	if repo.lease != "abc123" {
+		t.Errorf("Expected the push to be leased on the fetched branch, got %q", repo.lease)
+	}
+	if data, _ := os.ReadFile(filepath.Join(c.RepoPaths.Root(), "calc.go")); strings.Contains(string(data), "<<<<<<<") {
+		t.Errorf("Expected markers to be resolved, got:\n%s", data)
+	}
+	if s := c.Metrics.Snapshot(); s.BranchesRefreshed != 1 || s.BranchRefreshFailures != 0 {
+		t.Errorf("Expected one refreshed branch, got %+v", s)
+	}
+}
+
+func TestMaintainBranches_UnresolvedConflictCommentsOnce(t *testing.T) {
+	repo := &fakeRefreshRepo{open: true, result: repository.RefreshResult{
+		Strategy: repository.RefreshMerge, Conflicts: []string{"calc.go"}, LeaseHash: "abc123", BaseHash: "base1",
+	}}
+	c := newMaintenanceCoordinator(t, repo, &mockHealingAgent{}, false)
+
+	c.maintainBranches(context.Background())
+	c.maintainBranches(context.Background())
+
+	if got := strings.Join(repo.calls, ","); got != "refresh,abort,refresh,abort" {
+		t.Errorf("Expected both cycles to abort without pushing, got %s", got)
+	}
+	if len(repo.comments) != 1 || !strings.Contains(repo.comments[0], "- `calc.go`") {
+		t.Errorf("Expected exactly one comment listing calc.go, got %q", repo.comments)
+	}
+
+	// Once the base moves again, the branch is retried.
+	repo.result.BaseHash = "base2"
+	c.maintainBranches(context.Background())
+	if len(repo.comments) != 2 {
+		t.Errorf("Expected a new comment after the base moved, got %d", len(repo.comments))
+	}
+}
+
+func TestMaintainBranches_SkipsClosedPRs(t *testing.T) {
+	repo := &fakeRefreshRepo{open: false}
+	c := newMaintenanceCoordinator(t, repo, &mockHealingAgent{}, true)
+
+	c.maintainBranches(context.Background())
+
+	if len(repo.calls) != 0 {
+		t.Errorf("Expected closed PRs to be left alone, got %v", repo.calls)
+	}
+}
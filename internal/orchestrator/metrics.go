@@ -26,9 +26,14 @@ type Metrics struct {
 	simpleContextUsed int64
 
 	// Self-healing metrics
-	healAttempts  int64 // Total healing attempts across all tickets
-	healSuccesses int64 // Tickets successfully healed
-	healFailures  int64 // Tickets that failed healing
+	healAttempts     int64 // Total healing attempts across all tickets
+	healSuccesses    int64 // Tickets successfully healed
+	healFailures     int64 // Tickets that failed healing
+	healOscillations int64 // Tickets where healing stopped because an error came back
+
+	// Branch maintenance
+	branchesRefreshed     int64 // PR branches brought up to date with their base and pushed
+	branchRefreshFailures int64 // Refreshes left for a human (unresolved conflict, failing gates)
 
 	// Performance tracking (using milliseconds for atomic operations)
 	totalExecutionTimeMs int64
@@ -75,8 +80,13 @@ func (m *Metrics) AddHealAttempts(n int) {
 		atomic.AddInt64(&m.healAttempts, int64(n))
 	}
 }
-func (m *Metrics) IncHealSuccesses() { atomic.AddInt64(&m.healSuccesses, 1) }
-func (m *Metrics) IncHealFailures()  { atomic.AddInt64(&m.healFailures, 1) }
+func (m *Metrics) IncHealSuccesses()    { atomic.AddInt64(&m.healSuccesses, 1) }
+func (m *Metrics) IncHealFailures()     { atomic.AddInt64(&m.healFailures, 1) }
+func (m *Metrics) IncHealOscillations() { atomic.AddInt64(&m.healOscillations, 1) }
+
+// Branch maintenance tracking
+func (m *Metrics) IncBranchesRefreshed()     { atomic.AddInt64(&m.branchesRefreshed, 1) }
+func (m *Metrics) IncBranchRefreshFailures() { atomic.AddInt64(&m.branchRefreshFailures, 1) }
 
 // Performance tracking
 func (m *Metrics) AddExecutionTime(d time.Duration) {
@@ -106,9 +116,14 @@ type MetricsSnapshot struct {
 	SimpleContextUsed int64
 
 	// Self-healing
-	HealAttempts  int64
-	HealSuccesses int64
-	HealFailures  int64
+	HealAttempts     int64
+	HealSuccesses    int64
+	HealFailures     int64
+	HealOscillations int64
+
+	// Branch maintenance
+	BranchesRefreshed     int64
+	BranchRefreshFailures int64
 
 	// Performance
 	TotalExecutionTime time.Duration
@@ -163,6 +178,9 @@ func (m *Metrics) Snapshot() MetricsSnapshot {
 		HealAttempts:      atomic.LoadInt64(&m.healAttempts),
 		HealSuccesses:     atomic.LoadInt64(&m.healSuccesses),
 		HealFailures:      atomic.LoadInt64(&m.healFailures),
+		HealOscillations:  atomic.LoadInt64(&m.healOscillations),
+		BranchesRefreshed:     atomic.LoadInt64(&m.branchesRefreshed),
+		BranchRefreshFailures: atomic.LoadInt64(&m.branchRefreshFailures),
 		TotalExecutionTime: totalExecutionTime,
 		AvgExecutionTime:   avgExecTime,
 		TotalFilesChanged:  totalFilesChanged,
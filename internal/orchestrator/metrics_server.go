@@ -127,6 +127,18 @@ func (ms *MetricsServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "# TYPE ai_intern_heal_failures_total counter\n")
 	fmt.Fprintf(w, "ai_intern_heal_failures_total %d\n\n", snapshot.HealFailures)
 
+	fmt.Fprintf(w, "# HELP ai_intern_heal_oscillations_total Number of tickets where self-healing stopped because a fixed error came back\n")
+	fmt.Fprintf(w, "# TYPE ai_intern_heal_oscillations_total counter\n")
+	fmt.Fprintf(w, "ai_intern_heal_oscillations_total %d\n\n", snapshot.HealOscillations)
+
+	fmt.Fprintf(w, "# HELP ai_intern_branches_refreshed_total Number of PR branches refreshed onto a moved base branch\n")
+	fmt.Fprintf(w, "# TYPE ai_intern_branches_refreshed_total counter\n")
+	fmt.Fprintf(w, "ai_intern_branches_refreshed_total %d\n\n", snapshot.BranchesRefreshed)
+
+	fmt.Fprintf(w, "# HELP ai_intern_branch_refresh_failures_total Number of branch refreshes left for a human\n")
+	fmt.Fprintf(w, "# TYPE ai_intern_branch_refresh_failures_total counter\n")
+	fmt.Fprintf(w, "ai_intern_branch_refresh_failures_total %d\n\n", snapshot.BranchRefreshFailures)
+
 	fmt.Fprintf(w, "# HELP ai_intern_files_changed_total Total number of files changed\n")
 	fmt.Fprintf(w, "# TYPE ai_intern_files_changed_total counter\n")
 	fmt.Fprintf(w, "ai_intern_files_changed_total %d\n\n", snapshot.TotalFilesChanged)
@@ -309,6 +321,10 @@ func (ms *MetricsServer) handleDashboard(w http.ResponseWriter, r *http.Request)
                     <span class="metric-name">Failures</span>
                     <span class="metric-number error">%d</span>
                 </div>
+                <div class="metric-row">
+                    <span class="metric-name">Oscillations</span>
+                    <span class="metric-number error">%d</span>
+                </div>
                 <div class="metric-row">
                     <span class="metric-name">Success Rate</span>
                     <span class="metric-number">%s</span>
@@ -370,6 +386,7 @@ func (ms *MetricsServer) handleDashboard(w http.ResponseWriter, r *http.Request)
 		snapshot.HealAttempts,
 		snapshot.HealSuccesses,
 		snapshot.HealFailures,
+		snapshot.HealOscillations,
 		calculateSuccessRate(snapshot.HealSuccesses, snapshot.HealAttempts),
 		snapshot.SmartContextUsed,
 		snapshot.SimpleContextUsed,
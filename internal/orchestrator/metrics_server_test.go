@@ -59,6 +59,8 @@ func TestHandleMetrics(t *testing.T) {
 	metrics.IncSmartContextUsed()
 	metrics.AddHealAttempts(2)
 	metrics.IncHealSuccesses()
+	metrics.IncHealOscillations()
+	metrics.IncBranchesRefreshed()
 
 	server := NewMetricsServer(metrics, 9090)
 
@@ -100,6 +102,9 @@ func TestHandleMetrics(t *testing.T) {
 		"ai_intern_heal_attempts_total 2",
 		"# HELP ai_intern_heal_successes_total",
 		"ai_intern_heal_successes_total 1",
+		"ai_intern_heal_oscillations_total 1",
+		"ai_intern_branches_refreshed_total 1",
+		"ai_intern_branch_refresh_failures_total 0",
 	}
 
 	for _, expected := range expectedMetrics {
@@ -3,9 +3,136 @@ package orchestrator
 import (
 	"fmt"
 	"intern/internal/ai/agent"
+	"intern/internal/codeowners"
+	"intern/internal/config"
+	"intern/internal/policy"
+	"intern/internal/repository"
+	"intern/internal/ticketing"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"text/template"
+
+	"github.com/jenish-jain/logger"
 )
 
+// prTemplateFile lets a target repository override the PR body. It's a Go
+// text/template rendered with PRBodyData; see defaultPRTemplate for an example.
+// The write policy always protects it, so a change can't rewrite its own PR.
+const prTemplateFile = ".ai-intern/pr_template.md"
+
+// PRBodyData is everything a PR body template can reference
+type PRBodyData struct {
+	Ticket       ticketing.Ticket
+	TicketURL    string          // Browsable link to the ticket, "" if unknown
+	Files        []PRFileSummary // Every file the PR touches, in change order
+	LineCounts   bool            // False when the diff couldn't be computed
+	LinesAdded   int
+	LinesRemoved int
+	Approvals    []policy.Approval // Changed paths the write policy says need sign-off
+	NewAPIs      []string          // Exported symbols added, as "path.Symbol"
+	AINotes      []string          // Judgment calls the model flagged for review, as "path: note"
+	GateNotes    []string          // Quality gate results
+	Healing      []PRHealAttempt   // Self-healing attempts, oldest first
+	Coverage     string            // Markdown coverage table, "" when not measured
+	Usage        PRUsage
+	LinkedPRs    []PRLink // The same ticket's PRs in other repositories
+}
+
+// PRLink is a PR opened for the same ticket in another repository
+type PRLink struct {
+	Repo string
+	URL  string
+}
+
+// PRFileSummary is one row of the PR's changed-files table
+type PRFileSummary struct {
+	Path      string
+	Operation string
+	Added     int
+	Removed   int
+}
+
+// PRHealAttempt summarizes one self-healing attempt for reviewers
+type PRHealAttempt struct {
+	Attempt   int
+	ErrorType string
+	Error     string // First error lines of the failure this attempt targeted
+	Model     string
+	Files     int // Number of files the fix touched
+	Outcome   string
+}
+
+// PRUsage is the AI spend on the ticket, planning and self-healing combined
+type PRUsage struct {
+	InputTokens  int
+	OutputTokens int
+	PlanningCost float64
+	HealingCost  float64
+	TotalCost    float64
+	Model        string
+}
+
+const defaultPRTemplate = `## Ticket
+{{if .TicketURL}}[{{.Ticket.Key}}]({{.TicketURL}}){{else}}{{.Ticket.Key}}{{end}}{{with .Ticket.Summary}}: {{.}}{{end}}
+
+## Description
+{{with trim .Ticket.Description}}{{.}}{{else}}(no description provided){{end}}
+{{- if .LinkedPRs}}
+
+## Linked Pull Requests
+This ticket spans several repositories. Review and merge these together:
+{{- range .LinkedPRs}}
+- {{.Repo}}: {{.URL}}{{end}}{{end}}
+
+## Changes
+{{if not .Files}}(no changes)
+{{else if .LineCounts}}{{len .Files}} file(s), +{{.LinesAdded}} -{{.LinesRemoved}}
+
+| File | Change | + | - |
+|---|---|---|---|
+{{range .Files}}| ` + "`{{.Path}}`" + ` | {{.Operation}} | {{.Added}} | {{.Removed}} |
+{{end}}{{else}}{{range .Files}}- ` + "`{{.Path}}`" + ` ({{.Operation}})
+{{end}}{{end}}
+{{- if .Approvals}}
+## Requires Approval
+Repository policy requires a human to approve these changes before merging:
+{{range .Approvals}}- ` + "`{{.Path}}`" + `: {{.Reason}}{{with .Owners}} ({{join . ", "}}){{end}}
+{{end}}{{end}}
+{{- if .NewAPIs}}
+## New APIs
+{{range .NewAPIs}}- ` + "`{{.}}`" + `
+{{end}}{{end}}
+{{- if .AINotes}}
+## Review Notes
+Judgment calls made while implementing this ticket - please confirm or override:
+{{range .AINotes}}- {{.}}
+{{end}}{{end}}
+## Quality Gates
+{{range .GateNotes}}- {{.}}
+{{else}}(none run)
+{{end}}
+{{- if .Healing}}
+## Self-Healing
+| # | Error | Model | Files | Outcome |
+|---|---|---|---|---|
+{{range .Healing}}| {{.Attempt}} | {{.ErrorType}}: {{.Error}} | {{.Model}} | {{.Files}} | {{.Outcome}} |
+{{end}}{{end}}
+{{- with .Coverage}}
+## Coverage
+{{.}}{{end}}
+## Cost
+{{.Usage.InputTokens}} input / {{.Usage.OutputTokens}} output tokens, {{printf "$%.4f" .Usage.TotalCost}}
+{{- if .Usage.HealingCost}} (planning {{printf "$%.4f" .Usage.PlanningCost}}, self-healing {{printf "$%.4f" .Usage.HealingCost}}){{end}}
+{{- with .Usage.Model}}, {{.}}{{end}}
+`
+
+var prTemplateFuncs = template.FuncMap{
+	"trim": strings.TrimSpace,
+	"join": strings.Join,
+}
+
 func buildPRTitle(ticketKey, summary string) string {
 	if strings.TrimSpace(summary) == "" {
 		return ticketKey
@@ -13,38 +140,210 @@ func buildPRTitle(ticketKey, summary string) string {
 	return fmt.Sprintf("%s: %s", ticketKey, summary)
 }
 
-// buildPRBody renders a markdown body including ticket info, description and file list
-func buildPRBody(ticketKey, summary, description string, changes []agent.CodeChange, notes []string) string {
+// buildPRBody renders data with the target repository's PR template when it
+// has one, falling back to defaultPRTemplate if it's missing or broken - a
+// bad template should never block the PR.
+func buildPRBody(repoRoot string, data PRBodyData) string {
+	if raw, err := os.ReadFile(filepath.Join(repoRoot, prTemplateFile)); err == nil {
+		body, err := renderPRTemplate(string(raw), data)
+		if err == nil {
+			return body
+		}
+		logger.Warn("Invalid PR template, using the default", "path", prTemplateFile, "error", err)
+	}
+	body, err := renderPRTemplate(defaultPRTemplate, data)
+	if err != nil {
+		// The default template is static; this only fires on a programming error.
+		logger.Error("Failed to render default PR template", "error", err)
+		return fmt.Sprintf("%s\n\n%s\n", buildPRTitle(data.Ticket.Key, data.Ticket.Summary), data.Ticket.Description)
+	}
+	return body
+}
+
+func renderPRTemplate(text string, data PRBodyData) (string, error) {
+	tmpl, err := template.New("pr").Funcs(prTemplateFuncs).Parse(text)
+	if err != nil {
+		return "", err
+	}
 	var b strings.Builder
-	b.WriteString("## Ticket\n")
-	b.WriteString(fmt.Sprintf("- Key: %s\n", ticketKey))
-	if strings.TrimSpace(summary) != "" {
-		b.WriteString(fmt.Sprintf("- Summary: %s\n", summary))
-	}
-	b.WriteString("\n## Description\n")
-	if strings.TrimSpace(description) == "" {
-		b.WriteString("(no description provided)\n")
-	} else {
-		b.WriteString(description)
-		b.WriteString("\n")
-	}
-	b.WriteString("\n## Changeset\n")
-	if len(changes) == 0 {
-		b.WriteString("(no changes)\n")
-	} else {
-		for _, ch := range changes {
-			b.WriteString(fmt.Sprintf("- %s (%s)\n", ch.Path, ch.Operation))
-		}
-	}
-	if len(notes) > 0 {
-		b.WriteString("\n## Notes\n")
-		for _, n := range notes {
-			b.WriteString(fmt.Sprintf("- %s\n", n))
-		}
-	}
-	b.WriteString("\n## Checklist\n")
-	b.WriteString("- [ ] Code compiles\n")
-	b.WriteString("- [ ] Tests (if any) pass locally\n")
-	b.WriteString("- [ ] Review requested\n")
-	return b.String()
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// requiredApprovals lists the changed paths the repository's write policy
+// says need a human to sign off
+func requiredApprovals(repoRoot string, paths []string) []policy.Approval {
+	pol, err := policy.Load(repoRoot)
+	if err != nil {
+		logger.Warn("Failed to load write policy for PR approvals", "error", err)
+		return nil
+	}
+	return pol.Approvals(paths)
+}
+
+// pullRequestOptions builds the draft/label/reviewer/assignee settings for a
+// ticket's PR from config, the CODEOWNERS of the changed paths and the
+// ticket's reporter. Paths that need approval always make the PR a draft
+// and request their owners' review, whatever the reviewer settings.
+func pullRequestOptions(cfg *config.Config, repoRoot string, ticket ticketing.Ticket, paths []string, approvals []policy.Approval) repository.PullRequestOptions {
+	opts := repository.PullRequestOptions{
+		Draft:  cfg.PRDraft || len(approvals) > 0,
+		Labels: cfg.PRLabels,
+	}
+	reporter := reporterLogin(cfg.PRReporterGitHubMap, ticket.Reporter)
+
+	var owners []string
+	for _, a := range approvals {
+		owners = append(owners, a.Owners...)
+	}
+	if cfg.PRReviewersFromCodeowners {
+		co, err := codeowners.Load(repoRoot)
+		if err != nil {
+			logger.Warn("Failed to read CODEOWNERS, requesting no code-owner reviews", "error", err)
+		}
+		owners = append(owners, co.OwnersOf(paths)...)
+	}
+	if reporter != "" {
+		owners = append(owners, "@"+reporter)
+	}
+	seen := make(map[string]bool)
+	for _, owner := range owners {
+		name := strings.TrimPrefix(owner, "@")
+		if owner == name || seen[strings.ToLower(name)] {
+			continue // email owners can't be requested by address
+		}
+		seen[strings.ToLower(name)] = true
+		if org, team, isTeam := strings.Cut(name, "/"); isTeam {
+			// Only teams in the repository's own organization can review.
+			if strings.EqualFold(org, cfg.GitHubOwner) {
+				opts.TeamReviewers = append(opts.TeamReviewers, team)
+			}
+			continue
+		}
+		opts.Reviewers = append(opts.Reviewers, name)
+	}
+
+	for _, a := range cfg.PRAssignees {
+		if a == "reporter" {
+			if reporter != "" {
+				opts.Assignees = append(opts.Assignees, reporter)
+			}
+			continue
+		}
+		opts.Assignees = append(opts.Assignees, strings.TrimPrefix(a, "@"))
+	}
+	return opts
+}
+
+// reporterLogin maps a ticket reporter to a GitHub login, ignoring case
+func reporterLogin(logins map[string]string, reporter string) string {
+	reporter = strings.TrimSpace(reporter)
+	if reporter == "" {
+		return ""
+	}
+	for name, login := range logins {
+		if strings.EqualFold(name, reporter) {
+			return strings.TrimPrefix(login, "@")
+		}
+	}
+	return ""
+}
+
+// prFileSummaries lists every file in changes with its line counts from
+// stats. Files only in stats (e.g. touched by a manual commit) are appended.
+// Returns false when stats is nil, i.e. the diff couldn't be computed.
+func prFileSummaries(changes []agent.CodeChange, stats []repository.FileStat) ([]PRFileSummary, bool) {
+	byPath := make(map[string]repository.FileStat, len(stats))
+	for _, s := range stats {
+		byPath[s.Path] = s
+	}
+	files := make([]PRFileSummary, 0, len(changes))
+	listed := make(map[string]bool, len(changes))
+	for _, ch := range changes {
+		s := byPath[ch.Path]
+		files = append(files, PRFileSummary{Path: ch.Path, Operation: string(ch.Operation), Added: s.Added, Removed: s.Removed})
+		listed[ch.Path] = true
+	}
+	for _, s := range stats {
+		if !listed[s.Path] {
+			files = append(files, PRFileSummary{Path: s.Path, Operation: "other", Added: s.Added, Removed: s.Removed})
+		}
+	}
+	return files, stats != nil
+}
+
+// prHealAttempts summarizes a successful self-healing run for the PR body
+func prHealAttempts(result *SelfHealingResult) []PRHealAttempt {
+	if result == nil {
+		return nil
+	}
+	attempts := make([]PRHealAttempt, 0, len(result.Attempts))
+	for i, a := range result.Attempts {
+		outcome := "same error"
+		switch {
+		case len(a.FixedChanges) == 0:
+			outcome = "no usable fix"
+		case a.ErrorChanged && result.Success && i == len(result.Attempts)-1:
+			outcome = "resolved"
+		case a.ErrorChanged:
+			outcome = "error changed"
+		}
+		model := a.Model
+		if model == "" {
+			model = "-"
+		}
+		attempts = append(attempts, PRHealAttempt{
+			Attempt:   a.Attempt,
+			ErrorType: a.ErrorType,
+			Error:     tableCell(extractErrorSummary(a.ErrorOutput), 160),
+			Model:     model,
+			Files:     len(a.FixedChanges),
+			Outcome:   outcome,
+		})
+	}
+	return attempts
+}
+
+// prUsage totals planning and self-healing spend
+func prUsage(planning *agent.UsageMetrics, heal *SelfHealingResult) PRUsage {
+	var u PRUsage
+	if planning != nil {
+		u.InputTokens = planning.InputTokens
+		u.OutputTokens = planning.OutputTokens
+		u.PlanningCost = planning.EstimatedCost
+		u.Model = planning.Model
+	}
+	if heal != nil {
+		for _, a := range heal.Attempts {
+			if a.Metrics != nil {
+				u.InputTokens += a.Metrics.InputTokens
+				u.OutputTokens += a.Metrics.OutputTokens
+			}
+		}
+		u.HealingCost = heal.TotalCost
+	}
+	u.TotalCost = u.PlanningCost + u.HealingCost
+	return u
+}
+
+// tableCell makes s safe for a one-line markdown table cell
+func tableCell(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > max {
+		s = s[:max] + "..."
+	}
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+var jiraRESTIssueRe = regexp.MustCompile(`^(https?://[^/]+(?:/[^/]+)*?)/rest/api/\d+/issue/[^/]+$`)
+
+// ticketBrowseURL turns a ticket's URL into one a reviewer can open. JIRA
+// reports the REST "self" link, which is rewritten to the /browse/KEY page.
+func ticketBrowseURL(t ticketing.Ticket) string {
+	if m := jiraRESTIssueRe.FindStringSubmatch(t.URL); m != nil && t.Key != "" {
+		return m[1] + "/browse/" + t.Key
+	}
+	return t.URL
 }
@@ -0,0 +1,198 @@
+package orchestrator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"intern/internal/ai/agent"
+	"intern/internal/config"
+	"intern/internal/policy"
+	"intern/internal/repository"
+	"intern/internal/ticketing"
+)
+
+func samplePRBodyData() PRBodyData {
+	changes := []agent.CodeChange{
+		{Path: "calc/calc.go", Operation: agent.OperationEdit},
+		{Path: "calc/calc_test.go", Operation: agent.OperationCreate},
+	}
+	files, ok := prFileSummaries(changes, []repository.FileStat{
+		{Path: "calc/calc.go", Added: 5, Removed: 2},
+		{Path: "calc/calc_test.go", Added: 12},
+	})
+	heal := &SelfHealingResult{
+		Success:   true,
+		TotalCost: 0.02,
+		Attempts: []HealResult{{
+			Attempt:      1,
+			ErrorType:    "build",
+			ErrorOutput:  "./calc/calc.go:4:2: undefined: foo",
+			FixedChanges: changes[:1],
+			Metrics:      &agent.UsageMetrics{InputTokens: 100, OutputTokens: 10},
+			Model:        "anthropic",
+			ErrorChanged: true,
+		}},
+	}
+	return PRBodyData{
+		Ticket:       ticketing.Ticket{Key: "PROJ-1", Summary: "Add Sub", Description: "Add a Sub function.", URL: "https://acme.atlassian.net/rest/api/2/issue/10001"},
+		TicketURL:    "https://acme.atlassian.net/browse/PROJ-1",
+		Files:        files,
+		LineCounts:   ok,
+		LinesAdded:   17,
+		LinesRemoved: 2,
+		NewAPIs:      []string{"calc/calc.go.Sub"},
+		AINotes:      []string{"calc/calc.go: named it Sub to match Add"},
+		GateNotes:    []string{"go vet: PASSED"},
+		Healing:      prHealAttempts(heal),
+		Usage:        prUsage(&agent.UsageMetrics{InputTokens: 1000, OutputTokens: 200, EstimatedCost: 0.05, Model: "claude-sonnet-4"}, heal),
+	}
+}
+
+func TestBuildPRBody_Default(t *testing.T) {
+	body := buildPRBody(t.TempDir(), samplePRBodyData())
+
+	for _, want := range []string{
+		"[PROJ-1](https://acme.atlassian.net/browse/PROJ-1): Add Sub",
+		"Add a Sub function.",
+		"2 file(s), +17 -2",
+		"| `calc/calc.go` | edit | 5 | 2 |",
+		"| `calc/calc_test.go` | create | 12 | 0 |",
+		"## New APIs\n- `calc/calc.go.Sub`",
+		"- calc/calc.go: named it Sub to match Add",
+		"go vet: PASSED",
+		"| 1 | build: ./calc/calc.go:4:2: undefined: foo | anthropic | 1 | resolved |",
+		"1100 input / 210 output tokens, $0.0700 (planning $0.0500, self-healing $0.0200), claude-sonnet-4",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected body to contain %q, got:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "## Coverage") {
+		t.Error("Expected no coverage section when coverage wasn't measured")
+	}
+	if !strings.Contains(body, "Add a Sub function.\n\n## Changes") {
+		t.Errorf("Expected no linked PRs section for a single-repository ticket, got:\n%s", body)
+	}
+}
+
+func TestBuildPRBody_LinkedPRs(t *testing.T) {
+	data := samplePRBodyData()
+	data.LinkedPRs = []PRLink{{Repo: "api-client", URL: "https://github.com/acme/api-client/pull/3"}}
+
+	body := buildPRBody(t.TempDir(), data)
+	want := "Add a Sub function.\n\n## Linked Pull Requests\nThis ticket spans several repositories. Review and merge these together:\n- api-client: https://github.com/acme/api-client/pull/3\n\n## Changes"
+	if !strings.Contains(body, want) {
+		t.Errorf("Expected the linked PRs after the description, got:\n%s", body)
+	}
+}
+
+func TestBuildPRBody_WithoutLineCounts(t *testing.T) {
+	data := samplePRBodyData()
+	data.Files, data.LineCounts = prFileSummaries([]agent.CodeChange{{Path: "a.go", Operation: agent.OperationCreate}}, nil)
+	data.Healing, data.NewAPIs, data.AINotes = nil, nil, nil
+
+	body := buildPRBody(t.TempDir(), data)
+	if !strings.Contains(body, "- `a.go` (create)") {
+		t.Errorf("Expected a plain file list without line counts, got:\n%s", body)
+	}
+	for _, unwanted := range []string{"## New APIs", "## Review Notes", "## Self-Healing"} {
+		if strings.Contains(body, unwanted) {
+			t.Errorf("Expected %q to be omitted when empty", unwanted)
+		}
+	}
+}
+
+func TestBuildPRBody_RepoTemplate(t *testing.T) {
+	repoRoot := t.TempDir()
+	os.MkdirAll(filepath.Join(repoRoot, ".ai-intern"), 0755)
+	tmpl := "Fixes {{.Ticket.Key}} (+{{.LinesAdded}}/-{{.LinesRemoved}})"
+	os.WriteFile(filepath.Join(repoRoot, prTemplateFile), []byte(tmpl), 0644)
+
+	if got := buildPRBody(repoRoot, samplePRBodyData()); got != "Fixes PROJ-1 (+17/-2)" {
+		t.Errorf("Expected the repository template to be used, got %q", got)
+	}
+
+	os.WriteFile(filepath.Join(repoRoot, prTemplateFile), []byte("{{.NoSuchField}}"), 0644)
+	if got := buildPRBody(repoRoot, samplePRBodyData()); !strings.Contains(got, "## Ticket") {
+		t.Errorf("Expected fallback to the default template for a broken override, got %q", got)
+	}
+}
+
+func TestTicketBrowseURL(t *testing.T) {
+	tests := []struct {
+		ticket ticketing.Ticket
+		want   string
+	}{
+		{ticketing.Ticket{Key: "PROJ-1", URL: "https://acme.atlassian.net/rest/api/2/issue/10001"}, "https://acme.atlassian.net/browse/PROJ-1"},
+		{ticketing.Ticket{Key: "PROJ-2", URL: "https://jira.acme.com/jira/rest/api/3/issue/PROJ-2"}, "https://jira.acme.com/jira/browse/PROJ-2"},
+		{ticketing.Ticket{Key: "SLACK-1", URL: "https://acme.slack.com/archives/C1/p1"}, "https://acme.slack.com/archives/C1/p1"},
+		{ticketing.Ticket{Key: "NONE-1"}, ""},
+	}
+	for _, tt := range tests {
+		if got := ticketBrowseURL(tt.ticket); got != tt.want {
+			t.Errorf("ticketBrowseURL(%s) = %q, want %q", tt.ticket.Key, got, tt.want)
+		}
+	}
+}
+
+func TestPullRequestOptions(t *testing.T) {
+	repoRoot := t.TempDir()
+	os.MkdirAll(filepath.Join(repoRoot, ".github"), 0755)
+	os.WriteFile(filepath.Join(repoRoot, ".github", "CODEOWNERS"), []byte(
+		"*              @acme/platform\n"+
+			"internal/auth/ @alice @other-org/security sec@acme.com\n"), 0644)
+
+	cfg := &config.Config{
+		GitHubOwner:               "acme",
+		PRDraft:                   true,
+		PRLabels:                  []string{"ai-generated"},
+		PRReviewersFromCodeowners: true,
+		PRReporterGitHubMap:       map[string]string{"Jane Doe": "@janed"},
+		PRAssignees:               []string{"reporter", "@lead"},
+	}
+	ticket := ticketing.Ticket{Key: "PROJ-1", Reporter: "jane doe"}
+
+	opts := pullRequestOptions(cfg, repoRoot, ticket, []string{"internal/auth/jwt.go", "main.go"}, nil)
+
+	if !opts.Draft || !reflect.DeepEqual(opts.Labels, []string{"ai-generated"}) {
+		t.Errorf("Expected draft with the configured labels, got %+v", opts)
+	}
+	if want := []string{"alice", "janed"}; !reflect.DeepEqual(opts.Reviewers, want) {
+		t.Errorf("Reviewers = %v, want %v", opts.Reviewers, want)
+	}
+	if want := []string{"platform"}; !reflect.DeepEqual(opts.TeamReviewers, want) {
+		t.Errorf("TeamReviewers = %v, want %v (teams outside the org are skipped)", opts.TeamReviewers, want)
+	}
+	if want := []string{"janed", "lead"}; !reflect.DeepEqual(opts.Assignees, want) {
+		t.Errorf("Assignees = %v, want %v", opts.Assignees, want)
+	}
+
+	cfg.PRReviewersFromCodeowners = false
+	ticket.Reporter = "Unmapped Person"
+	opts = pullRequestOptions(cfg, repoRoot, ticket, []string{"main.go"}, nil)
+	if len(opts.Reviewers) != 0 || len(opts.TeamReviewers) != 0 || !reflect.DeepEqual(opts.Assignees, []string{"lead"}) {
+		t.Errorf("Expected no reviewers and only the static assignee, got %+v", opts)
+	}
+}
+
+func TestPullRequestOptions_Approvals(t *testing.T) {
+	cfg := &config.Config{GitHubOwner: "acme"}
+	approvals := []policy.Approval{{Path: "db/migrations/001.sql", Reason: "owned by @acme/dba", Owners: []string{"@acme/dba", "@dbadmin"}}}
+
+	opts := pullRequestOptions(cfg, t.TempDir(), ticketing.Ticket{Key: "PROJ-1"}, []string{"db/migrations/001.sql"}, approvals)
+	if !opts.Draft {
+		t.Error("Expected a draft PR when changes need approval")
+	}
+	if !reflect.DeepEqual(opts.Reviewers, []string{"dbadmin"}) || !reflect.DeepEqual(opts.TeamReviewers, []string{"dba"}) {
+		t.Errorf("Expected the approvers to be requested, got %+v", opts)
+	}
+
+	data := samplePRBodyData()
+	data.Approvals = approvals
+	if body := buildPRBody(t.TempDir(), data); !strings.Contains(body, "## Requires Approval\nRepository policy requires a human to approve these changes before merging:\n- `db/migrations/001.sql`: owned by @acme/dba (@acme/dba, @dbadmin)") {
+		t.Errorf("Expected a Requires Approval section, got:\n%s", body)
+	}
+}
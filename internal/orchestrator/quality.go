@@ -3,18 +3,36 @@ package orchestrator
 import (
 	"context"
 	"fmt"
-	"os/exec"
 	"strings"
 	"time"
 
 	"intern/internal/config"
+	"intern/internal/sandbox"
 )
 
-func runCommandCapture(ctx context.Context, dir string, name string, args ...string) (string, error) {
-	cmd := exec.CommandContext(ctx, name, args...)
-	cmd.Dir = dir
-	out, err := cmd.CombinedOutput()
-	return string(out), err
+// sandboxConfig maps the SANDBOX_* settings onto the executor's config.
+// SandboxTimeout is validated at load time; an unparseable value here (only
+// possible in hand-built configs) falls back to the sandbox default.
+func sandboxConfig(cfg *config.Config) sandbox.Config {
+	sc := sandbox.DefaultConfig()
+	sc.Enabled = cfg.SandboxEnabled
+	sc.AllowNetwork = cfg.SandboxAllowNetwork
+	sc.AllowDegraded = cfg.SandboxAllowDegraded
+	if d, err := time.ParseDuration(cfg.SandboxTimeout); err == nil && d > 0 {
+		sc.Timeout = d
+	}
+	if cfg.SandboxCPUSeconds > 0 {
+		sc.CPUSeconds = cfg.SandboxCPUSeconds
+	}
+	if cfg.SandboxMemoryMB > 0 {
+		sc.MemoryMB = cfg.SandboxMemoryMB
+	}
+	sc.EnvAllowlist = cfg.SandboxEnvAllowlist
+	return sc
+}
+
+func runCommandCapture(ctx context.Context, executor sandbox.Executor, dir string, name string, args ...string) (string, error) {
+	return executor.Run(ctx, dir, name, args...)
 }
 
 func truncateMiddle(s string, max int) string {
@@ -27,18 +45,14 @@ func truncateMiddle(s string, max int) string {
 }
 
 // runQualityGates executes optional go vet and go test before PR.
+// Commands run through executor, which enforces the per-command timeout.
 // Returns notes to include in PR body and ok=false when any enabled gate fails.
-func runQualityGates(ctx context.Context, cfg *config.Config, repoRoot string) ([]string, bool) {
+func runQualityGates(ctx context.Context, cfg *config.Config, executor sandbox.Executor, repoRoot string) ([]string, bool) {
 	notes := []string{}
 	ok := true
 
-	// Use a short timeout per command to avoid hanging
-	perCmdTimeout := 10 * time.Minute
-
 	if cfg.RunVetBeforePR {
-		ctxVet, cancel := context.WithTimeout(ctx, perCmdTimeout)
-		out, err := runCommandCapture(ctxVet, repoRoot, "go", "vet", "./...")
-		cancel()
+		out, err := runCommandCapture(ctx, executor, repoRoot, "go", "vet", "./...")
 		if err != nil {
 			notes = append(notes, "go vet: FAILED")
 			notes = append(notes, fmt.Sprintf("```\n%s\n```", truncateMiddle(strings.TrimSpace(out), 8000)))
@@ -51,9 +65,7 @@ func runQualityGates(ctx context.Context, cfg *config.Config, repoRoot string) (
 	}
 
 	if cfg.RunTestsBeforePR {
-		ctxTest, cancel := context.WithTimeout(ctx, perCmdTimeout)
-		out, err := runCommandCapture(ctxTest, repoRoot, "go", "test", "./...")
-		cancel()
+		out, err := runCommandCapture(ctx, executor, repoRoot, "go", "test", "./...")
 		if err != nil {
 			notes = append(notes, "go test: FAILED")
 			notes = append(notes, fmt.Sprintf("```\n%s\n```", truncateMiddle(strings.TrimSpace(out), 8000)))
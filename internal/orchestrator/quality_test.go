@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"intern/internal/config"
+	"intern/internal/sandbox"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -44,7 +45,7 @@ func TestRunQualityGates_AllSkipped(t *testing.T) {
 		RunTestsBeforePR: false,
 	}
 
-	notes, ok := runQualityGates(context.Background(), cfg, "/fake/path")
+	notes, ok := runQualityGates(context.Background(), cfg, sandbox.New(sandboxConfig(cfg)), "/fake/path")
 	assert.True(t, ok)
 	assert.Contains(t, notes, "go vet: skipped")
 	assert.Contains(t, notes, "go test: skipped")
@@ -93,7 +94,7 @@ func TestMain(t *testing.T) {
 		RunTestsBeforePR: true,
 	}
 
-	notes, ok := runQualityGates(context.Background(), cfg, tmpDir)
+	notes, ok := runQualityGates(context.Background(), cfg, sandbox.New(sandboxConfig(cfg)), tmpDir)
 	assert.True(t, ok, "quality gates should pass for valid project")
 
 	// Should have run both vet and test
@@ -141,7 +142,7 @@ func main() {
 		RunTestsBeforePR: false,
 	}
 
-	notes, ok := runQualityGates(context.Background(), cfg, tmpDir)
+	notes, ok := runQualityGates(context.Background(), cfg, sandbox.New(sandboxConfig(cfg)), tmpDir)
 	assert.False(t, ok, "quality gates should fail when vet fails")
 	assert.Contains(t, notes, "go vet: FAILED")
 	assert.Contains(t, notes, "go test: skipped")
@@ -191,7 +192,7 @@ func TestAdd(t *testing.T) {
 		RunTestsBeforePR: true,
 	}
 
-	notes, ok := runQualityGates(context.Background(), cfg, tmpDir)
+	notes, ok := runQualityGates(context.Background(), cfg, sandbox.New(sandboxConfig(cfg)), tmpDir)
 	assert.False(t, ok, "quality gates should fail when tests fail")
 	assert.Contains(t, notes, "go vet: skipped")
 	assert.Contains(t, notes, "go test: FAILED")
@@ -203,7 +204,7 @@ func TestRunQualityGates_NonexistentDirectory(t *testing.T) {
 		RunTestsBeforePR: true,
 	}
 
-	notes, ok := runQualityGates(context.Background(), cfg, "/nonexistent/path")
+	notes, ok := runQualityGates(context.Background(), cfg, sandbox.New(sandboxConfig(cfg)), "/nonexistent/path")
 	assert.False(t, ok, "quality gates should fail for nonexistent directory")
 
 	// Both should fail
@@ -0,0 +1,123 @@
+package orchestrator
+
+import (
+	"regexp"
+	"strings"
+
+	"intern/internal/config"
+	"intern/internal/indexer"
+	"intern/internal/ticketing"
+)
+
+// repoHint matches an explicit "repo: payments-api" (or "repo:acme/payments-api")
+// in a ticket description; a ticket may carry several
+var repoHint = regexp.MustCompile(`(?i)\brepo:\s*([\w.-]+(?:/[\w.-]+)?)`)
+
+// routeIndexTopFiles is how many of a repository's best-matching files count
+// towards its keyword score, so a big repository doesn't win just by having
+// more files that mention a word
+const routeIndexTopFiles = 5
+
+// RepoTarget pairs a repository's routing rules with the Coordinator that
+// works on it
+type RepoTarget struct {
+	Config      config.RepoConfig
+	Coordinator *Coordinator
+}
+
+// routeTicket picks the repositories a ticket belongs to, trying in order:
+// repo: hints in the description, JIRA components, labels, and the best
+// keyword match against each repository's index. Every repository matched
+// by the first signal that matches anything is returned, so a ticket naming
+// two repositories (or carrying two routed components) becomes a linked
+// change across both; the keyword match picks one. Tickets matching nothing
+// go to the first target. The reason is for logging.
+func routeTicket(targets []RepoTarget, ticket ticketing.Ticket) ([]RepoTarget, string) {
+	if len(targets) == 1 {
+		return targets, "only repository"
+	}
+
+	var hinted []RepoTarget
+	for _, m := range repoHint.FindAllStringSubmatch(ticket.Description, -1) {
+		for _, t := range targets {
+			if matchesRepoName(t.Config, m[1]) && !containsTarget(hinted, t) {
+				hinted = append(hinted, t)
+			}
+		}
+	}
+	if len(hinted) > 0 {
+		return hinted, "repo hint"
+	}
+	if matched := filterTargets(targets, func(r config.RepoConfig) bool { return containsFold(r.Components, ticket.Components) }); len(matched) > 0 {
+		return matched, "component"
+	}
+	if matched := filterTargets(targets, func(r config.RepoConfig) bool { return containsFold(r.Labels, ticket.Labels) }); len(matched) > 0 {
+		return matched, "label"
+	}
+
+	keywords := indexer.ExtractKeywords(ticket.Summary + " " + ticket.Description)
+	best, bestScore := -1, 0.0
+	for i, t := range targets {
+		if score := indexScore(t.Coordinator.RepoPaths.Root(), keywords); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best >= 0 {
+		return targets[best : best+1], "index keywords"
+	}
+	return targets[:1], "default"
+}
+
+// filterTargets returns the targets whose config satisfies match, in order
+func filterTargets(targets []RepoTarget, match func(config.RepoConfig) bool) []RepoTarget {
+	var out []RepoTarget
+	for _, t := range targets {
+		if match(t.Config) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// containsTarget reports whether targets already holds t's repository
+func containsTarget(targets []RepoTarget, t RepoTarget) bool {
+	for _, existing := range targets {
+		if existing.Config.Name == t.Config.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesRepoName reports whether hint names r by routing name, repo or
+// owner/repo
+func matchesRepoName(r config.RepoConfig, hint string) bool {
+	return strings.EqualFold(hint, r.Name) || strings.EqualFold(hint, r.Repo) ||
+		(r.Owner != "" && strings.EqualFold(hint, r.Owner+"/"+r.Repo))
+}
+
+// containsFold reports whether any of values appears in want, ignoring case
+func containsFold(want, values []string) bool {
+	for _, w := range want {
+		for _, v := range values {
+			if strings.EqualFold(w, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// indexScore sums the scores of the files in repoRoot's index that best match
+// keywords; 0 if the repository hasn't been indexed
+func indexScore(repoRoot string, keywords []string) float64 {
+	index, err := indexer.New(repoRoot).LoadIndex()
+	if err != nil {
+		return 0
+	}
+	total := 0.0
+	for _, s := range indexer.SelectTopFiles(indexer.ScoreFiles(index, keywords), routeIndexTopFiles) {
+		total += s.Score
+	}
+	return total
+}
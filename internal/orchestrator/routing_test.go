@@ -0,0 +1,85 @@
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+
+	"intern/internal/config"
+	"intern/internal/indexer"
+	"intern/internal/repository"
+	"intern/internal/ticketing"
+)
+
+// routingTargets builds targets whose indexes contain the given files
+func routingTargets(t *testing.T, repos map[string][]string, order ...string) []RepoTarget {
+	t.Helper()
+	workDir := t.TempDir()
+	var targets []RepoTarget
+	for _, name := range order {
+		paths, err := repository.NewRepositoryPath(workDir, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		index := &indexer.FileIndex{Files: map[string]indexer.FileMetadata{}}
+		for _, f := range repos[name] {
+			index.Files[f] = indexer.FileMetadata{Path: f, Category: "core"}
+		}
+		if err := indexer.New(paths.Root()).SaveIndex(index); err != nil {
+			t.Fatal(err)
+		}
+		targets = append(targets, RepoTarget{
+			Config:      config.RepoConfig{Name: name, Owner: "acme", Repo: name},
+			Coordinator: &Coordinator{RepoPaths: paths},
+		})
+	}
+	targets[1].Config.Components = []string{"Billing"}
+	targets[2].Config.Labels = []string{"frontend"}
+	return targets
+}
+
+func TestRouteTicket(t *testing.T) {
+	targets := routingTargets(t, map[string][]string{
+		"api":      {"internal/server/handler.go"},
+		"payments": {"internal/invoice/invoice.go", "internal/invoice/refund.go"},
+		"web":      {"src/checkout/cart.tsx"},
+	}, "api", "payments", "web")
+
+	tests := []struct {
+		name   string
+		ticket ticketing.Ticket
+		want   string
+		reason string
+	}{
+		{"repo hint wins over component", ticketing.Ticket{Description: "Fix it.\nrepo: acme/web", Components: []string{"billing"}}, "web", "repo hint"},
+		{"component", ticketing.Ticket{Summary: "Bump timeout", Components: []string{"billing"}}, "payments", "component"},
+		{"label", ticketing.Ticket{Summary: "Bump timeout", Labels: []string{"Frontend"}}, "web", "label"},
+		{"index keywords", ticketing.Ticket{Summary: "Refund partially paid invoice"}, "payments", "index keywords"},
+		{"two hints make a linked change", ticketing.Ticket{Description: "repo: api\nrepo: web\nrepo: api"}, "api,web", "repo hint"},
+		{"component beats label", ticketing.Ticket{Components: []string{"Billing", "Other"}, Labels: []string{"frontend"}}, "payments", "component"},
+		{"unknown hint falls through", ticketing.Ticket{Description: "repo: nope", Labels: []string{"frontend"}}, "web", "label"},
+		{"default", ticketing.Ticket{Summary: "Something unrelated"}, "api", "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := routeTicket(targets, tt.ticket)
+			var names []string
+			for _, g := range got {
+				names = append(names, g.Config.Name)
+			}
+			if strings.Join(names, ",") != tt.want || reason != tt.reason {
+				t.Errorf("Expected %s by %s, got %v by %s", tt.want, tt.reason, names, reason)
+			}
+		})
+	}
+}
+
+func TestNewDispatcher_SharesMetricsAndState(t *testing.T) {
+	state := NewState(t.TempDir() + "/state.json")
+	a := &Coordinator{Metrics: NewMetrics()}
+	b := &Coordinator{Metrics: NewMetrics()}
+	d := NewDispatcher(nil, &config.Config{}, state, RepoTarget{Coordinator: a}, RepoTarget{Coordinator: b})
+
+	if b.Metrics != d.Metrics || a.Metrics != d.Metrics || b.State != state {
+		t.Error("Expected every coordinator to share the dispatcher's metrics and state")
+	}
+}
@@ -1,66 +1,63 @@
 package orchestrator
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"intern/internal/ai/agent"
+	"intern/internal/sandbox"
 
 	"github.com/jenish-jain/logger"
 )
 
 // HealResult represents the result of a single healing attempt
 type HealResult struct {
-	Attempt      int                  // Attempt number (1-based)
-	Success      bool                 // Whether the healing succeeded
-	ErrorType    string               // Type of error ("test", "vet", "build")
-	ErrorOutput  string               // Error output from quality gate
-	FixedChanges []agent.CodeChange   // Changes applied to fix the error
-	Metrics      *agent.UsageMetrics  // AI usage metrics for this healing attempt
+	Attempt        int                 // Attempt number (1-based)
+	Success        bool                // Whether the healing succeeded
+	ErrorType      string              // Type of error ("test", "vet", "build", "coverage")
+	ErrorOutput    string              // Error output from quality gate
+	ErrorSignature string              // Normalized error lines, for spotting repeats
+	FixedChanges   []agent.CodeChange  // Changes applied to fix the error
+	Metrics        *agent.UsageMetrics // AI usage metrics for this healing attempt
+	Model          string              // Escalation tier that generated this attempt's fixes
+	ErrorChanged   bool                // Whether the gates reported a different error after this fix
 }
 
 // SelfHealingResult contains the overall result of the self-healing process
 type SelfHealingResult struct {
-	Attempts      []HealResult // All healing attempts
-	Success       bool         // Whether healing ultimately succeeded
-	TotalAttempts int          // Total number of attempts made
-	TotalCost     float64      // Total cost of all healing attempts
+	Attempts      []HealResult   // All healing attempts
+	Success       bool           // Whether healing ultimately succeeded
+	TotalAttempts int            // Total number of attempts made
+	TotalCost     float64        // Total cost of all healing attempts, across every model tried
+	Coverage      *CoverageDelta // Coverage before/after, when RequireTests is enabled
+	Oscillated    bool           // Stopped early because an error already "fixed" came back
 }
 
-// runQualityGate executes a quality gate command and returns the output and error
-func runQualityGate(ctx context.Context, repoPath, command string, args ...string) (string, error) {
-	cmd := exec.CommandContext(ctx, command, args...)
-	cmd.Dir = repoPath
-
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
-	output := stdout.String() + stderr.String()
-
-	return output, err
+// runQualityGate executes a quality gate command through executor and returns
+// the combined output and error
+func runQualityGate(ctx context.Context, executor sandbox.Executor, repoPath, command string, args ...string) (string, error) {
+	return executor.Run(ctx, repoPath, command, args...)
 }
 
 // runGoVet runs go vet on the repository
-func runGoVet(ctx context.Context, repoPath string) (string, error) {
-	return runQualityGate(ctx, repoPath, "go", "vet", "./...")
+func runGoVet(ctx context.Context, executor sandbox.Executor, repoPath string) (string, error) {
+	return runQualityGate(ctx, executor, repoPath, "go", "vet", "./...")
 }
 
 // runGoTest runs go test on the repository
-func runGoTest(ctx context.Context, repoPath string) (string, error) {
-	return runQualityGate(ctx, repoPath, "go", "test", "./...")
+func runGoTest(ctx context.Context, executor sandbox.Executor, repoPath string) (string, error) {
+	return runQualityGate(ctx, executor, repoPath, "go", "test", "./...")
 }
 
 // runGoBuild runs go build on the repository
-func runGoBuild(ctx context.Context, repoPath string) (string, error) {
-	return runQualityGate(ctx, repoPath, "go", "build", "./...")
+func runGoBuild(ctx context.Context, executor sandbox.Executor, repoPath string) (string, error) {
+	return runQualityGate(ctx, executor, repoPath, "go", "build", "./...")
 }
 
 // applyCodeChange applies a single healing fix to disk, mirroring the
@@ -76,7 +73,7 @@ func applyCodeChange(repoPath string, change agent.CodeChange) error {
 		logger.Debug("Deleted file during self-healing", "path", change.Path)
 		return nil
 	case agent.OperationEdit:
-		if err := applyEditChange(repoPath, change); err != nil {
+		if err := applyEditChange(repoPath, change, nil); err != nil {
 			return fmt.Errorf("edit %s: %w", change.Path, err)
 		}
 		logger.Debug("Edited file during self-healing", "path", change.Path)
@@ -153,26 +150,33 @@ func collectFileContents(repoPath string, previousChanges []agent.CodeChange, er
 	return fileContents
 }
 
-// tryHealErrors attempts to fix errors by calling the AI agent's FixErrors method
+// tryHealErrors attempts to fix errors by calling FixErrors on the given
+// escalation tier. On failure after the model was called, the returned
+// HealResult still carries the tier and usage so the cost is accounted for.
 func (c *Coordinator) tryHealErrors(
 	ctx context.Context,
+	tier agent.Tier,
 	ticketKey, ticketSummary, errorType, errorOutput string,
 	previousChanges []agent.CodeChange,
+	history []agent.HealAttempt,
 	repoPath string,
 ) (*HealResult, error) {
 	logger.Info("Attempting to heal errors",
 		"ticket", ticketKey,
 		"error_type", errorType,
-		"previous_changes", len(previousChanges))
+		"model", tier.Name,
+		"previous_changes", len(previousChanges),
+		"history", len(history))
 
 	// Give the model the ground truth: current on-disk content for every file
 	// it previously touched plus every file referenced in the error output.
 	fileContents := collectFileContents(repoPath, previousChanges, errorOutput)
 
 	// Call AI to generate fixes
-	fixes, metrics, err := c.Agent.FixErrors(ctx, ticketKey, ticketSummary, errorType, errorOutput, previousChanges, fileContents)
+	fixes, metrics, err := tier.Agent.FixErrors(ctx, ticketKey, ticketSummary, errorType, errorOutput, previousChanges, fileContents, history)
+	partial := &HealResult{ErrorType: errorType, ErrorOutput: errorOutput, Metrics: metrics, Model: tier.Name}
 	if err != nil {
-		return nil, fmt.Errorf("AI fix generation failed: %w", err)
+		return partial, fmt.Errorf("AI fix generation failed: %w", err)
 	}
 
 	logger.Info("AI generated fixes",
@@ -181,9 +185,12 @@ func (c *Coordinator) tryHealErrors(
 		"cost", metrics.EstimatedCost)
 
 	// Validate the fixes before applying (reject go.mod/go.sum, path traversal, etc.)
-	validatedFixes, valErr := validatePlannedChanges(repoPath, fixes, c.Cfg.AllowedWriteDirs, c.Cfg.PlanMaxFiles)
+	validatedFixes, rejections, valErr := validatePlannedChanges(repoPath, fixes, c.Cfg.AllowedWriteDirs, c.Cfg.PlanMaxFiles)
+	for _, r := range rejections {
+		logger.Warn("Dropped healing fix", "ticket", ticketKey, "path", r.Path, "reason", r.Reason)
+	}
 	if valErr != nil {
-		return nil, fmt.Errorf("fix validation failed: %w", valErr)
+		return partial, fmt.Errorf("fix validation failed: %w", valErr)
 	}
 
 	logger.Info("Validated healing fixes",
@@ -194,7 +201,7 @@ func (c *Coordinator) tryHealErrors(
 	// Apply the validated fixes
 	for _, change := range validatedFixes {
 		if err := applyCodeChange(repoPath, change); err != nil {
-			return nil, fmt.Errorf("failed to apply fix for %s: %w", change.Path, err)
+			return partial, fmt.Errorf("failed to apply fix for %s: %w", change.Path, err)
 		}
 	}
 
@@ -204,19 +211,35 @@ func (c *Coordinator) tryHealErrors(
 		ErrorOutput:  errorOutput,
 		FixedChanges: validatedFixes, // Use validated fixes, not raw AI output
 		Metrics:      metrics,
+		Model:        tier.Name,
 	}, nil
 }
 
-// selfHealingPipeline implements the self-healing retry loop
+// selfHealingPipeline implements the self-healing retry loop. baseline is
+// the pre-change coverage of the affected packages, used by the RequireTests
+// policy (nil when the policy is off or nothing could be measured).
 func (c *Coordinator) selfHealingPipeline(
 	ctx context.Context,
 	ticketKey, ticketSummary string,
 	initialChanges []agent.CodeChange,
 	repoPath string,
+	baseline *CoverageSnapshot,
 ) (*SelfHealingResult, error) {
 	if !c.Cfg.SelfHealEnabled {
-		// Self-healing disabled, return success immediately
-		return &SelfHealingResult{Success: true}, nil
+		// Self-healing disabled: nothing to retry, but the test policy still
+		// decides whether the ticket may proceed to a PR.
+		result := &SelfHealingResult{Success: true}
+		if c.Cfg.RequireTests {
+			coverage, problem := c.coverageGate(ctx, repoPath, initialChanges, baseline)
+			result.Coverage = coverage
+			if problem != "" {
+				logger.Error("Test policy not satisfied and self-healing is disabled",
+					"ticket", ticketKey,
+					"problem", problem)
+				result.Success = false
+			}
+		}
+		return result, nil
 	}
 
 	result := &SelfHealingResult{
@@ -226,6 +249,18 @@ func (c *Coordinator) selfHealingPipeline(
 
 	// Track current changes (starts with initial changes)
 	currentChanges := initialChanges
+	// Every change made so far, for the test policy: a test written by the
+	// plan still counts after a later attempt fixes an unrelated vet error.
+	allChanges := initialChanges
+	// Applied attempts, fed back to the model, with where each is in
+	// result.Attempts (failed attempts sit between them), and the error
+	// signatures those attempts tried to fix: seeing one again means a fix
+	// didn't stick.
+	var history []agent.HealAttempt
+	var historyIndex []int
+	attemptedSignatures := make(map[string]int)
+	// Whether the last applied attempt awaits the gate run that judges it
+	unjudged := false
 
 	// Try up to MaxAttempts times
 	for attempt := 1; attempt <= c.Cfg.SelfHealMaxAttempts; attempt++ {
@@ -240,7 +275,11 @@ func (c *Coordinator) selfHealingPipeline(
 
 		// Check build (if enabled)
 		if c.Cfg.SelfHealOnBuild {
-			output, err := runGoBuild(ctx, repoPath)
+			output, err := runGoBuild(ctx, c.gateExecutor(), repoPath)
+			if errors.Is(err, sandbox.ErrIsolationUnavailable) {
+				// Not something the model can fix; don't pay it to try
+				return result, err
+			}
 			if err != nil {
 				errorType = "build"
 				errorOutput = output
@@ -253,7 +292,10 @@ func (c *Coordinator) selfHealingPipeline(
 
 		// Check vet (if enabled and no build error)
 		if !hasError && c.Cfg.SelfHealOnVet {
-			output, err := runGoVet(ctx, repoPath)
+			output, err := runGoVet(ctx, c.gateExecutor(), repoPath)
+			if errors.Is(err, sandbox.ErrIsolationUnavailable) {
+				return result, err
+			}
 			if err != nil {
 				// Check if this is a go.sum checksum mismatch error (can't be healed by AI)
 				if strings.Contains(output, "go.sum") && strings.Contains(output, "checksum mismatch") {
@@ -280,7 +322,10 @@ func (c *Coordinator) selfHealingPipeline(
 
 		// Check tests (if enabled and no other errors)
 		if !hasError && c.Cfg.SelfHealOnTests {
-			output, err := runGoTest(ctx, repoPath)
+			output, err := runGoTest(ctx, c.gateExecutor(), repoPath)
+			if errors.Is(err, sandbox.ErrIsolationUnavailable) {
+				return result, err
+			}
 			if err != nil {
 				errorType = "test"
 				errorOutput = output
@@ -291,6 +336,30 @@ func (c *Coordinator) selfHealingPipeline(
 			}
 		}
 
+		// Check test policy (if enabled and everything else passes)
+		if !hasError && c.Cfg.RequireTests {
+			coverage, problem := c.coverageGate(ctx, repoPath, allChanges, baseline)
+			result.Coverage = coverage
+			if problem != "" {
+				errorType = "coverage"
+				errorOutput = problem
+				hasError = true
+				logger.Warn("Test policy not satisfied, attempting heal",
+					"ticket", ticketKey,
+					"attempt", attempt)
+			}
+		}
+
+		signature := ""
+		if hasError {
+			signature = errorSignature(errorType, errorOutput)
+		}
+		if n := len(history); n > 0 && unjudged {
+			history[n-1].ErrorChanged = !hasError || signature != history[n-1].ErrorSignature
+			result.Attempts[historyIndex[n-1]].ErrorChanged = history[n-1].ErrorChanged
+			unjudged = false
+		}
+
 		// If no errors, we're done!
 		if !hasError {
 			logger.Info("Quality gates passed",
@@ -301,37 +370,92 @@ func (c *Coordinator) selfHealingPipeline(
 			break
 		}
 
+		// An error an earlier attempt already tried to fix is back. Once the
+		// ladder is exhausted the same model would only go round in circles,
+		// so stop instead of burning the remaining attempts.
+		if prev, seen := attemptedSignatures[signature]; seen && attempt > len(c.tiers()) {
+			logger.Error("Self-healing is oscillating, stopping early",
+				"ticket", ticketKey,
+				"attempt", attempt,
+				"error_type", errorType,
+				"first_attempted_in", prev)
+			result.Attempts = append(result.Attempts, HealResult{
+				Attempt:        attempt,
+				Success:        false,
+				ErrorType:      errorType,
+				ErrorOutput:    errorOutput,
+				ErrorSignature: signature,
+			})
+			result.Oscillated = true
+			result.Success = false
+			break
+		}
+
 		// If we have an error and haven't exceeded max attempts, try to heal
 		if hasError && attempt < c.Cfg.SelfHealMaxAttempts {
-			healResult, err := c.tryHealErrors(ctx, ticketKey, ticketSummary, errorType, errorOutput, currentChanges, repoPath)
+			previous := currentChanges
+			if errorType == "coverage" {
+				// Writing tests needs the code under test, not just the last fix.
+				previous = allChanges
+			}
+			tier := c.healTier(attempt)
+			healResult, err := c.tryHealErrors(ctx, tier, ticketKey, ticketSummary, errorType, errorOutput, previous, history, repoPath)
 			if err != nil {
 				logger.Error("Healing attempt failed",
 					"ticket", ticketKey,
 					"attempt", attempt,
+					"model", tier.Name,
 					"error", err)
 
 				// Record failed healing attempt
-				result.Attempts = append(result.Attempts, HealResult{
-					Attempt:     attempt,
-					Success:     false,
-					ErrorType:   errorType,
-					ErrorOutput: errorOutput,
-				})
+				failed := HealResult{
+					Attempt:        attempt,
+					Success:        false,
+					ErrorType:      errorType,
+					ErrorOutput:    errorOutput,
+					ErrorSignature: signature,
+					Model:          tier.Name,
+				}
+				if healResult != nil && healResult.Metrics != nil {
+					failed.Metrics = healResult.Metrics
+					result.TotalCost += healResult.Metrics.EstimatedCost
+				}
+				result.Attempts = append(result.Attempts, failed)
+
+				// A higher tier may manage what this one couldn't; only
+				// give up once the top of the ladder has failed too.
+				if attempt < len(c.tiers()) {
+					continue
+				}
 				result.Success = false
 				break
 			}
 
 			// Record successful healing attempt (fixes were applied)
 			healResult.Attempt = attempt
+			healResult.ErrorSignature = signature
+			historyIndex = append(historyIndex, len(result.Attempts))
 			result.Attempts = append(result.Attempts, *healResult)
+			history = append(history, agent.HealAttempt{
+				Attempt:        attempt,
+				ErrorType:      errorType,
+				ErrorSignature: signature,
+				Changes:        healResult.FixedChanges,
+			})
+			unjudged = true
+			if _, seen := attemptedSignatures[signature]; !seen {
+				attemptedSignatures[signature] = attempt
+			}
 			result.TotalCost += healResult.Metrics.EstimatedCost
 
 			// Update current changes to include the fixes
 			currentChanges = healResult.FixedChanges
+			allChanges = mergeChanges(allChanges, healResult.FixedChanges)
 
 			logger.Info("Healing attempt complete, will retry quality gates",
 				"ticket", ticketKey,
 				"attempt", attempt,
+				"model", tier.Name,
 				"cost", healResult.Metrics.EstimatedCost)
 
 			// Continue to next attempt (will re-run quality gates)
@@ -346,10 +470,11 @@ func (c *Coordinator) selfHealingPipeline(
 				"error_type", errorType)
 
 			result.Attempts = append(result.Attempts, HealResult{
-				Attempt:     attempt,
-				Success:     false,
-				ErrorType:   errorType,
-				ErrorOutput: errorOutput,
+				Attempt:        attempt,
+				Success:        false,
+				ErrorType:      errorType,
+				ErrorOutput:    errorOutput,
+				ErrorSignature: signature,
 			})
 			result.Success = false
 			break
@@ -374,6 +499,47 @@ func (c *Coordinator) selfHealingPipeline(
 	return result, nil
 }
 
+var (
+	sigPositionRe = regexp.MustCompile(`(\.[A-Za-z0-9]+):\d+(:\d+)?`)
+	sigDurationRe = regexp.MustCompile(`\(?\b\d+(\.\d+)?(ns|µs|ms|s)\)?`)
+	sigTempDirRe  = regexp.MustCompile(`/(tmp|var/folders)/\S*?/`)
+	sigAddressRe  = regexp.MustCompile(`0x[0-9a-f]+`)
+	sigNoiseRe    = regexp.MustCompile(`^(=== (RUN|PAUSE|CONT)|ok\s|PASS$|FAIL$|exit status \d+|coverage: )`)
+)
+
+// maxSignatureLines bounds the signature for builds with many errors; the
+// first lines after sorting are as good a fingerprint as all of them.
+const maxSignatureLines = 20
+
+// errorSignature reduces gate output to a stable fingerprint: the error lines
+// with line/column numbers, timings, temp paths and addresses stripped, sorted
+// and de-duplicated. Shifting code around an error doesn't change it; a
+// genuinely different error does.
+func errorSignature(errorType, errorOutput string) string {
+	seen := make(map[string]bool)
+	var lines []string
+	for _, line := range strings.Split(errorOutput, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || sigNoiseRe.MatchString(line) {
+			continue
+		}
+		line = sigPositionRe.ReplaceAllString(line, "$1")
+		line = sigDurationRe.ReplaceAllString(line, "")
+		line = sigTempDirRe.ReplaceAllString(line, "/")
+		line = sigAddressRe.ReplaceAllString(line, "0x")
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" && !seen[line] {
+			seen[line] = true
+			lines = append(lines, line)
+		}
+	}
+	sort.Strings(lines)
+	if len(lines) > maxSignatureLines {
+		lines = lines[:maxSignatureLines]
+	}
+	return errorType + ": " + strings.Join(lines, "\n")
+}
+
 // extractErrorSummary extracts a concise error summary from full error output
 // This is useful for logging and metrics
 func extractErrorSummary(errorOutput string) string {
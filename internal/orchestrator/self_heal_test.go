@@ -9,6 +9,7 @@ import (
 
 	"intern/internal/ai/agent"
 	"intern/internal/config"
+	"intern/internal/sandbox"
 
 	logger "github.com/jenish-jain/logger"
 )
@@ -133,13 +134,14 @@ FAIL    mypackage    0.123s`,
 func TestRunQualityGate(t *testing.T) {
 	tmpDir := t.TempDir()
 	ctx := context.Background()
+	executor := sandbox.New(sandbox.DefaultConfig())
 
 	// Create a simple valid Go project
 	os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644)
 	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
 
 	t.Run("go vet on valid project", func(t *testing.T) {
-		output, err := runQualityGate(ctx, tmpDir, "go", "vet", "./...")
+		output, err := runQualityGate(ctx, executor, tmpDir, "go", "vet", "./...")
 		if err != nil {
 			t.Logf("go vet failed (may be expected): %v, output: %s", err, output)
 		}
@@ -147,7 +149,7 @@ func TestRunQualityGate(t *testing.T) {
 	})
 
 	t.Run("invalid command", func(t *testing.T) {
-		_, err := runQualityGate(ctx, tmpDir, "nonexistent-command", "arg1")
+		_, err := runQualityGate(ctx, executor, tmpDir, "nonexistent-command", "arg1")
 		if err == nil {
 			t.Error("Expected error for invalid command")
 		}
@@ -157,12 +159,13 @@ func TestRunQualityGate(t *testing.T) {
 func TestRunGoVet(t *testing.T) {
 	tmpDir := t.TempDir()
 	ctx := context.Background()
+	executor := sandbox.New(sandbox.DefaultConfig())
 
 	// Create a simple valid Go project
 	os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644)
 	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
 
-	output, err := runGoVet(ctx, tmpDir)
+	output, err := runGoVet(ctx, executor, tmpDir)
 	// vet may succeed or fail, we just verify it runs
 	t.Logf("go vet output: %s, err: %v", output, err)
 }
@@ -170,13 +173,14 @@ func TestRunGoVet(t *testing.T) {
 func TestRunGoTest(t *testing.T) {
 	tmpDir := t.TempDir()
 	ctx := context.Background()
+	executor := sandbox.New(sandbox.DefaultConfig())
 
 	// Create a simple valid Go project with a test
 	os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644)
 	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n\nfunc Add(a, b int) int { return a + b }\n"), 0644)
 	os.WriteFile(filepath.Join(tmpDir, "main_test.go"), []byte("package main\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(2,3) != 5 { t.Error(\"failed\") }\n}\n"), 0644)
 
-	output, err := runGoTest(ctx, tmpDir)
+	output, err := runGoTest(ctx, executor, tmpDir)
 	if err != nil {
 		t.Logf("go test failed: %v, output: %s", err, output)
 	}
@@ -186,12 +190,13 @@ func TestRunGoTest(t *testing.T) {
 func TestRunGoBuild(t *testing.T) {
 	tmpDir := t.TempDir()
 	ctx := context.Background()
+	executor := sandbox.New(sandbox.DefaultConfig())
 
 	// Create a simple valid Go project
 	os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644)
 	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
 
-	output, err := runGoBuild(ctx, tmpDir)
+	output, err := runGoBuild(ctx, executor, tmpDir)
 	if err != nil {
 		t.Errorf("go build failed: %v, output: %s", err, output)
 	}
@@ -203,6 +208,7 @@ type mockHealingAgent struct {
 	fixesError    error
 	planCalled    bool
 	fixesCalled   bool
+	lastHistory   []agent.HealAttempt
 }
 
 func (m *mockHealingAgent) PlanChanges(ctx context.Context, ticketKey, ticketSummary, ticketDescription, repoContext string) ([]agent.CodeChange, []string, *agent.UsageMetrics, error) {
@@ -210,8 +216,9 @@ func (m *mockHealingAgent) PlanChanges(ctx context.Context, ticketKey, ticketSum
 	return nil, nil, nil, nil
 }
 
-func (m *mockHealingAgent) FixErrors(ctx context.Context, ticketKey, ticketSummary, errorType, errorOutput string, previousChanges []agent.CodeChange, fileContents map[string]string) ([]agent.CodeChange, *agent.UsageMetrics, error) {
+func (m *mockHealingAgent) FixErrors(ctx context.Context, ticketKey, ticketSummary, errorType, errorOutput string, previousChanges []agent.CodeChange, fileContents map[string]string, history []agent.HealAttempt) ([]agent.CodeChange, *agent.UsageMetrics, error) {
 	m.fixesCalled = true
+	m.lastHistory = history
 	return m.fixesResponse, &agent.UsageMetrics{EstimatedCost: 0.05}, m.fixesError
 }
 
@@ -256,7 +263,7 @@ func TestTryHealErrors(t *testing.T) {
 				},
 			}
 
-			result, err := coord.tryHealErrors(ctx, "TEST-123", "Test ticket", "test", "test error output", []agent.CodeChange{}, tmpDir)
+			result, err := coord.tryHealErrors(ctx, coord.healTier(1), "TEST-123", "Test ticket", "test", "test error output", []agent.CodeChange{}, nil, tmpDir)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("tryHealErrors() error = %v, wantErr %v", err, tt.wantErr)
@@ -285,7 +292,7 @@ func TestSelfHealingPipeline_Disabled(t *testing.T) {
 		},
 	}
 
-	result, err := coord.selfHealingPipeline(ctx, "TEST-123", "Test", []agent.CodeChange{}, tmpDir)
+	result, err := coord.selfHealingPipeline(ctx, "TEST-123", "Test", []agent.CodeChange{}, tmpDir, nil)
 	if err != nil {
 		t.Errorf("Unexpected error when self-healing disabled: %v", err)
 	}
@@ -316,7 +323,7 @@ func TestSelfHealingPipeline_NoErrors(t *testing.T) {
 		},
 	}
 
-	result, err := coord.selfHealingPipeline(ctx, "TEST-123", "Test", []agent.CodeChange{}, tmpDir)
+	result, err := coord.selfHealingPipeline(ctx, "TEST-123", "Test", []agent.CodeChange{}, tmpDir, nil)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -365,7 +372,7 @@ func TestSelfHealingPipeline_WithBuildError(t *testing.T) {
 		},
 	}
 
-	result, err := coord.selfHealingPipeline(ctx, "TEST-123", "Test", []agent.CodeChange{}, tmpDir)
+	result, err := coord.selfHealingPipeline(ctx, "TEST-123", "Test", []agent.CodeChange{}, tmpDir, nil)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -380,6 +387,144 @@ func TestSelfHealingPipeline_WithBuildError(t *testing.T) {
 	}
 }
 
+func TestSelfHealingPipeline_StopsWhenErrorRepeats(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n\nfunc main() {\n// missing brace\n"), 0644)
+
+	// The "fix" touches an unrelated file, so the same build error comes back.
+	mockAgent := &mockHealingAgent{
+		fixesResponse: []agent.CodeChange{
+			{Path: "doc.go", Operation: agent.OperationCreate, Content: "package main\n"},
+		},
+	}
+	metrics := NewMetrics()
+	coord := &Coordinator{
+		Agent:   mockAgent,
+		Metrics: metrics,
+		Cfg: &config.Config{
+			SelfHealEnabled:     true,
+			SelfHealMaxAttempts: 5,
+			SelfHealOnBuild:     true,
+			AllowedWriteDirs:    []string{"."},
+			PlanMaxFiles:        20,
+		},
+	}
+
+	result, err := coord.selfHealingPipeline(context.Background(), "TEST-123", "Test", nil, tmpDir, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Success || !result.Oscillated {
+		t.Fatalf("Expected an oscillation failure, got success=%v oscillated=%v", result.Success, result.Oscillated)
+	}
+	if len(result.Attempts) != 2 {
+		t.Fatalf("Expected to stop after the repeat on attempt 2, got %d attempts", len(result.Attempts))
+	}
+	if result.Attempts[0].ErrorChanged {
+		t.Error("Expected the first attempt to be recorded as not changing the error")
+	}
+	if result.Attempts[0].ErrorSignature != result.Attempts[1].ErrorSignature {
+		t.Errorf("Expected matching signatures, got %q and %q", result.Attempts[0].ErrorSignature, result.Attempts[1].ErrorSignature)
+	}
+}
+
+func TestSelfHealingPipeline_PassesHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n\nfunc main() {\n// missing brace\n"), 0644)
+
+	first := &mockHealingAgent{
+		fixesResponse: []agent.CodeChange{
+			{Path: "doc.go", Operation: agent.OperationCreate, Content: "package main\n"},
+		},
+	}
+	second := &mockHealingAgent{}
+	coord := &Coordinator{
+		Cfg: &config.Config{
+			SelfHealEnabled:     true,
+			SelfHealMaxAttempts: 3,
+			SelfHealOnBuild:     true,
+			AllowedWriteDirs:    []string{"."},
+			PlanMaxFiles:        20,
+		},
+		Escalation: []agent.Tier{{Name: "cheap", Agent: first}, {Name: "strong", Agent: second}},
+	}
+
+	if _, err := coord.selfHealingPipeline(context.Background(), "TEST-123", "Test", nil, tmpDir, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(second.lastHistory) != 1 {
+		t.Fatalf("Expected the second attempt to see 1 earlier attempt, got %d", len(second.lastHistory))
+	}
+	h := second.lastHistory[0]
+	if h.Attempt != 1 || h.ErrorType != "build" || len(h.Changes) != 1 || h.ErrorChanged {
+		t.Errorf("Unexpected history entry: %+v", h)
+	}
+}
+
+func TestSelfHealingPipeline_ErrorChangedAfterFailedHeal(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module test\n\ngo 1.21\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n\nfunc main() {\n// missing brace\n"), 0644)
+
+	// The first fix trades the syntax error for another; the second tier
+	// fails outright, and the third never gets to run
+	changes := &mockHealingAgent{
+		fixesResponse: []agent.CodeChange{
+			{Path: "main.go", Operation: agent.OperationEdit, Edits: []agent.EditHunk{
+				{Old: "func main() {\n// missing brace\n", New: "func main() { missing() }\n"},
+			}},
+		},
+	}
+	broken := &mockHealingAgent{fixesError: os.ErrNotExist}
+	coord := &Coordinator{
+		Cfg: &config.Config{
+			SelfHealEnabled:     true,
+			SelfHealMaxAttempts: 3,
+			SelfHealOnBuild:     true,
+			AllowedWriteDirs:    []string{"."},
+			PlanMaxFiles:        20,
+		},
+		Escalation: []agent.Tier{{Name: "cheap", Agent: changes}, {Name: "broken", Agent: broken}, {Name: "strong", Agent: &mockHealingAgent{}}},
+	}
+
+	result, err := coord.selfHealingPipeline(context.Background(), "TEST-123", "Test", nil, tmpDir, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.Attempts) != 3 {
+		t.Fatalf("Expected 3 attempts, got %d: %+v", len(result.Attempts), result.Attempts)
+	}
+	if !result.Attempts[0].ErrorChanged {
+		t.Error("Expected the applied attempt to be recorded as changing the error")
+	}
+	if result.Attempts[1].ErrorChanged || result.Attempts[1].Model != "broken" {
+		t.Errorf("Expected the failed attempt to be left unjudged, got %+v", result.Attempts[1])
+	}
+}
+
+func TestErrorSignature(t *testing.T) {
+	a := errorSignature("build", "# test\n./main.go:4:1: syntax error: unexpected EOF\n./util.go:9:2: undefined: foo\n")
+	b := errorSignature("build", "# test\n./util.go:12:2: undefined: foo\n./main.go:7:1: syntax error: unexpected EOF\n./main.go:7:1: syntax error: unexpected EOF\n")
+	if a != b {
+		t.Errorf("Expected line shifts and ordering to be ignored:\n%s\n---\n%s", a, b)
+	}
+
+	test1 := errorSignature("test", "--- FAIL: TestAdd (0.01s)\n    calc_test.go:10: got 4, want 5\nFAIL\nFAIL\ttest/calc\t0.003s\n")
+	test2 := errorSignature("test", "--- FAIL: TestAdd (0.20s)\n    calc_test.go:11: got 4, want 5\nFAIL\nFAIL\ttest/calc\t1.2s\n")
+	if test1 != test2 {
+		t.Errorf("Expected timings to be ignored:\n%s\n---\n%s", test1, test2)
+	}
+
+	if errorSignature("build", "./main.go:4:1: undefined: bar") == a {
+		t.Error("Expected a different error to produce a different signature")
+	}
+	if errorSignature("vet", "x") == errorSignature("build", "x") {
+		t.Error("Expected the error type to be part of the signature")
+	}
+}
+
 func TestHealResult_Fields(t *testing.T) {
 	result := HealResult{
 		Attempt:     1,
@@ -22,6 +22,8 @@ type TicketMetrics struct {
 	FilesInContext    int    `json:"files_in_context"`
 	ContextSizeBytes  int    `json:"context_size_bytes"`
 	KeywordsExtracted int    `json:"keywords_extracted"`   // Only for smart context
+	ContextTokens     int    `json:"context_tokens"`       // Estimated tokens of the packed context (smart context only)
+	TokenBudget       int    `json:"context_token_budget"` // Token budget it was packed into
 
 	// Error information (if failed)
 	ErrorMessage string `json:"error_message,omitempty"`
@@ -49,6 +51,8 @@ type TicketResult struct {
 	FilesInContext    int    `json:"files_in_context"`
 	ContextSizeBytes  int    `json:"context_size_bytes"`
 	KeywordsExtracted int    `json:"keywords_extracted,omitempty"`
+	ContextTokens     int    `json:"context_tokens,omitempty"`
+	TokenBudget       int    `json:"context_token_budget,omitempty"`
 
 	ErrorMessage string `json:"error_message,omitempty"`
 	RetryCount   int    `json:"retry_count"`
@@ -73,6 +77,8 @@ func (tm *TicketMetrics) ToResult() TicketResult {
 		FilesInContext:           tm.FilesInContext,
 		ContextSizeBytes:         tm.ContextSizeBytes,
 		KeywordsExtracted:        tm.KeywordsExtracted,
+		ContextTokens:            tm.ContextTokens,
+		TokenBudget:              tm.TokenBudget,
 		ErrorMessage:             tm.ErrorMessage,
 		RetryCount:               tm.RetryCount,
 		EstimatedFullContextCost: tm.EstimatedFullContextCost,
@@ -101,12 +107,14 @@ func NewTicketMetricsFromUsage(ticketKey string, usage *agent.UsageMetrics) *Tic
 		FilesInContext:    usage.ContextStats.FilesIncluded,
 		ContextSizeBytes:  usage.ContextStats.ContextBytes,
 		KeywordsExtracted: usage.ContextStats.Keywords,
+		ContextTokens:     usage.ContextStats.PackedTokens,
+		TokenBudget:       usage.ContextStats.TokenBudget,
 		Timestamp:         time.Now(),
 	}
 }
 
 // ApplyUsage fills in the AI-usage-derived fields (tokens, cost, context
-// stats) on an existing TicketMetrics once planning succeeds, leaving
+// stats) on an existing TicketMetrics once planning ends, leaving
 // TicketKey/Timestamp/Status untouched - Status is set separately once the
 // full pipeline completes, since planning succeeding doesn't guarantee the
 // rest of the ticket does.
@@ -121,6 +129,8 @@ func (tm *TicketMetrics) ApplyUsage(usage *agent.UsageMetrics) {
 	tm.FilesInContext = usage.ContextStats.FilesIncluded
 	tm.ContextSizeBytes = usage.ContextStats.ContextBytes
 	tm.KeywordsExtracted = usage.ContextStats.Keywords
+	tm.ContextTokens = usage.ContextStats.PackedTokens
+	tm.TokenBudget = usage.ContextStats.TokenBudget
 }
 
 // MarkFailed marks the ticket as failed with an error message.
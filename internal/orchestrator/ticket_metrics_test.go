@@ -22,6 +22,8 @@ func TestNewTicketMetricsFromUsage(t *testing.T) {
 				FilesIncluded: 12,
 				ContextBytes:  24576,
 				Keywords:      5,
+				PackedTokens:  6144,
+				TokenBudget:   8192,
 			},
 		}
 
@@ -36,6 +38,8 @@ func TestNewTicketMetricsFromUsage(t *testing.T) {
 		assert.Equal(t, 12, tm.FilesInContext)
 		assert.Equal(t, 24576, tm.ContextSizeBytes)
 		assert.Equal(t, 5, tm.KeywordsExtracted)
+		assert.Equal(t, 6144, tm.ContextTokens)
+		assert.Equal(t, 8192, tm.TokenBudget)
 	})
 
 	t.Run("with nil usage metrics", func(t *testing.T) {
@@ -3,20 +3,45 @@ package orchestrator
 import (
 	"fmt"
 	"intern/internal/ai/agent"
+	"intern/internal/policy"
+	"os"
 	"path/filepath"
 	"strings"
 
 	logger "github.com/jenish-jain/logger"
 )
 
-func validatePlannedChanges(root string, changes []agent.CodeChange, allowedDirs []string, maxFiles int) ([]agent.CodeChange, error) {
+// Rejection records a planned change that validation dropped and why, so
+// the model can be told and re-plan around it
+type Rejection struct {
+	Path   string
+	Reason string
+	Policy bool // Refused by a write rule rather than malformed
+}
+
+// validatePlannedChanges filters changes down to the ones that are safe to
+// apply under root: well-formed, inside allowedDirs and permitted by the
+// repository's write policy (see the policy package). Every dropped change
+// is returned as a Rejection, including when none are left and the error is
+// non-nil.
+func validatePlannedChanges(root string, changes []agent.CodeChange, allowedDirs []string, maxFiles int) ([]agent.CodeChange, []Rejection, error) {
 	logger.Debug("Validating planned changes", "total_changes", len(changes), "allowed_dirs", allowedDirs)
 
+	// Fail closed: a policy that can't be read must not let denied paths through.
+	pol, err := policy.Load(root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load write policy: %w", err)
+	}
+
 	if len(changes) > maxFiles {
 		logger.Debug("Truncating changes due to max files limit", "original", len(changes), "max", maxFiles)
 		changes = changes[:maxFiles]
 	}
 	var out []agent.CodeChange
+	var rejections []Rejection
+	reject := func(path, reason string, byPolicy bool) {
+		rejections = append(rejections, Rejection{Path: path, Reason: reason, Policy: byPolicy})
+	}
 	for _, ch := range changes {
 		p := strings.TrimSpace(ch.Path)
 		if p == "" {
@@ -26,17 +51,14 @@ func validatePlannedChanges(root string, changes []agent.CodeChange, allowedDirs
 		// No absolute paths
 		if filepath.IsAbs(p) {
 			logger.Debug("Skipping absolute path", "path", p)
+			reject(p, "absolute paths are not allowed - use a path relative to the repository root", false)
 			continue
 		}
 		// Normalize and guard traversal
 		clean := filepath.Clean(p)
 		if strings.HasPrefix(clean, "..") {
 			logger.Debug("Skipping path with traversal", "path", clean)
-			continue
-		}
-		// NEVER allow modifications to go.mod or go.sum - these are managed by Go tooling
-		if clean == "go.mod" || clean == "go.sum" {
-			logger.Warn("Rejecting attempt to modify Go dependency file", "path", clean)
+			reject(p, "path escapes the repository root", false)
 			continue
 		}
 		// Enforce allowlist, unless "*" opts out of it entirely (needed since
@@ -46,9 +68,17 @@ func validatePlannedChanges(root string, changes []agent.CodeChange, allowedDirs
 			// Allow root-level files if "." is in allowedDirs
 			if !inList(first, allowedDirs) && !(first == clean && inList(".", allowedDirs)) {
 				logger.Debug("Skipping path not in allowed directories", "path", clean, "first_segment", first, "allowed_dirs", allowedDirs)
+				reject(clean, fmt.Sprintf("outside the directories the agent may write to (%s)", strings.Join(allowedDirs, ", ")), true)
 				continue
 			}
 		}
+		// Repository write policy. go.mod and go.sum are always protected
+		// here - they are managed by Go tooling.
+		if v := pol.Check(clean, changedLines(root, clean, ch)); v != nil {
+			logger.Warn("Rejecting change refused by write policy", "path", clean, "rule", v.Rule, "reason", v.Reason)
+			reject(clean, v.Reason, true)
+			continue
+		}
 		// Ensure a payload is present: create needs content, edit needs hunks,
 		// delete needs neither.
 		switch ch.Operation {
@@ -56,11 +86,13 @@ func validatePlannedChanges(root string, changes []agent.CodeChange, allowedDirs
 		case agent.OperationEdit:
 			if len(ch.Edits) == 0 {
 				logger.Debug("Skipping edit with no hunks", "path", clean)
+				reject(clean, "edit has no hunks", false)
 				continue
 			}
 		default:
 			if strings.TrimSpace(ch.Content) == "" {
 				logger.Debug("Skipping file with empty content", "path", clean)
+				reject(clean, "create has no content", false)
 				continue
 			}
 		}
@@ -69,9 +101,66 @@ func validatePlannedChanges(root string, changes []agent.CodeChange, allowedDirs
 	}
 	logger.Debug("Validation complete", "accepted_changes", len(out), "rejected_changes", len(changes)-len(out))
 	if len(out) == 0 {
-		return nil, fmt.Errorf("no valid changes after validation")
+		return nil, rejections, fmt.Errorf("no valid changes after validation")
+	}
+	return out, rejections, nil
+}
+
+// changedLines estimates how many lines ch touches in path, for per-path
+// line limits: a create counts its content, an edit the larger side of each
+// hunk and a delete the file it removes.
+func changedLines(root, path string, ch agent.CodeChange) int {
+	switch ch.Operation {
+	case agent.OperationDelete:
+		data, err := os.ReadFile(filepath.Join(root, path))
+		if err != nil {
+			return 0
+		}
+		return countLines(string(data))
+	case agent.OperationEdit:
+		n := 0
+		for _, h := range ch.Edits {
+			n += max(countLines(h.Old), countLines(h.New))
+		}
+		return n
+	default:
+		return countLines(ch.Content)
+	}
+}
+
+func countLines(s string) int {
+	if s == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(s, "\n"), "\n") + 1
+}
+
+// policyRejections returns the rejections made by a write rule
+func policyRejections(rejections []Rejection) []Rejection {
+	var out []Rejection
+	for _, r := range rejections {
+		if r.Policy {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// formatRejections explains dropped changes to the model so its next plan
+// can work around them
+func formatRejections(rejections []Rejection) string {
+	if len(rejections) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("## Rejected Changes\n")
+	b.WriteString("Your previous plan for this ticket included changes that were refused and NOT applied:\n")
+	for _, r := range rejections {
+		fmt.Fprintf(&b, "- %s: %s\n", r.Path, r.Reason)
 	}
-	return out, nil
+	b.WriteString("Plan again without these changes. Do not touch refused paths under another name. ")
+	b.WriteString("If the ticket can't be completed without them, make the changes that are allowed and explain what is missing in a note.\n\n")
+	return b.String()
 }
 
 func firstSegment(p string) string {
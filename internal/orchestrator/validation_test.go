@@ -2,6 +2,7 @@ package orchestrator
 
 import (
 	"intern/internal/ai/agent"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -120,7 +121,7 @@ func TestValidatePlannedChanges(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			logger.Init("debug")
-			result, err := validatePlannedChanges("/fake/root", tt.changes, allowedDirs, maxFiles)
+			result, _, err := validatePlannedChanges("/fake/root", tt.changes, allowedDirs, maxFiles)
 
 			if tt.hasError {
 				assert.Error(t, err)
@@ -144,6 +145,47 @@ func TestValidatePlannedChanges(t *testing.T) {
 	}
 }
 
+func TestValidatePlannedChanges_WritePolicy(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, ".ai-intern"), 0755)
+	os.WriteFile(filepath.Join(root, ".ai-intern", "policy.yaml"), []byte(
+		"deny:\n  - .github/workflows/\nmax_lines:\n  - path: internal/legacy/\n    lines: 2\n"), 0644)
+
+	changes := []agent.CodeChange{
+		{Path: "internal/service.go", Content: "package internal", Operation: agent.OperationCreate},
+		{Path: ".github/workflows/ci.yml", Content: "on: push", Operation: agent.OperationCreate},
+		{Path: "go.sum", Content: "x", Operation: agent.OperationCreate},
+		{Path: "internal/legacy/old.go", Operation: agent.OperationEdit, Edits: []agent.EditHunk{{Old: "a", New: "b\nc\nd"}}},
+		{Path: "internal/empty.go", Operation: agent.OperationCreate},
+	}
+	valid, rejections, err := validatePlannedChanges(root, changes, []string{"*"}, 10)
+	assert.NoError(t, err)
+	assert.Len(t, valid, 1)
+	assert.Equal(t, "internal/service.go", valid[0].Path)
+
+	assert.Len(t, rejections, 4)
+	refused := policyRejections(rejections)
+	assert.Len(t, refused, 3, "the empty create is malformed, not refused by policy")
+
+	feedback := formatRejections(refused)
+	for _, want := range []string{".github/workflows/ci.yml: path denied", "go.sum: protected file", "internal/legacy/old.go: changes 3 lines, over the limit of 2"} {
+		assert.Contains(t, feedback, want)
+	}
+	assert.NotContains(t, feedback, "internal/empty.go")
+}
+
+func TestValidatePlannedChanges_InvalidPolicyFailsClosed(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, ".ai-intern"), 0755)
+	os.WriteFile(filepath.Join(root, ".ai-intern", "policy.yaml"), []byte("deny: [oops"), 0644)
+
+	valid, _, err := validatePlannedChanges(root, []agent.CodeChange{
+		{Path: "internal/service.go", Content: "package internal", Operation: agent.OperationCreate},
+	}, []string{"*"}, 10)
+	assert.Error(t, err)
+	assert.Nil(t, valid)
+}
+
 func TestFirstSegment(t *testing.T) {
 	tests := []struct {
 		input    string
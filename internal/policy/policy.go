@@ -0,0 +1,263 @@
+// Package policy loads a target repository's write policy from
+// .ai-intern/policy.yaml and its CODEOWNERS, and decides which paths the
+// agent may change, which need human approval, and how much.
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"intern/internal/codeowners"
+	"intern/internal/indexer"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is where a target repository declares its write policy
+const File = ".ai-intern/policy.yaml"
+
+// alwaysProtected can never be written: Go tooling owns the module files,
+// the policy and CODEOWNERS must not be able to loosen themselves, the PR
+// template must not let a change rewrite the PR body reviewers rely on, and
+// the index's ignore file must not hide files from later context selection.
+var alwaysProtected = append([]string{
+	"/go.mod", "/go.sum", "/" + File, "/.ai-intern/pr_template.md",
+	"/" + indexer.IndexDirName + "/" + indexer.IgnoreFileName,
+}, prefixed("/", codeowners.Locations)...)
+
+// Rules mirrors policy.yaml. Every path is a gitignore-style pattern, with
+// the same semantics as CODEOWNERS. Example:
+//
+//	deny:
+//	  - .github/workflows/
+//	  - infra/prod/
+//	require_approval:
+//	  - migrations/
+//	protected:
+//	  - go.work
+//	  - package-lock.json
+//	max_lines:
+//	  - path: "*"
+//	    lines: 400
+//	  - path: internal/legacy/
+//	    lines: 50
+//	owners:
+//	  deny: ["@acme/security"]
+//	  require_approval: ["@acme/dba"]
+type Rules struct {
+	Deny            []string    `yaml:"deny"`
+	RequireApproval []string    `yaml:"require_approval"`
+	Protected       []string    `yaml:"protected"`
+	MaxLines        []LineLimit `yaml:"max_lines"`
+	Owners          OwnerRules  `yaml:"owners"`
+}
+
+// LineLimit caps the lines changed in one file under Path. When several
+// limits match, the last one wins.
+type LineLimit struct {
+	Path  string `yaml:"path"`
+	Lines int    `yaml:"lines"`
+}
+
+// OwnerRules applies deny/require-approval to every path CODEOWNERS assigns
+// to one of the listed owners
+type OwnerRules struct {
+	Deny            []string `yaml:"deny"`
+	RequireApproval []string `yaml:"require_approval"`
+}
+
+// Violation explains why a planned change to Path was refused
+type Violation struct {
+	Path   string
+	Rule   string // "protected", "deny", "owners" or "max_lines"
+	Reason string
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("%s: %s", v.Path, v.Reason)
+}
+
+// Approval is a changed path that needs a human to sign off
+type Approval struct {
+	Path   string
+	Reason string
+	Owners []string // CODEOWNERS of the path, nil if none
+}
+
+// Policy is a compiled write policy. A nil Policy still enforces
+// the always-protected files.
+type Policy struct {
+	protected       []pattern
+	deny            []pattern
+	requireApproval []pattern
+	maxLines        []limit
+	ownerDeny       []string
+	ownerApproval   []string
+	owners          *codeowners.File
+}
+
+type pattern struct {
+	text string
+	re   *regexp.Regexp
+}
+
+type limit struct {
+	pattern
+	lines int
+}
+
+// defaultPolicy applies when a caller has no policy at all
+var defaultPolicy, _ = New(Rules{})
+
+// Load reads the policy and CODEOWNERS of the repository at repoRoot. A
+// repository with neither gets a policy that only protects the files in
+// alwaysProtected. A malformed policy is an error rather than being
+// ignored, so a typo can't silently open up denied paths.
+func Load(repoRoot string) (*Policy, error) {
+	var rules Rules
+	data, err := os.ReadFile(filepath.Join(repoRoot, filepath.FromSlash(File)))
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, &rules); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", File, err)
+		}
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("read %s: %w", File, err)
+	}
+	p, err := New(rules)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", File, err)
+	}
+	if p.owners, err = codeowners.Load(repoRoot); err != nil {
+		return nil, fmt.Errorf("read CODEOWNERS: %w", err)
+	}
+	return p, nil
+}
+
+// New compiles rules
+func New(rules Rules) (*Policy, error) {
+	p := &Policy{ownerDeny: rules.Owners.Deny, ownerApproval: rules.Owners.RequireApproval}
+	var err error
+	if p.protected, err = compile(append(append([]string{}, alwaysProtected...), rules.Protected...)); err != nil {
+		return nil, err
+	}
+	if p.deny, err = compile(rules.Deny); err != nil {
+		return nil, err
+	}
+	if p.requireApproval, err = compile(rules.RequireApproval); err != nil {
+		return nil, err
+	}
+	for _, l := range rules.MaxLines {
+		if l.Lines <= 0 {
+			return nil, fmt.Errorf("max_lines for %q must be positive, got %d", l.Path, l.Lines)
+		}
+		pats, err := compile([]string{l.Path})
+		if err != nil {
+			return nil, err
+		}
+		p.maxLines = append(p.maxLines, limit{pattern: pats[0], lines: l.Lines})
+	}
+	return p, nil
+}
+
+// Check returns why path may not be changed by linesChanged lines, or nil
+func (p *Policy) Check(path string, linesChanged int) *Violation {
+	if p == nil {
+		p = defaultPolicy
+	}
+	path = filepath.ToSlash(path)
+	if m := match(p.protected, path); m != "" {
+		return &Violation{Path: path, Rule: "protected", Reason: fmt.Sprintf("protected file (rule %q) - never modify or delete it", m)}
+	}
+	if m := match(p.deny, path); m != "" {
+		return &Violation{Path: path, Rule: "deny", Reason: fmt.Sprintf("path denied by repository policy (rule %q)", m)}
+	}
+	if owner := p.ownedBy(path, p.ownerDeny); owner != "" {
+		return &Violation{Path: path, Rule: "owners", Reason: fmt.Sprintf("path owned by %s, which repository policy reserves for humans", owner)}
+	}
+	for i := len(p.maxLines) - 1; i >= 0; i-- {
+		l := p.maxLines[i]
+		if !l.re.MatchString(path) {
+			continue
+		}
+		if linesChanged > l.lines {
+			return &Violation{Path: path, Rule: "max_lines", Reason: fmt.Sprintf("changes %d lines, over the limit of %d for %q - make a smaller, targeted change", linesChanged, l.lines, l.text)}
+		}
+		break
+	}
+	return nil
+}
+
+// Approvals lists the paths that may be changed but need a human to approve
+// the result, e.g. database migrations
+func (p *Policy) Approvals(paths []string) []Approval {
+	if p == nil {
+		return nil
+	}
+	var out []Approval
+	for _, path := range paths {
+		path = filepath.ToSlash(path)
+		reason := ""
+		if m := match(p.requireApproval, path); m != "" {
+			reason = fmt.Sprintf("matches require_approval rule %q", m)
+		} else if owner := p.ownedBy(path, p.ownerApproval); owner != "" {
+			reason = fmt.Sprintf("owned by %s", owner)
+		}
+		if reason != "" {
+			out = append(out, Approval{Path: path, Reason: reason, Owners: p.owners.Owners(path)})
+		}
+	}
+	return out
+}
+
+// ownedBy returns the first of owners that CODEOWNERS assigns path to
+func (p *Policy) ownedBy(path string, owners []string) string {
+	if len(owners) == 0 {
+		return ""
+	}
+	for _, o := range p.owners.Owners(path) {
+		for _, want := range owners {
+			if strings.EqualFold(o, want) {
+				return o
+			}
+		}
+	}
+	return ""
+}
+
+func compile(patterns []string) ([]pattern, error) {
+	out := make([]pattern, 0, len(patterns))
+	for _, text := range patterns {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		re, err := codeowners.CompilePattern(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", text, err)
+		}
+		out = append(out, pattern{text: text, re: re})
+	}
+	return out, nil
+}
+
+// match returns the first pattern matching path, or ""
+func match(patterns []pattern, path string) string {
+	for _, p := range patterns {
+		if p.re.MatchString(path) {
+			return p.text
+		}
+	}
+	return ""
+}
+
+func prefixed(prefix string, list []string) []string {
+	out := make([]string, len(list))
+	for i, s := range list {
+		out[i] = prefix + s
+	}
+	return out
+}
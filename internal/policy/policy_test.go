@@ -0,0 +1,114 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const sampleYAML = `deny:
+  - .github/workflows/
+  - /infra/prod/
+require_approval:
+  - migrations/
+protected:
+  - package-lock.json
+max_lines:
+  - path: "*"
+    lines: 100
+  - path: internal/legacy/
+    lines: 10
+owners:
+  deny: ["@acme/security"]
+  require_approval: ["@acme/dba"]
+`
+
+func loadSample(t *testing.T) *Policy {
+	t.Helper()
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, ".ai-intern"), 0755)
+	os.MkdirAll(filepath.Join(dir, ".github"), 0755)
+	os.WriteFile(filepath.Join(dir, File), []byte(sampleYAML), 0644)
+	os.WriteFile(filepath.Join(dir, ".github", "CODEOWNERS"), []byte(
+		"internal/auth/ @acme/security\n"+
+			"db/ @acme/dba\n"), 0644)
+	p, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestCheck(t *testing.T) {
+	p := loadSample(t)
+
+	tests := []struct {
+		path  string
+		lines int
+		rule  string
+	}{
+		{"internal/service.go", 50, ""},
+		{"go.mod", 1, "protected"},
+		{"tools/go.mod", 1, ""}, // only the root module file is always protected
+		{".ai-intern/policy.yaml", 1, "protected"},
+		{".github/CODEOWNERS", 1, "protected"},
+		{".ai-intern/pr_template.md", 1, "protected"},
+		{".ai-intern/ignore", 1, "protected"},
+		{"web/package-lock.json", 1, "protected"},
+		{".github/workflows/ci.yml", 1, "deny"},
+		{"infra/prod/main.tf", 1, "deny"},
+		{"infra/staging/main.tf", 1, ""},
+		{"internal/auth/jwt.go", 1, "owners"},
+		{"internal/service.go", 101, "max_lines"},
+		{"internal/legacy/old.go", 11, "max_lines"}, // the later, tighter limit wins
+		{"migrations/001.sql", 1, ""},               // needs approval, but may be written
+	}
+	for _, tt := range tests {
+		v := p.Check(tt.path, tt.lines)
+		got := ""
+		if v != nil {
+			got = v.Rule
+		}
+		if got != tt.rule {
+			t.Errorf("Check(%q, %d) rule = %q, want %q (%v)", tt.path, tt.lines, got, tt.rule, v)
+		}
+	}
+}
+
+func TestCheck_NilPolicy(t *testing.T) {
+	var p *Policy
+	if v := p.Check("go.sum", 1); v == nil || v.Rule != "protected" {
+		t.Errorf("Expected go.sum to be protected without a policy, got %v", v)
+	}
+	if v := p.Check("internal/x.go", 10000); v != nil {
+		t.Errorf("Expected no other limits without a policy, got %v", v)
+	}
+}
+
+func TestApprovals(t *testing.T) {
+	p := loadSample(t)
+	got := p.Approvals([]string{"migrations/001.sql", "db/schema.go", "internal/service.go"})
+	want := []Approval{
+		{Path: "migrations/001.sql", Reason: `matches require_approval rule "migrations/"`},
+		{Path: "db/schema.go", Reason: "owned by @acme/dba", Owners: []string{"@acme/dba"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Approvals() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, ".ai-intern"), 0755)
+
+	os.WriteFile(filepath.Join(dir, File), []byte("deny: [unterminated"), 0644)
+	if _, err := Load(dir); err == nil {
+		t.Error("Expected an error for malformed YAML")
+	}
+
+	os.WriteFile(filepath.Join(dir, File), []byte("max_lines:\n  - path: x/\n    lines: 0\n"), 0644)
+	if _, err := Load(dir); err == nil {
+		t.Error("Expected an error for a non-positive line limit")
+	}
+}
@@ -27,39 +27,71 @@ import (
 // - Reopens after 30s timeout to test recovery
 // - Logs state transitions for monitoring
 func NewAgent(cfg *config.Config) (agent.Agent, error) {
-	var baseAgent agent.Agent
-	var err error
+	baseAgent, err := newBaseAgent(cfg, cfg.AIProvider, "")
+	if err != nil {
+		return nil, err
+	}
+	return withCircuitBreaker(baseAgent), nil
+}
+
+// NewEscalationLadder builds one agent per AI_ESCALATION_LADDER tier, in
+// order, each with its own circuit breaker so an unreachable local model
+// doesn't trip the breaker for the paid one. Returns nil when no ladder is
+// configured.
+func NewEscalationLadder(cfg *config.Config) ([]agent.Tier, error) {
+	tiers := make([]agent.Tier, 0, len(cfg.AIEscalationLadder))
+	for _, entry := range cfg.AIEscalationLadder {
+		providerName, model := config.ParseEscalationTier(entry)
+		baseAgent, err := newBaseAgent(cfg, providerName, model)
+		if err != nil {
+			return nil, fmt.Errorf("escalation tier %q: %w", entry, err)
+		}
+		tiers = append(tiers, agent.Tier{Name: entry, Agent: withCircuitBreaker(baseAgent)})
+	}
+	if len(tiers) == 0 {
+		return nil, nil
+	}
+	return tiers, nil
+}
 
-	switch cfg.AIProvider {
+// newBaseAgent creates the client for providerName. model overrides the
+// provider's default model (OLLAMA_MODEL for ollama) when non-empty.
+func newBaseAgent(cfg *config.Config, providerName, model string) (agent.Agent, error) {
+	switch providerName {
 	case "anthropic":
 		if cfg.AnthropicAPIKey == "" {
 			return nil, fmt.Errorf("Anthropic API key is required for provider 'anthropic'")
 		}
-		baseAgent = anthropic.NewClient(cfg.AnthropicAPIKey)
+		client := anthropic.NewClient(cfg.AnthropicAPIKey)
+		if model != "" {
+			client.Model = model
+		}
+		return client, nil
 
 	case "ollama":
-		if cfg.OllamaModel == "" {
+		if model == "" {
+			model = cfg.OllamaModel
+		}
+		if model == "" {
 			return nil, fmt.Errorf("Ollama model is required for provider 'ollama'")
 		}
 		if cfg.OllamaBaseURL == "" {
 			return nil, fmt.Errorf("Ollama base URL is required for provider 'ollama'")
 		}
-		baseAgent = ollama.NewClient(cfg.OllamaBaseURL, cfg.OllamaModel)
+		return ollama.NewClient(cfg.OllamaBaseURL, model), nil
 
 	default:
-		return nil, fmt.Errorf("unsupported AI provider: %s (supported: anthropic, ollama)", cfg.AIProvider)
-	}
-
-	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("unsupported AI provider: %s (supported: anthropic, ollama)", providerName)
 	}
+}
 
-	// Wrap agent with circuit breaker for reliability
+// withCircuitBreaker wraps agent with circuit breaker for reliability
+func withCircuitBreaker(baseAgent agent.Agent) agent.Agent {
 	cbConfig := circuitbreaker.Config{
-		MaxFailures:           5,              // Open after 5 consecutive failures
+		MaxFailures:           5,                // Open after 5 consecutive failures
 		Timeout:               30 * time.Second, // Test recovery after 30s
-		MaxConcurrentHalfOpen: 1,              // Only one test request when recovering
+		MaxConcurrentHalfOpen: 1,                // Only one test request when recovering
 	}
 
-	return agent.NewCircuitBreakerAgent(baseAgent, cbConfig), nil
+	return agent.NewCircuitBreakerAgent(baseAgent, cbConfig)
 }
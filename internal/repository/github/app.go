@@ -0,0 +1,91 @@
+package github
+
+import (
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	gh "github.com/google/go-github/v58/github"
+	"golang.org/x/oauth2"
+)
+
+// appTokenRefreshMargin is how long before expiry an installation token is
+// replaced, so a clone or push started with it can't outlive it
+const appTokenRefreshMargin = 5 * time.Minute
+
+// appTokenSource mints installation tokens for a GitHub App: each token is
+// exchanged for a freshly signed JWT identifying the app.
+type appTokenSource struct {
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+	apiURL         *url.URL
+	httpClient     *http.Client
+}
+
+// NewAppTokenSource returns installation tokens for the GitHub App appID on
+// installationID, signed with the app's PEM-encoded private key. Tokens are
+// cached and refreshed shortly before they expire (GitHub issues them for an
+// hour). apiURL is the REST API root, e.g. "https://api.github.com/"; empty
+// means the public API. httpClient may be nil.
+func NewAppTokenSource(appID, installationID int64, privateKey []byte, apiURL string, httpClient *http.Client) (oauth2.TokenSource, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub App private key: %w", err)
+	}
+	if apiURL == "" {
+		apiURL = "https://api.github.com/"
+	}
+	if !strings.HasSuffix(apiURL, "/") {
+		apiURL += "/"
+	}
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub API URL %q: %w", apiURL, err)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	src := &appTokenSource{appID: appID, installationID: installationID, key: key, apiURL: u, httpClient: httpClient}
+	return oauth2.ReuseTokenSourceWithExpiry(nil, src, appTokenRefreshMargin), nil
+}
+
+// Token implements oauth2.TokenSource
+func (s *appTokenSource) Token() (*oauth2.Token, error) {
+	appJWT, err := s.jwt(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	client := gh.NewClient(s.httpClient).WithAuthToken(appJWT)
+	client.BaseURL = s.apiURL
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	tok, _, err := client.Apps.CreateInstallationToken(ctx, s.installationID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create installation token for app %d: %w", s.appID, err)
+	}
+	return &oauth2.Token{AccessToken: tok.GetToken(), TokenType: "Bearer", Expiry: tok.GetExpiresAt().Time}, nil
+}
+
+// jwt signs the short-lived token that authenticates as the app itself.
+// It's backdated a minute to tolerate clock drift, and GitHub rejects
+// lifetimes over ten minutes.
+func (s *appTokenSource) jwt(now time.Time) (string, error) {
+	claims := jwt.RegisteredClaims{
+		Issuer:    strconv.FormatInt(s.appID, 10),
+		IssuedAt:  jwt.NewNumericDate(now.Add(-time.Minute)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(9 * time.Minute)),
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(s.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign GitHub App JWT: %w", err)
+	}
+	return signed, nil
+}
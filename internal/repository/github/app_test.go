@@ -0,0 +1,117 @@
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// appServer stands in for the GitHub API: it checks the app JWT and issues
+// numbered installation tokens, each expiring after the next lifetime in
+// lifetimes (the last one repeats).
+func appServer(t *testing.T, key *rsa.PrivateKey, lifetimes ...time.Duration) (*httptest.Server, *int) {
+	t.Helper()
+	var mu sync.Mutex
+	issued := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		raw := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		claims := &jwt.RegisteredClaims{}
+		if _, err := jwt.ParseWithClaims(raw, claims, func(*jwt.Token) (any, error) { return &key.PublicKey, nil }); err != nil || claims.Issuer != "7" {
+			http.Error(w, `{"message":"bad JWT"}`, http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		lifetime := lifetimes[min(issued, len(lifetimes)-1)]
+		issued++
+		n := issued
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token":"ghs_%d","expires_at":%q}`, n, time.Now().Add(lifetime).UTC().Format(time.RFC3339))
+	})
+	mux.HandleFunc("/repos/acme/app", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ghs_") {
+			http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"name":"app"}`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &issued
+}
+
+func appKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func TestAppTokenSource_RefreshesBeforeExpiry(t *testing.T) {
+	key, keyPEM := appKey(t)
+	server, issued := appServer(t, key, 2*time.Minute, time.Hour)
+
+	ts, err := NewAppTokenSource(7, 42, keyPEM, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"ghs_1", "ghs_2", "ghs_2"} {
+		tok, err := ts.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.AccessToken != want {
+			t.Errorf("Call %d: expected %s, got %s", i+1, want, tok.AccessToken)
+		}
+	}
+	// The first token expired inside the refresh margin, the second didn't.
+	if *issued != 2 {
+		t.Errorf("Expected 2 installation tokens to be minted, got %d", *issued)
+	}
+}
+
+func TestAppAuth_UsedForGitAndAPI(t *testing.T) {
+	key, keyPEM := appKey(t)
+	server, _ := appServer(t, key, time.Hour)
+	ts, err := NewAppTokenSource(7, 42, keyPEM, server.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClient("", "acme", "app", nil, WithAppTokenSource(ts)).(*githubClient)
+	c.ghClient.BaseURL, _ = url.Parse(server.URL + "/")
+	if err := c.HealthCheck(context.Background()); err != nil {
+		t.Errorf("Expected API calls to use the installation token: %v", err)
+	}
+
+	auth, err := c.gitAuth()
+	if err != nil {
+		t.Fatal(err)
+	}
+	basic, ok := auth.(*githttp.BasicAuth)
+	if !ok || basic.Username != "x-access-token" || basic.Password != "ghs_1" {
+		t.Errorf("Expected x-access-token basic auth with the installation token, got %#v", auth)
+	}
+}
+
+func TestNewAppTokenSource_InvalidKey(t *testing.T) {
+	if _, err := NewAppTokenSource(7, 42, []byte("not a key"), "", nil); err == nil {
+		t.Error("Expected an invalid private key to be rejected")
+	}
+}
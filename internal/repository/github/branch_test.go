@@ -0,0 +1,74 @@
+package github
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckoutRemoteBranch(t *testing.T) {
+	c, _, _ := setupRefreshRepos(t)
+	ctx := context.Background()
+
+	// A stale local branch from a failed run must not win over the remote.
+	runGit(t, c.paths.Root(), "branch", "-f", "feature/PROJ-1", "main")
+
+	ok, err := c.CheckoutRemoteBranch(ctx, "feature/PROJ-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("Expected the pushed branch to be found on the remote")
+	}
+	if got := runGit(t, c.paths.Root(), "rev-parse", "--abbrev-ref", "HEAD"); got != "feature/PROJ-1" {
+		t.Errorf("Expected feature/PROJ-1 to be checked out, got %s", got)
+	}
+	if _, err := os.Stat(filepath.Join(c.paths.Root(), "sub.go")); err != nil {
+		t.Errorf("Expected the earlier run's sub.go in the working tree: %v", err)
+	}
+	if local, remote := runGit(t, c.paths.Root(), "rev-parse", "HEAD"), runGit(t, c.paths.Root(), "rev-parse", "origin/feature/PROJ-1"); local != remote {
+		t.Errorf("Expected the local branch at the remote commit %s, got %s", remote, local)
+	}
+}
+
+func TestCheckoutRemoteBranch_Missing(t *testing.T) {
+	c, _, _ := setupRefreshRepos(t)
+
+	ok, err := c.CheckoutRemoteBranch(context.Background(), "feature/PROJ-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("Expected no remote branch for PROJ-2")
+	}
+	if got := runGit(t, c.paths.Root(), "rev-parse", "--abbrev-ref", "HEAD"); got != "main" {
+		t.Errorf("Expected to stay on main, got %s", got)
+	}
+}
+
+func TestDiscardBranch(t *testing.T) {
+	c, _, _ := setupRefreshRepos(t)
+	ctx := context.Background()
+	root := c.paths.Root()
+
+	runGit(t, root, "checkout", "-q", "-b", "feature/PROJ-2")
+	writeAndCommit(t, root, "mul.go", "package calc\n\nfunc Mul(a, b int) int { return a * b }\n")
+	os.WriteFile(filepath.Join(root, "calc.go"), []byte("package calc\n// half-applied edit\n"), 0644)
+
+	if err := c.DiscardBranch(ctx, "feature/PROJ-2", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if got := runGit(t, root, "rev-parse", "--abbrev-ref", "HEAD"); got != "main" {
+		t.Errorf("Expected main to be checked out, got %s", got)
+	}
+	if got := runGit(t, root, "branch", "--list", "feature/PROJ-2"); got != "" {
+		t.Errorf("Expected the local branch to be deleted, got %q", got)
+	}
+	if got := runGit(t, root, "status", "--porcelain"); got != "" {
+		t.Errorf("Expected a clean working tree, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "mul.go")); !os.IsNotExist(err) {
+		t.Errorf("Expected the discarded commit's mul.go to be gone, got %v", err)
+	}
+}
@@ -2,7 +2,10 @@ package github
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	nethttp "net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -15,32 +18,173 @@ import (
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	gh "github.com/google/go-github/v58/github"
+	"github.com/jenish-jain/logger"
 	"golang.org/x/oauth2"
 )
 
+// Default identity for commits the agent makes, including merges and rebases
+const (
+	defaultAuthorName  = "AI Intern"
+	defaultAuthorEmail = "ai-intern@example.com"
+)
+
 type githubClient struct {
 	ghClient *gh.Client
 	owner    string
 	repo     string
-	token    string // Store the token for git operations
-	repoURL  string // Optional: override repository URL for testing
+	token    string                     // Store the token for git operations
+	repoURL  string                     // Optional: clone URL, e.g. a GHES host or a local path in tests
 	paths    *repository.RepositoryPath // Centralized path management
+
+	authorName  string
+	authorEmail string
+	signer      *Signer // Optional; commits are unsigned when nil
+
+	tokens oauth2.TokenSource // GitHub App installation tokens; nil authenticates with token
+
+	apiURL     string               // REST API root; empty means api.github.com
+	httpClient *nethttp.Client      // Base client for API calls, e.g. trusting a custom CA
+	caBundle   []byte               // Extra CA certificates for HTTPS git
+	sshAuth    transport.AuthMethod // Deploy key auth for git; overrides the token
+
+	clone CloneSettings
+}
+
+// CloneSettings trims what is cloned and checked out, for large
+// repositories. The zero value is a full clone of every branch.
+type CloneSettings struct {
+	Depth        int      // Commits of history to fetch; 0 fetches everything
+	SingleBranch bool     // Clone only Branch
+	Branch       string   // Branch to clone and check out; empty means the remote's default
+	SparsePaths  []string // Only check out these path prefixes; empty checks out everything
+}
+
+// WithCloneSettings applies s to the initial clone, and its depth and sparse
+// paths to later syncs and branch checkouts
+func WithCloneSettings(s CloneSettings) Option {
+	return func(c *githubClient) {
+		c.clone = s
+	}
+}
+
+// Option configures optional client behaviour
+type Option func(*githubClient)
+
+// WithCommitAuthor sets the author and committer identity of the agent's
+// commits
+func WithCommitAuthor(name, email string) Option {
+	return func(c *githubClient) {
+		c.authorName, c.authorEmail = name, email
+	}
+}
+
+// WithAppTokenSource authenticates git and API calls with tokens from ts
+// (see NewAppTokenSource) instead of the static token, fetching a current
+// token for every clone, fetch and push.
+func WithAppTokenSource(ts oauth2.TokenSource) Option {
+	return func(c *githubClient) {
+		c.tokens = ts
+	}
+}
+
+// WithRemoteURL clones from url instead of https://github.com/<owner>/<repo>.git
+func WithRemoteURL(url string) Option {
+	return func(c *githubClient) {
+		c.repoURL = url
+	}
+}
+
+// WithAPIURL sends REST API calls to apiURL, the API root of a GitHub
+// Enterprise Server ("https://<host>/api/v3/") or a proxy
+func WithAPIURL(apiURL string) Option {
+	return func(c *githubClient) {
+		c.apiURL = apiURL
+	}
+}
+
+// WithHTTPClient makes API calls through hc (see NewHTTPClient); the token
+// is added on top
+func WithHTTPClient(hc *nethttp.Client) Option {
+	return func(c *githubClient) {
+		c.httpClient = hc
+	}
 }
 
-func NewClient(token, owner, repo string, paths *repository.RepositoryPath) repository.RepositoryClient {
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	client := gh.NewClient(oauth2.NewClient(context.Background(), ts))
-	return &githubClient{
-		ghClient: client,
-		owner:    owner,
-		repo:     repo,
-		token:    token,
-		paths:    paths,
+// WithCABundle trusts the PEM certificates in bundle for HTTPS git, in
+// addition to the system roots
+func WithCABundle(bundle []byte) Option {
+	return func(c *githubClient) {
+		c.caBundle = bundle
 	}
 }
 
+// WithSSHAuth authenticates git over SSH (see NewSSHAuth) instead of with
+// the token; the remote URL must then be an SSH one
+func WithSSHAuth(auth transport.AuthMethod) Option {
+	return func(c *githubClient) {
+		c.sshAuth = auth
+	}
+}
+
+// WithSigner signs every commit the agent makes with s
+func WithSigner(s *Signer) Option {
+	return func(c *githubClient) {
+		c.signer = s
+	}
+}
+
+func NewClient(token, owner, repo string, paths *repository.RepositoryPath, opts ...Option) repository.RepositoryClient {
+	c := &githubClient{
+		owner:       owner,
+		repo:        repo,
+		token:       token,
+		paths:       paths,
+		authorName:  defaultAuthorName,
+		authorEmail: defaultAuthorEmail,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	ts := c.tokens
+	if ts == nil {
+		ts = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	}
+	ctx := context.Background()
+	if c.httpClient != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, c.httpClient)
+	}
+	c.ghClient = gh.NewClient(oauth2.NewClient(ctx, ts))
+	if c.apiURL != "" {
+		if u, err := url.Parse(strings.TrimSuffix(c.apiURL, "/") + "/"); err != nil {
+			logger.Warn("Invalid GitHub API URL, using api.github.com", "url", c.apiURL, "error", err)
+		} else {
+			c.ghClient.BaseURL = u
+		}
+	}
+	return c
+}
+
+// gitAuth returns the credentials for clone, fetch and push: the deploy key
+// when one is configured, otherwise the token. Installation
+// tokens must be sent with the "x-access-token" username; a personal access
+// token works with any.
+func (c *githubClient) gitAuth() (transport.AuthMethod, error) {
+	if c.sshAuth != nil {
+		return c.sshAuth, nil
+	}
+	if c.tokens == nil {
+		return &http.BasicAuth{Username: c.owner, Password: c.token}, nil
+	}
+	tok, err := c.tokens.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GitHub token: %w", err)
+	}
+	return &http.BasicAuth{Username: "x-access-token", Password: tok.AccessToken}, nil
+}
+
 func (c *githubClient) HealthCheck(ctx context.Context) error {
 	repo, _, err := c.ghClient.Repositories.Get(ctx, c.owner, c.repo)
 	if err != nil {
@@ -64,14 +208,44 @@ func (c *githubClient) CloneRepository(ctx context.Context, destPath string) err
 		url = fmt.Sprintf("https://github.com/%s/%s.git", c.owner, c.repo)
 	}
 
-	_, err := git.PlainCloneContext(ctx, destPath, false, &git.CloneOptions{
-		URL:      url,
-		Auth:     &http.BasicAuth{Username: c.owner, Password: c.token}, // Using token as password
-		Progress: os.Stdout,
-	})
+	auth, err := c.gitAuth()
+	if err != nil {
+		return err
+	}
+	opts := &git.CloneOptions{
+		URL:          url,
+		Auth:         auth,
+		CABundle:     c.caBundle,
+		Depth:        c.clone.Depth,
+		SingleBranch: c.clone.SingleBranch,
+		NoCheckout:   len(c.clone.SparsePaths) > 0,
+	}
+	if c.clone.Branch != "" {
+		opts.ReferenceName = plumbing.NewBranchReferenceName(c.clone.Branch)
+	}
+	if c.clone.Depth > 0 || c.clone.SingleBranch {
+		// Tags would drag in the history the options above leave out
+		opts.Tags = git.NoTags
+	}
+	repo, err := git.PlainCloneContext(ctx, destPath, false, opts)
 	if err != nil {
 		return fmt.Errorf("failed to clone repository %s/%s: %w", c.owner, c.repo, err)
 	}
+
+	if len(c.clone.SparsePaths) > 0 {
+		head, err := repo.Head()
+		if err != nil {
+			return fmt.Errorf("failed to resolve HEAD after clone: %w", err)
+		}
+		w, err := repo.Worktree()
+		if err != nil {
+			return fmt.Errorf("failed to get worktree: %w", err)
+		}
+		err = w.Checkout(&git.CheckoutOptions{Branch: head.Name(), SparseCheckoutDirectories: c.clone.SparsePaths})
+		if err != nil {
+			return fmt.Errorf("failed to check out sparse paths: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -82,17 +256,45 @@ func (c *githubClient) SyncWithRemote(ctx context.Context) error {
 		return fmt.Errorf("failed to open repository at %s: %w", repoPath, err)
 	}
 
-	w, err := repo.Worktree()
+	head, err := repo.Head()
 	if err != nil {
-		return fmt.Errorf("failed to get worktree: %w", err)
+		return fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	if !head.Name().IsBranch() {
+		return fmt.Errorf("HEAD is detached, not syncing")
 	}
+	branch := head.Name().Short()
 
-	err = w.PullContext(ctx, &git.PullOptions{
-		Auth:     &http.BasicAuth{Username: c.owner, Password: c.token},
-		Progress: os.Stdout,
+	auth, err := c.gitAuth()
+	if err != nil {
+		return err
+	}
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: "origin",
+		Auth:       auth,
+		CABundle:   c.caBundle,
+		Depth:      c.clone.Depth,
+		Tags:       git.NoTags,
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", branch, branch))},
 	})
 	if err != nil && err != git.NoErrAlreadyUpToDate {
-		return fmt.Errorf("failed to pull from remote: %w", err)
+		return fmt.Errorf("failed to fetch %s: %w", branch, err)
+	}
+	remote, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", branch), true)
+	if err != nil {
+		return fmt.Errorf("branch %s not found on remote: %w", branch, err)
+	}
+
+	// Reset rather than pull: the base branch only ever mirrors the remote,
+	// and a reset can't fail on diverged or shallow history the way a
+	// fast-forward merge can.
+	w, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+	err = w.ResetSparsely(&git.ResetOptions{Commit: remote.Hash(), Mode: git.HardReset}, c.clone.SparsePaths)
+	if err != nil {
+		return fmt.Errorf("failed to reset %s to origin/%s: %w", branch, branch, err)
 	}
 	return nil
 }
@@ -155,7 +357,8 @@ func (c *githubClient) SwitchBranch(ctx context.Context, branchName string) erro
 	}
 
 	err = w.Checkout(&git.CheckoutOptions{
-		Branch: plumbing.ReferenceName(fmt.Sprintf("refs/heads/%s", branchName)),
+		Branch:                    plumbing.ReferenceName(fmt.Sprintf("refs/heads/%s", branchName)),
+		SparseCheckoutDirectories: c.clone.SparsePaths,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to switch to branch %s: %w", branchName, err)
@@ -163,6 +366,82 @@ func (c *githubClient) SwitchBranch(ctx context.Context, branchName string) erro
 	return nil
 }
 
+// DiscardBranch abandons local work on branchName: baseBranch is checked
+// out, discarding uncommitted changes, and the local branch is deleted. The
+// remote branch, if any, is left alone.
+func (c *githubClient) DiscardBranch(ctx context.Context, branchName, baseBranch string) error {
+	repoPath := c.paths.Root()
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository at %s: %w", repoPath, err)
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	err = w.Checkout(&git.CheckoutOptions{
+		Branch:                    plumbing.ReferenceName(fmt.Sprintf("refs/heads/%s", baseBranch)),
+		Force:                     true,
+		SparseCheckoutDirectories: c.clone.SparsePaths,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to switch to branch %s: %w", baseBranch, err)
+	}
+	if err := repo.Storer.RemoveReference(plumbing.NewBranchReferenceName(branchName)); err != nil {
+		return fmt.Errorf("failed to delete local branch %s: %w", branchName, err)
+	}
+	return nil
+}
+
+// CheckoutRemoteBranch fetches branchName from the remote and checks it out
+// at the remote commit, replacing any local branch of that name, so work from
+// an earlier run is continued rather than overwritten. It reports false, with
+// the working tree untouched, when the branch doesn't exist on the remote.
+func (c *githubClient) CheckoutRemoteBranch(ctx context.Context, branchName string) (bool, error) {
+	repoPath := c.paths.Root()
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to open repository at %s: %w", repoPath, err)
+	}
+
+	auth, err := c.gitAuth()
+	if err != nil {
+		return false, err
+	}
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: "origin",
+		Auth:       auth,
+		CABundle:   c.caBundle,
+		Depth:      c.clone.Depth,
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", branchName, branchName))},
+	})
+	if errors.Is(err, git.NoMatchingRefSpecError{}) {
+		return false, nil
+	}
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return false, fmt.Errorf("failed to fetch %s: %w", branchName, err)
+	}
+	remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", branchName), true)
+	if err != nil {
+		return false, fmt.Errorf("branch %s not found after fetch: %w", branchName, err)
+	}
+
+	localRef := plumbing.NewBranchReferenceName(branchName)
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(localRef, remoteRef.Hash())); err != nil {
+		return false, fmt.Errorf("failed to reset local branch %s: %w", branchName, err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return false, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	if err := w.Checkout(&git.CheckoutOptions{Branch: localRef, SparseCheckoutDirectories: c.clone.SparsePaths}); err != nil {
+		return false, fmt.Errorf("failed to switch to branch %s: %w", branchName, err)
+	}
+	return true, nil
+}
+
 func (c *githubClient) AddFile(ctx context.Context, filePath string) error {
 	repoPath := c.paths.Root()
 	repo, err := git.PlainOpen(repoPath)
@@ -191,19 +470,30 @@ func (c *githubClient) Commit(ctx context.Context, message string) error {
 		return fmt.Errorf("failed to get worktree: %w", err)
 	}
 
-	_, err = w.Commit(message, &git.CommitOptions{
-		Author: &object.Signature{
-			Name:  "AI Intern",
-			Email: "ai-intern@example.com",
-			When:  time.Now(),
-		},
-	})
+	opts := &git.CommitOptions{Author: c.commitSignature()}
+	if c.signer != nil {
+		opts.Signer = c.signer
+	}
+	_, err = w.Commit(message, opts)
 	if err != nil {
 		return fmt.Errorf("failed to commit changes: %w", err)
 	}
 	return nil
 }
 
+// commitSignature is the agent's identity, falling back to the defaults for
+// clients built without NewClient
+func (c *githubClient) commitSignature() *object.Signature {
+	name, email := c.authorName, c.authorEmail
+	if name == "" {
+		name = defaultAuthorName
+	}
+	if email == "" {
+		email = defaultAuthorEmail
+	}
+	return &object.Signature{Name: name, Email: email, When: time.Now()}
+}
+
 func (c *githubClient) Push(ctx context.Context, branchName string) error {
 	repoPath := c.paths.Root()
 	repo, err := git.PlainOpen(repoPath)
@@ -212,8 +502,13 @@ func (c *githubClient) Push(ctx context.Context, branchName string) error {
 	}
 
 	refspec := fmt.Sprintf("refs/heads/%s:refs/heads/%s", branchName, branchName)
+	auth, err := c.gitAuth()
+	if err != nil {
+		return err
+	}
 	err = repo.PushContext(ctx, &git.PushOptions{
-		Auth:     &http.BasicAuth{Username: c.owner, Password: c.token},
+		Auth:     auth,
+		CABundle: c.caBundle,
 		RefSpecs: []config.RefSpec{config.RefSpec(refspec)},
 	})
 	if err != nil && err != git.NoErrAlreadyUpToDate {
@@ -231,7 +526,11 @@ func (c *githubClient) Push(ctx context.Context, branchName string) error {
 	return nil
 }
 
-func (c *githubClient) CreatePullRequest(ctx context.Context, baseBranch, headBranch, title, body string) (string, error) {
+// CreatePullRequest opens a pull request and then applies opts. The PR is
+// returned even if labels, reviewers or assignees can't be applied (e.g. an
+// unknown login), since those are conveniences and a retry would create a
+// duplicate PR; such failures are logged instead.
+func (c *githubClient) CreatePullRequest(ctx context.Context, baseBranch, headBranch, title, body string, opts repository.PullRequestOptions) (string, error) {
 	// Determine a valid base branch: prefer provided, else repo default
 	base := baseBranch
 	if base == "" {
@@ -257,6 +556,7 @@ func (c *githubClient) CreatePullRequest(ctx context.Context, baseBranch, headBr
 		Head:  gh.String(headBranch),
 		Base:  gh.String(base),
 		Body:  gh.String(body),
+		Draft: gh.Bool(opts.Draft),
 	}
 	pr, _, err := c.ghClient.PullRequests.Create(ctx, c.owner, c.repo, newPR)
 	if err != nil {
@@ -265,22 +565,203 @@ func (c *githubClient) CreatePullRequest(ctx context.Context, baseBranch, headBr
 	if pr == nil || pr.GetHTMLURL() == "" {
 		return "", fmt.Errorf("pull request created but URL missing")
 	}
+	c.applyPullRequestOptions(ctx, pr, opts)
 	return pr.GetHTMLURL(), nil
 }
 
+// FindOpenPullRequest returns the URL of the open pull request from
+// headBranch, or "" if there is none.
+func (c *githubClient) FindOpenPullRequest(ctx context.Context, headBranch string) (string, error) {
+	prs, _, err := c.ghClient.PullRequests.List(ctx, c.owner, c.repo, &gh.PullRequestListOptions{
+		State: "open",
+		Head:  c.owner + ":" + headBranch,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list pull requests for %s: %w", headBranch, err)
+	}
+	for _, pr := range prs {
+		if pr.GetHead().GetRef() == headBranch && pr.GetHTMLURL() != "" {
+			return pr.GetHTMLURL(), nil
+		}
+	}
+	return "", nil
+}
+
+// UpdatePullRequest replaces the title and body of the PR at prURL and
+// applies opts on top of what it already has (labels, reviewers and
+// assignees are added, never removed). The REST API can't convert a PR to
+// or from a draft, so opts.Draft is ignored. As with CreatePullRequest,
+// failures applying opts are only logged.
+func (c *githubClient) UpdatePullRequest(ctx context.Context, prURL, title, body string, opts repository.PullRequestOptions) error {
+	num, err := prNumber(prURL)
+	if err != nil {
+		return err
+	}
+	edit := &gh.PullRequest{Title: gh.String(title), Body: gh.String(body)}
+	pr, _, err := c.ghClient.PullRequests.Edit(ctx, c.owner, c.repo, num, edit)
+	if err != nil {
+		return fmt.Errorf("failed to update PR #%d: %w", num, err)
+	}
+	c.applyPullRequestOptions(ctx, pr, opts)
+	return nil
+}
+
+// applyPullRequestOptions labels pr, requests its reviews and assigns it.
+// The PR's author (the token's user or the App's bot) is never requested as
+// a reviewer: GitHub refuses the whole request if it is.
+func (c *githubClient) applyPullRequestOptions(ctx context.Context, pr *gh.PullRequest, opts repository.PullRequestOptions) {
+	number := pr.GetNumber()
+	opts.Reviewers = withoutLogin(opts.Reviewers, pr.GetUser().GetLogin())
+	if len(opts.Labels) > 0 {
+		if _, _, err := c.ghClient.Issues.AddLabelsToIssue(ctx, c.owner, c.repo, number, opts.Labels); err != nil {
+			logger.Warn("Failed to label pull request", "pr", number, "labels", opts.Labels, "error", err)
+		}
+	}
+	if len(opts.Reviewers) > 0 || len(opts.TeamReviewers) > 0 {
+		req := gh.ReviewersRequest{Reviewers: opts.Reviewers, TeamReviewers: opts.TeamReviewers}
+		if _, _, err := c.ghClient.PullRequests.RequestReviewers(ctx, c.owner, c.repo, number, req); err != nil {
+			logger.Warn("Failed to request reviewers", "pr", number, "reviewers", opts.Reviewers, "teams", opts.TeamReviewers, "error", err)
+		}
+	}
+	if len(opts.Assignees) > 0 {
+		if _, _, err := c.ghClient.Issues.AddAssignees(ctx, c.owner, c.repo, number, opts.Assignees); err != nil {
+			logger.Warn("Failed to assign pull request", "pr", number, "assignees", opts.Assignees, "error", err)
+		}
+	}
+}
+
+// withoutLogin returns logins without login, which GitHub compares
+// case-insensitively
+func withoutLogin(logins []string, login string) []string {
+	if login == "" {
+		return logins
+	}
+	var out []string
+	for _, l := range logins {
+		if !strings.EqualFold(l, login) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
 // IsPRMerged reports whether the PR at prURL (e.g.
 // "https://github.com/owner/repo/pull/123") has been merged.
 func (c *githubClient) IsPRMerged(ctx context.Context, prURL string) (bool, error) {
-	numStr := prURL[strings.LastIndex(prURL, "/")+1:]
-	num, err := strconv.Atoi(numStr)
+	pr, err := c.getPullRequest(ctx, prURL)
 	if err != nil {
-		return false, fmt.Errorf("invalid PR URL %q: %w", prURL, err)
+		return false, err
+	}
+	return pr.GetMerged(), nil
+}
+
+// IsPROpen reports whether the PR at prURL is still open, i.e. neither
+// merged nor closed.
+func (c *githubClient) IsPROpen(ctx context.Context, prURL string) (bool, error) {
+	pr, err := c.getPullRequest(ctx, prURL)
+	if err != nil {
+		return false, err
+	}
+	return pr.GetState() == "open", nil
+}
+
+// CommentOnPullRequest adds a conversation comment to the PR at prURL
+func (c *githubClient) CommentOnPullRequest(ctx context.Context, prURL, body string) error {
+	num, err := prNumber(prURL)
+	if err != nil {
+		return err
+	}
+	if _, _, err := c.ghClient.Issues.CreateComment(ctx, c.owner, c.repo, num, &gh.IssueComment{Body: gh.String(body)}); err != nil {
+		return fmt.Errorf("failed to comment on PR #%d: %w", num, err)
+	}
+	return nil
+}
+
+// convertToDraftMutation is the GraphQL mutation behind MarkPullRequestDraft
+const convertToDraftMutation = `mutation($id: ID!) {
+  convertPullRequestToDraft(input: {pullRequestId: $id}) { pullRequest { isDraft } }
+}`
+
+// markReadyMutation is the GraphQL mutation behind MarkPullRequestReady
+const markReadyMutation = `mutation($id: ID!) {
+  markPullRequestReadyForReview(input: {pullRequestId: $id}) { pullRequest { isDraft } }
+}`
+
+// MarkPullRequestDraft converts the open PR at prURL back to a draft. The
+// REST API can only set draft on creation, so this goes through GraphQL.
+func (c *githubClient) MarkPullRequestDraft(ctx context.Context, prURL string) error {
+	pr, err := c.getPullRequest(ctx, prURL)
+	if err != nil {
+		return err
+	}
+	if pr.GetDraft() {
+		return nil
+	}
+	if err := c.mutatePullRequest(ctx, pr, convertToDraftMutation); err != nil {
+		return fmt.Errorf("failed to mark PR #%d as draft: %w", pr.GetNumber(), err)
+	}
+	return nil
+}
+
+// MarkPullRequestReady marks the draft PR at prURL as ready for review,
+// undoing MarkPullRequestDraft
+func (c *githubClient) MarkPullRequestReady(ctx context.Context, prURL string) error {
+	pr, err := c.getPullRequest(ctx, prURL)
+	if err != nil {
+		return err
+	}
+	if !pr.GetDraft() {
+		return nil
+	}
+	if err := c.mutatePullRequest(ctx, pr, markReadyMutation); err != nil {
+		return fmt.Errorf("failed to mark PR #%d as ready for review: %w", pr.GetNumber(), err)
+	}
+	return nil
+}
+
+// mutatePullRequest runs a GraphQL mutation taking the PR's node ID as $id
+func (c *githubClient) mutatePullRequest(ctx context.Context, pr *gh.PullRequest, mutation string) error {
+	req, err := c.ghClient.NewRequest("POST", graphQLURL(c.ghClient.BaseURL), map[string]any{
+		"query":     mutation,
+		"variables": map[string]any{"id": pr.GetNodeID()},
+	})
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := c.ghClient.Do(ctx, req, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		return errors.New(resp.Errors[0].Message)
+	}
+	return nil
+}
+
+func (c *githubClient) getPullRequest(ctx context.Context, prURL string) (*gh.PullRequest, error) {
+	num, err := prNumber(prURL)
+	if err != nil {
+		return nil, err
 	}
 	pr, _, err := c.ghClient.PullRequests.Get(ctx, c.owner, c.repo, num)
 	if err != nil {
-		return false, fmt.Errorf("failed to get PR #%d: %w", num, err)
+		return nil, fmt.Errorf("failed to get PR #%d: %w", num, err)
 	}
-	return pr.GetMerged(), nil
+	return pr, nil
+}
+
+// prNumber extracts the PR number from a URL ending in "/pull/<n>"
+func prNumber(prURL string) (int, error) {
+	numStr := prURL[strings.LastIndex(prURL, "/")+1:]
+	num, err := strconv.Atoi(numStr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid PR URL %q: %w", prURL, err)
+	}
+	return num, nil
 }
 
 func (c *githubClient) HasLocalChanges(ctx context.Context) (bool, error) {
@@ -299,3 +780,45 @@ func (c *githubClient) HasLocalChanges(ctx context.Context) (bool, error) {
 	}
 	return !st.IsClean(), nil
 }
+
+// DiffStat returns per-file line counts between the merge base of baseBranch
+// and HEAD, i.e. what a pull request from the current branch would show.
+// baseBranch is resolved locally first, then as origin/<baseBranch>.
+func (c *githubClient) DiffStat(ctx context.Context, baseBranch string) ([]repository.FileStat, error) {
+	repoPath := c.paths.Root()
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository at %s: %w", repoPath, err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load HEAD commit: %w", err)
+	}
+	baseRef, err := repo.Reference(plumbing.NewBranchReferenceName(baseBranch), true)
+	if err != nil {
+		baseRef, err = repo.Reference(plumbing.NewRemoteReferenceName("origin", baseBranch), true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve base branch %s: %w", baseBranch, err)
+		}
+	}
+	baseCommit, err := repo.CommitObject(baseRef.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load base commit: %w", err)
+	}
+	if bases, err := baseCommit.MergeBase(headCommit); err == nil && len(bases) > 0 {
+		baseCommit = bases[0]
+	}
+	patch, err := baseCommit.PatchContext(ctx, headCommit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s..HEAD: %w", baseBranch, err)
+	}
+	var stats []repository.FileStat
+	for _, s := range patch.Stats() {
+		stats = append(stats, repository.FileStat{Path: s.Name, Added: s.Addition, Removed: s.Deletion})
+	}
+	return stats, nil
+}
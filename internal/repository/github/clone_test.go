@@ -0,0 +1,65 @@
+package github
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"intern/internal/repository"
+)
+
+func TestCloneRepository_ShallowSparse(t *testing.T) {
+	_, origin, other := setupRefreshRepos(t)
+	os.MkdirAll(filepath.Join(other, "docs"), 0755)
+	writeAndCommit(t, other, "docs/guide.md", "# Guide\n")
+	os.MkdirAll(filepath.Join(other, "pkg"), 0755)
+	writeAndCommit(t, other, "pkg/pkg.go", "package pkg\n")
+	runGit(t, other, "push", "-q", "origin", "main")
+
+	paths, err := repository.NewRepositoryPath(t.TempDir(), "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient("", "acme", "app", paths, WithRemoteURL(origin), WithCloneSettings(CloneSettings{
+		Depth: 1, SingleBranch: true, Branch: "main", SparsePaths: []string{"pkg"},
+	})).(*githubClient)
+	ctx := context.Background()
+	if err := c.CloneRepository(ctx, paths.Root()); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := runGit(t, paths.Root(), "rev-list", "--count", "HEAD"); n != "1" {
+		t.Errorf("Expected a depth-1 clone, got %s commits", n)
+	}
+	if branches := runGit(t, paths.Root(), "branch", "-r"); strings.Contains(branches, "feature/PROJ-1") {
+		t.Errorf("Expected only main to be cloned, got remote branches:\n%s", branches)
+	}
+	if _, err := os.Stat(filepath.Join(paths.Root(), "pkg", "pkg.go")); err != nil {
+		t.Errorf("Expected pkg/ to be checked out: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(paths.Root(), "docs", "guide.md")); !os.IsNotExist(err) {
+		t.Errorf("Expected docs/ to be left out of the sparse checkout, got %v", err)
+	}
+	if dirty, err := c.HasLocalChanges(ctx); err != nil || dirty {
+		t.Errorf("Expected paths outside the sparse checkout not to count as changes, got %v, %v", dirty, err)
+	}
+
+	// Sync fetches and resets onto the new remote head, staying sparse.
+	writeAndCommit(t, other, "pkg/more.go", "package pkg\n")
+	writeAndCommit(t, other, "docs/more.md", "# More\n")
+	runGit(t, other, "push", "-q", "origin", "main")
+	if err := c.SyncWithRemote(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if head, want := runGit(t, paths.Root(), "rev-parse", "HEAD"), runGit(t, other, "rev-parse", "HEAD"); head != want {
+		t.Errorf("Expected HEAD at the remote's %s after sync, got %s", want, head)
+	}
+	if _, err := os.Stat(filepath.Join(paths.Root(), "pkg", "more.go")); err != nil {
+		t.Errorf("Expected the synced pkg/more.go: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(paths.Root(), "docs")); !os.IsNotExist(err) {
+		t.Errorf("Expected docs/ to stay out of the checkout after sync, got %v", err)
+	}
+}
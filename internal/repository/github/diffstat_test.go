@@ -0,0 +1,79 @@
+package github
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"intern/internal/repository"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func commitFile(t *testing.T, w *git.Worktree, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Add(name); err != nil {
+		t.Fatal(err)
+	}
+	sig := &object.Signature{Name: "Test User", Email: "test@example.com", When: time.Now()}
+	if _, err := w.Commit("update "+name, &git.CommitOptions{Author: sig}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiffStat(t *testing.T) {
+	workDir := t.TempDir()
+	paths, err := repository.NewRepositoryPath(workDir, "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := paths.Root()
+
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, w, dir, "main.go", "package main\n\nfunc main() {}\n")
+	head, _ := r.Head()
+	if err := r.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("main"), head.Hash())); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature"), Create: true}); err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, w, dir, "main.go", "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println() }\n")
+	commitFile(t, w, dir, "util.go", "package main\n\nfunc util() {}\n")
+
+	c := &githubClient{paths: paths}
+	stats, err := c.DiffStat(context.Background(), "main")
+	if err != nil {
+		t.Fatalf("DiffStat() error = %v", err)
+	}
+
+	got := make(map[string]repository.FileStat)
+	for _, s := range stats {
+		got[s.Path] = s
+	}
+	if s := got["main.go"]; s.Added != 3 || s.Removed != 1 {
+		t.Errorf("main.go = +%d -%d, want +3 -1", s.Added, s.Removed)
+	}
+	if s := got["util.go"]; s.Added != 3 || s.Removed != 0 {
+		t.Errorf("util.go = +%d -%d, want +3 -0", s.Added, s.Removed)
+	}
+
+	if _, err := c.DiffStat(context.Background(), "missing"); err == nil {
+		t.Error("Expected an error for an unknown base branch")
+	}
+}
@@ -0,0 +1,81 @@
+package github
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// NewHTTPClient returns an HTTP client trusting the PEM certificates in
+// caBundle on top of the system roots, for a GHES instance or proxy signed
+// by an internal CA
+func NewHTTPClient(caBundle []byte) (*http.Client, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(caBundle) {
+		return nil, fmt.Errorf("no certificates found in CA bundle")
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	return &http.Client{Transport: transport}, nil
+}
+
+// NewSSHAuth loads a deploy key for git over SSH as the "git" user. The host
+// key is checked against knownHosts, or the default known_hosts files when
+// empty.
+func NewSSHAuth(keyPath, passphrase, knownHosts string) (transport.AuthMethod, error) {
+	auth, err := gitssh.NewPublicKeysFromFile("git", keyPath, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load SSH key: %w", err)
+	}
+	var files []string
+	if knownHosts != "" {
+		files = append(files, knownHosts)
+	}
+	callback, err := gitssh.NewKnownHostsCallback(files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known_hosts: %w", err)
+	}
+	auth.HostKeyCallback = callback
+	return auth, nil
+}
+
+// remoteURL derives the clone URL for owner/repo on the host serving apiURL
+// (github.com when empty; api.github.com is mapped back to github.com), over
+// SSH or HTTPS
+func remoteURL(apiURL, owner, repo string, overSSH bool) (string, error) {
+	host := "github.com"
+	if apiURL != "" {
+		u, err := url.Parse(apiURL)
+		if err != nil {
+			return "", fmt.Errorf("invalid GitHub API URL %q: %w", apiURL, err)
+		}
+		if u.Host != "api.github.com" {
+			host = u.Host
+		}
+	}
+	if overSSH {
+		return fmt.Sprintf("git@%s:%s/%s.git", (&url.URL{Host: host}).Hostname(), owner, repo), nil
+	}
+	return fmt.Sprintf("https://%s/%s/%s.git", host, owner, repo), nil
+}
+
+// graphQLURL returns the GraphQL endpoint next to the REST API root: GitHub
+// serves it at /graphql, GHES at /api/graphql beside /api/v3/
+func graphQLURL(apiURL *url.URL) string {
+	u := *apiURL
+	if strings.HasSuffix(u.Path, "/v3/") {
+		u.Path = strings.TrimSuffix(u.Path, "v3/") + "graphql"
+	} else {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/graphql"
+	}
+	return u.String()
+}
@@ -0,0 +1,111 @@
+package github
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestRemoteURL(t *testing.T) {
+	tests := []struct {
+		apiURL  string
+		overSSH bool
+		want    string
+	}{
+		{"", false, "https://github.com/acme/app.git"},
+		{"https://api.github.com/", true, "git@github.com:acme/app.git"},
+		{"https://ghe.acme.com/api/v3/", false, "https://ghe.acme.com/acme/app.git"},
+		{"https://ghe.acme.com:8443/api/v3/", true, "git@ghe.acme.com:acme/app.git"},
+	}
+	for _, tt := range tests {
+		got, err := remoteURL(tt.apiURL, "acme", "app", tt.overSSH)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("remoteURL(%q, ssh=%v) = %q, want %q", tt.apiURL, tt.overSSH, got, tt.want)
+		}
+	}
+}
+
+func TestGraphQLURL(t *testing.T) {
+	for api, want := range map[string]string{
+		"https://api.github.com/":      "https://api.github.com/graphql",
+		"https://ghe.acme.com/api/v3/": "https://ghe.acme.com/api/graphql",
+	} {
+		u, _ := url.Parse(api)
+		if got := graphQLURL(u); got != want {
+			t.Errorf("graphQLURL(%q) = %q, want %q", api, got, want)
+		}
+	}
+}
+
+func TestEnterpriseAPI_CustomCA(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/acme/app", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ghp_test" {
+			http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"name":"app"}`)
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	// Without the server's CA the TLS handshake must fail.
+	c := NewClient("ghp_test", "acme", "app", nil, WithAPIURL(server.URL+"/api/v3")).(*githubClient)
+	if err := c.HealthCheck(context.Background()); err == nil {
+		t.Fatal("Expected an untrusted certificate to be rejected")
+	}
+
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	hc, err := NewHTTPClient(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = NewClient("ghp_test", "acme", "app", nil, WithAPIURL(server.URL+"/api/v3"), WithHTTPClient(hc)).(*githubClient)
+	if err := c.HealthCheck(context.Background()); err != nil {
+		t.Errorf("Expected the API call to succeed with the CA bundle: %v", err)
+	}
+
+	if _, err := NewHTTPClient([]byte("not a certificate")); err == nil {
+		t.Error("Expected a bundle without certificates to be rejected")
+	}
+}
+
+func TestNewSSHAuth(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := ssh.MarshalPrivateKey(priv, "")
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "deploy_key")
+	os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600)
+	hostKey, _ := ssh.NewPublicKey(pub)
+	knownHosts := filepath.Join(dir, "known_hosts")
+	os.WriteFile(knownHosts, []byte("ghe.acme.com "+string(ssh.MarshalAuthorizedKey(hostKey))), 0644)
+
+	auth, err := NewSSHAuth(keyPath, "", knownHosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient("ghp_test", "acme", "app", nil, WithSSHAuth(auth)).(*githubClient)
+	if got, _ := c.gitAuth(); got != auth {
+		t.Errorf("Expected git to use the deploy key, got %#v", got)
+	}
+
+	if _, err := NewSSHAuth(filepath.Join(dir, "missing"), "", knownHosts); err == nil {
+		t.Error("Expected a missing key to be rejected")
+	}
+}
@@ -0,0 +1,85 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"intern/internal/config"
+	"intern/internal/repository"
+)
+
+// NewFromConfig creates the GitHub client for cfg: the repository, its API
+// and git endpoints (GitHub Enterprise Server, custom CA, SSH deploy key)
+// and credentials (GitHub App installation tokens when GITHUB_APP_ID is set,
+// otherwise GITHUB_TOKEN), plus the commit identity and, when
+// COMMIT_SIGNING_FORMAT is set, the key that signs every commit.
+func NewFromConfig(cfg *config.Config, paths *repository.RepositoryPath) (repository.RepositoryClient, error) {
+	opts := []Option{WithCommitAuthor(cfg.CommitAuthorName, cfg.CommitAuthorEmail)}
+
+	var httpClient *http.Client
+	if cfg.GitHubCABundle != "" {
+		bundle, err := os.ReadFile(cfg.GitHubCABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		if httpClient, err = NewHTTPClient(bundle); err != nil {
+			return nil, fmt.Errorf("CA bundle %s: %w", cfg.GitHubCABundle, err)
+		}
+		opts = append(opts, WithHTTPClient(httpClient), WithCABundle(bundle))
+	}
+	if cfg.GitHubAPIURL != "" {
+		opts = append(opts, WithAPIURL(cfg.GitHubAPIURL))
+	}
+
+	gitURL := cfg.GitHubGitURL
+	if gitURL == "" {
+		var err error
+		if gitURL, err = remoteURL(cfg.GitHubAPIURL, cfg.GitHubOwner, cfg.GitHubRepo, cfg.GitHubSSHKey != ""); err != nil {
+			return nil, err
+		}
+	}
+	opts = append(opts, WithRemoteURL(gitURL), WithCloneSettings(CloneSettings{
+		Depth:        cfg.CloneDepth,
+		SingleBranch: cfg.CloneSingleBranch,
+		Branch:       cfg.BaseBranch,
+		SparsePaths:  cfg.CloneSparsePaths,
+	}))
+	if cfg.GitHubSSHKey != "" {
+		auth, err := NewSSHAuth(cfg.GitHubSSHKey, cfg.GitHubSSHKeyPassphrase, cfg.GitHubSSHKnownHosts)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithSSHAuth(auth))
+	}
+
+	if cfg.GitHubAppID != 0 {
+		key, err := readPEM(cfg.GitHubAppPrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("GitHub App private key: %w", err)
+		}
+		ts, err := NewAppTokenSource(cfg.GitHubAppID, cfg.GitHubAppInstallationID, key, cfg.GitHubAPIURL, httpClient)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithAppTokenSource(ts))
+	}
+	if cfg.CommitSigningFormat != "" {
+		signer, err := NewSigner(cfg.CommitSigningFormat, cfg.CommitSigningKey, cfg.CommitSigningPassphrase)
+		if err != nil {
+			return nil, fmt.Errorf("commit signing: %w", err)
+		}
+		opts = append(opts, WithSigner(signer))
+	}
+	return NewClient(cfg.GitHubToken, cfg.GitHubOwner, cfg.GitHubRepo, paths, opts...), nil
+}
+
+// readPEM returns value itself when it's PEM-encoded (e.g. injected from a
+// secret manager), otherwise the contents of the file it names
+func readPEM(value string) ([]byte, error) {
+	if strings.HasPrefix(strings.TrimSpace(value), "-----BEGIN") {
+		return []byte(value), nil
+	}
+	return os.ReadFile(value)
+}
@@ -0,0 +1,212 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"intern/internal/repository"
+
+	gh "github.com/google/go-github/v58/github"
+	"github.com/jenish-jain/logger"
+)
+
+func init() {
+	logger.Init("error")
+}
+
+func TestCreatePullRequest_AppliesOptions(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]map[string]any)
+	record := func(r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]any
+		if len(body) > 0 && body[0] == '{' {
+			json.Unmarshal(body, &payload)
+		} else {
+			var list []any
+			json.Unmarshal(body, &list)
+			payload = map[string]any{"list": list}
+		}
+		mu.Lock()
+		requests[r.Method+" "+r.URL.Path] = payload
+		mu.Unlock()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/acme/app/git/ref/heads/main", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"ref":"refs/heads/main"}`)
+	})
+	mux.HandleFunc("/repos/acme/app/pulls", func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		fmt.Fprint(w, `{"number":7,"html_url":"https://github.com/acme/app/pull/7","user":{"login":"ai-intern[bot]"}}`)
+	})
+	mux.HandleFunc("/repos/acme/app/issues/7/labels", func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		fmt.Fprint(w, `[]`)
+	})
+	mux.HandleFunc("/repos/acme/app/pulls/7/requested_reviewers", func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/repos/acme/app/issues/7/assignees", func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		http.Error(w, `{"message":"Validation Failed"}`, http.StatusUnprocessableEntity)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := gh.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	c := &githubClient{ghClient: client, owner: "acme", repo: "app"}
+
+	opts := repository.PullRequestOptions{
+		Draft:         true,
+		Labels:        []string{"ai-generated"},
+		Reviewers:     []string{"alice", "AI-Intern[bot]"}, // The author can't review its own PR
+		TeamReviewers: []string{"platform"},
+		Assignees:     []string{"ghost"},
+	}
+	prURL, err := c.CreatePullRequest(context.Background(), "main", "feature/PROJ-1", "PROJ-1: x", "body", opts)
+	if err != nil {
+		t.Fatalf("Expected the PR to be returned despite the assignee failure, got %v", err)
+	}
+	if prURL != "https://github.com/acme/app/pull/7" {
+		t.Errorf("Unexpected PR URL %q", prURL)
+	}
+
+	if draft, _ := requests["POST /repos/acme/app/pulls"]["draft"].(bool); !draft {
+		t.Errorf("Expected a draft PR, got %v", requests["POST /repos/acme/app/pulls"])
+	}
+	if labels := fmt.Sprint(requests["POST /repos/acme/app/issues/7/labels"]["list"]); labels != "[ai-generated]" {
+		t.Errorf("Expected the ai-generated label, got %s", labels)
+	}
+	reviewers := requests["POST /repos/acme/app/pulls/7/requested_reviewers"]
+	if fmt.Sprint(reviewers["reviewers"]) != "[alice]" || fmt.Sprint(reviewers["team_reviewers"]) != "[platform]" {
+		t.Errorf("Expected alice and the platform team as reviewers, got %v", reviewers)
+	}
+	if _, ok := requests["POST /repos/acme/app/issues/7/assignees"]; !ok {
+		t.Error("Expected assignees to be requested")
+	}
+}
+
+func TestFindAndUpdatePullRequest(t *testing.T) {
+	var edited map[string]any
+	var listQuery url.Values
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/acme/app/pulls", func(w http.ResponseWriter, r *http.Request) {
+		listQuery = r.URL.Query()
+		if listQuery.Get("head") == "acme:feature/PROJ-1" {
+			fmt.Fprint(w, `[{"number":7,"html_url":"https://github.com/acme/app/pull/7","head":{"ref":"feature/PROJ-1"}}]`)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	})
+	mux.HandleFunc("/repos/acme/app/pulls/7", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			http.Error(w, "unexpected method", http.StatusMethodNotAllowed)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&edited)
+		fmt.Fprint(w, `{"number":7,"user":{"login":"ai-intern"}}`)
+	})
+	mux.HandleFunc("/repos/acme/app/issues/7/labels", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	})
+	reviewersRequested := false
+	mux.HandleFunc("/repos/acme/app/pulls/7/requested_reviewers", func(w http.ResponseWriter, r *http.Request) {
+		reviewersRequested = true
+		fmt.Fprint(w, `{}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := gh.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	c := &githubClient{ghClient: client, owner: "acme", repo: "app"}
+	ctx := context.Background()
+
+	prURL, err := c.FindOpenPullRequest(ctx, "feature/PROJ-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prURL != "https://github.com/acme/app/pull/7" {
+		t.Fatalf("Expected the open PR, got %q", prURL)
+	}
+	if listQuery.Get("state") != "open" {
+		t.Errorf("Expected only open PRs to be listed, got %v", listQuery)
+	}
+	if none, err := c.FindOpenPullRequest(ctx, "feature/PROJ-2"); err != nil || none != "" {
+		t.Errorf("Expected no PR for PROJ-2, got %q, %v", none, err)
+	}
+
+	opts := repository.PullRequestOptions{Labels: []string{"ai-generated"}, Reviewers: []string{"ai-intern"}}
+	if err := c.UpdatePullRequest(ctx, prURL, "PROJ-1: y", "new body", opts); err != nil {
+		t.Fatal(err)
+	}
+	if edited["title"] != "PROJ-1: y" || edited["body"] != "new body" {
+		t.Errorf("Expected the title and body to be replaced, got %v", edited)
+	}
+	if reviewersRequested {
+		t.Error("Expected no review to be requested from the PR's own author")
+	}
+}
+
+func TestMarkPullRequestDraft(t *testing.T) {
+	var mutation map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/acme/app/pulls/7", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"number":7,"node_id":"PR_kw7","draft":false}`)
+	})
+	mux.HandleFunc("/api/graphql", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&mutation)
+		fmt.Fprint(w, `{"data":{"convertPullRequestToDraft":{"pullRequest":{"isDraft":true}}}}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := gh.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/api/v3/")
+	c := &githubClient{ghClient: client, owner: "acme", repo: "app"}
+
+	if err := c.MarkPullRequestDraft(context.Background(), "https://ghe.acme.com/acme/app/pull/7"); err != nil {
+		t.Fatal(err)
+	}
+	vars, _ := mutation["variables"].(map[string]any)
+	if vars["id"] != "PR_kw7" || !strings.Contains(fmt.Sprint(mutation["query"]), "convertPullRequestToDraft") {
+		t.Errorf("Expected a convertPullRequestToDraft mutation for the PR's node ID, got %v", mutation)
+	}
+}
+
+func TestMarkPullRequestReady(t *testing.T) {
+	var mutation map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/acme/app/pulls/7", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"number":7,"node_id":"PR_kw7","draft":true}`)
+	})
+	mux.HandleFunc("/api/graphql", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&mutation)
+		fmt.Fprint(w, `{"data":{"markPullRequestReadyForReview":{"pullRequest":{"isDraft":false}}}}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := gh.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/api/v3/")
+	c := &githubClient{ghClient: client, owner: "acme", repo: "app"}
+
+	if err := c.MarkPullRequestReady(context.Background(), "https://ghe.acme.com/acme/app/pull/7"); err != nil {
+		t.Fatal(err)
+	}
+	vars, _ := mutation["variables"].(map[string]any)
+	if vars["id"] != "PR_kw7" || !strings.Contains(fmt.Sprint(mutation["query"]), "markPullRequestReadyForReview") {
+		t.Errorf("Expected a markPullRequestReadyForReview mutation for the PR's node ID, got %v", mutation)
+	}
+}
@@ -0,0 +1,194 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"intern/internal/repository"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/jenish-jain/logger"
+)
+
+// RefreshBranch brings branchName up to date with baseBranch as they are on
+// the remote. The local branch is reset to the remote one first, so commits
+// pushed by reviewers are kept and the later force-push can't drop them.
+//
+// go-git can't rebase or merge, so those steps run the git CLI. They are
+// local-only: fetching and pushing stay in go-git with the token. When a
+// merge conflicts, it's left in progress with the conflicted files reported
+// in the result: resolve them and call ContinueRefresh, or AbortRefresh.
+func (c *githubClient) RefreshBranch(ctx context.Context, branchName, baseBranch string, strategy repository.RefreshStrategy) (*repository.RefreshResult, error) {
+	repoPath := c.paths.Root()
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository at %s: %w", repoPath, err)
+	}
+
+	auth, err := c.gitAuth()
+	if err != nil {
+		return nil, err
+	}
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: "origin",
+		Auth:       auth,
+		CABundle:   c.caBundle,
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", baseBranch, baseBranch)),
+			config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", branchName, branchName)),
+		},
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return nil, fmt.Errorf("failed to fetch %s and %s: %w", baseBranch, branchName, err)
+	}
+	remoteBranch, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", branchName), true)
+	if err != nil {
+		return nil, fmt.Errorf("branch %s not found on remote: %w", branchName, err)
+	}
+	remoteBase, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", baseBranch), true)
+	if err != nil {
+		return nil, fmt.Errorf("base branch %s not found on remote: %w", baseBranch, err)
+	}
+	result := &repository.RefreshResult{LeaseHash: remoteBranch.Hash().String(), BaseHash: remoteBase.Hash().String()}
+
+	originBase := "origin/" + baseBranch
+	if _, err := c.git(ctx, "checkout", "-B", branchName, "origin/"+branchName); err != nil {
+		return nil, fmt.Errorf("failed to check out %s: %w", branchName, err)
+	}
+	if _, err := c.git(ctx, "merge-base", "--is-ancestor", originBase, "HEAD"); err == nil {
+		result.UpToDate = true
+		return result, nil
+	}
+
+	if strategy != repository.RefreshMerge {
+		if _, err := c.git(ctx, "rebase", originBase); err == nil {
+			result.Strategy = repository.RefreshRebase
+			return result, nil
+		}
+		logger.Info("Rebase conflicted, merging the base instead", "branch", branchName, "base", baseBranch)
+		if _, err := c.git(ctx, "rebase", "--abort"); err != nil {
+			return nil, fmt.Errorf("failed to abort rebase of %s: %w", branchName, err)
+		}
+	}
+
+	result.Strategy = repository.RefreshMerge
+	if _, mergeErr := c.git(ctx, "merge", "--no-edit", originBase); mergeErr != nil {
+		out, err := c.git(ctx, "diff", "--name-only", "--diff-filter=U")
+		if err != nil || strings.TrimSpace(out) == "" {
+			c.git(ctx, "merge", "--abort")
+			return nil, fmt.Errorf("failed to merge %s into %s: %w", baseBranch, branchName, mergeErr)
+		}
+		result.Conflicts = strings.Fields(out)
+	}
+	return result, nil
+}
+
+// ContinueRefresh commits an in-progress merge once its conflicts are
+// resolved in the working tree, along with paths, the files the resolution
+// wrote (created or deleted ones included). Files still containing conflict
+// markers are refused rather than committed.
+func (c *githubClient) ContinueRefresh(ctx context.Context, message string, paths []string) error {
+	out, err := c.git(ctx, "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return fmt.Errorf("failed to list conflicted files: %w", err)
+	}
+	conflicted := strings.Fields(out)
+	var unresolved []string
+	for _, path := range conflicted {
+		data, err := os.ReadFile(filepath.Join(c.paths.Root(), path))
+		if err == nil && conflictMarkerRe.Match(data) {
+			unresolved = append(unresolved, path)
+		}
+	}
+	if len(unresolved) > 0 {
+		return fmt.Errorf("unresolved conflicts remain: %s", strings.Join(unresolved, ", "))
+	}
+	if staged := append(conflicted, paths...); len(staged) > 0 {
+		if _, err := c.git(ctx, append([]string{"add", "-A", "--"}, staged...)...); err != nil {
+			return fmt.Errorf("failed to stage resolved files: %w", err)
+		}
+	}
+	if _, err := c.git(ctx, "commit", "--no-verify", "-m", message); err != nil {
+		return fmt.Errorf("failed to commit merge: %w", err)
+	}
+	return nil
+}
+
+// conflictMarkerRe matches the start or end line of a conflict hunk
+var conflictMarkerRe = regexp.MustCompile(`(?m)^(<{7}|>{7})( |$)`)
+
+// AbortRefresh abandons an in-progress merge left by RefreshBranch
+func (c *githubClient) AbortRefresh(ctx context.Context) error {
+	if _, err := c.git(ctx, "merge", "--abort"); err != nil {
+		return fmt.Errorf("failed to abort merge: %w", err)
+	}
+	return nil
+}
+
+// ForcePushWithLease force-pushes branchName, but only if the remote branch
+// is still at expectedHash - anything pushed since the refresh is kept.
+func (c *githubClient) ForcePushWithLease(ctx context.Context, branchName, expectedHash string) error {
+	repoPath := c.paths.Root()
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository at %s: %w", repoPath, err)
+	}
+	ref := plumbing.NewBranchReferenceName(branchName)
+	auth, err := c.gitAuth()
+	if err != nil {
+		return err
+	}
+	err = repo.PushContext(ctx, &git.PushOptions{
+		RemoteName: "origin",
+		Auth:       auth,
+		CABundle:   c.caBundle,
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", ref, ref))},
+		ForceWithLease: &git.ForceWithLease{
+			RefName: ref,
+			Hash:    plumbing.NewHash(expectedHash),
+		},
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to force-push %s: %w", branchName, err)
+	}
+	return nil
+}
+
+// git runs a local-only git command in the repository with the agent's
+// commit identity and signing key, returning stdout
+func (c *githubClient) git(ctx context.Context, args ...string) (string, error) {
+	sub := args[0]
+	author := c.commitSignature()
+	prefix := []string{
+		"-C", c.paths.Root(),
+		"-c", "user.name=" + author.Name,
+		"-c", "user.email=" + author.Email,
+		"-c", "core.editor=true",
+	}
+	if c.signer != nil {
+		for _, kv := range c.signer.gitConfig {
+			prefix = append(prefix, "-c", kv)
+		}
+	}
+	args = append(prefix, args...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return stdout.String(), fmt.Errorf("git %s: %w: %s", sub, err, strings.TrimSpace(stderr.String()))
+		}
+		return stdout.String(), err
+	}
+	return stdout.String(), nil
+}
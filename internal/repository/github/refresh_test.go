@@ -0,0 +1,144 @@
+package github
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"intern/internal/repository"
+)
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	args = append([]string{"-C", dir, "-c", "user.name=Test User", "-c", "user.email=test@example.com"}, args...)
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func writeAndCommit(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", name)
+	runGit(t, dir, "commit", "-q", "-m", "update "+name)
+}
+
+// setupRefreshRepos creates a bare origin with a main branch and a pushed
+// feature branch, a clone for the agent, and a second clone standing in for
+// other people pushing to main.
+func setupRefreshRepos(t *testing.T) (c *githubClient, origin, other string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git CLI not available")
+	}
+	root := t.TempDir()
+	origin = filepath.Join(root, "origin.git")
+	runGit(t, root, "init", "-q", "--bare", "-b", "main", origin)
+
+	other = filepath.Join(root, "other")
+	runGit(t, root, "clone", "-q", origin, other)
+	runGit(t, other, "checkout", "-q", "-b", "main")
+	writeAndCommit(t, other, "calc.go", "package calc\n\nfunc Add(a, b int) int { return a + b }\n")
+	runGit(t, other, "push", "-q", "origin", "main")
+
+	paths, err := repository.NewRepositoryPath(filepath.Join(root, "work"), "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, root, "clone", "-q", origin, paths.Root())
+	runGit(t, paths.Root(), "checkout", "-q", "-b", "feature/PROJ-1")
+	writeAndCommit(t, paths.Root(), "sub.go", "package calc\n\nfunc Sub(a, b int) int { return a - b }\n")
+	runGit(t, paths.Root(), "push", "-q", "origin", "feature/PROJ-1")
+	runGit(t, paths.Root(), "checkout", "-q", "main")
+
+	return &githubClient{owner: "acme", repo: "app", paths: paths}, origin, other
+}
+
+func TestRefreshBranch_Rebase(t *testing.T) {
+	c, origin, other := setupRefreshRepos(t)
+	ctx := context.Background()
+
+	res, err := c.RefreshBranch(ctx, "feature/PROJ-1", "main", repository.RefreshRebase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.UpToDate {
+		t.Fatalf("Expected an up-to-date branch before main moves, got %+v", res)
+	}
+
+	writeAndCommit(t, other, "mul.go", "package calc\n\nfunc Mul(a, b int) int { return a * b }\n")
+	runGit(t, other, "push", "-q", "origin", "main")
+
+	res, err = c.RefreshBranch(ctx, "feature/PROJ-1", "main", repository.RefreshRebase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.UpToDate || res.Strategy != repository.RefreshRebase || len(res.Conflicts) != 0 {
+		t.Fatalf("Expected a clean rebase, got %+v", res)
+	}
+	if err := c.ForcePushWithLease(ctx, "feature/PROJ-1", res.LeaseHash); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, origin, "merge-base", "--is-ancestor", "main", "feature/PROJ-1")
+	if parents := runGit(t, origin, "rev-list", "--parents", "-n", "1", "feature/PROJ-1"); len(strings.Fields(parents)) != 2 {
+		t.Errorf("Expected a linear history after rebase, got parents %q", parents)
+	}
+}
+
+func TestRefreshBranch_ConflictResolvedByMerge(t *testing.T) {
+	c, origin, other := setupRefreshRepos(t)
+	ctx := context.Background()
+
+	// The feature branch and main both change the same line.
+	runGit(t, c.paths.Root(), "checkout", "-q", "feature/PROJ-1")
+	writeAndCommit(t, c.paths.Root(), "calc.go", "package calc\n\nfunc Add(a, b int) int { return b + a }\n")
+	runGit(t, c.paths.Root(), "push", "-q", "origin", "feature/PROJ-1")
+	runGit(t, c.paths.Root(), "checkout", "-q", "main")
+	writeAndCommit(t, other, "calc.go", "package calc\n\nfunc Add(x, y int) int { return x + y }\n")
+	runGit(t, other, "push", "-q", "origin", "main")
+
+	res, err := c.RefreshBranch(ctx, "feature/PROJ-1", "main", repository.RefreshRebase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Strategy != repository.RefreshMerge || len(res.Conflicts) != 1 || res.Conflicts[0] != "calc.go" {
+		t.Fatalf("Expected a merge conflict in calc.go, got %+v", res)
+	}
+	data, _ := os.ReadFile(filepath.Join(c.paths.Root(), "calc.go"))
+	if !strings.Contains(string(data), "<<<<<<<") {
+		t.Fatalf("Expected conflict markers, got:\n%s", data)
+	}
+
+	if err := c.ContinueRefresh(ctx, "merge main", nil); err == nil {
+		t.Fatal("Expected ContinueRefresh to refuse while markers are unresolved")
+	}
+
+	// The resolution also creates a file, which must go into the merge commit
+	os.WriteFile(filepath.Join(c.paths.Root(), "calc.go"), []byte("package calc\n\nfunc Add(x, y int) int { return y + x }\n"), 0644)
+	os.WriteFile(filepath.Join(c.paths.Root(), "sum.go"), []byte("package calc\n"), 0644)
+	if err := c.ContinueRefresh(ctx, "merge main", []string{"calc.go", "sum.go"}); err != nil {
+		t.Fatal(err)
+	}
+	if files := runGit(t, c.paths.Root(), "ls-tree", "--name-only", "HEAD"); !strings.Contains(files, "sum.go") {
+		t.Errorf("Expected the created file in the merge commit, got %q", files)
+	}
+
+	// Someone pushes to the PR branch after the refresh: the lease must hold.
+	runGit(t, other, "fetch", "-q", "origin")
+	runGit(t, other, "checkout", "-q", "-b", "review", "origin/feature/PROJ-1")
+	writeAndCommit(t, other, "review.go", "package calc\n")
+	runGit(t, other, "push", "-q", "origin", "review:feature/PROJ-1")
+	if err := c.ForcePushWithLease(ctx, "feature/PROJ-1", res.LeaseHash); err == nil {
+		t.Fatal("Expected the force-push to be rejected after the remote branch moved")
+	}
+	if got := runGit(t, origin, "log", "-1", "--format=%s", "feature/PROJ-1"); got != "update review.go" {
+		t.Errorf("Expected the reviewer's commit to survive, got %q", got)
+	}
+}
@@ -0,0 +1,162 @@
+package github
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+	"golang.org/x/crypto/ssh"
+)
+
+// Signer signs the agent's commits. go-git commits are signed in process;
+// merges and rebases run through the git CLI, which is pointed at the same
+// key via gitConfig - so for GPG the key must also be in the gpg keyring of
+// the user running the agent.
+type Signer struct {
+	signer    git.Signer
+	gitConfig []string // "key=value" pairs passed to git with -c
+}
+
+// NewSigner loads a commit signing key. format is "gpg" for an armored
+// OpenPGP private key or "ssh" for an OpenSSH private key; passphrase
+// unlocks an encrypted key and may be empty.
+func NewSigner(format, keyPath, passphrase string) (*Signer, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	switch format {
+	case "gpg":
+		return newGPGSigner(data, passphrase)
+	case "ssh":
+		abs, err := filepath.Abs(keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve signing key path: %w", err)
+		}
+		return newSSHSigner(data, passphrase, abs)
+	default:
+		return nil, fmt.Errorf("unsupported signing format %q", format)
+	}
+}
+
+// Sign implements git.Signer
+func (s *Signer) Sign(message io.Reader) ([]byte, error) {
+	return s.signer.Sign(message)
+}
+
+func newGPGSigner(data []byte, passphrase string) (*Signer, error) {
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GPG key: %w", err)
+	}
+	for _, entity := range keyring {
+		if entity.PrivateKey == nil {
+			continue
+		}
+		if entity.PrivateKey.Encrypted {
+			if err := entity.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+				return nil, fmt.Errorf("failed to decrypt GPG key: %w", err)
+			}
+		}
+		return &Signer{
+			signer: &gpgSigner{entity: entity},
+			gitConfig: []string{
+				"commit.gpgsign=true",
+				fmt.Sprintf("user.signingkey=%X", entity.PrimaryKey.Fingerprint),
+			},
+		}, nil
+	}
+	return nil, fmt.Errorf("GPG key file contains no private key")
+}
+
+type gpgSigner struct {
+	entity *openpgp.Entity
+}
+
+func (s *gpgSigner) Sign(message io.Reader) ([]byte, error) {
+	var b bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&b, s.entity, message, nil); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func newSSHSigner(data []byte, passphrase, keyPath string) (*Signer, error) {
+	var key ssh.Signer
+	var err error
+	if passphrase != "" {
+		key, err = ssh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase))
+	} else {
+		key, err = ssh.ParsePrivateKey(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH key: %w", err)
+	}
+	return &Signer{
+		signer: &sshSigner{key: key},
+		gitConfig: []string{
+			"commit.gpgsign=true",
+			"gpg.format=ssh",
+			"user.signingkey=" + keyPath,
+		},
+	}, nil
+}
+
+// sshSigner produces the armored SSHSIG signatures git verifies with
+// gpg.format=ssh (see PROTOCOL.sshsig in OpenSSH)
+type sshSigner struct {
+	key ssh.Signer
+}
+
+const (
+	sshSigMagic     = "SSHSIG"
+	sshSigNamespace = "git"
+	sshSigHash      = "sha512"
+)
+
+func (s *sshSigner) Sign(message io.Reader) ([]byte, error) {
+	h := sha512.New()
+	if _, err := io.Copy(h, message); err != nil {
+		return nil, err
+	}
+	signed := append([]byte(sshSigMagic), ssh.Marshal(struct {
+		Namespace, Reserved, HashAlgorithm string
+		Hash                               []byte
+	}{sshSigNamespace, "", sshSigHash, h.Sum(nil)})...)
+
+	var sig *ssh.Signature
+	var err error
+	if algSigner, ok := s.key.(ssh.AlgorithmSigner); ok && s.key.PublicKey().Type() == ssh.KeyAlgoRSA {
+		// ssh-rsa (SHA-1) signatures are rejected by current OpenSSH
+		sig, err = algSigner.SignWithAlgorithm(rand.Reader, signed, ssh.KeyAlgoRSASHA512)
+	} else {
+		sig, err = s.key.Sign(rand.Reader, signed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	blob := append([]byte(sshSigMagic), ssh.Marshal(struct {
+		Version                                       uint32
+		PublicKey, Namespace, Reserved, HashAlgorithm string
+		Signature                                     []byte
+	}{1, string(s.key.PublicKey().Marshal()), sshSigNamespace, "", sshSigHash, ssh.Marshal(sig)})...)
+
+	encoded := base64.StdEncoding.EncodeToString(blob)
+	var b strings.Builder
+	b.WriteString("-----BEGIN SSH SIGNATURE-----\n")
+	for len(encoded) > 70 {
+		b.WriteString(encoded[:70] + "\n")
+		encoded = encoded[70:]
+	}
+	b.WriteString(encoded + "\n-----END SSH SIGNATURE-----\n")
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,121 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"intern/internal/repository"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/go-git/go-git/v5"
+	"golang.org/x/crypto/ssh"
+)
+
+// commitSigned makes one commit in a fresh repository through a client
+// signing with signer and returns the client and the commit
+func commitSigned(t *testing.T, signer *Signer) (*githubClient, *git.Repository) {
+	t.Helper()
+	paths, err := repository.NewRepositoryPath(t.TempDir(), "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := git.PlainInit(paths.Root(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient("", "acme", "app", paths, WithCommitAuthor("Release Bot", "bot@example.com"), WithSigner(signer)).(*githubClient)
+	os.WriteFile(filepath.Join(paths.Root(), "a.go"), []byte("package a\n"), 0644)
+	if err := c.AddFile(context.Background(), "a.go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Commit(context.Background(), "feat(PROJ-1): add a\n\nTicket: PROJ-1\n"); err != nil {
+		t.Fatal(err)
+	}
+	return c, repo
+}
+
+func TestCommit_GPGSigned(t *testing.T) {
+	entity, err := openpgp.NewEntity("Release Bot", "", "bot@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var private, public bytes.Buffer
+	w, _ := armor.Encode(&private, openpgp.PrivateKeyType, nil)
+	entity.SerializePrivate(w, nil)
+	w.Close()
+	w, _ = armor.Encode(&public, openpgp.PublicKeyType, nil)
+	entity.Serialize(w)
+	w.Close()
+
+	keyPath := filepath.Join(t.TempDir(), "key.asc")
+	os.WriteFile(keyPath, private.Bytes(), 0600)
+	signer, err := NewSigner("gpg", keyPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, repo := commitSigned(t, signer)
+	head, _ := repo.Head()
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.Author.Name != "Release Bot" || commit.Author.Email != "bot@example.com" {
+		t.Errorf("Expected the configured author, got %s <%s>", commit.Author.Name, commit.Author.Email)
+	}
+	if commit.PGPSignature == "" {
+		t.Fatal("Expected a signed commit")
+	}
+	if _, err := commit.Verify(public.String()); err != nil {
+		t.Errorf("Signature did not verify: %v", err)
+	}
+}
+
+func TestCommit_SSHSigned(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not available to verify SSH signatures")
+	}
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "id_ed25519")
+	os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600)
+	sshPub, _ := ssh.NewPublicKey(pub)
+	allowed := filepath.Join(dir, "allowed_signers")
+	os.WriteFile(allowed, append([]byte(`bot@example.com namespaces="git" `), ssh.MarshalAuthorizedKey(sshPub)...), 0644)
+
+	signer, err := NewSigner("ssh", keyPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := commitSigned(t, signer)
+	verify := func(what string) {
+		t.Helper()
+		out, err := exec.Command("git", "-C", c.paths.Root(), "-c", "gpg.format=ssh",
+			"-c", "gpg.ssh.allowedSignersFile="+allowed, "verify-commit", "HEAD").CombinedOutput()
+		if err != nil {
+			t.Errorf("%s signature did not verify: %v\n%s", what, err, out)
+		}
+	}
+	verify("go-git commit")
+
+	// Merges and rebases run through the git CLI and must be signed too.
+	if _, err := c.git(context.Background(), "commit", "--allow-empty", "-m", "merge"); err != nil {
+		t.Fatal(err)
+	}
+	verify("git CLI commit")
+}
@@ -10,12 +10,64 @@ type RepositoryClient interface {
 	ListFiles(ctx context.Context, path string) ([]string, error)
 	CreateBranch(ctx context.Context, branchName string) error
 	SwitchBranch(ctx context.Context, branchName string) error
+	CheckoutRemoteBranch(ctx context.Context, branchName string) (bool, error)
+	DiscardBranch(ctx context.Context, branchName, baseBranch string) error
 	AddFile(ctx context.Context, filePath string) error
 	Commit(ctx context.Context, message string) error
 	Push(ctx context.Context, branchName string) error
-	CreatePullRequest(ctx context.Context, baseBranch, headBranch, title, body string) (string, error)
+	CreatePullRequest(ctx context.Context, baseBranch, headBranch, title, body string, opts PullRequestOptions) (string, error)
+	FindOpenPullRequest(ctx context.Context, headBranch string) (string, error)
+	UpdatePullRequest(ctx context.Context, prURL, title, body string, opts PullRequestOptions) error
 	HasLocalChanges(ctx context.Context) (bool, error)
 	IsPRMerged(ctx context.Context, prURL string) (bool, error)
+	DiffStat(ctx context.Context, baseBranch string) ([]FileStat, error)
+	IsPROpen(ctx context.Context, prURL string) (bool, error)
+	CommentOnPullRequest(ctx context.Context, prURL, body string) error
+	MarkPullRequestDraft(ctx context.Context, prURL string) error
+	MarkPullRequestReady(ctx context.Context, prURL string) error
+	RefreshBranch(ctx context.Context, branchName, baseBranch string, strategy RefreshStrategy) (*RefreshResult, error)
+	ContinueRefresh(ctx context.Context, message string, paths []string) error
+	AbortRefresh(ctx context.Context) error
+	ForcePushWithLease(ctx context.Context, branchName, expectedHash string) error
+}
+
+// RefreshStrategy is how RefreshBranch brings a branch up to date with its base
+type RefreshStrategy string
+
+const (
+	// RefreshRebase replays the branch onto the base, falling back to a merge
+	// when the rebase conflicts so the conflict can be resolved in one step
+	RefreshRebase RefreshStrategy = "rebase"
+	// RefreshMerge merges the base into the branch
+	RefreshMerge RefreshStrategy = "merge"
+)
+
+// RefreshResult describes what RefreshBranch did to a branch
+type RefreshResult struct {
+	UpToDate  bool            // The branch already contained the base; nothing changed
+	Strategy  RefreshStrategy // How the base was applied
+	Conflicts []string        // Files left with conflict markers while a merge is in progress
+	LeaseHash string          // Remote branch commit a force-push must replace
+	BaseHash  string          // Base branch commit the branch was refreshed onto
+}
+
+// PullRequestOptions configures a pull request beyond its title and body.
+// Reviewers and assignees are GitHub logins without the "@"; team reviewers
+// are team slugs within the repository owner's organization.
+type PullRequestOptions struct {
+	Draft         bool
+	Labels        []string
+	Reviewers     []string
+	TeamReviewers []string
+	Assignees     []string
+}
+
+// FileStat is the number of lines added and removed in one file, as in
+// `git diff --stat`
+type FileStat struct {
+	Path    string
+	Added   int
+	Removed int
 }
 
 type RepositoryService struct {
@@ -46,6 +98,10 @@ func (r *RepositoryService) SwitchBranch(ctx context.Context, branchName string)
 	return r.Client.SwitchBranch(ctx, branchName)
 }
 
+func (r *RepositoryService) CheckoutRemoteBranch(ctx context.Context, branchName string) (bool, error) {
+	return r.Client.CheckoutRemoteBranch(ctx, branchName)
+}
+
 func (r *RepositoryService) AddFile(ctx context.Context, filePath string) error {
 	return r.Client.AddFile(ctx, filePath)
 }
@@ -58,8 +114,16 @@ func (r *RepositoryService) Push(ctx context.Context, branchName string) error {
 	return r.Client.Push(ctx, branchName)
 }
 
-func (r *RepositoryService) CreatePullRequest(ctx context.Context, baseBranch, headBranch, title, body string) (string, error) {
-	return r.Client.CreatePullRequest(ctx, baseBranch, headBranch, title, body)
+func (r *RepositoryService) CreatePullRequest(ctx context.Context, baseBranch, headBranch, title, body string, opts PullRequestOptions) (string, error) {
+	return r.Client.CreatePullRequest(ctx, baseBranch, headBranch, title, body, opts)
+}
+
+func (r *RepositoryService) FindOpenPullRequest(ctx context.Context, headBranch string) (string, error) {
+	return r.Client.FindOpenPullRequest(ctx, headBranch)
+}
+
+func (r *RepositoryService) UpdatePullRequest(ctx context.Context, prURL, title, body string, opts PullRequestOptions) error {
+	return r.Client.UpdatePullRequest(ctx, prURL, title, body, opts)
 }
 
 func (r *RepositoryService) HasLocalChanges(ctx context.Context) (bool, error) {
@@ -69,3 +133,43 @@ func (r *RepositoryService) HasLocalChanges(ctx context.Context) (bool, error) {
 func (r *RepositoryService) IsPRMerged(ctx context.Context, prURL string) (bool, error) {
 	return r.Client.IsPRMerged(ctx, prURL)
 }
+
+func (r *RepositoryService) DiffStat(ctx context.Context, baseBranch string) ([]FileStat, error) {
+	return r.Client.DiffStat(ctx, baseBranch)
+}
+
+func (r *RepositoryService) IsPROpen(ctx context.Context, prURL string) (bool, error) {
+	return r.Client.IsPROpen(ctx, prURL)
+}
+
+func (r *RepositoryService) CommentOnPullRequest(ctx context.Context, prURL, body string) error {
+	return r.Client.CommentOnPullRequest(ctx, prURL, body)
+}
+
+func (r *RepositoryService) DiscardBranch(ctx context.Context, branchName, baseBranch string) error {
+	return r.Client.DiscardBranch(ctx, branchName, baseBranch)
+}
+
+func (r *RepositoryService) MarkPullRequestDraft(ctx context.Context, prURL string) error {
+	return r.Client.MarkPullRequestDraft(ctx, prURL)
+}
+
+func (r *RepositoryService) MarkPullRequestReady(ctx context.Context, prURL string) error {
+	return r.Client.MarkPullRequestReady(ctx, prURL)
+}
+
+func (r *RepositoryService) RefreshBranch(ctx context.Context, branchName, baseBranch string, strategy RefreshStrategy) (*RefreshResult, error) {
+	return r.Client.RefreshBranch(ctx, branchName, baseBranch, strategy)
+}
+
+func (r *RepositoryService) ContinueRefresh(ctx context.Context, message string, paths []string) error {
+	return r.Client.ContinueRefresh(ctx, message, paths)
+}
+
+func (r *RepositoryService) AbortRefresh(ctx context.Context) error {
+	return r.Client.AbortRefresh(ctx)
+}
+
+func (r *RepositoryService) ForcePushWithLease(ctx context.Context, branchName, expectedHash string) error {
+	return r.Client.ForcePushWithLease(ctx, branchName, expectedHash)
+}
@@ -0,0 +1,534 @@
+package sandbox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// unprivilegedID is the uid/gid sandboxed commands run as: "nobody". As
+// root it's the host's nobody; otherwise it's mapped to a subordinate uid
+// from /etc/subuid, so the command never shares the agent's uid.
+const unprivilegedID = 65534
+
+// initArg0 is the argv[0] the agent's own executable is re-run with to set
+// up an isolated command from inside its namespaces (see sandboxInit), and
+// mappedInitArg0 the one it re-execs itself with once its user namespace
+// is mapped
+const (
+	initArg0       = "ai-intern-sandbox-init"
+	mappedInitArg0 = "ai-intern-sandbox-init-mapped"
+)
+
+// initStopGrace is how long the init process gets to stop the command and
+// hand its files back after a timeout, before it's killed
+const initStopGrace = 10 * time.Second
+
+// systemPaths are the host paths an isolated command sees, read-only, on
+// top of the toolchain: programs and libraries, and the few /etc files
+// that name resolution, TLS and user lookups read
+var systemPaths = []string{
+	"/bin", "/sbin", "/usr", "/lib", "/lib32", "/lib64", "/libx32",
+	"/etc/alternatives", "/etc/ssl", "/etc/pki", "/etc/ca-certificates",
+	"/etc/resolv.conf", "/etc/hosts", "/etc/nsswitch.conf",
+	"/etc/passwd", "/etc/group", "/etc/localtime",
+}
+
+// lockedFlags maps the statfs flags of a mount to the mount flags a
+// remount of it must keep
+var lockedFlags = map[int64]uintptr{
+	0x2:    syscall.MS_NOSUID,     // ST_NOSUID
+	0x4:    syscall.MS_NODEV,      // ST_NODEV
+	0x8:    syscall.MS_NOEXEC,     // ST_NOEXEC
+	0x400:  syscall.MS_NOATIME,    // ST_NOATIME
+	0x800:  syscall.MS_NODIRATIME, // ST_NODIRATIME
+	0x1000: syscall.MS_RELATIME,   // ST_RELATIME
+}
+
+// devices are the device nodes an isolated command can use
+var devices = []string{"/dev/null", "/dev/zero", "/dev/full", "/dev/random", "/dev/urandom", "/dev/tty"}
+
+func init() {
+	if len(os.Args) > 1 && (os.Args[0] == initArg0 || os.Args[0] == mappedInitArg0) {
+		os.Exit(sandboxInit(os.Args[1], os.Args[2:]))
+	}
+}
+
+// initSpec is what the init process of an isolated command is told to do
+type initSpec struct {
+	Root     string   // Empty directory the new root filesystem is assembled in
+	Dir      string   // Working directory of the command
+	ReadOnly []string // Bound read-only, where they exist
+	Private  []string // Bound read-write; handed to the sandbox user for the run when Chown
+	Shared   []string // Bound read-write; their top level handed to the sandbox user when Chown
+	Chown    bool     // The command runs in a user namespace and must be given its files
+}
+
+// isolate runs cmd in fresh mount, PID, IPC and UTS namespaces - and a
+// network namespace with only a downed loopback when v.Network is off -
+// through an init process (this executable, re-run as initArg0). The
+// command sees a root filesystem of the system and toolchain directories
+// (read-only), the directories in v, a private /proc, a few devices and an
+// empty /tmp: nothing else of the host, so no secrets in files or in other
+// processes' environments. It runs as unprivilegedID: as root the host's
+// nobody; otherwise, inside a user namespace, a subordinate uid mapped with
+// newuidmap. Anything that stops this is returned as ErrIsolationUnavailable.
+func isolate(cmd *exec.Cmd, v view) (string, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return "", unavailable(err)
+	}
+	asRoot := os.Geteuid() == 0
+
+	spec := initSpec{
+		Root:     filepath.Join(v.Root, "rootfs"),
+		Dir:      cmd.Dir,
+		ReadOnly: append(append([]string{}, systemPaths...), toolchainPaths(cmd.Env)...),
+		Private:  v.Private,
+		Shared:   v.Shared,
+		Chown:    !asRoot,
+	}
+	var uidMap, gidMap []string
+	if !asRoot {
+		if uidMap, gidMap, err = idMaps(); err != nil {
+			return "", unavailable(err)
+		}
+	}
+	if err := os.MkdirAll(spec.Root, 0755); err != nil {
+		return "", unavailable(err)
+	}
+	// MkdirTemp creates the sandbox dir 0700; the sandbox user must be able
+	// to enter it (dropOwnership sees to that as root).
+	if err := os.Chmod(v.Root, 0755); err != nil {
+		return "", unavailable(err)
+	}
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return "", unavailable(err)
+	}
+
+	// The init process reports a failed setup on the status pipe (closed
+	// empty once the command starts) and waits on the sync pipe until its
+	// user namespace is mapped
+	statusR, statusW, err := os.Pipe()
+	if err != nil {
+		return "", unavailable(err)
+	}
+	defer statusR.Close()
+	syncR, syncW, err := os.Pipe()
+	if err != nil {
+		statusW.Close()
+		return "", unavailable(err)
+	}
+	defer syncW.Close()
+
+	cmd.Args = append([]string{initArg0, string(specJSON), cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = self
+	cmd.ExtraFiles = []*os.File{statusW, syncR}
+	attr := &syscall.SysProcAttr{
+		Setpgid:    true,
+		Pdeathsig:  syscall.SIGKILL,
+		Cloneflags: syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+	}
+	if !v.Network {
+		attr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+	if !asRoot {
+		attr.Cloneflags |= syscall.CLONE_NEWUSER
+	}
+	cmd.SysProcAttr = attr
+	// Give init the chance to stop the command and hand its files back;
+	// killing init takes the whole PID namespace with it
+	cmd.Cancel = func() error { return cmd.Process.Signal(syscall.SIGTERM) }
+	cmd.WaitDelay = initStopGrace
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Start()
+	statusW.Close()
+	syncR.Close()
+	if err != nil {
+		return "", unavailable(err)
+	}
+
+	if !asRoot {
+		if err := mapIDs(cmd.Process.Pid, uidMap, gidMap); err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return "", unavailable(err)
+		}
+	}
+	syncW.Close()
+
+	if status, _ := io.ReadAll(statusR); len(status) > 0 {
+		cmd.Wait()
+		return "", unavailable(errors.New(string(status)))
+	}
+	err = cmd.Wait()
+	return stdout.String() + stderr.String(), err
+}
+
+// degrade configures cmd for a run without namespaces: its own process
+// group, killed as a group on timeout, and - as root - an unprivileged uid
+func degrade(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid:   true,
+		Pdeathsig: syscall.SIGKILL,
+	}
+	if os.Geteuid() == 0 {
+		cmd.SysProcAttr.Credential = &syscall.Credential{Uid: unprivilegedID, Gid: unprivilegedID}
+	}
+
+	// go test forks test binaries; kill the whole group, not just the shell.
+	cmd.Cancel = func() error {
+		if cmd.Process == nil {
+			return nil
+		}
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+func unavailable(err error) error {
+	return fmt.Errorf("%w: %v", ErrIsolationUnavailable, err)
+}
+
+// toolchainPaths returns the Go toolchain the command's PATH resolves
+// "go" to, and GOROOT if set
+func toolchainPaths(env []string) []string {
+	var paths []string
+	for _, kv := range env {
+		if k, v, _ := strings.Cut(kv, "="); k == "GOROOT" && v != "" {
+			paths = append(paths, v)
+		}
+	}
+	if goBin, err := exec.LookPath("go"); err == nil {
+		if resolved, err := filepath.EvalSymlinks(goBin); err == nil {
+			paths = append(paths, filepath.Dir(filepath.Dir(resolved)))
+		}
+	}
+	return paths
+}
+
+// idMaps returns the newuidmap/newgidmap arguments for a user namespace in
+// which 0 is the agent (so init can set the namespace up) and
+// unprivilegedID is the first of the agent's subordinate ids
+func idMaps() ([]string, []string, error) {
+	for _, tool := range []string{"newuidmap", "newgidmap"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return nil, nil, fmt.Errorf("%s not found to run gates under a subordinate uid; install the uidmap package, or run as root", tool)
+		}
+	}
+	u, err := user.Current()
+	if err != nil {
+		return nil, nil, err
+	}
+	subUID, err := subordinateID("/etc/subuid", u.Username, u.Uid)
+	if err != nil {
+		return nil, nil, err
+	}
+	subGID, err := subordinateID("/etc/subgid", u.Username, u.Uid)
+	if err != nil {
+		return nil, nil, err
+	}
+	id := strconv.Itoa(unprivilegedID)
+	return []string{"0", u.Uid, "1", id, strconv.Itoa(subUID), "1"},
+		[]string{"0", strconv.Itoa(os.Getgid()), "1", id, strconv.Itoa(subGID), "1"}, nil
+}
+
+// subordinateID returns the first id of the range /etc/subuid (or subgid)
+// delegates to the user, named or by uid
+func subordinateID(path, name, uid string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("no subordinate ids for %s to run gates under (%w); add a range to /etc/subuid and /etc/subgid, or run as root", name, err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(strings.TrimSpace(scanner.Text()), ":")
+		if len(fields) != 3 || (fields[0] != name && fields[0] != uid) {
+			continue
+		}
+		start, err := strconv.Atoi(fields[1])
+		count, countErr := strconv.Atoi(fields[2])
+		if err == nil && countErr == nil && count > 0 {
+			return start, nil
+		}
+	}
+	return 0, fmt.Errorf("no subordinate ids for %s in %s to run gates under; add a range, or run as root", name, path)
+}
+
+// mapIDs maps the user namespace of pid with the setuid newuidmap and
+// newgidmap helpers, which check the ranges against /etc/subuid and
+// /etc/subgid
+func mapIDs(pid int, uidMap, gidMap []string) error {
+	for _, m := range []struct {
+		tool   string
+		ranges []string
+	}{{"newuidmap", uidMap}, {"newgidmap", gidMap}} {
+		out, err := exec.Command(m.tool, append([]string{strconv.Itoa(pid)}, m.ranges...)...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%s: %v %s", m.tool, err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+// sandboxInit is the init process of an isolated command, PID 1 of its
+// namespaces: it assembles the command's root filesystem, runs the command
+// as the sandbox user, and when it exits kills anything it left behind and
+// hands its files back. Setup failures are written to the status pipe (fd
+// 3) instead of running the command.
+func sandboxInit(specJSON string, argv []string) int {
+	status := os.NewFile(3, "status")
+	fail := func(err error) int {
+		fmt.Fprint(status, err.Error())
+		return 1
+	}
+	var spec initSpec
+	if err := json.Unmarshal([]byte(specJSON), &spec); err != nil {
+		return fail(err)
+	}
+
+	if os.Args[0] == initArg0 {
+		// Wait until the parent has mapped our user namespace, if any
+		sync := os.NewFile(4, "sync")
+		io.Copy(io.Discard, sync)
+		sync.Close()
+		// We were exec'd before the mapping existed, which cost us our
+		// capabilities in the namespace; exec'ing again as its root
+		// restores them
+		if spec.Chown {
+			args := append([]string{mappedInitArg0}, os.Args[1:]...)
+			return fail(syscall.Exec("/proc/self/exe", args, os.Environ()))
+		}
+	}
+	syscall.CloseOnExec(3)
+	if spec.Chown {
+		if err := handOver(spec, 0, unprivilegedID); err != nil {
+			return fail(fmt.Errorf("hand files to the sandbox user: %w", err))
+		}
+		defer handOver(spec, unprivilegedID, 0)
+	}
+	if err := buildRoot(spec); err != nil {
+		return fail(fmt.Errorf("set up the sandbox filesystem: %w", err))
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM)
+	go func() {
+		<-stop
+		syscall.Kill(-1, syscall.SIGKILL)
+	}()
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = spec.Dir
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Credential: &syscall.Credential{Uid: unprivilegedID, Gid: unprivilegedID}}
+	if err := cmd.Start(); err != nil {
+		return fail(fmt.Errorf("start %s as the sandbox user: %w", argv[0], err))
+	}
+	status.Close()
+	cmd.Wait()
+
+	// Background processes and test binaries die with the command
+	syscall.Kill(-1, syscall.SIGKILL)
+
+	ws, _ := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return ws.ExitStatus()
+}
+
+// buildRoot assembles the command's root filesystem in spec.Root and
+// pivots into it, detaching the host's
+func buildRoot(spec initSpec) error {
+	// Nothing mounted from here on may propagate back to the host
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+	root := spec.Root
+	if err := syscall.Mount("tmpfs", root, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=0755"); err != nil {
+		return fmt.Errorf("mount root: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "tmp"), 0755); err != nil {
+		return err
+	}
+	if err := syscall.Mount("tmpfs", filepath.Join(root, "tmp"), "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777"); err != nil {
+		return fmt.Errorf("mount /tmp: %w", err)
+	}
+
+	for _, p := range spec.ReadOnly {
+		if err := bind(root, p, true); err != nil {
+			return err
+		}
+	}
+	for _, p := range append(append([]string{}, spec.Private...), spec.Shared...) {
+		if err := bind(root, p, false); err != nil {
+			return err
+		}
+	}
+	for _, d := range devices {
+		if err := bind(root, d, false); err != nil {
+			return err
+		}
+	}
+	for link, target := range map[string]string{"fd": "/proc/self/fd", "stdin": "/proc/self/fd/0", "stdout": "/proc/self/fd/1", "stderr": "/proc/self/fd/2"} {
+		if err := os.Symlink(target, filepath.Join(root, "dev", link)); err != nil {
+			return err
+		}
+	}
+
+	// A /proc of this PID namespace only: no other process is visible
+	if err := os.MkdirAll(filepath.Join(root, "proc"), 0555); err != nil {
+		return err
+	}
+	if err := syscall.Mount("proc", filepath.Join(root, "proc"), "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("mount /proc: %w", err)
+	}
+
+	oldRoot := filepath.Join(root, ".oldroot")
+	if err := os.Mkdir(oldRoot, 0700); err != nil {
+		return err
+	}
+	if err := syscall.PivotRoot(root, oldRoot); err != nil {
+		return fmt.Errorf("pivot_root: %w", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return err
+	}
+	if err := syscall.Unmount("/.oldroot", syscall.MNT_DETACH); err != nil {
+		return fmt.Errorf("detach host root: %w", err)
+	}
+	if err := os.Remove("/.oldroot"); err != nil {
+		return err
+	}
+	return os.Chdir(spec.Dir)
+}
+
+// bind mounts the host path p at the same path under root. Missing paths
+// are skipped; relative symlinks (e.g. /bin -> usr/bin) are recreated.
+func bind(root, p string, readOnly bool) error {
+	info, err := os.Lstat(p)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	target := filepath.Join(root, p)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		if link, err := os.Readlink(p); err == nil && !filepath.IsAbs(link) {
+			return os.Symlink(link, target)
+		}
+		if info, err = os.Stat(p); err != nil {
+			return nil // Dangling
+		}
+	}
+
+	if info.IsDir() {
+		err = os.MkdirAll(target, 0755)
+	} else {
+		err = os.WriteFile(target, nil, 0644)
+	}
+	if err != nil {
+		return err
+	}
+	if err := syscall.Mount(p, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind %s: %w", p, err)
+	}
+	if !readOnly {
+		return nil
+	}
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(target, &st); err != nil {
+		return err
+	}
+	// A remount must keep the flags the mount is locked with in a user
+	// namespace
+	flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY)
+	for stFlag, msFlag := range lockedFlags {
+		if int64(st.Flags)&stFlag != 0 {
+			flags |= msFlag
+		}
+	}
+	if err := syscall.Mount("", target, "", flags, ""); err != nil {
+		return fmt.Errorf("make %s read-only: %w", p, err)
+	}
+	return nil
+}
+
+// handOver changes the owner of the private directories (recursively) and
+// the shared ones (top level only - their contents belong to the sandbox
+// user already) from one id to another, leaving anything else alone
+func handOver(spec initSpec, from, to int) error {
+	chown := func(p string) error {
+		var st syscall.Stat_t
+		if err := syscall.Lstat(p, &st); err != nil || int(st.Uid) != from {
+			return nil
+		}
+		return os.Lchown(p, to, to)
+	}
+	for _, dir := range spec.Private {
+		err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			return chown(p)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, dir := range spec.Shared {
+		if err := chown(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropOwnership hands the per-run sandbox tree (recursively) and the shared
+// cache dirs (top level only - their contents are created by the sandbox
+// user itself) to the unprivileged user. No-op unless running as root.
+func dropOwnership(root string, cacheDirs ...string) error {
+	if os.Geteuid() != 0 {
+		return nil
+	}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, unprivilegedID, unprivilegedID)
+	})
+	if err != nil {
+		return err
+	}
+	// MkdirTemp creates root 0700; the sandbox user must be able to enter it.
+	if err := os.Chmod(root, 0755); err != nil {
+		return err
+	}
+	for _, d := range cacheDirs {
+		if err := os.Chown(d, unprivilegedID, unprivilegedID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// isolate refuses to run anything outside Linux: without mount, PID and
+// network namespaces a command would see the host's files and processes.
+func isolate(cmd *exec.Cmd, v view) (string, error) {
+	return "", fmt.Errorf("%w: %v", ErrIsolationUnavailable, errors.New("namespaces are only available on Linux"))
+}
+
+// degrade is a no-op outside Linux, where commands keep the agent's uid.
+func degrade(cmd *exec.Cmd) {}
+
+// dropOwnership is a no-op outside Linux, where commands keep the agent's uid.
+func dropOwnership(root string, cacheDirs ...string) error { return nil }
@@ -0,0 +1,434 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jenish-jain/logger"
+)
+
+// Executor runs quality-gate commands (go build/vet/test) against a target
+// repository. Implementations decide how much of the host the command can
+// see; the orchestrator only cares about combined output and the exit error.
+type Executor interface {
+	Run(ctx context.Context, dir, name string, args ...string) (string, error)
+}
+
+// Config holds sandbox configuration
+type Config struct {
+	// Enabled runs commands in an isolated working copy and a private view
+	// of the filesystem and processes, with dropped privileges, resource
+	// limits and (by default) no network. When false,
+	// commands run in place on the host - but still with a scrubbed
+	// environment, since AI-written code should never see agent secrets.
+	Enabled bool
+	// AllowNetwork leaves the host network reachable from inside the sandbox
+	AllowNetwork bool
+	// Timeout is the wall-clock limit per command
+	Timeout time.Duration
+	// CPUSeconds is the RLIMIT_CPU applied to the command (0 = unlimited)
+	CPUSeconds int
+	// MemoryMB is the RLIMIT_AS applied to the command in MiB (0 = unlimited)
+	MemoryMB int
+	// EnvAllowlist names extra host environment variables passed through in
+	// addition to the toolchain defaults (see baseEnvAllowlist)
+	EnvAllowlist []string
+	// CacheDir holds the sandbox's build and module caches, kept across runs
+	// so every gate doesn't start cold
+	CacheDir string
+	// AllowDegraded lets commands run when the platform refuses to create
+	// the isolated process (no namespaces, or as a non-root agent no
+	// subordinate uid to run them as), with environment scrubbing, rlimits
+	// and - as root - an unprivileged uid only. Off by default: the command
+	// would see the host's filesystem, processes and network and, as a
+	// non-root agent, share its uid, and so could read the agent's secrets
+	// from /proc or its .env.
+	AllowDegraded bool
+}
+
+// ErrIsolationUnavailable is returned instead of running a command when the
+// sandbox can't isolate it and Config.AllowDegraded is off
+var ErrIsolationUnavailable = errors.New("sandbox isolation unavailable")
+
+// limitFailedExit is the exit status of the rlimit wrapper when a limit
+// can't be applied, and limitFailedMarker starts the message it prints
+const (
+	limitFailedExit   = 125
+	limitFailedMarker = "ai-intern-sandbox: failed to set "
+)
+
+// DefaultConfig returns a sensible default configuration
+func DefaultConfig() Config {
+	return Config{
+		Enabled:    true,
+		Timeout:    10 * time.Minute,
+		CPUSeconds: 600,
+		MemoryMB:   4096,
+		CacheDir:   filepath.Join(os.TempDir(), "ai-intern-sandbox-cache"),
+	}
+}
+
+// baseEnvAllowlist is the set of host variables the Go toolchain needs to
+// work. Everything else - GITHUB_TOKEN, ANTHROPIC_API_KEY, JIRA_API_TOKEN,
+// SLACK_*, cloud credentials - is dropped before the command starts.
+var baseEnvAllowlist = []string{
+	"PATH", "HOME", "USER", "XDG_CACHE_HOME", "LANG", "LC_ALL", "TZ",
+	"GOROOT", "GOPATH", "GOCACHE", "GOMODCACHE", "GOTOOLCHAIN", "GOFLAGS",
+	"GOPROXY", "GOSUMDB", "GONOSUMDB", "GONOSUMCHECK", "GOPRIVATE", "GONOPROXY",
+	"GOOS", "GOARCH", "CGO_ENABLED",
+}
+
+// ScrubEnv filters environ (os.Environ() format) down to baseEnvAllowlist
+// plus extra. Order is preserved.
+func ScrubEnv(environ []string, extra []string) []string {
+	allowed := make(map[string]bool, len(baseEnvAllowlist)+len(extra))
+	for _, k := range baseEnvAllowlist {
+		allowed[k] = true
+	}
+	for _, k := range extra {
+		allowed[strings.TrimSpace(k)] = true
+	}
+
+	out := make([]string, 0, len(allowed))
+	for _, kv := range environ {
+		k, _, ok := strings.Cut(kv, "=")
+		if ok && allowed[k] {
+			out = append(out, kv)
+		}
+	}
+	return out
+}
+
+// New creates an Executor for the given configuration
+func New(config Config) Executor {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultConfig().Timeout
+	}
+	if config.CacheDir == "" {
+		config.CacheDir = DefaultConfig().CacheDir
+	}
+	if !config.Enabled {
+		return &HostExecutor{config: config}
+	}
+	return &Sandbox{config: config, isolate: isolate, degrade: degrade}
+}
+
+// HostExecutor runs commands in place on the host with a scrubbed
+// environment and a timeout, but no filesystem, privilege or network
+// isolation. Used when the sandbox is disabled.
+type HostExecutor struct {
+	config Config
+}
+
+// Run executes name with args in dir and returns combined stdout+stderr
+func (h *HostExecutor) Run(ctx context.Context, dir, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = ScrubEnv(os.Environ(), h.config.EnvAllowlist)
+	return runCapture(cmd)
+}
+
+// Sandbox runs each command against a private copy of the repository, as an
+// unprivileged user, under CPU/memory/time limits, in its own mount and PID
+// namespaces that show it only the working copy, its caches and the system
+// and toolchain directories - and by default in a network namespace with no
+// interfaces up.
+type Sandbox struct {
+	config Config
+
+	// isolate runs a command in the view of the filesystem it's given and
+	// returns its combined output, or ErrIsolationUnavailable without running
+	// it (isolate, replaced in tests)
+	isolate func(cmd *exec.Cmd, v view) (string, error)
+	// degrade prepares a command for a run without isolation (degrade)
+	degrade func(cmd *exec.Cmd)
+
+	// isolationErr is set once isolation fails (e.g. in a container
+	// without CAP_SYS_ADMIN and with user namespaces disabled, or for a
+	// non-root agent without subordinate uids), so
+	// later commands are refused (or run degraded) straight away instead of
+	// failing and retrying every time.
+	mu           sync.Mutex
+	isolationErr error
+}
+
+// view is what an isolated command can see of the host besides the system
+// and toolchain directories
+type view struct {
+	Root    string   // Per-run sandbox directory
+	Private []string // Per-run directories, read-write
+	Shared  []string // Caches shared between runs, read-write
+	Network bool     // Leave the host network reachable
+}
+
+// Run copies dir into a fresh working copy, executes name with args there
+// and returns combined stdout+stderr with working-copy paths rewritten back
+// to repo-relative ones. Writes made by the command never reach dir.
+func (s *Sandbox) Run(ctx context.Context, dir, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	root, err := os.MkdirTemp("", "ai-intern-sandbox-*")
+	if err != nil {
+		return "", fmt.Errorf("create sandbox dir: %w", err)
+	}
+	defer os.RemoveAll(root)
+
+	workDir := filepath.Join(root, "src")
+	homeDir := filepath.Join(root, "home")
+	tmpDir := filepath.Join(root, "tmp")
+	for _, d := range []string{homeDir, tmpDir, filepath.Join(s.config.CacheDir, "build"), filepath.Join(s.config.CacheDir, "mod")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return "", fmt.Errorf("create sandbox dir %s: %w", d, err)
+		}
+	}
+	if err := copyTree(dir, workDir); err != nil {
+		return "", fmt.Errorf("copy working tree into sandbox: %w", err)
+	}
+
+	if err := dropOwnership(root, filepath.Join(s.config.CacheDir, "build"), filepath.Join(s.config.CacheDir, "mod")); err != nil {
+		logger.Warn("Failed to hand sandbox dirs to unprivileged user", "error", err)
+	}
+
+	env := s.env(homeDir, tmpDir)
+	v := view{
+		Root:    root,
+		Private: []string{workDir, homeDir, tmpDir},
+		Shared:  []string{filepath.Join(s.config.CacheDir, "build"), filepath.Join(s.config.CacheDir, "mod")},
+	}
+
+	// Module downloads happen before isolation: `go mod download` only
+	// fetches what go.mod lists (which the agent is never allowed to edit)
+	// and runs no repository code, so it's safe to give it the network the
+	// gate itself won't have.
+	if !s.config.AllowNetwork {
+		if _, statErr := os.Stat(filepath.Join(workDir, "go.mod")); statErr == nil {
+			s.prefetchModules(ctx, workDir, env, v)
+		}
+		env = append(env, "GOPROXY=off")
+	}
+
+	out, runErr := s.runIsolated(ctx, workDir, env, v, name, args)
+	out = strings.ReplaceAll(out, workDir+string(filepath.Separator), "")
+	out = strings.ReplaceAll(out, workDir, ".")
+	return out, runErr
+}
+
+// env builds the scrubbed environment for a sandboxed command. HOME and the
+// build/module caches are overridden (later entries win): they point into
+// the sandbox rather than the host's, which the unprivileged sandbox user
+// typically can't read (e.g. /root/go).
+func (s *Sandbox) env(homeDir, tmpDir string) []string {
+	return append(ScrubEnv(os.Environ(), s.config.EnvAllowlist),
+		"HOME="+homeDir,
+		"TMPDIR="+tmpDir,
+		"GOCACHE="+filepath.Join(s.config.CacheDir, "build"),
+		"GOMODCACHE="+filepath.Join(s.config.CacheDir, "mod"),
+		"GOTOOLCHAIN=local",
+	)
+}
+
+// prefetchModules populates the module cache for workDir. Failures are
+// logged and otherwise ignored - the gate itself will report anything that
+// is actually missing.
+func (s *Sandbox) prefetchModules(ctx context.Context, workDir string, env []string, v view) {
+	newCmd := func() *exec.Cmd {
+		cmd := exec.CommandContext(ctx, "go", "mod", "download")
+		cmd.Dir = workDir
+		cmd.Env = env
+		return cmd
+	}
+
+	// Same user as the gate (so it can use the cache afterwards), but with
+	// the network left up.
+	v.Network = true
+	out, err := s.isolate(newCmd(), v)
+	if errors.Is(err, ErrIsolationUnavailable) {
+		cmd := newCmd()
+		s.degrade(cmd)
+		out, err = runCapture(cmd)
+	}
+	if err != nil {
+		logger.Warn("Sandbox module prefetch failed", "error", err, "output", truncate(out, 500))
+	}
+}
+
+// runIsolated runs the command under resource limits and platform
+// isolation. If the platform can't isolate it, the command is refused with
+// ErrIsolationUnavailable - now and for every later command - unless
+// Config.AllowDegraded is set, in which case it runs without namespaces,
+// but still under an unprivileged uid when the agent runs as root.
+func (s *Sandbox) runIsolated(ctx context.Context, workDir string, env []string, v view, name string, args []string) (string, error) {
+	s.mu.Lock()
+	isolationErr := s.isolationErr
+	s.mu.Unlock()
+
+	if isolationErr == nil {
+		v.Network = s.config.AllowNetwork
+		out, err := s.isolate(s.command(ctx, workDir, env, name, args), v)
+		if !errors.Is(err, ErrIsolationUnavailable) {
+			return out, limitError(out, err)
+		}
+		isolationErr = err
+		s.mu.Lock()
+		s.isolationErr = err
+		s.mu.Unlock()
+		if s.config.AllowDegraded {
+			logger.Warn("Sandbox isolation unavailable, running degraded with environment scrubbing, rlimits and an unprivileged uid only",
+				"error", err)
+		}
+	}
+
+	if !s.config.AllowDegraded {
+		err := fmt.Errorf("%w; refusing to run %s without isolation; set SANDBOX_ALLOW_DEGRADED=true to run gates with environment scrubbing and rlimits only",
+			isolationErr, name)
+		return err.Error(), err
+	}
+
+	// No namespaces, but as root still drop to the unprivileged uid; if
+	// even that is refused, the command doesn't run.
+	cmd := s.command(ctx, workDir, env, name, args)
+	s.degrade(cmd)
+	out, err := runCapture(cmd)
+	if err != nil && cmd.Process == nil {
+		err = fmt.Errorf("%w (%v): cannot drop privileges for %s", ErrIsolationUnavailable, err, name)
+		return err.Error(), err
+	}
+	return out, limitError(out, err)
+}
+
+// command wraps name/args in a shell that applies rlimits before exec'ing,
+// since os/exec has no portable way to set limits on the child only. A
+// limit that can't be applied stops the command (see limitError).
+func (s *Sandbox) command(ctx context.Context, workDir string, env []string, name string, args []string) *exec.Cmd {
+	var script strings.Builder
+	if s.config.CPUSeconds > 0 {
+		fmt.Fprintf(&script, "ulimit -t %d || { echo '%sCPU time limit (ulimit -t %d)' >&2; exit %d; }; ",
+			s.config.CPUSeconds, limitFailedMarker, s.config.CPUSeconds, limitFailedExit)
+	}
+	if s.config.MemoryMB > 0 {
+		fmt.Fprintf(&script, "ulimit -v %d || { echo '%smemory limit (ulimit -v %d)' >&2; exit %d; }; ",
+			s.config.MemoryMB*1024, limitFailedMarker, s.config.MemoryMB*1024, limitFailedExit)
+	}
+	script.WriteString(`exec "$@"`)
+
+	shArgs := append([]string{"-c", script.String(), "sh", name}, args...)
+	cmd := exec.CommandContext(ctx, "/bin/sh", shArgs...)
+	cmd.Dir = workDir
+	cmd.Env = env
+	return cmd
+}
+
+// limitError turns the rlimit wrapper's failure into an error naming the
+// limit, rather than a failure of the command itself
+func limitError(out string, err error) error {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != limitFailedExit {
+		return err
+	}
+	i := strings.Index(out, limitFailedMarker)
+	if i < 0 {
+		return err
+	}
+	msg, _, _ := strings.Cut(out[i+len("ai-intern-sandbox: "):], "\n")
+	return fmt.Errorf("sandbox could not apply resource limits: %s: %s", msg, strings.TrimSpace(out[:i]))
+}
+
+// runCapture runs cmd and returns stdout followed by stderr
+func runCapture(cmd *exec.Cmd) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stdout.String() + stderr.String(), err
+}
+
+// copyTree copies the regular files and directories under src into dst,
+// skipping VCS metadata and the agent's own state directory. Symlinks are
+// recreated rather than followed - except ones that point outside the tree,
+// which are dropped: a planted link must not reach the host.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (rel == ".git" || rel == ".ai-intern") {
+			return fs.SkipDir
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, 0755)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if escapes(rel, link) {
+				return nil
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			return copyFile(path, target)
+		default:
+			return nil // sockets, devices, etc.
+		}
+	})
+}
+
+// escapes reports whether a symlink at rel (relative to the tree root)
+// pointing to link resolves outside the tree
+func escapes(rel, link string) bool {
+	if filepath.IsAbs(link) {
+		return true
+	}
+	resolved := filepath.Join(filepath.Dir(rel), link)
+	return resolved == ".." || strings.HasPrefix(resolved, ".."+string(filepath.Separator))
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "...(truncated)"
+}
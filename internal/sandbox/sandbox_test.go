@@ -0,0 +1,257 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	logger "github.com/jenish-jain/logger"
+)
+
+func init() {
+	// Initialize logger for tests
+	logger.Init("error")
+}
+
+func testConfig(t *testing.T) Config {
+	cfg := DefaultConfig()
+	cfg.Timeout = 30 * time.Second
+	cfg.CacheDir = t.TempDir()
+	return cfg
+}
+
+func TestScrubEnv(t *testing.T) {
+	environ := []string{
+		"PATH=/usr/bin",
+		"GITHUB_TOKEN=secret",
+		"ANTHROPIC_API_KEY=secret",
+		"GOFLAGS=-count=1",
+		"MY_VAR=kept",
+		"malformed",
+	}
+
+	got := ScrubEnv(environ, []string{" MY_VAR "})
+	want := []string{"PATH=/usr/bin", "GOFLAGS=-count=1", "MY_VAR=kept"}
+
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ScrubEnv() = %v, want %v", got, want)
+	}
+}
+
+func TestNew_SelectsExecutor(t *testing.T) {
+	cfg := testConfig(t)
+
+	if _, ok := New(cfg).(*Sandbox); !ok {
+		t.Error("Expected *Sandbox when enabled")
+	}
+
+	cfg.Enabled = false
+	if _, ok := New(cfg).(*HostExecutor); !ok {
+		t.Error("Expected *HostExecutor when disabled")
+	}
+}
+
+func TestExecutors_HideSecrets(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "ghp_should_not_leak")
+
+	for _, enabled := range []bool{true, false} {
+		cfg := testConfig(t)
+		cfg.Enabled = enabled
+
+		out, err := New(cfg).Run(context.Background(), t.TempDir(), "sh", "-c", "echo token=$GITHUB_TOKEN")
+		if err != nil {
+			t.Fatalf("enabled=%v: unexpected error: %v (output: %s)", enabled, err, out)
+		}
+		if strings.Contains(out, "ghp_should_not_leak") {
+			t.Errorf("enabled=%v: secret leaked into command output: %q", enabled, out)
+		}
+	}
+}
+
+func TestSandbox_WritesDoNotReachSource(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(src, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := New(testConfig(t)).Run(context.Background(), src, "sh", "-c",
+		"test -f main.go && test ! -d .git && echo changed > main.go && touch created.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v (output: %s)", err, out)
+	}
+
+	data, err := os.ReadFile(filepath.Join(src, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "package main\n" {
+		t.Errorf("Source file was modified: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(src, "created.txt")); !os.IsNotExist(err) {
+		t.Error("File created in sandbox leaked into source dir")
+	}
+}
+
+func TestSandbox_RewritesWorkDirPaths(t *testing.T) {
+	out, err := New(testConfig(t)).Run(context.Background(), t.TempDir(), "sh", "-c", `echo "$PWD/pkg/a.go:1"`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.TrimSpace(out) != "pkg/a.go:1" {
+		t.Errorf("Expected repo-relative path, got %q", out)
+	}
+}
+
+func TestExecutors_Timeout(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		cfg := testConfig(t)
+		cfg.Enabled = enabled
+		cfg.Timeout = 200 * time.Millisecond
+
+		start := time.Now()
+		_, err := New(cfg).Run(context.Background(), t.TempDir(), "sleep", "10")
+		if err == nil {
+			t.Errorf("enabled=%v: expected timeout error", enabled)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("enabled=%v: timeout not enforced, took %v", enabled, elapsed)
+		}
+	}
+}
+
+func TestExecutors_FailingCommand(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		cfg := testConfig(t)
+		cfg.Enabled = enabled
+
+		out, err := New(cfg).Run(context.Background(), t.TempDir(), "sh", "-c", "echo boom >&2; exit 3")
+		if err == nil {
+			t.Errorf("enabled=%v: expected error for non-zero exit", enabled)
+		}
+		if !strings.Contains(out, "boom") {
+			t.Errorf("enabled=%v: expected stderr in output, got %q", enabled, out)
+		}
+	}
+}
+
+// brokenIsolation refuses to run anything, like a host that refuses
+// namespaces
+func brokenIsolation(cmd *exec.Cmd, v view) (string, error) {
+	return "", unavailable(errors.New("operation not permitted"))
+}
+
+func TestSandbox_RefusesWithoutIsolation(t *testing.T) {
+	sb := New(testConfig(t)).(*Sandbox)
+	sb.isolate = brokenIsolation
+
+	for i := 0; i < 2; i++ { // The failure is remembered, not retried degraded
+		out, err := sb.Run(context.Background(), t.TempDir(), "sh", "-c", "echo ran")
+		if !errors.Is(err, ErrIsolationUnavailable) {
+			t.Fatalf("run %d: expected ErrIsolationUnavailable, got %v", i, err)
+		}
+		if strings.Contains(out, "ran") {
+			t.Errorf("run %d: command ran without isolation: %q", i, out)
+		}
+		if !strings.Contains(out, "SANDBOX_ALLOW_DEGRADED") {
+			t.Errorf("run %d: expected the opt-in to be named, got %q", i, out)
+		}
+	}
+}
+
+func TestSandbox_AllowDegraded(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.AllowDegraded = true
+	sb := New(cfg).(*Sandbox)
+	sb.isolate = brokenIsolation
+
+	out, err := sb.Run(context.Background(), t.TempDir(), "id", "-u")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v (output: %s)", err, out)
+	}
+	if os.Geteuid() == 0 && strings.TrimSpace(out) != "65534" {
+		t.Errorf("Expected degraded command to drop to uid 65534, got %q", out)
+	}
+}
+
+func TestSandbox_HidesHostFilesAndProcesses(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("sandbox isolation needs Linux namespaces")
+	}
+	t.Setenv("GITHUB_TOKEN", "ghp_should_not_leak")
+	secret := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(secret, []byte("JIRA_API_TOKEN=should_not_leak\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// World-readable all the way down, as a .env copied from .env.example is
+	for dir := filepath.Dir(secret); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if info, err := os.Stat(dir); err == nil && info.Mode().Perm()&0005 != 0005 {
+			os.Chmod(dir, info.Mode().Perm()|0005)
+		}
+	}
+
+	script := fmt.Sprintf("cat %s; cat /proc/%d/environ; test -e %s && echo visible",
+		secret, os.Getpid(), filepath.Dir(secret))
+	out, err := New(testConfig(t)).Run(context.Background(), t.TempDir(), "sh", "-c", script)
+	if errors.Is(err, ErrIsolationUnavailable) {
+		t.Fatalf("Isolation unavailable: %v", err)
+	}
+	for _, leak := range []string{"should_not_leak", "visible"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("Sandboxed command could see the host (%q): %s", leak, out)
+		}
+	}
+}
+
+func TestCopyTree_DropsEscapingSymlinks(t *testing.T) {
+	src, dst := t.TempDir(), filepath.Join(t.TempDir(), "copy")
+	if err := os.MkdirAll(filepath.Join(src, "pkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"abs":        "/etc/passwd",
+		"pkg/up":     "../../outside",
+		"pkg/parent": "..",
+		"pkg/inside": "../go.mod",
+		"sibling":    "pkg",
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(src, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := copyTree(src, dst); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for link, kept := range map[string]bool{"abs": false, "pkg/up": false, "pkg/parent": true, "pkg/inside": true, "sibling": true} {
+		_, err := os.Lstat(filepath.Join(dst, link))
+		if got := err == nil; got != kept {
+			t.Errorf("%s -> %s: copied=%v, want %v", link, links[link], got, kept)
+		}
+	}
+}
+
+func TestLimitError(t *testing.T) {
+	out, err := runCapture(exec.Command("sh", "-c",
+		"echo 'sh: ulimit: Operation not permitted' >&2; echo '"+limitFailedMarker+"CPU time limit (ulimit -t 600)' >&2; exit 125"))
+	got := limitError(out, err)
+	if got == nil || !strings.Contains(got.Error(), "could not apply resource limits: failed to set CPU time limit (ulimit -t 600): sh: ulimit: Operation not permitted") {
+		t.Errorf("Expected limit failure to be reported, got %v", got)
+	}
+
+	// A command's own exit status is left alone
+	out, err = runCapture(exec.Command("sh", "-c", "exit 125"))
+	if got := limitError(out, err); got != err {
+		t.Errorf("Expected command error unchanged, got %v", got)
+	}
+}
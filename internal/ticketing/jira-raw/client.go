@@ -177,7 +177,7 @@ func (c *client) GetTickets(ctx context.Context, assignee, project string) ([]ti
 	params := url.Values{}
 	params.Set("jql", jql)
 	params.Set("maxResults", "100")
-	params.Set("fields", "id,key,summary,description,status,priority,assignee,reporter")
+	params.Set("fields", "id,key,summary,description,status,priority,assignee,reporter,components,labels")
 	params.Set("expand", "schema,names")
 
 	endpoint := "/rest/api/3/search/jql?" + params.Encode()
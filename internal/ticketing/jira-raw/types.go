@@ -63,8 +63,12 @@ type Issue struct {
 			Name    string `json:"name"`
 			ID      string `json:"id"`
 		} `json:"priority"`
-		Assignee *User `json:"assignee"`
-		Reporter *User `json:"reporter"`
+		Assignee   *User `json:"assignee"`
+		Reporter   *User `json:"reporter"`
+		Components []struct {
+			Name string `json:"name"`
+		} `json:"components"`
+		Labels []string `json:"labels"`
 	} `json:"fields"`
 }
 
@@ -167,6 +171,10 @@ func (i *Issue) ToTicket() ticketing.Ticket {
 		Status:      i.Fields.Status.Name,
 		Priority:    i.Fields.Priority.Name,
 		URL:         i.Self,
+		Labels:      i.Fields.Labels,
+	}
+	for _, c := range i.Fields.Components {
+		ticket.Components = append(ticket.Components, c.Name)
 	}
 
 	// Handle assignee
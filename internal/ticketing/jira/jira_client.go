@@ -52,6 +52,10 @@ func (c *client) GetTickets(ctx context.Context, assignee, project string) ([]ti
 	}
 	var tickets []ticketing.Ticket
 	for _, issue := range issues {
+		var components []string
+		for _, c := range issue.Fields.Components {
+			components = append(components, c.Name)
+		}
 		tickets = append(tickets, ticketing.Ticket{
 			ID:          issue.ID,
 			Key:         issue.Key,
@@ -62,6 +66,8 @@ func (c *client) GetTickets(ctx context.Context, assignee, project string) ([]ti
 			Assignee:    getUserName(issue.Fields.Assignee),
 			Reporter:    getUserName(issue.Fields.Reporter),
 			URL:         issue.Self,
+			Components:  components,
+			Labels:      issue.Fields.Labels,
 		})
 	}
 	logger.Debug("fetched tickets from JIRA", "tickets", tickets)
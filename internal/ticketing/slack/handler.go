@@ -26,22 +26,23 @@ const eventDedupeWindow = 10 * time.Minute
 var mentionPrefix = regexp.MustCompile(`^\s*<@[A-Z0-9]+>\s*`)
 
 // Handler serves Slack's Events API webhook (POST /slack/events) and turns
-// incoming messages into single-ticket runs on the coordinator. This is the
-// request-driven entrypoint the Cloud Run deployment triggers on.
+// incoming messages into single-ticket runs, routed to a repository by the
+// dispatcher. This is the request-driven entrypoint the Cloud Run deployment
+// triggers on.
 type Handler struct {
 	client        *Client
 	signingSecret string
-	coordinator   *orchestrator.Coordinator
+	dispatcher    *orchestrator.Dispatcher
 
 	mu   sync.Mutex
 	seen map[string]time.Time // event_id -> received time, dedupes Slack retries
 }
 
-func NewHandler(client *Client, signingSecret string, coordinator *orchestrator.Coordinator) *Handler {
+func NewHandler(client *Client, signingSecret string, dispatcher *orchestrator.Dispatcher) *Handler {
 	return &Handler{
 		client:        client,
 		signingSecret: signingSecret,
-		coordinator:   coordinator,
+		dispatcher:    dispatcher,
 		seen:          make(map[string]time.Time),
 	}
 }
@@ -166,19 +167,13 @@ func (h *Handler) handleCallback(ctx context.Context, event slackevents.EventsAP
 	summary := summarize(ask)
 	_ = h.client.PostReply(ctx, key, fmt.Sprintf("On it — working on: %s", summary))
 
-	if err := h.coordinator.PrepareRepository(ctx); err != nil {
-		logger.Error("Slack: repository preparation failed", "error", err)
-		_ = h.client.PostReply(ctx, key, fmt.Sprintf("Couldn't prepare the repository: %v", err))
-		return
-	}
-
-	if err := h.coordinator.ProcessTicket(ctx, key, summary, ask); err != nil {
+	if err := h.dispatcher.ProcessTicket(ctx, key, summary, ask); err != nil {
 		logger.Error("Slack: ticket processing failed", "key", key, "error", err)
 		_ = h.client.PostReply(ctx, key, fmt.Sprintf("Failed: %v%s", err, h.costSuffix(key)))
 		return
 	}
 
-	if entry, ok := h.coordinator.Journal.Find(key); ok && entry.PRURL != "" {
+	if entry, ok := h.dispatcher.JournalEntry(key); ok && entry.PRURL != "" {
 		_ = h.client.PostReply(ctx, key, fmt.Sprintf("Done — opened %s%s", entry.PRURL, h.costSuffix(key)))
 	} else {
 		_ = h.client.PostReply(ctx, key, fmt.Sprintf("Done.%s", h.costSuffix(key)))
@@ -190,7 +185,7 @@ func (h *Handler) handleCallback(ctx context.Context, event slackevents.EventsAP
 // smart context selection was used), or "" if no metrics were recorded yet
 // (e.g. processing failed before any AI call was made).
 func (h *Handler) costSuffix(key string) string {
-	tm, ok := h.coordinator.LastTicketMetrics(key)
+	tm, ok := h.dispatcher.LastTicketMetrics(key)
 	if !ok || (tm.InputTokens == 0 && tm.OutputTokens == 0) {
 		return ""
 	}
@@ -10,6 +10,8 @@ type Ticket struct {
 	Assignee    string
 	Reporter    string
 	URL         string
+	Components  []string // JIRA components, used for repository routing
+	Labels      []string
 }
 
 var PriorityMap = map[string]int{